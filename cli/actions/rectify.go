@@ -19,7 +19,7 @@ func (sr *Rectify) Do() error {
 		return err
 	}
 
-	if err := sr.Resolver.Begin(gdm, sr.State.Defs.Clusters).Wait(); err != nil {
+	if err := sr.Resolver.Begin(gdm, sr.State.Defs.Clusters, sr.State.Defs.FreezeWindows).Wait(); err != nil {
 		return err
 	}
 
@@ -9,6 +9,7 @@ import (
 
 	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/ext/git"
+	"github.com/opentable/sous/ext/webhook"
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/server"
 	"github.com/opentable/sous/util/logging"
@@ -28,6 +29,14 @@ type Server struct {
 	*config.Config
 	ServerHandler http.Handler
 	*sous.AutoResolver
+	Webhooks *webhook.Notifier
+	// R11nStore, if not nil, keeps the rectification queues across restarts.
+	R11nStore sous.R11nStore
+	// Membership, if not nil, finds the sibling servers by gossip.
+	Membership *sous.Membership
+	// Leadership, if not nil, elects the server which rectifies each
+	// cluster.
+	Leadership *sous.Leadership
 }
 
 // Do runs the server.
@@ -38,13 +47,76 @@ func (ss *Server) Do() error {
 
 	reportServerMessage("Starting scheduled GDM resolution.  Filtering the GDM to resolve on this server", ss.DeployFilterFlags, ss.ListenAddr, ss.Log)
 
+	ss.restoreQueues()
 	ss.AutoResolver.Kickoff()
+	// The server runs until the process exits, so the notifier does too.
+	ss.Webhooks.Start(nil)
+	ss.Membership.Start(nil)
+	ss.Leadership.Start(nil)
 
 	reportServerMessage("Sous Server Running", ss.DeployFilterFlags, ss.ListenAddr, ss.Log)
 
+	if ac := ss.Config.Authorization; ac.TLSCert != "" {
+		return server.RunTLS(ss.ListenAddr, ss.ServerHandler, ac.TLSCert, ac.TLSKey, ac.ClientCA)
+	}
 	return server.Run(ss.ListenAddr, ss.ServerHandler)
 }
 
+// restoreQueues makes the rectification queues durable, and restores any
+// rectifications left queued when the server last stopped which are still
+// wanted. If the store cannot be read, or the current state cannot be
+// gathered to re-resolve them against, the server runs with queues held only
+// in memory.
+func (ss *Server) restoreQueues() {
+	if ss.R11nStore == nil {
+		return
+	}
+	rez := ss.AutoResolver.Resolver
+	basis, err := ss.restoreBasis()
+	if err != nil {
+		msg := fmt.Sprintf("Not persisting rectification queues: reading current state: %v", err)
+		reportServerMessage(msg, ss.DeployFilterFlags, ss.ListenAddr, ss.Log)
+		return
+	}
+	gate := &sous.DeployerRolloutGate{Deployer: rez.Deployer, Registry: rez.Registry}
+	restored, err := rez.QueueSet.Persist(ss.R11nStore, rez.ResolveFilter, basis, gate, ss.Log)
+	if err != nil {
+		msg := fmt.Sprintf("Not persisting rectification queues: %v", err)
+		reportServerMessage(msg, ss.DeployFilterFlags, ss.ListenAddr, ss.Log)
+		return
+	}
+	msg := fmt.Sprintf("Restored %d queued rectifications", len(restored))
+	reportServerMessage(msg, ss.DeployFilterFlags, ss.ListenAddr, ss.Log)
+}
+
+// restoreBasis gathers the current GDM and running deployments for stored
+// rectifications to be re-resolved against. It contends for the lease on each
+// cluster first, so that only the clusters this server leads are restored.
+func (ss *Server) restoreBasis() (sous.R11nRestoreBasis, error) {
+	rez := ss.AutoResolver.Resolver
+	state, err := ss.AutoResolver.StateReader.ReadState()
+	if err != nil {
+		return sous.R11nRestoreBasis{}, err
+	}
+	intended, err := state.Deployments()
+	if err != nil {
+		return sous.R11nRestoreBasis{}, err
+	}
+	clusters := rez.FilteredClusters(state.Defs.Clusters)
+	if ss.Leadership != nil {
+		ss.Leadership.Contend(clusters.Names()...)
+	}
+	actual, err := rez.Deployer.RunningDeployments(rez.Registry, clusters)
+	if err != nil {
+		return sous.R11nRestoreBasis{}, err
+	}
+	return sous.R11nRestoreBasis{
+		Intended: intended,
+		Actual:   actual,
+		Leads:    ss.Leadership.Leads,
+	}, nil
+}
+
 func ensureGDMExists(repo, localPath string, filterFlags config.DeployFilterFlags, listenAddress string, log logging.LogSink) error {
 	s, err := os.Stat(localPath)
 	if err == nil && s.IsDir() {
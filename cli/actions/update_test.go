@@ -122,7 +122,7 @@ func TestUpdateRetryLoop(t *testing.T) {
 
 	ls := logging.SilentLogSet()
 
-	deps, err := updateRetryLoop(ls, cl, sourceID, depID, user)
+	deps, err := updateRetryLoop(ls, cl, sourceID, depID, user, "")
 
 	assert.NoError(t, err)
 	assert.Equal(t, 1, deps.Len())
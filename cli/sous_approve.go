@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousApprove is the description of the `sous approve` command.
+type SousApprove struct {
+	graph.HTTPClient
+	User  sous.User
+	flags struct {
+		id     string
+		reject bool
+	}
+}
+
+func init() { TopLevelCommands["approve"] = &SousApprove{} }
+
+const sousApproveHelp = `approves a change awaiting approval
+
+usage: sous approve -id <pending change id> [-reject]
+
+Approving a pending change writes it to the GDM, so Sous will deploy it. You
+cannot approve your own changes: you must be an owner of each manifest the
+change affects, or an approver for each cluster. Use -reject to reject the
+change instead; the author of a change may reject it to withdraw it.
+
+Use 'sous pending' to list the changes awaiting approval.
+`
+
+// Help prints the help
+func (*SousApprove) Help() string { return sousApproveHelp }
+
+// AddFlags adds the flags for sous approve.
+func (sa *SousApprove) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&sa.flags.id, "id", "", "the ID of the pending change")
+	fs.BoolVar(&sa.flags.reject, "reject", false, "reject the change instead of approving it")
+}
+
+// RegisterOn adds flag options to the graph.
+func (*SousApprove) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+}
+
+// Execute defines the behavior of `sous approve`
+func (sa *SousApprove) Execute(args []string) cmdr.Result {
+	if sa.flags.id == "" {
+		return cmdr.UsageErrorf("-id is required")
+	}
+
+	body := server.PendingChangeBody{}
+	updater, err := sa.Retrieve("./pending-change", map[string]string{"id": sa.flags.id}, &body, sa.User.HTTPHeaders())
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	if body.Status != sous.PendingStatusPending {
+		return cmdr.UsageErrorf("pending change %s is already %s", body.ID, body.Status)
+	}
+
+	body.Status = sous.PendingStatusApproved
+	if sa.flags.reject {
+		body.Status = sous.PendingStatusRejected
+	}
+	if _, err := updater.Update(&body, sa.User.HTTPHeaders()); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "Pending change %s by %s was %s, with these changes:\n", body.ID, body.Author, body.Status)
+	w := &tabwriter.Writer{}
+	w.Init(out, 2, 4, 2, ' ', 0)
+	for _, dc := range body.Changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", dc.Kind, dc.DeploymentID, strings.Join(dc.Diffs, "; "))
+	}
+	w.Flush()
+
+	return cmdr.SuccessData(out.Bytes())
+}
@@ -4,9 +4,12 @@ import (
 	"flag"
 	"fmt"
 
+	"github.com/opentable/sous/cli/actions"
 	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/cmdr"
+	"github.com/pkg/errors"
 )
 
 // SousDeploy is the command description for `sous deploy`.
@@ -18,6 +21,7 @@ type SousDeploy struct {
 	DeployFilterFlags config.DeployFilterFlags `inject:"optional"`
 	dryrunOption      string
 	waitStable        bool
+	overrideFreeze    string
 }
 
 func init() { TopLevelCommands["deploy"] = &SousDeploy{} }
@@ -28,6 +32,9 @@ usage: sous deploy -cluster <name> -tag <semver>
 
 sous deploy will deploy the version tag for this application in the named
 cluster.
+
+If the cluster is frozen, the deploy is rejected unless -override-freeze is
+given with the reason for overriding the freeze. Overrides are audited.
 `
 
 // Help returns the help string for this command.
@@ -42,6 +49,8 @@ func (sd *SousDeploy) AddFlags(fs *flag.FlagSet) {
 	fs.StringVar(&sd.dryrunOption, "dry-run", "none",
 		"prevent rectify from actually changing things - "+
 			"values are none,scheduler,registry,both")
+	fs.StringVar(&sd.overrideFreeze, "override-freeze", "",
+		"deploy even if the cluster is frozen, giving the reason for overriding the freeze")
 }
 
 // Execute fulfills the cmdr.Executor interface.
@@ -51,7 +60,14 @@ func (sd *SousDeploy) Execute(args []string) cmdr.Result {
 	if err != nil {
 		return cmdr.EnsureErrorResult(err)
 	}
+	if sd.overrideFreeze != "" {
+		update.(*actions.Update).FreezeOverride = sd.overrideFreeze
+	}
 	if err := update.Do(); err != nil {
+		if pa, is := errors.Cause(err).(*sous.PendingApprovalError); is {
+			return cmdr.Successf("Deploy is awaiting approval as pending change %s.\n"+
+				"Another owner of the manifest can approve it with: sous approve -id %s", pa.ID, pa.ID)
+		}
 		return cmdr.EnsureErrorResult(err)
 	}
 
@@ -63,10 +63,16 @@ func (smg *SousManifestSet) Execute(args []string) cmdr.Result {
 
 	messages.ReportLogFieldsMessage("Manifest in Execute", logging.ExtraDebug1Level, smg.LogSink, yml)
 
-	_, err = up.Update(&yml, nil)
+	updated, err := up.Update(&yml, smg.User.HTTPHeaders())
 	if err != nil {
 		return EnsureErrorResult(err)
 	}
+	if updated != nil {
+		if pa := sous.PendingApprovalFromLocation(updated.Location()); pa != nil {
+			return cmdr.Successf("Changes to clusters requiring approval are awaiting approval as pending change %s.\n"+
+				"Another owner of the manifest can approve them with: sous approve -id %s", pa.ID, pa.ID)
+		}
+	}
 
 	return cmdr.Success()
 }
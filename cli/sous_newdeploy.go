@@ -5,6 +5,7 @@ import (
 
 	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/server"
 	"github.com/opentable/sous/util/cmdr"
 	"github.com/opentable/sous/util/logging"
@@ -21,6 +22,7 @@ type SousNewDeploy struct {
 	HTTPClient        *graph.ClusterSpecificHTTPClient
 	TargetManifestID  graph.TargetManifestID
 	LogSink           graph.LogSink
+	User              sous.User
 	dryrunOption      string
 	waitStable        bool
 }
@@ -80,12 +82,16 @@ func (sd *SousNewDeploy) Execute(args []string) cmdr.Result {
 
 	d.Deployment.SourceID.Version = newVersion
 
-	updateResponse, err := updater.Update(d, nil)
+	updateResponse, err := updater.Update(d, sd.User.HTTPHeaders())
 	if err != nil {
 		return cmdr.EnsureErrorResult(err)
 	}
 
 	location := updateResponse.Location()
+	if pa := sous.PendingApprovalFromLocation(location); pa != nil {
+		return cmdr.Successf("Deployment is awaiting approval as pending change %s.\n"+
+			"Another owner of the manifest can approve it with: sous approve -id %s", pa.ID, pa.ID)
+	}
 
 	return cmdr.Successf("Deployment queued at: %s", location)
 }
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"flag"
+	"time"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPause is the description of the `sous pause` command.
+type SousPause struct {
+	DeployFilterFlags config.DeployFilterFlags `inject:"optional"`
+	TargetManifestID  graph.TargetManifestID
+	graph.HTTPClient
+	User  sous.User
+	flags struct {
+		reason string
+		length time.Duration
+	}
+}
+
+func init() { TopLevelCommands["pause"] = &SousPause{} }
+
+const sousPauseHelp = `stops Sous from rectifying a deployment for a while
+
+usage: sous pause -cluster <name> -reason <reason> [-for <duration>]
+
+While a deployment is paused, Sous leaves it as it is running, even if it
+differs from the GDM, so that it can be fixed by hand, e.g. directly in
+Singularity during an incident. The pause takes effect from the server's next
+resolution, and lapses by itself after the given duration.
+
+Use 'sous resume' to resume the deployment before then.
+`
+
+// Help prints the help
+func (*SousPause) Help() string { return sousPauseHelp }
+
+// AddFlags adds the flags for sous pause.
+func (sp *SousPause) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sp.DeployFilterFlags, MetadataFilterFlagsHelp)
+	fs.StringVar(&sp.flags.reason, "reason", "", "why the deployment is paused")
+	fs.DurationVar(&sp.flags.length, "for", time.Hour, "how long until the pause lapses")
+}
+
+// RegisterOn adds flag options to the graph.
+func (sp *SousPause) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+	psy.Add(&sp.DeployFilterFlags)
+}
+
+// Execute defines the behavior of `sous pause`
+func (sp *SousPause) Execute(args []string) cmdr.Result {
+	if sp.DeployFilterFlags.Cluster == "" {
+		return cmdr.UsageErrorf("-cluster is required")
+	}
+	if sp.flags.reason == "" {
+		return cmdr.UsageErrorf("-reason is required")
+	}
+	if sp.flags.length <= 0 {
+		return cmdr.UsageErrorf("-for must be positive, not %s", sp.flags.length)
+	}
+
+	q := sp.TargetManifestID.QueryMap()
+	q["cluster"] = sp.DeployFilterFlags.Cluster
+	body := server.PauseBody{}
+	updater, err := sp.Retrieve("./pause", q, &body, sp.User.HTTPHeaders())
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	until := time.Now().Add(sp.flags.length)
+	body.Pause = &sous.Pause{Reason: sp.flags.reason, Until: until}
+	if _, err := updater.Update(&body, sp.User.HTTPHeaders()); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	return cmdr.Successf("Paused %s in %s until %s.", sous.ManifestID(sp.TargetManifestID), sp.DeployFilterFlags.Cluster, until.Format(time.RFC3339))
+}
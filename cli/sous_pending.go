@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPending is the description of the `sous pending` command.
+type SousPending struct {
+	graph.HTTPClient
+}
+
+func init() { TopLevelCommands["pending"] = &SousPending{} }
+
+const sousPendingHelp = `lists changes awaiting approval
+
+usage: sous pending
+
+Changes to deployments in clusters which require approval are held as pending
+changes until someone other than their author approves them with 'sous approve'.
+`
+
+// Help prints the help
+func (*SousPending) Help() string { return sousPendingHelp }
+
+// RegisterOn adds flag options to the graph.
+func (*SousPending) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+}
+
+// Execute defines the behavior of `sous pending`
+func (sp *SousPending) Execute(args []string) cmdr.Result {
+	pending := server.PendingChangesBody{}
+	if _, err := sp.Retrieve("./pending-changes", nil, &pending, nil); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	out := &bytes.Buffer{}
+	w := &tabwriter.Writer{}
+	w.Init(out, 2, 4, 2, ' ', 0)
+	for _, pc := range pending.Changes {
+		for _, pd := range pc.Deployments {
+			change := "remove"
+			if pd.Deployment != nil {
+				change = pd.Deployment.SourceID.Version.String()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				pc.ID,
+				pc.Proposed.Format(time.RFC3339),
+				pc.Author.Email,
+				pd.DeploymentID,
+				change,
+			)
+		}
+	}
+	w.Flush()
+
+	return cmdr.SuccessData(out.Bytes())
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	sing "github.com/opentable/go-singularity"
+	"github.com/opentable/sous/ext/singularity"
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPlumbingImport is the description of the `sous plumbing import` command
+type SousPlumbingImport struct {
+	*sous.State
+	sous.Registry
+	StateWriter graph.StateWriter
+	User        sous.User
+	LogSink     graph.LogSink
+	flags       struct {
+		cluster string
+		adopt   bool
+	}
+}
+
+func init() { PlumbingSubcommands["import"] = &SousPlumbingImport{} }
+
+// Help prints the help
+func (*SousPlumbingImport) Help() string {
+	return `Imports the Singularity requests of a cluster which Sous does not manage.
+
+usage: sous plumbing import -cluster <cluster> [-adopt]
+
+Each request's current deploy, and the Sous labels on its Docker image, are
+used to reconstruct a deployment to the cluster, and the changes adding them
+to the GDM would make are listed. Requests which cannot be imported are
+listed with the reason.
+
+With -adopt, each request is deployed again with the metadata Sous uses to
+recognise its own deploys, and the imported deployments are written to the
+GDM. Sous manages the requests from then on.
+`
+}
+
+// AddFlags adds the flags for sous plumbing import.
+func (spi *SousPlumbingImport) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&spi.flags.cluster, "cluster", "", "the cluster whose Singularity requests to import")
+	fs.BoolVar(&spi.flags.adopt, "adopt", false, "bring the imported requests under Sous's management")
+}
+
+// RegisterOn adds flag options to the graph.
+func (*SousPlumbingImport) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+}
+
+// Execute defines the behavior of `sous plumbing import`
+func (spi *SousPlumbingImport) Execute(args []string) cmdr.Result {
+	cluster, ok := spi.State.Defs.Clusters[spi.flags.cluster]
+	if !ok {
+		return cmdr.UsageErrorf("cluster %q not defined, pick one of: %s", spi.flags.cluster, spi.State.Defs.Clusters)
+	}
+	if kind := cluster.NormalizedKind(); kind != sous.ClusterKindSingularity {
+		return cmdr.UsageErrorf("cluster %q is a %s cluster; only Singularity requests can be imported", spi.flags.cluster, kind)
+	}
+	if cluster.Name == "" {
+		cluster = cluster.Clone()
+		cluster.Name = spi.flags.cluster
+	}
+
+	client := sing.NewClient(cluster.BaseURL, spi.LogSink.LogSink)
+	imports, skipped, err := singularity.ImportRequests(client, spi.Registry, cluster, spi.LogSink.Child("import"))
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	// Requests are adopted before the GDM is written, so that Sous never sees
+	// an imported deployment in the GDM without a deploy it recognises, which
+	// it would create a second request for.
+	if spi.flags.adopt {
+		ra := singularity.NewRectiAgent(spi.Registry)
+		adopted := imports[:0]
+		for _, imp := range imports {
+			if err := ra.Adopt(imp); err != nil {
+				skipped[imp.RequestID] = fmt.Errorf("adopting: %s", err)
+				continue
+			}
+			adopted = append(adopted, imp)
+		}
+		imports = adopted
+	}
+
+	current, err := spi.State.Deployments()
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	proposed := current.Clone()
+	deps := make([]*sous.Deployment, len(imports))
+	for i, imp := range imports {
+		deps[i] = imp.Deployable.Deployment
+		proposed.Set(deps[i].ID(), deps[i])
+	}
+	changes := sous.GDMChanges(current.Diff(proposed).Collect())
+
+	if spi.flags.adopt && len(deps) > 0 {
+		if err := spi.State.UpdateDeployments(deps...); err != nil {
+			return cmdr.EnsureErrorResult(err)
+		}
+		if err := spi.StateWriter.WriteState(spi.State, spi.User); err != nil {
+			return cmdr.EnsureErrorResult(err)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	requestIDs := map[sous.DeploymentID]string{}
+	for _, imp := range imports {
+		requestIDs[imp.Deployable.ID()] = imp.RequestID
+	}
+	if spi.flags.adopt {
+		fmt.Fprintf(out, "Adopted %d requests from %s, making these changes to the GDM:\n", len(imports), spi.flags.cluster)
+	} else {
+		fmt.Fprintf(out, "Importing %d requests from %s would make these changes to the GDM:\n", len(imports), spi.flags.cluster)
+	}
+	w := &tabwriter.Writer{}
+	w.Init(out, 2, 4, 2, ' ', 0)
+	for _, dc := range changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", dc.Kind, dc.DeploymentID, requestIDs[dc.DeploymentID], strings.Join(dc.Diffs, "; "))
+	}
+	w.Flush()
+
+	if len(skipped) > 0 {
+		fmt.Fprintf(out, "Could not import %d requests:\n", len(skipped))
+		ids := make([]string, 0, len(skipped))
+		for id := range skipped {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		w.Init(out, 2, 4, 2, ' ', 0)
+		for _, id := range ids {
+			fmt.Fprintf(w, "%s\t%s\n", id, skipped[id])
+		}
+		w.Flush()
+	}
+
+	return cmdr.SuccessData(out.Bytes())
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPlumbingLint is the description of the `sous plumbing lint` command
+type SousPlumbingLint struct {
+	graph.HTTPClient
+}
+
+func init() { PlumbingSubcommands["lint"] = &SousPlumbingLint{} }
+
+// Help prints the help
+func (*SousPlumbingLint) Help() string {
+	return `Checks the whole GDM against the lint rules in its Defs.
+
+Lists every deployment which breaks a rule. Fails if any breaks a rule of
+severity "error", or if any rule is itself invalid; changes which break such
+rules are rejected by the server.
+`
+}
+
+// RegisterOn adds flag options to the graph.
+func (*SousPlumbingLint) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+}
+
+// Execute defines the behavior of `sous plumbing lint`
+func (spl *SousPlumbingLint) Execute(args []string) cmdr.Result {
+	body := server.LintBody{}
+	if _, err := spl.Retrieve("./lint", nil, &body, nil); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	out := &bytes.Buffer{}
+	for _, r := range body.Rules {
+		fmt.Fprintf(out, "invalid rule: %s\n", r)
+	}
+	errs := 0
+	w := &tabwriter.Writer{}
+	w.Init(out, 2, 4, 2, ' ', 0)
+	for _, f := range body.Flaws {
+		if f.Severity == sous.LintError {
+			errs++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Severity, f.DeploymentID, f.Rule, f.Desc)
+	}
+	w.Flush()
+
+	if errs > 0 || len(body.Rules) > 0 {
+		return cmdr.UnknownErrorf("%sFound %d errors, %d invalid rules.", out, errs, len(body.Rules))
+	}
+	fmt.Fprintf(out, "Found no errors, and %d warnings.\n", len(body.Flaws))
+	return cmdr.SuccessData(out.Bytes())
+}
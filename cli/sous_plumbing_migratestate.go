@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/opentable/sous/ext/storage"
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPlumbingMigrateState is the description of the `sous plumbing migrate-state` command
+type SousPlumbingMigrateState struct {
+	graph.HTTPClient
+	User    sous.User
+	LogSink graph.LogSink
+	flags   struct {
+		from, to   string
+		verifyOnly bool
+	}
+}
+
+func init() { PlumbingSubcommands["migrate-state"] = &SousPlumbingMigrateState{} }
+
+// Help prints the help
+func (*SousPlumbingMigrateState) Help() string {
+	return `Copies the GDM from one storage backend to another.
+
+usage: sous plumbing migrate-state -from <location> -to <location> [-verify-only]
+       sous plumbing migrate-state -verify-only
+
+Locations are one of:
+  git:<dir>         a GDM committed to the git repository at dir
+  disk:<dir>        a GDM in dir, not committed to git
+  kv:<path>         a key-value store in the Bolt file at path
+  postgres:<conn>   a Postgres database, given by its connection string
+
+The GDM is read from -from, its flaws are repaired, and it is written to -to.
+It is then read back from -to, and every difference from what was written is
+listed, including in each deployment's Rollout, Pause, FreezeOverride and
+AutoRollback. It fails if there are any.
+
+With -verify-only, nothing is written: the GDMs at -from and -to are compared.
+Without -from and -to, the server is asked to compare the copies of the GDM
+it keeps, for instance in git and Postgres.
+`
+}
+
+// AddFlags adds the flags for sous plumbing migrate-state.
+func (spm *SousPlumbingMigrateState) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&spm.flags.from, "from", "", "the location of the GDM to copy")
+	fs.StringVar(&spm.flags.to, "to", "", "the location to copy the GDM to")
+	fs.BoolVar(&spm.flags.verifyOnly, "verify-only", false, "compare the GDMs without copying")
+}
+
+// RegisterOn adds flag options to the graph.
+func (*SousPlumbingMigrateState) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+}
+
+// Execute defines the behavior of `sous plumbing migrate-state`
+func (spm *SousPlumbingMigrateState) Execute(args []string) cmdr.Result {
+	if spm.flags.from == "" && spm.flags.to == "" && spm.flags.verifyOnly {
+		return spm.reconcileServer()
+	}
+	if spm.flags.from == "" || spm.flags.to == "" {
+		return cmdr.UsageErrorf("-from and -to are both required, unless -verify-only is given alone")
+	}
+
+	from, err := storage.OpenStateManager(spm.flags.from, spm.LogSink.Child("from"))
+	if err != nil {
+		return cmdr.UsageErrorf("-from: %s", err)
+	}
+	to, err := storage.OpenStateManager(spm.flags.to, spm.LogSink.Child("to"))
+	if err != nil {
+		return cmdr.UsageErrorf("-to: %s", err)
+	}
+
+	out := &bytes.Buffer{}
+	var cmp *sous.StateComparison
+	if spm.flags.verifyOnly {
+		if cmp, err = storage.CompareStateManagers(from, to); err != nil {
+			return cmdr.EnsureErrorResult(err)
+		}
+	} else {
+		migration, err := storage.MigrateState(from, to, spm.User)
+		if err != nil {
+			return cmdr.EnsureErrorResult(err)
+		}
+		for _, repaired := range migration.Repaired {
+			fmt.Fprintf(out, "repaired\t%s\n", repaired)
+		}
+		cmp = migration.Comparison
+	}
+	return comparisonResult(out, cmp, spm.flags.from, spm.flags.to)
+}
+
+func (spm *SousPlumbingMigrateState) reconcileServer() cmdr.Result {
+	body := server.StateReconciliationBody{}
+	if _, err := spm.Retrieve("./state-reconciliation", nil, &body, nil); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	cmp := &sous.StateComparison{Defs: body.Defs, Changes: body.Changes}
+	return comparisonResult(&bytes.Buffer{}, cmp, "the server's primary state", "its secondary")
+}
+
+// comparisonResult lists the differences in cmp after out, and fails if
+// there are any.
+func comparisonResult(out *bytes.Buffer, cmp *sous.StateComparison, from, to string) cmdr.Result {
+	writeComparison(out, cmp)
+	if !cmp.Same() {
+		return cmdr.UnknownErrorf("%s%s differs from %s in %d defs and %d deployments.",
+			out, to, from, len(cmp.Defs), len(cmp.Changes))
+	}
+	fmt.Fprintf(out, "%s matches %s.\n", to, from)
+	return cmdr.SuccessData(out.Bytes())
+}
+
+func writeComparison(out io.Writer, cmp *sous.StateComparison) {
+	w := &tabwriter.Writer{}
+	w.Init(out, 2, 4, 2, ' ', 0)
+	for _, d := range cmp.Defs {
+		fmt.Fprintf(w, "defs\t%s\n", d)
+	}
+	for _, dc := range cmp.Changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", dc.Kind, dc.DeploymentID, strings.Join(dc.Diffs, "; "))
+	}
+	w.Flush()
+}
@@ -1,6 +1,9 @@
 package cli
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/opentable/sous/ext/storage"
 	"github.com/opentable/sous/graph"
 	"github.com/opentable/sous/lib"
@@ -21,6 +24,9 @@ func (*SousPlumbingNormalizeGDM) Help() string {
 Loads and saves the GDM, such that it's storage format will be normalized.
 This is needed sometimes after manually editing the GDM, so that spurious
 formatting changes won't be considered real, conflicting updates.
+
+Also lists every env var in the GDM which breaks its definition in
+Defs.EnvVars, and fails if there are any.
 `
 }
 
@@ -35,5 +41,12 @@ func (sqa *SousPlumbingNormalizeGDM) Execute(args []string) cmdr.Result {
 	if err := dsm.WriteState(state, sous.User{}); err != nil {
 		return EnsureErrorResult(err)
 	}
+	if flaws := state.ValidateEnv(); len(flaws) > 0 {
+		descs := make([]string, len(flaws))
+		for i, f := range flaws {
+			descs[i] = fmt.Sprint(f)
+		}
+		return cmdr.UnknownErrorf("Normalized, but found %d env var violations:\n%s", len(flaws), strings.Join(descs, "\n"))
+	}
 	return cmdr.Success("Normalized.")
 }
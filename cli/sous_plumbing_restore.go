@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPlumbingRestore is the description of the `sous plumbing restore` command
+type SousPlumbingRestore struct {
+	graph.HTTPClient
+	flags struct {
+		revision, at         string
+		repo, offset, flavor string
+		dryRun               bool
+	}
+}
+
+func init() { PlumbingSubcommands["restore"] = &SousPlumbingRestore{} }
+
+// Help prints the help
+func (*SousPlumbingRestore) Help() string {
+	return `Restores the GDM, or a single manifest, to an earlier point in time.
+
+usage: sous plumbing restore (-revision <rev> | -at <time>) [-repo <repo> [-offset <dir>] [-flavor <flavor>]]
+
+The earlier state is written as a new change to the GDM, so Sous will
+rectify the clusters back to it. Cluster definitions are not restored.
+The restore is checked as any other change to the GDM would be: frozen
+clusters reject it, and changes to clusters which require approval are held
+as a pending change.
+Use -dry-run to list the changes a restore would make without making them.
+`
+}
+
+// AddFlags adds the flags for sous plumbing restore.
+func (spr *SousPlumbingRestore) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&spr.flags.revision, "revision", "", "the revision of the GDM to restore")
+	fs.StringVar(&spr.flags.at, "at", "", "restore the GDM as it was at this time (RFC3339)")
+	fs.StringVar(&spr.flags.repo, "repo", "", "restore only the manifest for this repo")
+	fs.StringVar(&spr.flags.offset, "offset", "", "the offset of the manifest to restore (requires -repo)")
+	fs.StringVar(&spr.flags.flavor, "flavor", "", "the flavor of the manifest to restore (requires -repo)")
+	fs.BoolVar(&spr.flags.dryRun, "dry-run", false, "list the changes a restore would make, without making them")
+}
+
+// RegisterOn adds flag options to the graph.
+func (*SousPlumbingRestore) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+}
+
+// Execute defines the behavior of `sous plumbing restore`
+func (spr *SousPlumbingRestore) Execute(args []string) cmdr.Result {
+	if (spr.flags.revision == "") == (spr.flags.at == "") {
+		return cmdr.UsageErrorf("exactly one of -revision or -at is required")
+	}
+
+	params := map[string]string{}
+	if spr.flags.revision != "" {
+		params["revision"] = spr.flags.revision
+	}
+	if spr.flags.at != "" {
+		if _, err := time.Parse(time.RFC3339, spr.flags.at); err != nil {
+			return cmdr.UsageErrorf("-at must be an RFC3339 time: %s", err)
+		}
+		params["at"] = spr.flags.at
+	}
+	if spr.flags.repo != "" {
+		params["repo"] = spr.flags.repo
+		params["offset"] = spr.flags.offset
+		params["flavor"] = spr.flags.flavor
+	} else if spr.flags.offset != "" || spr.flags.flavor != "" {
+		return cmdr.UsageErrorf("-offset and -flavor require -repo")
+	}
+
+	body := server.RestoreBody{}
+	updater, err := spr.Retrieve("./restore", params, &body, nil)
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	if len(body.Changes) == 0 {
+		return cmdr.Successf("Nothing to restore: the GDM already matches revision %s.", body.Revision)
+	}
+
+	if !spr.flags.dryRun {
+		updated, err := updater.Update(&body, nil)
+		if err != nil {
+			return cmdr.EnsureErrorResult(err)
+		}
+		if updated != nil {
+			if pa := sous.PendingApprovalFromLocation(updated.Location()); pa != nil {
+				return cmdr.Successf("Changes to clusters requiring approval are awaiting approval as pending change %s.\n"+
+					"Another owner of the manifest can approve them with: sous approve -id %s", pa.ID, pa.ID)
+			}
+		}
+	}
+
+	out := &bytes.Buffer{}
+	if spr.flags.dryRun {
+		fmt.Fprintf(out, "Restoring revision %s would make these changes:\n", body.Revision)
+	} else {
+		fmt.Fprintf(out, "Restored revision %s, making these changes:\n", body.Revision)
+	}
+	w := &tabwriter.Writer{}
+	w.Init(out, 2, 4, 2, ' ', 0)
+	for _, dc := range body.Changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", dc.Kind, dc.DeploymentID, strings.Join(dc.Diffs, "; "))
+	}
+	w.Flush()
+
+	return cmdr.SuccessData(out.Bytes())
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"flag"
+	"time"
+
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPlumbingToken is the description of the `sous plumbing token` command
+type SousPlumbingToken struct {
+	graph.LocalSousConfig
+	flags struct {
+		name, email string
+		ttl         time.Duration
+	}
+}
+
+func init() { PlumbingSubcommands["token"] = &SousPlumbingToken{} }
+
+// Help prints the help
+func (*SousPlumbingToken) Help() string {
+	return `Issues a token identifying a user to Sous servers.
+
+usage: sous plumbing token -name <name> -email <email> [-ttl <duration>]
+
+The token is signed with Authorization.TokenKey from this machine's config,
+which must match the key configured on the servers. The user should set it
+as User.Token in their config, or in SOUS_USER_TOKEN. The token expires after
+-ttl, 720h (30 days) by default, after which a new one must be issued.
+`
+}
+
+// AddFlags adds the flags for sous plumbing token.
+func (spt *SousPlumbingToken) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&spt.flags.name, "name", "", "the name of the user")
+	fs.StringVar(&spt.flags.email, "email", "", "the email address of the user")
+	fs.DurationVar(&spt.flags.ttl, "ttl", server.DefaultUserTokenTTL, "how long the token lasts, e.g. 720h")
+}
+
+// Execute defines the behavior of `sous plumbing token`
+func (spt *SousPlumbingToken) Execute(args []string) cmdr.Result {
+	user := sous.User{Name: spt.flags.name, Email: spt.flags.email}
+	if !user.Complete() {
+		return cmdr.UsageErrorf("both -name and -email are required")
+	}
+	key := spt.LocalSousConfig.Authorization.TokenKey
+	if key == "" {
+		return cmdr.UsageErrorf("no Authorization.TokenKey is configured")
+	}
+	if spt.flags.ttl <= 0 {
+		return cmdr.UsageErrorf("-ttl must be positive")
+	}
+	token, err := server.SignUserToken(user, []byte(key), spt.flags.ttl)
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success(token)
+}
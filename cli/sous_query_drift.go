@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousQueryDrift is the description of the `sous query drift` command.
+type SousQueryDrift struct {
+	graph.HTTPClient
+	flags struct {
+		cluster string
+	}
+}
+
+func init() { QuerySubcommands["drift"] = &SousQueryDrift{} }
+
+const sousQueryDriftHelp = `How the deployments running on the clusters differ from the GDM.
+
+Lists each deployment which the server would change if it rectified now:
+"added" deployments are in the GDM but not running, "removed" deployments are
+running but not in the GDM, and "modified" deployments are running
+differently, for instance after a manual change in Singularity. Nothing is
+changed.`
+
+// Help prints the help
+func (*SousQueryDrift) Help() string { return sousQueryDriftHelp }
+
+// RegisterOn registers items on the DI graph
+func (*SousQueryDrift) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+}
+
+// AddFlags adds the flags for sous query drift.
+func (sqd *SousQueryDrift) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&sqd.flags.cluster, "cluster", "", "only report drift in this cluster")
+}
+
+// Execute defines the behavior of `sous query drift`
+func (sqd *SousQueryDrift) Execute(args []string) cmdr.Result {
+	params := map[string]string{}
+	if sqd.flags.cluster != "" {
+		params["cluster"] = sqd.flags.cluster
+	}
+
+	body := server.DriftBody{}
+	if _, err := sqd.Retrieve("./drift", params, &body, nil); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	out := &bytes.Buffer{}
+	w := &tabwriter.Writer{}
+	w.Init(out, 2, 4, 2, ' ', 0)
+	for _, dc := range body.Changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", dc.Kind, dc.DeploymentID, strings.Join(dc.Diffs, "; "))
+	}
+	w.Flush()
+
+	report := sous.DriftReport{Clusters: body.Clusters, Changes: body.Changes}
+	counts := report.Counts()
+	for _, cluster := range body.Clusters {
+		c := counts[cluster]
+		fmt.Fprintf(out, "%s: %d drifted (%d added, %d removed, %d modified)\n",
+			cluster, c.Total(), c.Added, c.Removed, c.Modified)
+	}
+	return cmdr.SuccessData(out.Bytes())
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+type (
+	// SousQueryHistory is the description of the `sous query history` command.
+	SousQueryHistory struct {
+		graph.HTTPClient
+		flags struct {
+			repo, offset, flavor, cluster, user, since string
+			limit                                      int
+		}
+	}
+
+	// copied from server - avoiding coupling to server implemention
+	historyData struct {
+		Changes []sous.StateChange
+	}
+)
+
+func init() { QuerySubcommands["history"] = &SousQueryHistory{} }
+
+const sousQueryHistoryHelp = `The recorded history of changes to the GDM.
+
+Each change lists the user who made it, when it was made, and the
+deployments it affected. Use the flags to narrow the history to a single
+manifest, cluster or user.`
+
+// Help prints the help
+func (*SousQueryHistory) Help() string { return sousQueryHistoryHelp }
+
+// RegisterOn registers items on the DI graph
+func (*SousQueryHistory) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+	psy.Add(&config.DeployFilterFlags{})
+}
+
+// AddFlags adds the flags for sous query history.
+func (sqh *SousQueryHistory) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&sqh.flags.repo, "repo", "", "only show changes to manifests from this repo")
+	fs.StringVar(&sqh.flags.offset, "offset", "", "only show changes to manifests at this offset (requires -repo)")
+	fs.StringVar(&sqh.flags.flavor, "flavor", "", "only show changes to manifests with this flavor (requires -repo)")
+	fs.StringVar(&sqh.flags.cluster, "cluster", "", "only show changes to deployments in this cluster")
+	fs.StringVar(&sqh.flags.user, "user", "", "only show changes made by this user (name or email)")
+	fs.StringVar(&sqh.flags.since, "since", "", "only show changes made at or after this time (RFC3339)")
+	fs.IntVar(&sqh.flags.limit, "limit", 20, "the maximum number of changes to show; 0 for all")
+}
+
+// Execute defines the behavior of `sous query history`
+func (sqh *SousQueryHistory) Execute(args []string) cmdr.Result {
+	params := map[string]string{}
+	if sqh.flags.repo != "" {
+		params["repo"] = sqh.flags.repo
+		params["offset"] = sqh.flags.offset
+		params["flavor"] = sqh.flags.flavor
+	}
+	if sqh.flags.cluster != "" {
+		params["cluster"] = sqh.flags.cluster
+	}
+	if sqh.flags.user != "" {
+		params["user"] = sqh.flags.user
+	}
+	if sqh.flags.since != "" {
+		if _, err := time.Parse(time.RFC3339, sqh.flags.since); err != nil {
+			return cmdr.UsageErrorf("-since must be an RFC3339 time: %s", err)
+		}
+		params["since"] = sqh.flags.since
+	}
+	if sqh.flags.limit > 0 {
+		params["limit"] = strconv.Itoa(sqh.flags.limit)
+	}
+
+	history := &historyData{}
+	if _, err := sqh.Retrieve("./history", params, history, nil); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	out := &bytes.Buffer{}
+	w := &tabwriter.Writer{}
+	w.Init(out, 2, 4, 2, ' ', 0)
+
+	for _, change := range history.Changes {
+		for _, dc := range change.Deployments {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+				change.ID,
+				change.At.Format(time.RFC3339),
+				change.User.Email,
+				dc.Kind,
+				dc.DeploymentID,
+				strings.Join(dc.Diffs, "; "),
+			)
+		}
+	}
+	w.Flush()
+
+	return cmdr.SuccessData(out.Bytes())
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousResume is the description of the `sous resume` command.
+type SousResume struct {
+	DeployFilterFlags config.DeployFilterFlags `inject:"optional"`
+	TargetManifestID  graph.TargetManifestID
+	graph.HTTPClient
+	User sous.User
+}
+
+func init() { TopLevelCommands["resume"] = &SousResume{} }
+
+const sousResumeHelp = `resumes a deployment paused by 'sous pause'
+
+usage: sous resume -cluster <name>
+
+Sous rectifies the deployment again from the server's next resolution,
+reverting any changes made by hand while it was paused.
+`
+
+// Help prints the help
+func (*SousResume) Help() string { return sousResumeHelp }
+
+// AddFlags adds the flags for sous resume.
+func (sr *SousResume) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sr.DeployFilterFlags, MetadataFilterFlagsHelp)
+}
+
+// RegisterOn adds flag options to the graph.
+func (sr *SousResume) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+	psy.Add(&sr.DeployFilterFlags)
+}
+
+// Execute defines the behavior of `sous resume`
+func (sr *SousResume) Execute(args []string) cmdr.Result {
+	if sr.DeployFilterFlags.Cluster == "" {
+		return cmdr.UsageErrorf("-cluster is required")
+	}
+
+	mid := sous.ManifestID(sr.TargetManifestID)
+	q := sr.TargetManifestID.QueryMap()
+	q["cluster"] = sr.DeployFilterFlags.Cluster
+	body := server.PauseBody{}
+	deleter, err := sr.Retrieve("./pause", q, &body, sr.User.HTTPHeaders())
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	if body.Pause == nil {
+		return cmdr.Successf("%s in %s is not paused.", mid, sr.DeployFilterFlags.Cluster)
+	}
+
+	if err := deleter.Delete(sr.User.HTTPHeaders()); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	return cmdr.Successf("Resumed %s in %s, which was %s.", mid, sr.DeployFilterFlags.Cluster, describePause(body.Pause, time.Now()))
+}
+
+// describePause describes a pause for the user.
+func describePause(p *sous.Pause, now time.Time) string {
+	state := "lapsed"
+	if p.ActiveAt(now) {
+		state = "in effect"
+	}
+	return fmt.Sprintf("paused by %s at %s until %s (%s): %s", p.User,
+		p.At.Format(time.RFC3339), p.Until.Format(time.RFC3339), state, p.Reason)
+}
@@ -0,0 +1,60 @@
+package config
+
+import "github.com/pkg/errors"
+
+// Identity sources for AuthorizationConfig.Identity.
+const (
+	// IdentityHeaders trusts the Sous-User-Name and Sous-User-Email headers
+	// sent by clients.
+	IdentityHeaders = "headers"
+	// IdentityToken identifies users by a token signed with
+	// AuthorizationConfig.TokenKey.
+	IdentityToken = "token"
+	// IdentityTLS identifies users by the client certificate they present,
+	// which must be signed by AuthorizationConfig.ClientCA.
+	IdentityTLS = "tls"
+)
+
+// AuthorizationConfig configures how a Sous server identifies users, and
+// whether it authorizes their writes.
+type AuthorizationConfig struct {
+	// Enforce turns on authorization of writes. When it is false, any user
+	// may make any write.
+	Enforce bool `env:"SOUS_AUTH_ENFORCE"`
+	// Admins lists users (by email or name) who may make any write.
+	Admins []string `yaml:",omitempty"`
+	// Identity is how the server identifies users: one of "headers" (the
+	// default), "token" or "tls".
+	Identity string `env:"SOUS_AUTH_IDENTITY"`
+	// TokenKey is the secret used to sign and verify user tokens.
+	TokenKey string `env:"SOUS_AUTH_TOKEN_KEY"`
+	// TLSCert and TLSKey are the files containing the server's certificate
+	// and private key. If they are set, the server only accepts HTTPS.
+	TLSCert string `env:"SOUS_TLS_CERT"`
+	TLSKey  string `env:"SOUS_TLS_KEY"`
+	// ClientCA is a file containing the certificates of the authorities
+	// which sign client certificates.
+	ClientCA string `env:"SOUS_TLS_CLIENT_CA"`
+}
+
+// Validate returns an error if this config is invalid.
+func (ac AuthorizationConfig) Validate() error {
+	switch ac.Identity {
+	default:
+		return errors.Errorf("Identity must be one of %q, %q or %q, not %q",
+			IdentityHeaders, IdentityToken, IdentityTLS, ac.Identity)
+	case "", IdentityHeaders:
+	case IdentityToken:
+		if ac.TokenKey == "" {
+			return errors.Errorf("Identity %q requires a TokenKey", IdentityToken)
+		}
+	case IdentityTLS:
+		if ac.TLSCert == "" || ac.TLSKey == "" || ac.ClientCA == "" {
+			return errors.Errorf("Identity %q requires TLSCert, TLSKey and ClientCA", IdentityTLS)
+		}
+	}
+	if (ac.TLSCert == "") != (ac.TLSKey == "") {
+		return errors.New("TLSCert and TLSKey must be set together")
+	}
+	return nil
+}
@@ -8,7 +8,10 @@ import (
 	"path"
 
 	"github.com/opentable/sous/ext/docker"
+	"github.com/opentable/sous/ext/kubernetes"
+	"github.com/opentable/sous/ext/secrets"
 	"github.com/opentable/sous/ext/storage"
+	"github.com/opentable/sous/ext/webhook"
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/firsterr"
 	"github.com/opentable/sous/util/logging"
@@ -22,22 +25,30 @@ type (
 		// StateLocation is either a file containing a pre-compiled state, or
 		// a directory containing the state as a tree.
 		StateLocation string `env:"SOUS_STATE_LOCATION"`
+		// KVStatePath, if set, is a BoltDB file in which the server keeps the
+		// state as key-value pairs, instead of in the tree at StateLocation.
+		KVStatePath string `env:"SOUS_KV_STATE_PATH"`
 		// Server is the location of a Sous Server which this sous instance
 		// considers the master. If this is not set, this node is considered
 		// to be a master. This value must be in URL format.
 		Server string `env:"SOUS_SERVER"`
 		// Database contains configuration for the local Postgresql DB.
 		Database storage.PostgresConfig
-		// SiblingURLs is a temporary measure for setting up a distributed cluster
-		// of sous servers. Each server must be configured with accessible URLs for
-		// all the servers in production, as named by cluster.
-		// (someday this should be replaced with a gossip protocol)
+		// SiblingURLs are the URLs of the sous servers in production, as
+		// named by cluster. If Membership.URL is set, they are only the seeds
+		// the server first gossips with, and its siblings are the servers
+		// found to be live by gossip. Otherwise, each server must be
+		// configured with accessible URLs for all the servers.
 		SiblingURLs map[string]string `env:"SOUS_SIBLING_URLS"`
+		// Membership configures how the server finds its siblings by gossip.
+		Membership MembershipConfig
 		// BuildStateDir is a directory where information about builds
 		// performed by this user on this machine are stored.
 		BuildStateDir string `env:"SOUS_BUILD_STATE_DIR"`
 		// Docker is the Docker configuration.
 		Docker docker.Config
+		// Kubernetes is the configuration for clusters of Kind "kubernetes".
+		Kubernetes kubernetes.Config
 		// Logging is the logging configuration.
 		Logging logging.Config
 		// User identifies the user of this client.
@@ -45,6 +56,15 @@ type (
 		// MaxHTTPConcurrencySingularity is the maximum number of concurrent
 		// requests that can be made to a single Singularity instance.
 		MaxHTTPConcurrencySingularity int `env:"MAX_HTTP_CONCURRENCY_SINGULARITY"`
+		// Authorization configures how the server identifies users and
+		// authorizes their writes.
+		Authorization AuthorizationConfig
+		// Webhooks configures the webhooks the server notifies of deploy
+		// events.
+		Webhooks webhook.Config
+		// Secrets configures how the server resolves the secrets deployments
+		// refer to.
+		Secrets secrets.Config
 	}
 )
 
@@ -71,16 +91,30 @@ func (c Config) Validate() error {
 			return errors.Wrapf(err, "Config.SiblingURLs[%s]", n)
 		}
 	}
+	if err := c.Membership.Validate(); err != nil {
+		return errors.Wrapf(err, "Config.Membership")
+	}
 	if err := c.Logging.Validate(); err != nil {
 		return errors.Wrapf(err, "Config.Logging")
 	}
+	if err := c.Authorization.Validate(); err != nil {
+		return errors.Wrapf(err, "Config.Authorization")
+	}
+	if err := c.Webhooks.Validate(); err != nil {
+		return errors.Wrapf(err, "Config.Webhooks")
+	}
+	if err := c.Secrets.Validate(); err != nil {
+		return errors.Wrapf(err, "Config.Secrets")
+	}
 	return nil
 }
 
 // DefaultConfig returns the default configuration.
 func DefaultConfig() Config {
 	return Config{
-		Docker: docker.DefaultConfig(),
+		Docker:                        docker.DefaultConfig(),
+		Kubernetes:                    kubernetes.DefaultConfig(),
+		Webhooks:                      webhook.DefaultConfig(),
 		MaxHTTPConcurrencySingularity: 10,
 	}
 }
@@ -99,6 +133,9 @@ func (c *Config) Equal(other *Config) bool {
 	if c.Docker != other.Docker {
 		return false
 	}
+	if c.Kubernetes != other.Kubernetes {
+		return false
+	}
 	if !c.Logging.Equal(other.Logging) {
 		return false
 	}
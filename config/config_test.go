@@ -76,6 +76,46 @@ func TestConfig_Validate(t *testing.T) {
 
 	cfg.Server = ""
 	checkValid()
+
+	cfg.Membership.URL = "sous.local"
+	checkNotValid()
+
+	cfg.Membership.URL = "http://sous.local"
+	cfg.Membership.IntervalSeconds = 5
+	cfg.Membership.FailAfterSeconds = 5
+	checkNotValid()
+
+	cfg.Membership.FailAfterSeconds = 30
+	checkValid()
+
+	cfg.Membership.LeaseSeconds = -1
+	checkNotValid()
+
+	cfg.Membership.LeaseSeconds = 30
+	checkValid()
+
+	cfg.Membership.URL = ""
+	checkNotValid()
+
+	cfg.Membership.URL = "http://sous.local"
+	checkValid()
+
+	cfg.Authorization.Identity = "magic"
+	checkNotValid()
+
+	cfg.Authorization.Identity = IdentityToken
+	checkNotValid()
+
+	cfg.Authorization.TokenKey = "secret"
+	checkValid()
+
+	cfg.Authorization.Identity = IdentityTLS
+	cfg.Authorization.TLSCert = "server.crt"
+	checkNotValid()
+
+	cfg.Authorization.TLSKey = "server.key"
+	cfg.Authorization.ClientCA = "clients.crt"
+	checkValid()
 }
 
 func TestConfig_Equals(t *testing.T) {
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MembershipConfig configures how a Sous server finds its siblings by gossip.
+type MembershipConfig struct {
+	// URL is where the other servers can reach this one. If it is empty, the
+	// server does not take part in gossip, and its siblings are exactly those
+	// in SiblingURLs.
+	URL string `env:"SOUS_MEMBERSHIP_URL"`
+	// IntervalSeconds is the time between gossip rounds; the default is one
+	// second.
+	IntervalSeconds int `env:"SOUS_MEMBERSHIP_INTERVAL"`
+	// FailAfterSeconds is how long a sibling may go unheard from before it is
+	// considered to have failed; the default is ten seconds.
+	FailAfterSeconds int `env:"SOUS_MEMBERSHIP_FAIL_AFTER"`
+	// LeaseSeconds is how long the lease which makes a server the leader of a
+	// cluster lasts unless it is renewed. If it is zero, no leader is
+	// elected, and the server rectifies every cluster it resolves. Leader
+	// election requires URL, and a database shared by the servers.
+	LeaseSeconds int `env:"SOUS_LEADER_LEASE"`
+	// Key is a secret shared by the servers, with which they sign the
+	// requests they make of each other. A server accepts gossip from servers
+	// it does not know of already only if it is signed with Key, so without
+	// one, each server must list the others in SiblingURLs.
+	Key string `env:"SOUS_MEMBERSHIP_KEY"`
+}
+
+// Validate returns an error if this config is invalid.
+func (mc MembershipConfig) Validate() error {
+	if mc.URL != "" {
+		if err := checkURL(mc.URL); err != nil {
+			return errors.Wrapf(err, "URL")
+		}
+	}
+	if mc.IntervalSeconds < 0 {
+		return errors.Errorf("IntervalSeconds must not be negative, got %d", mc.IntervalSeconds)
+	}
+	if mc.FailAfterSeconds < 0 {
+		return errors.Errorf("FailAfterSeconds must not be negative, got %d", mc.FailAfterSeconds)
+	}
+	if mc.FailAfterSeconds != 0 && mc.FailAfterSeconds <= mc.IntervalSeconds {
+		return errors.Errorf("FailAfterSeconds (%d) must be longer than IntervalSeconds (%d)",
+			mc.FailAfterSeconds, mc.IntervalSeconds)
+	}
+	if mc.LeaseSeconds < 0 {
+		return errors.Errorf("LeaseSeconds must not be negative, got %d", mc.LeaseSeconds)
+	}
+	if mc.LeaseSeconds != 0 && mc.URL == "" {
+		return errors.Errorf("LeaseSeconds requires URL, to identify the leader")
+	}
+	return nil
+}
+
+// Interval returns the time between gossip rounds, or def if it is not set.
+func (mc MembershipConfig) Interval(def time.Duration) time.Duration {
+	if mc.IntervalSeconds == 0 {
+		return def
+	}
+	return time.Duration(mc.IntervalSeconds) * time.Second
+}
+
+// FailAfter returns how long a sibling may go unheard from before it is
+// considered to have failed, or def if it is not set.
+func (mc MembershipConfig) FailAfter(def time.Duration) time.Duration {
+	if mc.FailAfterSeconds == 0 {
+		return def
+	}
+	return time.Duration(mc.FailAfterSeconds) * time.Second
+}
+
+// LeaseTTL returns how long a cluster's lease lasts unless it is renewed, or
+// zero if no leader is elected.
+func (mc MembershipConfig) LeaseTTL() time.Duration {
+	return time.Duration(mc.LeaseSeconds) * time.Second
+}
@@ -0,0 +1,188 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultNamespace is the namespace Sous manages objects in unless
+	// configured otherwise.
+	DefaultNamespace = "default"
+
+	deploymentsAPI = "apis/apps/v1"
+	// cronJobsAPI is batch/v1, not batch/v1beta1, as only batch/v1 CronJobs
+	// have a timeZone.
+	cronJobsAPI = "apis/batch/v1"
+)
+
+type (
+	// apiClient abstracts the raw interactions with the Kubernetes API server.
+	apiClient interface {
+		// Deployments lists the Deployments managed by Sous.
+		Deployments(baseURL string) ([]*deployment, error)
+		// CronJobs lists the CronJobs managed by Sous.
+		CronJobs(baseURL string) ([]*cronJob, error)
+		// CreateDeployment creates a new Deployment.
+		CreateDeployment(baseURL string, d *deployment) error
+		// UpdateDeployment replaces an existing Deployment.
+		UpdateDeployment(baseURL string, d *deployment) error
+		// DeleteDeployment deletes a Deployment by name.
+		DeleteDeployment(baseURL, name string) error
+		// CreateCronJob creates a new CronJob.
+		CreateCronJob(baseURL string, c *cronJob) error
+		// UpdateCronJob replaces an existing CronJob.
+		UpdateCronJob(baseURL string, c *cronJob) error
+		// DeleteCronJob deletes a CronJob by name.
+		DeleteCronJob(baseURL, name string) error
+	}
+
+	// HTTPClient talks to Kubernetes API servers over HTTP.
+	HTTPClient struct {
+		// Namespace is the namespace all objects are read from and written to.
+		Namespace string
+		// BearerToken, if not empty, is sent in the Authorization header.
+		BearerToken string
+		http        *http.Client
+	}
+
+	// APIError is returned when the API server responds with a non-2xx status.
+	APIError struct {
+		Method, URL string
+		Status      int
+		Body        string
+	}
+)
+
+// NewHTTPClient returns an HTTPClient using hc for requests. If hc is nil,
+// http.DefaultClient is used.
+func NewHTTPClient(hc *http.Client, namespace string) *HTTPClient {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	return &HTTPClient{http: hc, Namespace: namespace}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.Status, e.Body)
+}
+
+// IsNotFound returns true if err is an APIError with a 404 status.
+func IsNotFound(err error) bool {
+	apiErr, is := errors.Cause(err).(*APIError)
+	return is && apiErr.Status == http.StatusNotFound
+}
+
+func (c *HTTPClient) collectionURL(baseURL, api, resource string) string {
+	return fmt.Sprintf("%s/%s/namespaces/%s/%s", strings.TrimRight(baseURL, "/"), api, url.PathEscape(c.Namespace), resource)
+}
+
+func (c *HTTPClient) itemURL(baseURL, api, resource, name string) string {
+	return c.collectionURL(baseURL, api, resource) + "/" + url.PathEscape(name)
+}
+
+func managedSelector() string {
+	return url.QueryEscape(ManagedLabel + "=true")
+}
+
+// Deployments implements apiClient.
+func (c *HTTPClient) Deployments(baseURL string) ([]*deployment, error) {
+	list := deploymentList{}
+	u := c.collectionURL(baseURL, deploymentsAPI, "deployments") + "?labelSelector=" + managedSelector()
+	if err := c.do("GET", u, nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// CronJobs implements apiClient.
+func (c *HTTPClient) CronJobs(baseURL string) ([]*cronJob, error) {
+	list := cronJobList{}
+	u := c.collectionURL(baseURL, cronJobsAPI, "cronjobs") + "?labelSelector=" + managedSelector()
+	if err := c.do("GET", u, nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// CreateDeployment implements apiClient.
+func (c *HTTPClient) CreateDeployment(baseURL string, d *deployment) error {
+	d.Metadata.Namespace = c.Namespace
+	return c.do("POST", c.collectionURL(baseURL, deploymentsAPI, "deployments"), d, nil)
+}
+
+// UpdateDeployment implements apiClient.
+func (c *HTTPClient) UpdateDeployment(baseURL string, d *deployment) error {
+	d.Metadata.Namespace = c.Namespace
+	return c.do("PUT", c.itemURL(baseURL, deploymentsAPI, "deployments", d.Metadata.Name), d, nil)
+}
+
+// DeleteDeployment implements apiClient.
+func (c *HTTPClient) DeleteDeployment(baseURL, name string) error {
+	return c.do("DELETE", c.itemURL(baseURL, deploymentsAPI, "deployments", name), nil, nil)
+}
+
+// CreateCronJob implements apiClient.
+func (c *HTTPClient) CreateCronJob(baseURL string, cj *cronJob) error {
+	cj.Metadata.Namespace = c.Namespace
+	return c.do("POST", c.collectionURL(baseURL, cronJobsAPI, "cronjobs"), cj, nil)
+}
+
+// UpdateCronJob implements apiClient.
+func (c *HTTPClient) UpdateCronJob(baseURL string, cj *cronJob) error {
+	cj.Metadata.Namespace = c.Namespace
+	return c.do("PUT", c.itemURL(baseURL, cronJobsAPI, "cronjobs", cj.Metadata.Name), cj, nil)
+}
+
+// DeleteCronJob implements apiClient.
+func (c *HTTPClient) DeleteCronJob(baseURL, name string) error {
+	return c.do("DELETE", c.itemURL(baseURL, cronJobsAPI, "cronjobs", name), nil, nil)
+}
+
+func (c *HTTPClient) do(method, u string, body, into interface{}) error {
+	var reqBody *bytes.Buffer
+	if body != nil {
+		reqBody = &bytes.Buffer{}
+		if err := json.NewEncoder(reqBody).Encode(body); err != nil {
+			return errors.Wrapf(err, "encoding body for %s %s", method, u)
+		}
+	} else {
+		reqBody = bytes.NewBuffer(nil)
+	}
+	req, err := http.NewRequest(method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	}
+
+	rz, err := c.http.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s", method, u)
+	}
+	defer rz.Body.Close()
+
+	if rz.StatusCode < 200 || rz.StatusCode > 299 {
+		b, _ := ioutil.ReadAll(rz.Body)
+		return &APIError{Method: method, URL: u, Status: rz.StatusCode, Body: string(b)}
+	}
+	if into == nil {
+		return nil
+	}
+	return errors.Wrapf(json.NewDecoder(rz.Body).Decode(into), "decoding response to %s %s", method, u)
+}
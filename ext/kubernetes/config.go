@@ -0,0 +1,17 @@
+package kubernetes
+
+// Config is the configuration for talking to Kubernetes API servers.
+type Config struct {
+	// Namespace is the namespace Sous manages objects in.
+	Namespace string `env:"SOUS_KUBERNETES_NAMESPACE"`
+	// BearerToken is used to authenticate to the API servers, if set.
+	BearerToken string `env:"SOUS_KUBERNETES_TOKEN"`
+}
+
+// DefaultConfig builds a default configuration, which can be then overridden by
+// client code.
+func DefaultConfig() Config {
+	return Config{
+		Namespace: DefaultNamespace,
+	}
+}
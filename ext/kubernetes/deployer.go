@@ -0,0 +1,189 @@
+// Package kubernetes implements a sous.Deployer for clusters of Kind
+// "kubernetes". Long-running deployments are managed as apps/v1 Deployments,
+// and scheduled deployments as batch/v1 CronJobs.
+package kubernetes
+
+import (
+	"fmt"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	deployer struct {
+		Client apiClient
+		log    logging.LogSink
+	}
+)
+
+// NewDeployer creates a new Kubernetes-based sous.Deployer.
+func NewDeployer(c apiClient, ls logging.LogSink) sous.Deployer {
+	return &deployer{Client: c, log: ls}
+}
+
+// RunningDeployments collects the Sous-managed Deployments and CronJobs from
+// the API servers of the given clusters.
+func (r *deployer) RunningDeployments(reg sous.Registry, clusters sous.Clusters) (sous.DeployStates, error) {
+	states := sous.NewDeployStates()
+
+	seen := map[string]struct{}{}
+	for _, cluster := range clusters {
+		// Several Sous clusters may share a single API server, in which case
+		// it only needs to be read once.
+		if _, done := seen[cluster.BaseURL]; done {
+			continue
+		}
+		seen[cluster.BaseURL] = struct{}{}
+
+		deps, err := r.Client.Deployments(cluster.BaseURL)
+		if err != nil {
+			return states, errors.Wrapf(err, "listing deployments in %s", cluster.BaseURL)
+		}
+		for _, kd := range deps {
+			ds, err := stateFromDeployment(kd, clusters)
+			if err := r.addState(states, ds, err); err != nil {
+				return states, err
+			}
+		}
+
+		jobs, err := r.Client.CronJobs(cluster.BaseURL)
+		if err != nil {
+			return states, errors.Wrapf(err, "listing cronjobs in %s", cluster.BaseURL)
+		}
+		for _, cj := range jobs {
+			ds, err := stateFromCronJob(cj, clusters)
+			if err := r.addState(states, ds, err); err != nil {
+				return states, err
+			}
+		}
+	}
+	return states, nil
+}
+
+func (r *deployer) addState(states sous.DeployStates, ds *sous.DeployState, err error) error {
+	if err != nil {
+		if ignorableObjectError(err) {
+			messages.ReportLogFieldsMessage("Skipping object", logging.DebugLevel, r.log, err)
+			return nil
+		}
+		return err
+	}
+	if !states.Add(ds) {
+		return errors.Errorf("duplicate deployment %q in kubernetes", ds.ID())
+	}
+	return nil
+}
+
+// Rectify invokes actions to ensure that the real world matches pair.Post,
+// given that it currently matches pair.Prior.
+func (r *deployer) Rectify(pair *sous.DeployablePair) sous.DiffResolution {
+	switch k := pair.Kind(); k {
+	default:
+		panic(fmt.Sprintf("unrecognised kind %q", k))
+	case sous.SameKind:
+		resolution := pair.SameResolution()
+		if pair.Post.Status == sous.DeployStatusFailed {
+			resolution.Error = sous.WrapResolveError(&sous.FailedStatusError{})
+		}
+		return resolution
+	case sous.AddedKind:
+		result := sous.DiffResolution{DeploymentID: pair.ID()}
+		if err := r.create(pair.Post); err != nil {
+			result.Desc = "not created"
+			result.Error = sous.WrapResolveError(&sous.CreateError{Deployment: pair.Post.Deployment.Clone(), Err: err})
+		} else {
+			result.Desc = sous.CreateDiff
+		}
+		messages.ReportLogFieldsMessage("Result of create", logging.InformationLevel, r.log, result)
+		return result
+	case sous.RemovedKind:
+		result := sous.DiffResolution{DeploymentID: pair.ID()}
+		if err := r.delete(pair.Prior); err != nil {
+			result.Desc = "not deleted"
+			result.Error = sous.WrapResolveError(&sous.DeleteError{Deployment: pair.Prior.Deployment.Clone(), Err: err})
+		} else {
+			result.Desc = sous.DeleteDiff
+		}
+		messages.ReportLogFieldsMessage("Result of delete", logging.InformationLevel, r.log, result)
+		return result
+	case sous.ModifiedKind:
+		result := sous.DiffResolution{DeploymentID: pair.ID()}
+		if err := r.modify(pair); err != nil {
+			dp := &sous.DeploymentPair{
+				Prior: pair.Prior.Deployment.Clone(),
+				Post:  pair.Post.Deployment.Clone(),
+			}
+			result.Desc = "not updated"
+			result.Error = sous.WrapResolveError(&sous.ChangeError{Deployments: dp, Err: err})
+		} else {
+			result.Desc = sous.ModifyDiff
+		}
+		messages.ReportLogFieldsMessage("Result of modify", logging.InformationLevel, r.log, result)
+		return result
+	}
+}
+
+func (r *deployer) create(d *sous.Deployable) error {
+	baseURL := d.Deployment.Cluster.BaseURL
+	if usesCronJob(d.Deployment.Kind) {
+		cj, err := cronJobFor(d)
+		if err != nil {
+			return err
+		}
+		return r.Client.CreateCronJob(baseURL, cj)
+	}
+	kd, err := deploymentFor(d)
+	if err != nil {
+		return err
+	}
+	return r.Client.CreateDeployment(baseURL, kd)
+}
+
+func (r *deployer) delete(d *sous.Deployable) error {
+	name, err := MakeObjectName(d.ID())
+	if err != nil {
+		return err
+	}
+	baseURL := d.Deployment.Cluster.BaseURL
+	if usesCronJob(d.Deployment.Kind) {
+		err = r.Client.DeleteCronJob(baseURL, name)
+	} else {
+		err = r.Client.DeleteDeployment(baseURL, name)
+	}
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (r *deployer) modify(pair *sous.DeployablePair) error {
+	// A change between a Deployment and a CronJob can't be made in place.
+	if usesCronJob(pair.Prior.Deployment.Kind) != usesCronJob(pair.Post.Deployment.Kind) {
+		if err := r.delete(pair.Prior); err != nil {
+			return err
+		}
+		return r.create(pair.Post)
+	}
+
+	// The API server rejects the update if the object has changed since it was
+	// read, rather than overwriting that change.
+	read, _ := pair.ExecutorData.(objectData)
+	baseURL := pair.Post.Deployment.Cluster.BaseURL
+	if usesCronJob(pair.Post.Deployment.Kind) {
+		cj, err := cronJobFor(pair.Post)
+		if err != nil {
+			return err
+		}
+		cj.Metadata.ResourceVersion = read.resourceVersion
+		return r.Client.UpdateCronJob(baseURL, cj)
+	}
+	kd, err := deploymentFor(pair.Post)
+	if err != nil {
+		return err
+	}
+	kd.Metadata.ResourceVersion = read.resourceVersion
+	return r.Client.UpdateDeployment(baseURL, kd)
+}
@@ -0,0 +1,227 @@
+package kubernetes
+
+import (
+	"regexp"
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDeployable(cluster *sous.Cluster, kind sous.ManifestKind) *sous.Deployable {
+	return &sous.Deployable{
+		Status: sous.DeployStatusActive,
+		Deployment: &sous.Deployment{
+			DeployConfig: sous.DeployConfig{
+				Resources:    sous.Resources{"cpus": "0.5", "memory": "256", "ports": "2"},
+				Metadata:     sous.Metadata{"team": "blue"},
+				Env:          sous.Env{"GREETING": "hello"},
+				NumInstances: 3,
+				Volumes:      sous.Volumes{{Host: "/srv/data", Container: "/data", Mode: sous.ReadOnly}},
+				Startup: sous.Startup{
+					CheckReadyProtocol: "HTTP",
+					CheckReadyURIPath:  "/health",
+					Timeout:            30,
+				},
+			},
+			ClusterName: cluster.Name,
+			Cluster:     cluster,
+			SourceID:    sous.MustNewSourceID("github.com/opentable/example", "api", "1.2.3"),
+			Flavor:      "vanilla",
+			Owners:      sous.NewOwnerSet("sam", "judson"),
+			Kind:        kind,
+		},
+		BuildArtifact: &sous.BuildArtifact{Name: "docker.example.com/example/api:1.2.3"},
+	}
+}
+
+func setupDeployer(t *testing.T) (*fakeAPIServer, sous.Deployer, sous.Clusters) {
+	srv := newFakeAPIServer()
+	clusters := sous.Clusters{
+		"k8s-west": &sous.Cluster{Name: "k8s-west", Kind: sous.ClusterKindKubernetes, BaseURL: srv.URL},
+	}
+	return srv, NewDeployer(NewHTTPClient(nil, ""), logging.SilentLogSet()), clusters
+}
+
+func TestDeployer_CreateAndReadBack(t *testing.T) {
+	srv, d, clusters := setupDeployer(t)
+	defer srv.Close()
+
+	placement := sous.Placement{
+		RequiredAttributes:  map[string]string{"zone": "us-west-2a"},
+		PreferredAttributes: map[string]string{"disk": "ssd"},
+	}
+	for _, kind := range []sous.ManifestKind{sous.ManifestKindService, sous.ManifestKindScheduled} {
+		post := testDeployable(clusters["k8s-west"], kind)
+		if kind == sous.ManifestKindService {
+			post.Deployment.Placement = placement
+		}
+		if kind == sous.ManifestKindScheduled {
+			post.Deployment.Flavor = "nightly"
+			post.Deployment.Schedule = "0 2 * * *"
+			post.Deployment.ScheduleTimeZone = "Europe/London"
+			post.Deployment.MaxExecutionSeconds = 3600
+		}
+		rez := d.Rectify(&sous.DeployablePair{Post: post})
+		require.Nil(t, rez.Error, "creating %s", kind)
+		assert.Equal(t, sous.CreateDiff, rez.Desc)
+	}
+	assert.Len(t, srv.deployments, 1)
+	assert.Len(t, srv.cronJobs, 1)
+	for _, dep := range srv.deployments {
+		spec := dep.Spec.Template.Spec
+		assert.Equal(t, placement.RequiredAttributes, spec.NodeSelector)
+		require.NotNil(t, spec.Affinity)
+		assert.Equal(t, "disk", spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].Preference.MatchExpressions[0].Key)
+	}
+
+	states, err := d.RunningDeployments(nil, clusters)
+	require.NoError(t, err)
+	require.Equal(t, 2, states.Len())
+
+	for _, kind := range []sous.ManifestKind{sous.ManifestKindService, sous.ManifestKindScheduled} {
+		want := testDeployable(clusters["k8s-west"], kind)
+		if kind == sous.ManifestKindService {
+			want.Deployment.Placement = placement
+		}
+		if kind == sous.ManifestKindScheduled {
+			want.Deployment.Flavor = "nightly"
+			want.Deployment.Schedule = "0 2 * * *"
+			want.Deployment.ScheduleTimeZone = "Europe/London"
+			want.Deployment.MaxExecutionSeconds = 3600
+		}
+		got, ok := states.Get(want.ID())
+		require.True(t, ok, "missing %q", want.ID())
+		assert.Equal(t, sous.DeployStatusActive, got.Status)
+		different, diffs := want.Deployment.Diff(&got.Deployment)
+		assert.False(t, different, "%s: %v", kind, diffs)
+	}
+}
+
+func TestDeployer_RejectsSecretRefs(t *testing.T) {
+	srv, d, clusters := setupDeployer(t)
+	defer srv.Close()
+
+	post := testDeployable(clusters["k8s-west"], sous.ManifestKindService)
+	post.Deployment.Env["DB_PASSWORD"] = "secret://db/prod#password"
+	rez := d.Rectify(&sous.DeployablePair{Post: post})
+	assert.NotNil(t, rez.Error, "a secret ref should not be put in a pod spec")
+	assert.Len(t, srv.deployments, 0)
+}
+
+func TestReadinessProbeFor(t *testing.T) {
+	http := readinessProbeFor(sous.Startup{CheckReadyProtocol: "HTTPS", CheckReadyURIPath: "/health", CheckReadyPortIndex: 1})
+	require.NotNil(t, http)
+	require.NotNil(t, http.HTTPGet)
+	assert.Equal(t, httpGetAction{Path: "/health", Port: basePort + 1, Scheme: "HTTPS"}, *http.HTTPGet)
+
+	tcp := readinessProbeFor(sous.Startup{CheckReadyProtocol: sous.CheckReadyTCP, CheckReadyRetries: 4})
+	require.NotNil(t, tcp)
+	assert.Nil(t, tcp.HTTPGet)
+	assert.Equal(t, &tcpSocketAction{Port: basePort}, tcp.TCPSocket)
+	assert.Equal(t, 4, tcp.FailureThreshold)
+
+	exec := readinessProbeFor(sous.Startup{CheckReadyProtocol: sous.CheckReadyExec, CheckReadyCommand: []string{"/bin/ready", "-q"}})
+	require.NotNil(t, exec)
+	assert.Equal(t, &execAction{Command: []string{"/bin/ready", "-q"}}, exec.Exec)
+
+	assert.Nil(t, readinessProbeFor(sous.Startup{CheckReadyProtocol: sous.CheckReadyTCP, SkipCheck: true}))
+	assert.Nil(t, readinessProbeFor(sous.Startup{CheckReadyProtocol: sous.CheckReadyHTTP}))
+}
+
+func TestDeployer_ModifyAndDelete(t *testing.T) {
+	srv, d, clusters := setupDeployer(t)
+	defer srv.Close()
+
+	prior := testDeployable(clusters["k8s-west"], sous.ManifestKindService)
+	require.Nil(t, d.Rectify(&sous.DeployablePair{Post: prior}).Error)
+
+	states, err := d.RunningDeployments(nil, clusters)
+	require.NoError(t, err)
+	read, ok := states.Get(prior.ID())
+	require.True(t, ok)
+
+	post := testDeployable(clusters["k8s-west"], sous.ManifestKindService)
+	post.Deployment.NumInstances = 5
+	post.Deployment.SourceID = sous.MustNewSourceID("github.com/opentable/example", "api", "1.2.4")
+	post.BuildArtifact.Name = "docker.example.com/example/api:1.2.4"
+	rez := d.Rectify(&sous.DeployablePair{Prior: prior, Post: post, ExecutorData: read.ExecutorData})
+	require.Nil(t, rez.Error)
+	assert.Equal(t, sous.ModifyDiff, rez.Desc)
+
+	// The object has changed since it was read, so the update is rejected.
+	rez = d.Rectify(&sous.DeployablePair{Prior: prior, Post: post, ExecutorData: read.ExecutorData})
+	assert.NotNil(t, rez.Error, "an update of a stale version should be rejected")
+
+	name, err := MakeObjectName(post.ID())
+	require.NoError(t, err)
+	kd := srv.deployments[name]
+	require.NotNil(t, kd)
+	assert.EqualValues(t, 5, kd.Spec.Replicas)
+	assert.Equal(t, "docker.example.com/example/api:1.2.4", kd.Spec.Template.Spec.Containers[0].Image)
+
+	rez = d.Rectify(&sous.DeployablePair{Prior: post})
+	require.Nil(t, rez.Error)
+	assert.Equal(t, sous.DeleteDiff, rez.Desc)
+	assert.Len(t, srv.deployments, 0)
+}
+
+func TestDeployer_IgnoresOtherClusters(t *testing.T) {
+	srv, d, clusters := setupDeployer(t)
+	defer srv.Close()
+
+	other := &sous.Cluster{Name: "k8s-east", Kind: sous.ClusterKindKubernetes, BaseURL: srv.URL}
+	require.Nil(t, d.Rectify(&sous.DeployablePair{Post: testDeployable(other, sous.ManifestKindService)}).Error)
+
+	states, err := d.RunningDeployments(nil, clusters)
+	require.NoError(t, err)
+	assert.Equal(t, 0, states.Len())
+}
+
+func TestDeploymentStatusOf(t *testing.T) {
+	kd := &deployment{}
+	kd.Metadata.Generation = 2
+	kd.Spec.Replicas = 2
+	kd.Status = deploymentStatus{ObservedGeneration: 1, UpdatedReplicas: 2, AvailableReplicas: 2}
+	assert.Equal(t, sous.DeployStatusPending, deploymentStatusOf(kd))
+
+	kd.Status.ObservedGeneration = 2
+	assert.Equal(t, sous.DeployStatusActive, deploymentStatusOf(kd))
+
+	kd.Status.AvailableReplicas = 1
+	assert.Equal(t, sous.DeployStatusPending, deploymentStatusOf(kd))
+
+	kd.Status.Conditions = []deploymentCondition{{Type: "Progressing", Status: "False", Reason: "ProgressDeadlineExceeded"}}
+	assert.Equal(t, sous.DeployStatusFailed, deploymentStatusOf(kd))
+}
+
+func TestMakeObjectName(t *testing.T) {
+	dns1123 := regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	ids := []sous.DeploymentID{
+		{ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/repo"}}, Cluster: "some-cluster"},
+		{ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/repo"}}, Cluster: "some_cluster"},
+		{
+			ManifestID: sous.ManifestID{
+				Source: sous.SourceLocation{
+					Repo: "github.com/ihaveanincrediblylongname/AndILikeMyProjectsToHaveIncrediblyLongNamesToo",
+					Dir:  "and/also/i/bury/my/services/super/deep",
+				},
+				Flavor: "wellwehavetohaveaflavor",
+			},
+			Cluster: "foo",
+		},
+	}
+	seen := map[string]sous.DeploymentID{}
+	for _, id := range ids {
+		name, err := MakeObjectName(id)
+		require.NoError(t, err)
+		assert.True(t, len(name) <= maxObjectNameLen, "%q is too long", name)
+		assert.Regexp(t, dns1123, name)
+		if prev, dup := seen[name]; dup {
+			t.Errorf("%q and %q both named %q", prev, id, name)
+		}
+		seen[name] = id
+	}
+}
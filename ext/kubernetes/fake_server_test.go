@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fakeAPIServer is a tiny in-memory stand-in for the parts of the Kubernetes
+// API that the deployer uses.
+type fakeAPIServer struct {
+	sync.Mutex
+	deployments map[string]*deployment
+	cronJobs    map[string]*cronJob
+	requests    []string
+	version     int
+	*httptest.Server
+}
+
+func newFakeAPIServer() *fakeAPIServer {
+	f := &fakeAPIServer{
+		deployments: map[string]*deployment{},
+		cronJobs:    map[string]*cronJob{},
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+func (f *fakeAPIServer) serve(rw http.ResponseWriter, req *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	f.requests = append(f.requests, req.Method+" "+req.URL.Path)
+
+	var resource, name string
+	switch {
+	case strings.HasPrefix(req.URL.Path, "/apis/apps/v1/namespaces/default/deployments"):
+		resource = "deployments"
+		name = strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, "/apis/apps/v1/namespaces/default/deployments"), "/")
+	case strings.HasPrefix(req.URL.Path, "/apis/batch/v1/namespaces/default/cronjobs"):
+		resource = "cronjobs"
+		name = strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, "/apis/batch/v1/namespaces/default/cronjobs"), "/")
+	default:
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch req.Method {
+	case "GET":
+		if name != "" || req.URL.Query().Get("labelSelector") != ManagedLabel+"=true" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if resource == "deployments" {
+			list := deploymentList{Items: []*deployment{}}
+			for _, d := range f.deployments {
+				list.Items = append(list.Items, d)
+			}
+			json.NewEncoder(rw).Encode(list)
+			return
+		}
+		list := cronJobList{Items: []*cronJob{}}
+		for _, c := range f.cronJobs {
+			list.Items = append(list.Items, c)
+		}
+		json.NewEncoder(rw).Encode(list)
+	case "POST", "PUT":
+		if resource == "deployments" {
+			d := &deployment{}
+			json.NewDecoder(req.Body).Decode(d)
+			current, exists := f.deployments[d.Metadata.Name]
+			if exists == (req.Method == "POST") || (exists && staleUpdate(current.Metadata, d.Metadata)) {
+				rw.WriteHeader(http.StatusConflict)
+				return
+			}
+			f.nextVersion(&d.Metadata)
+			// Pretend the rollout completes immediately.
+			d.Metadata.Generation++
+			d.Status = deploymentStatus{
+				ObservedGeneration: d.Metadata.Generation,
+				Replicas:           d.Spec.Replicas,
+				UpdatedReplicas:    d.Spec.Replicas,
+				AvailableReplicas:  d.Spec.Replicas,
+			}
+			f.deployments[d.Metadata.Name] = d
+			json.NewEncoder(rw).Encode(d)
+			return
+		}
+		c := &cronJob{}
+		json.NewDecoder(req.Body).Decode(c)
+		current, exists := f.cronJobs[c.Metadata.Name]
+		if exists == (req.Method == "POST") || (exists && staleUpdate(current.Metadata, c.Metadata)) {
+			rw.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.nextVersion(&c.Metadata)
+		f.cronJobs[c.Metadata.Name] = c
+		json.NewEncoder(rw).Encode(c)
+	case "DELETE":
+		if resource == "deployments" {
+			if _, ok := f.deployments[name]; !ok {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(f.deployments, name)
+			return
+		}
+		if _, ok := f.cronJobs[name]; !ok {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.cronJobs, name)
+	}
+}
+
+// staleUpdate reports whether an update to current does not carry its
+// resourceVersion. Unlike the real API server, the fake rejects updates that
+// carry none, as Sous always sends the version it read.
+func staleUpdate(current, update objectMeta) bool {
+	return update.ResourceVersion != current.ResourceVersion
+}
+
+func (f *fakeAPIServer) nextVersion(meta *objectMeta) {
+	f.version++
+	meta.ResourceVersion = strconv.Itoa(f.version)
+}
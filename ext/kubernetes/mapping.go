@@ -0,0 +1,468 @@
+package kubernetes
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ManagedLabel is the label Sous puts on every object it manages, and
+	// selects on when listing objects.
+	ManagedLabel = "com.opentable.sous.managed"
+	// ObjectNameLabel labels pods with the name of the object which owns them,
+	// and is used as the Deployment selector.
+	ObjectNameLabel = "com.opentable.sous.object_name"
+	// KindAnnotation records the sous.ManifestKind of a deployment.
+	KindAnnotation = "com.opentable.sous.kind"
+	// OwnersAnnotation records the owners of a deployment, comma separated.
+	OwnersAnnotation = "com.opentable.sous.owners"
+	// StartupAnnotation records the JSON encoded sous.Startup of a deployment.
+	StartupAnnotation = "com.opentable.sous.startup"
+	// MetadataAnnotation records the JSON encoded metadata of a deployment.
+	MetadataAnnotation = "com.opentable.sous.metadata"
+	// PlacementAnnotation records the JSON encoded sous.Placement of a
+	// deployment, if it has one.
+	PlacementAnnotation = "com.opentable.sous.placement"
+
+	// Kubernetes object names must be valid DNS-1123 labels.
+	maxObjectNameLen = 63
+	// basePort is the container port assigned to PORT0; PORTn is basePort+n.
+	basePort = 8080
+
+	containerName = "app"
+)
+
+var (
+	illegalNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+	portEnvName      = regexp.MustCompile(`^PORT[0-9]+$`)
+)
+
+type (
+	nonSousError struct{ name string }
+
+	notThisClusterError struct{ name, cluster string }
+)
+
+func (e nonSousError) Error() string {
+	return fmt.Sprintf("%q is not managed by sous", e.name)
+}
+
+func (e notThisClusterError) Error() string {
+	return fmt.Sprintf("%q belongs to cluster %q which is not being considered", e.name, e.cluster)
+}
+
+func ignorableObjectError(err error) bool {
+	switch errors.Cause(err).(type) {
+	default:
+		return false
+	case nonSousError, notThisClusterError:
+		return true
+	}
+}
+
+// MakeObjectName creates a Kubernetes object name from a sous.DeploymentID.
+// The result is a DNS-1123 label, unique per DeploymentID.
+func MakeObjectName(did sous.DeploymentID) (string, error) {
+	sn, err := did.ManifestID.Source.ShortName()
+	if err != nil {
+		return "", err
+	}
+	parts := []string{}
+	for _, p := range []string{sn, did.ManifestID.Source.Dir, did.ManifestID.Flavor, did.Cluster} {
+		p = strings.Trim(illegalNameChars.ReplaceAllString(strings.ToLower(p), "-"), "-")
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	digest := fmt.Sprintf("%x", sha1.Sum(did.Digest()))[:8]
+	base := strings.Join(parts, "-")
+	if max := maxObjectNameLen - len(digest) - 1; len(base) > max {
+		base = strings.TrimRight(base[:max], "-")
+	}
+	return base + "-" + digest, nil
+}
+
+func usesCronJob(kind sous.ManifestKind) bool {
+	return kind.IsScheduled()
+}
+
+func objectMetaFor(name string, d *sous.Deployment) (objectMeta, error) {
+	startup, err := json.Marshal(d.Startup)
+	if err != nil {
+		return objectMeta{}, err
+	}
+	md := "{}"
+	if len(d.Metadata) > 0 {
+		b, err := json.Marshal(d.Metadata)
+		if err != nil {
+			return objectMeta{}, err
+		}
+		md = string(b)
+	}
+	meta := objectMeta{
+		Name: name,
+		Labels: map[string]string{
+			ManagedLabel:    "true",
+			ObjectNameLabel: name,
+		},
+		Annotations: map[string]string{
+			sous.ClusterNameLabel: d.ClusterName,
+			sous.FlavorLabel:      d.Flavor,
+			sous.RepoLabel:        d.SourceID.Location.Repo,
+			sous.PathLabel:        d.SourceID.Location.Dir,
+			sous.VersionLabel:     d.SourceID.Version.String(),
+			KindAnnotation:        string(d.Kind),
+			OwnersAnnotation:      strings.Join(d.Owners.Slice(), ","),
+			StartupAnnotation:     string(startup),
+			MetadataAnnotation:    md,
+		},
+	}
+	if !d.Placement.IsZero() {
+		b, err := json.Marshal(d.Placement)
+		if err != nil {
+			return objectMeta{}, err
+		}
+		meta.Annotations[PlacementAnnotation] = string(b)
+	}
+	return meta, nil
+}
+
+func podTemplateFor(name string, d *sous.Deployable) (podTemplateSpec, error) {
+	if d.BuildArtifact == nil || d.BuildArtifact.Name == "" {
+		return podTemplateSpec{}, &sous.MissingImageNameError{Cause: errors.Errorf("no build artifact for %q", d.ID())}
+	}
+	dep := d.Deployment
+	// Validation rejects these, but a ref must never reach the pod spec.
+	if flaws := dep.Env.ValidateFor(dep.Cluster); len(flaws) > 0 {
+		return podTemplateSpec{}, errors.Errorf("%s: %s", d.ID(), flaws[0])
+	}
+
+	c := container{
+		Name:  containerName,
+		Image: d.BuildArtifact.Name,
+		Resources: resourceRequirements{
+			Limits: map[string]string{
+				"cpu":    strconv.FormatFloat(dep.Resources.Cpus(), 'f', -1, 64),
+				"memory": strconv.FormatFloat(dep.Resources.Memory(), 'f', -1, 64) + "Mi",
+			},
+		},
+	}
+
+	envNames := make([]string, 0, len(dep.Env))
+	for k := range dep.Env {
+		envNames = append(envNames, k)
+	}
+	sort.Strings(envNames)
+	for _, k := range envNames {
+		c.Env = append(c.Env, envVar{Name: k, Value: dep.Env[k]})
+	}
+	for i := int32(0); i < dep.Resources.Ports(); i++ {
+		port := int32(basePort) + i
+		c.Ports = append(c.Ports, containerPort{Name: fmt.Sprintf("port%d", i), ContainerPort: port})
+		c.Env = append(c.Env, envVar{Name: fmt.Sprintf("PORT%d", i), Value: strconv.Itoa(int(port))})
+	}
+
+	volumes := []volume{}
+	for i, v := range dep.Volumes {
+		vn := fmt.Sprintf("vol%d", i)
+		volumes = append(volumes, volume{Name: vn, HostPath: &hostPathVolumeSource{Path: v.Host}})
+		c.VolumeMounts = append(c.VolumeMounts, volumeMount{Name: vn, MountPath: v.Container, ReadOnly: v.Mode == sous.ReadOnly})
+	}
+
+	c.ReadinessProbe = readinessProbeFor(dep.Startup)
+
+	spec := podSpec{Containers: []container{c}, Volumes: volumes}
+	if len(dep.Placement.RequiredAttributes) > 0 {
+		spec.NodeSelector = dep.Placement.Clone().RequiredAttributes
+	}
+	spec.Affinity = affinityFor(dep.Placement.PreferredAttributes)
+
+	return podTemplateSpec{
+		Metadata: objectMeta{Labels: map[string]string{ManagedLabel: "true", ObjectNameLabel: name}},
+		Spec:     spec,
+	}, nil
+}
+
+// affinityFor returns an affinity which prefers nodes labelled with each of
+// attrs, or nil if there are none.
+func affinityFor(attrs map[string]string) *affinity {
+	if len(attrs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	na := &nodeAffinity{}
+	for _, k := range keys {
+		na.PreferredDuringSchedulingIgnoredDuringExecution = append(na.PreferredDuringSchedulingIgnoredDuringExecution,
+			preferredSchedulingTerm{
+				Weight: 1,
+				Preference: nodeSelectorTerm{
+					MatchExpressions: []nodeSelectorRequirement{{Key: k, Operator: "In", Values: []string{attrs[k]}}},
+				},
+			})
+	}
+	return &affinity{NodeAffinity: na}
+}
+
+// readinessProbeFor returns the probe which performs the check startup
+// describes, or nil if there is none.
+func readinessProbeFor(startup sous.Startup) *probe {
+	if startup.SkipCheck {
+		return nil
+	}
+	p := &probe{
+		InitialDelaySeconds: startup.ConnectDelay,
+		TimeoutSeconds:      startup.CheckReadyURITimeout,
+		PeriodSeconds:       startup.CheckReadyInterval,
+		FailureThreshold:    startup.CheckReadyRetries,
+	}
+	port := int32(basePort + startup.CheckReadyPortIndex)
+	switch protocol := strings.ToUpper(startup.CheckReadyProtocol); protocol {
+	default:
+		if startup.CheckReadyURIPath == "" {
+			return nil
+		}
+		p.HTTPGet = &httpGetAction{
+			Path:   startup.CheckReadyURIPath,
+			Port:   port,
+			Scheme: protocol,
+		}
+	case sous.CheckReadyTCP:
+		p.TCPSocket = &tcpSocketAction{Port: port}
+	case sous.CheckReadyExec:
+		p.Exec = &execAction{Command: startup.CheckReadyCommand}
+	}
+	return p
+}
+
+func deploymentFor(d *sous.Deployable) (*deployment, error) {
+	name, err := MakeObjectName(d.ID())
+	if err != nil {
+		return nil, err
+	}
+	meta, err := objectMetaFor(name, d.Deployment)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := podTemplateFor(name, d)
+	if err != nil {
+		return nil, err
+	}
+	return &deployment{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Metadata:   meta,
+		Spec: deploymentSpec{
+			Replicas: int32(d.Deployment.NumInstances),
+			Selector: labelSelector{MatchLabels: map[string]string{ObjectNameLabel: name}},
+			Template: tmpl,
+		},
+	}, nil
+}
+
+func cronJobFor(d *sous.Deployable) (*cronJob, error) {
+	name, err := MakeObjectName(d.ID())
+	if err != nil {
+		return nil, err
+	}
+	meta, err := objectMetaFor(name, d.Deployment)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := podTemplateFor(name, d)
+	if err != nil {
+		return nil, err
+	}
+	tmpl.Spec.RestartPolicy = "OnFailure"
+	parallelism := int32(d.Deployment.NumInstances)
+	// Kubernetes defaults the backoff limit to 6, so it is always set.
+	backoffLimit := int32(d.Deployment.RetriesOnFailure)
+	cj := &cronJob{
+		APIVersion: "batch/v1",
+		Kind:       "CronJob",
+		Metadata:   meta,
+		Spec: cronJobSpec{
+			Schedule:          d.Deployment.Schedule,
+			ConcurrencyPolicy: "Forbid",
+			JobTemplate: jobTemplateSpec{
+				Spec: jobSpec{Parallelism: &parallelism, BackoffLimit: &backoffLimit, Template: tmpl},
+			},
+		},
+	}
+	if tz := d.Deployment.ScheduleTimeZone; tz != "" {
+		cj.Spec.TimeZone = &tz
+	}
+	if max := int64(d.Deployment.MaxExecutionSeconds); max > 0 {
+		cj.Spec.JobTemplate.Spec.ActiveDeadlineSeconds = &max
+	}
+	return cj, nil
+}
+
+// deploymentFromObject rebuilds a sous.Deployment from the metadata and pod
+// template of a Kubernetes object written by Sous.
+func deploymentFromObject(meta objectMeta, tmpl podTemplateSpec, clusters sous.Clusters) (*sous.Deployment, error) {
+	ann := meta.Annotations
+	repo, ok := ann[sous.RepoLabel]
+	if !ok || meta.Labels[ManagedLabel] != "true" {
+		return nil, nonSousError{name: meta.Name}
+	}
+	clusterName := ann[sous.ClusterNameLabel]
+	cluster, ok := clusters[clusterName]
+	if !ok {
+		return nil, notThisClusterError{name: meta.Name, cluster: clusterName}
+	}
+	sid, err := sous.NewSourceID(repo, ann[sous.PathLabel], ann[sous.VersionLabel])
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing source ID of %q", meta.Name)
+	}
+
+	d := &sous.Deployment{
+		ClusterName: clusterName,
+		Cluster:     cluster,
+		SourceID:    sid,
+		Flavor:      ann[sous.FlavorLabel],
+		Kind:        sous.ManifestKind(ann[KindAnnotation]),
+		Owners:      sous.NewOwnerSet(),
+	}
+	if owners := ann[OwnersAnnotation]; owners != "" {
+		d.Owners = sous.NewOwnerSet(strings.Split(owners, ",")...)
+	}
+	if s := ann[StartupAnnotation]; s != "" {
+		if err := json.Unmarshal([]byte(s), &d.Startup); err != nil {
+			return nil, errors.Wrapf(err, "parsing startup of %q", meta.Name)
+		}
+	}
+	if s := ann[PlacementAnnotation]; s != "" {
+		if err := json.Unmarshal([]byte(s), &d.Placement); err != nil {
+			return nil, errors.Wrapf(err, "parsing placement of %q", meta.Name)
+		}
+	}
+	d.Metadata = sous.Metadata{}
+	if s := ann[MetadataAnnotation]; s != "" {
+		if err := json.Unmarshal([]byte(s), &d.Metadata); err != nil {
+			return nil, errors.Wrapf(err, "parsing metadata of %q", meta.Name)
+		}
+	}
+
+	if len(tmpl.Spec.Containers) != 1 {
+		return nil, errors.Errorf("%q has %d containers, expected 1", meta.Name, len(tmpl.Spec.Containers))
+	}
+	c := tmpl.Spec.Containers[0]
+
+	d.Env = sous.Env{}
+	for _, e := range c.Env {
+		if portEnvName.MatchString(e.Name) {
+			continue
+		}
+		d.Env[e.Name] = e.Value
+	}
+
+	d.Resources = sous.Resources{
+		"cpus":   c.Resources.Limits["cpu"],
+		"memory": strings.TrimSuffix(c.Resources.Limits["memory"], "Mi"),
+		"ports":  strconv.Itoa(len(c.Ports)),
+	}
+
+	hostPaths := map[string]string{}
+	for _, v := range tmpl.Spec.Volumes {
+		if v.HostPath != nil {
+			hostPaths[v.Name] = v.HostPath.Path
+		}
+	}
+	d.Volumes = sous.Volumes{}
+	for _, m := range c.VolumeMounts {
+		mode := sous.ReadWrite
+		if m.ReadOnly {
+			mode = sous.ReadOnly
+		}
+		d.Volumes = append(d.Volumes, &sous.Volume{Host: hostPaths[m.Name], Container: m.MountPath, Mode: mode})
+	}
+
+	return d, nil
+}
+
+// objectData is the ExecutorData of a DeployState read from Kubernetes. It
+// records the resourceVersion of the object read, so that an update replaces
+// that version of it and no other.
+type objectData struct {
+	name, resourceVersion string
+}
+
+func objectDataOf(meta objectMeta) objectData {
+	return objectData{name: meta.Name, resourceVersion: meta.ResourceVersion}
+}
+
+func stateFromDeployment(kd *deployment, clusters sous.Clusters) (*sous.DeployState, error) {
+	d, err := deploymentFromObject(kd.Metadata, kd.Spec.Template, clusters)
+	if err != nil {
+		return nil, err
+	}
+	d.NumInstances = int(kd.Spec.Replicas)
+	return &sous.DeployState{
+		Deployment:      *d,
+		Status:          deploymentStatusOf(kd),
+		ExecutorMessage: deploymentMessageOf(kd),
+		ExecutorData:    objectDataOf(kd.Metadata),
+	}, nil
+}
+
+func stateFromCronJob(cj *cronJob, clusters sous.Clusters) (*sous.DeployState, error) {
+	d, err := deploymentFromObject(cj.Metadata, cj.Spec.JobTemplate.Spec.Template, clusters)
+	if err != nil {
+		return nil, err
+	}
+	d.Schedule = cj.Spec.Schedule
+	if tz := cj.Spec.TimeZone; tz != nil {
+		d.ScheduleTimeZone = *tz
+	}
+	js := cj.Spec.JobTemplate.Spec
+	if p := js.Parallelism; p != nil {
+		d.NumInstances = int(*p)
+	}
+	if max := js.ActiveDeadlineSeconds; max != nil {
+		d.MaxExecutionSeconds = int(*max)
+	}
+	if b := js.BackoffLimit; b != nil {
+		d.RetriesOnFailure = int(*b)
+	}
+	return &sous.DeployState{
+		Deployment:   *d,
+		Status:       sous.DeployStatusActive,
+		ExecutorData: objectDataOf(cj.Metadata),
+	}, nil
+}
+
+func deploymentStatusOf(kd *deployment) sous.DeployStatus {
+	for _, c := range kd.Status.Conditions {
+		if c.Type == "Progressing" && c.Reason == "ProgressDeadlineExceeded" {
+			return sous.DeployStatusFailed
+		}
+	}
+	if kd.Metadata.Generation > kd.Status.ObservedGeneration {
+		return sous.DeployStatusPending
+	}
+	if kd.Status.UpdatedReplicas == kd.Spec.Replicas && kd.Status.AvailableReplicas == kd.Spec.Replicas {
+		return sous.DeployStatusActive
+	}
+	return sous.DeployStatusPending
+}
+
+func deploymentMessageOf(kd *deployment) string {
+	for _, c := range kd.Status.Conditions {
+		if c.Status != "True" || c.Type == "Progressing" && c.Reason == "ProgressDeadlineExceeded" {
+			return c.Message
+		}
+	}
+	return ""
+}
@@ -0,0 +1,184 @@
+package kubernetes
+
+// The types in this file are the subset of the Kubernetes API objects that
+// Sous reads and writes. They are kept deliberately small: fields Sous does not
+// manage are dropped on read and left to the API server's defaults on write.
+
+type (
+	objectMeta struct {
+		Name              string            `json:"name"`
+		Namespace         string            `json:"namespace,omitempty"`
+		Labels            map[string]string `json:"labels,omitempty"`
+		Annotations       map[string]string `json:"annotations,omitempty"`
+		ResourceVersion   string            `json:"resourceVersion,omitempty"`
+		Generation        int64             `json:"generation,omitempty"`
+		DeletionTimestamp string            `json:"deletionTimestamp,omitempty"`
+	}
+
+	labelSelector struct {
+		MatchLabels map[string]string `json:"matchLabels,omitempty"`
+	}
+
+	deployment struct {
+		APIVersion string           `json:"apiVersion"`
+		Kind       string           `json:"kind"`
+		Metadata   objectMeta       `json:"metadata"`
+		Spec       deploymentSpec   `json:"spec"`
+		Status     deploymentStatus `json:"status,omitempty"`
+	}
+
+	deploymentList struct {
+		Items []*deployment `json:"items"`
+	}
+
+	deploymentSpec struct {
+		Replicas int32           `json:"replicas"`
+		Selector labelSelector   `json:"selector"`
+		Template podTemplateSpec `json:"template"`
+	}
+
+	deploymentStatus struct {
+		ObservedGeneration int64                 `json:"observedGeneration,omitempty"`
+		Replicas           int32                 `json:"replicas,omitempty"`
+		UpdatedReplicas    int32                 `json:"updatedReplicas,omitempty"`
+		AvailableReplicas  int32                 `json:"availableReplicas,omitempty"`
+		Conditions         []deploymentCondition `json:"conditions,omitempty"`
+	}
+
+	deploymentCondition struct {
+		Type    string `json:"type"`
+		Status  string `json:"status"`
+		Reason  string `json:"reason,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+
+	cronJob struct {
+		APIVersion string      `json:"apiVersion"`
+		Kind       string      `json:"kind"`
+		Metadata   objectMeta  `json:"metadata"`
+		Spec       cronJobSpec `json:"spec"`
+	}
+
+	cronJobList struct {
+		Items []*cronJob `json:"items"`
+	}
+
+	cronJobSpec struct {
+		Schedule          string          `json:"schedule"`
+		TimeZone          *string         `json:"timeZone,omitempty"`
+		ConcurrencyPolicy string          `json:"concurrencyPolicy,omitempty"`
+		JobTemplate       jobTemplateSpec `json:"jobTemplate"`
+	}
+
+	jobTemplateSpec struct {
+		Metadata objectMeta `json:"metadata,omitempty"`
+		Spec     jobSpec    `json:"spec"`
+	}
+
+	jobSpec struct {
+		Parallelism           *int32          `json:"parallelism,omitempty"`
+		ActiveDeadlineSeconds *int64          `json:"activeDeadlineSeconds,omitempty"`
+		BackoffLimit          *int32          `json:"backoffLimit,omitempty"`
+		Template              podTemplateSpec `json:"template"`
+	}
+
+	podTemplateSpec struct {
+		Metadata objectMeta `json:"metadata,omitempty"`
+		Spec     podSpec    `json:"spec"`
+	}
+
+	podSpec struct {
+		Containers    []container       `json:"containers"`
+		Volumes       []volume          `json:"volumes,omitempty"`
+		RestartPolicy string            `json:"restartPolicy,omitempty"`
+		NodeSelector  map[string]string `json:"nodeSelector,omitempty"`
+		Affinity      *affinity         `json:"affinity,omitempty"`
+	}
+
+	affinity struct {
+		NodeAffinity *nodeAffinity `json:"nodeAffinity,omitempty"`
+	}
+
+	nodeAffinity struct {
+		PreferredDuringSchedulingIgnoredDuringExecution []preferredSchedulingTerm `json:"preferredDuringSchedulingIgnoredDuringExecution,omitempty"`
+	}
+
+	preferredSchedulingTerm struct {
+		Weight     int32            `json:"weight"`
+		Preference nodeSelectorTerm `json:"preference"`
+	}
+
+	nodeSelectorTerm struct {
+		MatchExpressions []nodeSelectorRequirement `json:"matchExpressions,omitempty"`
+	}
+
+	nodeSelectorRequirement struct {
+		Key      string   `json:"key"`
+		Operator string   `json:"operator"`
+		Values   []string `json:"values,omitempty"`
+	}
+
+	container struct {
+		Name           string               `json:"name"`
+		Image          string               `json:"image"`
+		Env            []envVar             `json:"env,omitempty"`
+		Ports          []containerPort      `json:"ports,omitempty"`
+		Resources      resourceRequirements `json:"resources,omitempty"`
+		VolumeMounts   []volumeMount        `json:"volumeMounts,omitempty"`
+		ReadinessProbe *probe               `json:"readinessProbe,omitempty"`
+	}
+
+	envVar struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	containerPort struct {
+		Name          string `json:"name,omitempty"`
+		ContainerPort int32  `json:"containerPort"`
+	}
+
+	resourceRequirements struct {
+		Limits   map[string]string `json:"limits,omitempty"`
+		Requests map[string]string `json:"requests,omitempty"`
+	}
+
+	volume struct {
+		Name     string                `json:"name"`
+		HostPath *hostPathVolumeSource `json:"hostPath,omitempty"`
+	}
+
+	hostPathVolumeSource struct {
+		Path string `json:"path"`
+	}
+
+	volumeMount struct {
+		Name      string `json:"name"`
+		MountPath string `json:"mountPath"`
+		ReadOnly  bool   `json:"readOnly,omitempty"`
+	}
+
+	probe struct {
+		HTTPGet             *httpGetAction   `json:"httpGet,omitempty"`
+		TCPSocket           *tcpSocketAction `json:"tcpSocket,omitempty"`
+		Exec                *execAction      `json:"exec,omitempty"`
+		InitialDelaySeconds int              `json:"initialDelaySeconds,omitempty"`
+		TimeoutSeconds      int              `json:"timeoutSeconds,omitempty"`
+		PeriodSeconds       int              `json:"periodSeconds,omitempty"`
+		FailureThreshold    int              `json:"failureThreshold,omitempty"`
+	}
+
+	httpGetAction struct {
+		Path   string `json:"path,omitempty"`
+		Port   int32  `json:"port"`
+		Scheme string `json:"scheme,omitempty"`
+	}
+
+	tcpSocketAction struct {
+		Port int32 `json:"port"`
+	}
+
+	execAction struct {
+		Command []string `json:"command"`
+	}
+)
@@ -38,8 +38,10 @@ type (
 		Instances int
 		// Owners is a comma-separated list of email addresses.
 		Owners []string
-		// NOTE: We do not currently support Daemon, RackSensitive or LoadBalanced
-		//Daemon, RackSensitive, LoadBalanced bool
+		// RackSensitive spreads instances evenly across racks.
+		RackSensitive bool
+		// NOTE: We do not currently support Daemon or LoadBalanced
+		//Daemon, LoadBalanced bool
 	}
 )
 
@@ -197,6 +199,7 @@ func (mp *ManifestParser) parseSingleOTPLConfig(wd shell.Shell) *otplDeployConfi
 		return deploySpec
 	}
 	deploySpec.Spec.NumInstances = request.Instances
+	deploySpec.Spec.Placement.RackSensitive = request.RackSensitive
 	deploySpec.Owners = request.Owners
 	return deploySpec
 }
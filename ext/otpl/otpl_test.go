@@ -76,6 +76,7 @@ func TestManifestParser_ParseManifest(t *testing.T) {
 		"config/cluster1.flavor1/singularity-request.json": `{
 	        "owners": ["owner1@example.com"],
 	        "instances": 2,
+	        "rackSensitive": true,
 	        "other fields": "are ignored"
 	    }`,
 		"config/cluster1.flavor1/singularity.json": `{
@@ -154,6 +155,7 @@ func TestManifestParser_ParseManifest(t *testing.T) {
 						},
 						NumInstances: 2,
 						Volumes:      sous.Volumes(nil),
+						Placement:    sous.Placement{RackSensitive: true},
 					},
 					Version: semv.MustParse("0.0.0"),
 				},
@@ -0,0 +1,54 @@
+package secrets
+
+import (
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// Config configures the provider of the secrets deployments refer to.
+type Config struct {
+	// Provider is the kind of provider: "file", "vault", or empty for none.
+	Provider string `env:"SOUS_SECRETS_PROVIDER"`
+	// Dir is the directory holding secrets, for the file provider.
+	Dir string `env:"SOUS_SECRETS_DIR"`
+	// VaultAddr is the URL of the Vault server, for the vault provider.
+	VaultAddr string `env:"SOUS_SECRETS_VAULT_ADDR"`
+	// VaultToken authenticates to the Vault server.
+	VaultToken string `env:"SOUS_SECRETS_VAULT_TOKEN" yaml:",omitempty"`
+	// VaultMount is the path at which the KV (version 2) secrets engine is
+	// mounted. It defaults to "secret".
+	VaultMount string `env:"SOUS_SECRETS_VAULT_MOUNT"`
+}
+
+const (
+	// FileProviderKind is the Provider for the FileProvider.
+	FileProviderKind = "file"
+	// VaultProviderKind is the Provider for the VaultProvider.
+	VaultProviderKind = "vault"
+)
+
+// DefaultVaultMount is the default VaultMount.
+const DefaultVaultMount = "secret"
+
+// Validate returns an error if the configuration is invalid.
+func (c Config) Validate() error {
+	switch c.Provider {
+	default:
+		return errors.Errorf("Provider %q is not %q or %q", c.Provider, FileProviderKind, VaultProviderKind)
+	case "":
+	case FileProviderKind:
+		if c.Dir == "" {
+			return errors.Errorf("Dir is required by the %s provider", FileProviderKind)
+		}
+	case VaultProviderKind:
+		u, err := url.Parse(c.VaultAddr)
+		if err != nil {
+			return errors.Wrapf(err, "VaultAddr %q is not a valid URL", c.VaultAddr)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return errors.Errorf("VaultAddr %q must begin with http:// or https://", c.VaultAddr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/yaml"
+	"github.com/pkg/errors"
+)
+
+type (
+	// FileProvider resolves secrets from files under Dir. The file Dir/Path
+	// holds a secret; a secret with keys is a YAML or JSON map from key to
+	// value, and a secret without is the contents of the file.
+	FileProvider struct {
+		Dir string
+	}
+
+	// VaultProvider resolves secrets from the KV (version 2) secrets engine of
+	// a Vault server, or anything with the same API. Every ref must have a
+	// key.
+	VaultProvider struct {
+		Addr, Token, Mount string
+		Client             *http.Client
+	}
+)
+
+// NewProvider returns the SecretProvider described by cfg, or nil if cfg
+// describes none.
+func NewProvider(cfg Config) sous.SecretProvider {
+	switch cfg.Provider {
+	default:
+		return nil
+	case FileProviderKind:
+		return FileProvider{Dir: cfg.Dir}
+	case VaultProviderKind:
+		return NewVaultProvider(cfg.VaultAddr, cfg.VaultToken, cfg.VaultMount)
+	}
+}
+
+// Secret implements sous.SecretProvider on FileProvider.
+func (fp FileProvider) Secret(ref sous.SecretRef) (string, error) {
+	// ParseSecretRef only allows clean, relative paths, but refs could be
+	// built by hand.
+	p := filepath.Join(fp.Dir, filepath.FromSlash(ref.Path))
+	if rel, err := filepath.Rel(fp.Dir, p); err != nil || strings.HasPrefix(rel, "..") {
+		return "", errors.Errorf("secret %s is outside %s", ref, fp.Dir)
+	}
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return "", errors.Wrapf(err, "reading secret %s", ref)
+	}
+	if ref.Key == "" {
+		return strings.TrimRight(string(b), "\n"), nil
+	}
+	values := map[string]string{}
+	if err := yaml.Unmarshal(b, &values); err != nil {
+		return "", errors.Wrapf(err, "reading secret %s", ref)
+	}
+	return keyOf(ref, values)
+}
+
+// NewVaultProvider returns a VaultProvider for the server at addr.
+func NewVaultProvider(addr, token, mount string) *VaultProvider {
+	if mount == "" {
+		mount = DefaultVaultMount
+	}
+	return &VaultProvider{
+		Addr:   strings.TrimSuffix(addr, "/"),
+		Token:  token,
+		Mount:  strings.Trim(mount, "/"),
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Secret implements sous.SecretProvider on VaultProvider.
+func (vp *VaultProvider) Secret(ref sous.SecretRef) (string, error) {
+	if ref.Key == "" {
+		return "", errors.Errorf("secret %s has no key", ref)
+	}
+	url := fmt.Sprintf("%s/v1/%s/data/%s", vp.Addr, vp.Mount, ref.Path)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", vp.Token)
+	res, err := vp.Client.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "reading secret %s", ref)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", errors.Errorf("reading secret %s: GET %s: %s", ref, url, res.Status)
+	}
+	body := struct {
+		Data struct {
+			Data map[string]string
+		}
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", errors.Wrapf(err, "reading secret %s", ref)
+	}
+	return keyOf(ref, body.Data.Data)
+}
+
+func keyOf(ref sous.SecretRef, values map[string]string) (string, error) {
+	v, ok := values[ref.Key]
+	if !ok {
+		return "", errors.Errorf("secret %s has no key %q", ref.Path, ref.Key)
+	}
+	return v, nil
+}
@@ -0,0 +1,114 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+)
+
+func TestFileProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "db"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "db", "prod"), []byte("user: app\npassword: hunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "api-key"), []byte("abc123\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fp := NewProvider(Config{Provider: FileProviderKind, Dir: dir})
+	testCases := map[sous.SecretRef]string{
+		{Path: "db/prod", Key: "password"}: "hunter2",
+		{Path: "api-key"}:                  "abc123",
+	}
+	for ref, want := range testCases {
+		got, err := fp.Secret(ref)
+		if err != nil {
+			t.Errorf("%s: %v", ref, err)
+		} else if got != want {
+			t.Errorf("%s: got %q; want %q", ref, got, want)
+		}
+	}
+	for _, ref := range []sous.SecretRef{
+		{Path: "db/prod", Key: "host"},
+		{Path: "missing"},
+		{Path: "../outside"},
+	} {
+		if _, err := fp.Secret(ref); err == nil {
+			t.Errorf("%s: no error", ref)
+		}
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "t0ken" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/kv/data/db/prod" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data": {"data": {"password": "hunter2"}, "metadata": {"version": 3}}}`))
+	}))
+	defer srv.Close()
+
+	vp := NewProvider(Config{Provider: VaultProviderKind, VaultAddr: srv.URL + "/", VaultToken: "t0ken", VaultMount: "kv"})
+	got, err := vp.Secret(sous.SecretRef{Path: "db/prod", Key: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "hunter2" {
+		t.Errorf("got %q; want hunter2", got)
+	}
+	for _, ref := range []sous.SecretRef{
+		{Path: "db/prod"},
+		{Path: "db/prod", Key: "user"},
+		{Path: "db/dev", Key: "password"},
+	} {
+		if _, err := vp.Secret(ref); err == nil {
+			t.Errorf("%s: no error", ref)
+		}
+	}
+
+	unauthorized := NewVaultProvider(srv.URL, "wrong", "kv")
+	if _, err := unauthorized.Secret(sous.SecretRef{Path: "db/prod", Key: "password"}); err == nil {
+		t.Errorf("no error with the wrong token")
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	for _, valid := range []Config{
+		{},
+		{Provider: FileProviderKind, Dir: "/etc/sous/secrets"},
+		{Provider: VaultProviderKind, VaultAddr: "https://vault.example.com"},
+	} {
+		if err := valid.Validate(); err != nil {
+			t.Errorf("%+v: %v", valid, err)
+		}
+	}
+	for _, invalid := range []Config{
+		{Provider: "keyring"},
+		{Provider: FileProviderKind},
+		{Provider: VaultProviderKind, VaultAddr: "vault.example.com"},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("%+v: no error", invalid)
+		}
+	}
+	if NewProvider(Config{}) != nil {
+		t.Errorf("NewProvider with no provider should return nil")
+	}
+}
@@ -192,6 +192,14 @@ func (r *deployer) RectifySingleCreate(d *sous.DeployablePair) (err error) {
 
 func (r *deployer) RectifySingleDelete(d *sous.DeployablePair) (err error) {
 	defer rectifyRecover(d, "RectifySingleDelete", &err)
+	// Canaries are removed by the rollouts which created them.
+	if sous.IsCanary(d.ID()) {
+		reqID, err := computeRequestID(d.Prior)
+		if err != nil {
+			return err
+		}
+		return r.Client.DeleteRequest(d.Prior.Deployment.Cluster.BaseURL, reqID, "removing canary after rollout")
+	}
 	data, ok := d.ExecutorData.(*singularityTaskData)
 	if !ok {
 		return errors.Errorf("Delete record %#v doesn't contain Singularity compatible data: was %T\n\t%#v", d.ID(), data, d)
@@ -219,6 +227,15 @@ func (r *deployer) RectifySingleModification(pair *sous.DeployablePair) (err err
 	defer rectifyRecover(pair, "RectifySingleModification", &err)
 
 	data, ok := pair.ExecutorData.(*singularityTaskData)
+	if !ok && sous.IsCanary(pair.ID()) {
+		// Canaries are created by rollouts, which know their request ID only
+		// by the deployment ID, just as RectifySingleCreate does.
+		reqID, err := computeRequestID(pair.Post)
+		if err != nil {
+			return err
+		}
+		data, ok = &singularityTaskData{requestID: reqID}, true
+	}
 	if !ok {
 		err := errors.Errorf("Modification record %#v doesn't contain Singularity compatible data: was %T\n\t%#v", pair.ID(), data, pair)
 		reportDeployerMessage("Error modification not compatible with Singularity", pair, diffs, nil, err, logging.WarningLevel, r.log)
@@ -260,15 +277,25 @@ func (r *deployer) RectifySingleModification(pair *sous.DeployablePair) (err err
 // could report ("deploy required because of %v", diffs)
 
 func changesReq(pair *sous.DeployablePair) bool {
-	return (pair.Prior.Kind == sous.ManifestKindScheduled && pair.Prior.Schedule != pair.Post.Schedule) ||
+	return (pair.Prior.Kind.IsScheduled() && changesSchedule(pair)) ||
 		pair.Prior.Kind != pair.Post.Kind ||
 		pair.Prior.NumInstances != pair.Post.NumInstances ||
-		!pair.Prior.Owners.Equal(pair.Post.Owners)
+		!pair.Prior.Owners.Equal(pair.Post.Owners) ||
+		!pair.Prior.Placement.Equal(pair.Post.Placement)
+}
+
+func changesSchedule(pair *sous.DeployablePair) bool {
+	return pair.Prior.Schedule != pair.Post.Schedule ||
+		pair.Prior.ScheduleTimeZone != pair.Post.ScheduleTimeZone ||
+		pair.Prior.MaxExecutionSeconds != pair.Post.MaxExecutionSeconds ||
+		pair.Prior.RetriesOnFailure != pair.Post.RetriesOnFailure
 }
 
 func changesDep(pair *sous.DeployablePair) bool {
 	return pair.Post.Status == sous.DeployStatusFailed ||
 		pair.Prior.Status == sous.DeployStatusFailed ||
+		// The kind of a ScheduledJob is recorded in the deploy's metadata.
+		pair.Prior.Kind != pair.Post.Kind ||
 		!(pair.Prior.SourceID.Equal(pair.Post.SourceID) &&
 			pair.Prior.Resources.Equal(pair.Post.Resources) &&
 			pair.Prior.Env.Equal(pair.Post.Env) &&
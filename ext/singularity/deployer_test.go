@@ -279,7 +279,7 @@ func matchedPair(t *testing.T, startDep *sous.Deployment) *sous.DeployablePair {
 	req := &dtos.SingularityRequest{}
 	jsonRoundtrip(t, aReq, req)
 
-	aDepReq, err := buildDeployRequest(deployable, reqID, map[string]string{})
+	aDepReq, err := buildDeployRequest(deployable, reqID, map[string]string{}, nil, logging.SilentLogSet())
 	assert.NoError(t, err)
 	assert.NotNil(t, aDepReq)
 
@@ -299,6 +299,7 @@ func matchedPair(t *testing.T, startDep *sous.Deployment) *sous.DeployablePair {
 	assert.NoError(t, db.unpackDeployConfig(), "Could not convert data from a SingularityDeploy to a sous.Deployment.")
 	assert.NoError(t, db.determineManifestKind(), "Could not determine SingularityRequestType.")
 	assert.NoError(t, db.extractSchedule(), "Could not determine schedule.")
+	assert.NoError(t, db.extractPlacement(), "Could not determine placement.")
 
 	post := &db.Target.Deployment
 
@@ -372,6 +373,58 @@ func TestScheduling(t *testing.T) {
 	assert.False(t, changesDep(pair), "Roundtrip of Deployment through Singularity DTOs reported as changing Deploy!")
 }
 
+func TestSchedulingOptions(t *testing.T) {
+	startDep := baseDeployment()
+	startDep.Kind = sous.ScheduledJob
+	startDep.Schedule = "30 3 * * MON-FRI"
+	startDep.ScheduleTimeZone = "America/Los_Angeles"
+	startDep.MaxExecutionSeconds = 600
+	startDep.RetriesOnFailure = 2
+	pair := matchedPair(t, startDep)
+
+	diff, diffs := pair.Prior.Deployment.Diff(pair.Post.Deployment)
+	assert.False(t, diff, "%v", diffs)
+	assert.Equal(t, sous.ScheduledJob, pair.Post.Kind)
+	assert.False(t, changesReq(pair), "Roundtrip of scheduling options through Singularity DTOs reported as changing Request!")
+	assert.False(t, changesDep(pair), "Roundtrip of scheduling options through Singularity DTOs reported as changing Deploy!")
+
+	pair.Prior.RetriesOnFailure = 0
+	pair.Prior.MaxExecutionSeconds = 0
+
+	diff, diffs = pair.Prior.Deployment.Diff(pair.Post.Deployment)
+	assert.True(t, diff)
+	assert.Len(t, diffs, 2)
+	assert.True(t, changesReq(pair), "Updating scheduling options reported as not changing Request!")
+	assert.False(t, changesDep(pair), "Updating scheduling options reported as changing Deploy!")
+}
+
+func TestPlacement(t *testing.T) {
+	for _, perHost := range []int{0, 1} {
+		startDep := baseDeployment()
+		startDep.Placement = sous.Placement{
+			RequiredAttributes:  map[string]string{"zone": "us-west-2a"},
+			PreferredAttributes: map[string]string{"disk": "ssd"},
+			RackSensitive:       true,
+			MaxInstancesPerHost: perHost,
+		}
+		pair := matchedPair(t, startDep)
+
+		diff, diffs := pair.Prior.Deployment.Diff(pair.Post.Deployment)
+		assert.False(t, diff, "%d per host: %v", perHost, diffs)
+		assert.False(t, changesReq(pair), "Roundtrip of placement through Singularity DTOs reported as changing Request!")
+		assert.False(t, changesDep(pair), "Roundtrip of placement through Singularity DTOs reported as changing Deploy!")
+	}
+
+	pair := matchedPair(t, baseDeployment())
+	pair.Prior.Placement.RackSensitive = true
+
+	diff, diffs := pair.Prior.Deployment.Diff(pair.Post.Deployment)
+	assert.True(t, diff)
+	assert.Len(t, diffs, 1)
+	assert.True(t, changesReq(pair), "Updating placement reported as not changing Request!")
+	assert.False(t, changesDep(pair), "Updating placement reported as changing Deploy!")
+}
+
 func TestSchedulingOnlyForScheduled(t *testing.T) {
 	startDep := baseDeployment()
 	startDep.Schedule = "* 3 * * *"
@@ -683,3 +736,51 @@ func TestOptMaxHTTPReqsPerServer(t *testing.T) {
 		t.Fatalf("got %d; want %d", deployer2.ReqsPerServer, x)
 	}
 }
+
+func TestRectifyCanary(t *testing.T) {
+	drc := sous.NewDummyRectificationClient()
+	deployer := NewDeployer(drc, logging.SilentLogSet())
+
+	dpl := &sous.Deployment{
+		SourceID: sous.SourceID{
+			Location: sous.SourceLocation{
+				Repo: "fake.tld/org/project",
+			},
+			Version: semv.MustParse("0.0.2"),
+		},
+		Flavor: sous.CanaryFlavor,
+		DeployConfig: sous.DeployConfig{
+			NumInstances: 1,
+			Resources:    sous.Resources{},
+		},
+		ClusterName: "cluster",
+		Cluster: &sous.Cluster{
+			BaseURL: "cluster",
+		},
+	}
+	prior := &sous.Deployable{Deployment: dpl.Clone(), Status: sous.DeployStatusActive}
+	post := &sous.Deployable{Deployment: dpl.Clone(), Status: sous.DeployStatusActive}
+	post.NumInstances = 5
+	reqID, err := MakeRequestID(dpl.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A canary pair carries no Singularity data: its request is found by its
+	// deployment ID.
+	scale := &sous.DeployablePair{Prior: prior, Post: post}
+	scale.SetID(dpl.ID())
+	rez := deployer.Rectify(scale)
+	assert.Equal(t, sous.ModifyDiff, rez.Desc)
+	assert.Nil(t, rez.Error)
+	assert.Len(t, drc.Created, 1)
+
+	remove := &sous.DeployablePair{Prior: post}
+	remove.SetID(dpl.ID())
+	rez = deployer.Rectify(remove)
+	assert.Equal(t, sous.DeleteDiff, rez.Desc)
+	assert.Nil(t, rez.Error)
+	if assert.Len(t, drc.Deleted, 1) {
+		assert.Equal(t, reqID, drc.Deleted[0].Reqid)
+	}
+}
@@ -105,17 +105,20 @@ func (db *deploymentBuilder) isRetryable(err error) bool {
 // BuildDeployment does all the work to collect the data for a Deployment
 // from Singularity based on the initial SingularityRequest.
 func BuildDeployment(reg sous.ImageLabeller, clusters sous.Clusters, req SingReq, log logging.LogSink) (sous.DeployState, error) {
-	messages.ReportLogFieldsMessage("Build Deployment", logging.ExtraDebug1Level, log, req.ReqParent)
+	messages.ReportLogFieldsMessage("Build Deployment", logging.ExtraDebug1Level, log, redactedRequestParent(req.ReqParent))
 	db := deploymentBuilder{registry: reg, clusters: clusters, req: req, log: log}
 	return db.Target, db.canRetry(db.completeConstruction())
 }
 
-func (db *deploymentBuilder) completeConstruction() error {
-	wrapError := func(fn func() error, msgStr string) func() error {
-		return func() error {
-			return errors.Wrap(fn(), msgStr)
-		}
+// wrapError returns a step of construction which wraps any error from fn
+// with msgStr.
+func wrapError(fn func() error, msgStr string) func() error {
+	return func() error {
+		return errors.Wrap(fn(), msgStr)
 	}
+}
+
+func (db *deploymentBuilder) completeConstruction() error {
 	return firsterr.Returned(
 		wrapError(db.basics, "Failed to extract basic information from original request."),
 		wrapError(db.determineDeployStatus, "Failed to determine deploy status."),
@@ -129,6 +132,7 @@ func (db *deploymentBuilder) completeConstruction() error {
 		wrapError(db.unpackDeployConfig, "Could not convert data from a SingularityDeploy to a sous.Deployment."),
 		wrapError(db.determineManifestKind, "Could not determine SingularityRequestType."),
 		wrapError(db.extractSchedule, "Could not determine Singularity schedule."),
+		wrapError(db.extractPlacement, "Could not determine Singularity placement."),
 	)
 }
 
@@ -220,7 +224,7 @@ func (db *deploymentBuilder) retrieveHistoricDeploy() error {
 
 	partialHistory := depHistList[0]
 
-	messages.ReportLogFieldsMessage("Partial history.", logging.ExtraDebug1Level, db.log, db.reqID, partialHistory)
+	messages.ReportLogFieldsMessage("Partial history.", logging.ExtraDebug1Level, db.log, db.reqID, redactedHistory(partialHistory))
 	if partialHistory.DeployMarker == nil {
 		return malformedResponse{"Singularity deploy history had no deploy marker."}
 	}
@@ -239,12 +243,56 @@ func (db *deploymentBuilder) retrieveLiveDeploy() error {
 		return errors.Wrapf(err, "%q %#v", db.reqID, db.depMarker)
 	}
 
-	messages.ReportLogFieldsMessage("Deploy history entry retrieved.", logging.ExtraDebug1Level, db.log, db.reqID, dh)
+	messages.ReportLogFieldsMessage("Deploy history entry retrieved.", logging.ExtraDebug1Level, db.log, db.reqID, redactedHistory(dh))
 	db.history = dh
 
 	return nil
 }
 
+// redactedDeploy returns a copy of deploy which is safe to log: the values of
+// the secrets in its Env are replaced with sous.RedactedValue. If its secret
+// references cannot be decoded, its whole Env is redacted.
+func redactedDeploy(deploy *dtos.SingularityDeploy) *dtos.SingularityDeploy {
+	if deploy == nil || len(deploy.Env) == 0 {
+		return deploy
+	}
+	label, ok := deploy.Metadata[sous.SecretRefsLabel]
+	if !ok {
+		return deploy
+	}
+	refs, err := sous.DecodeSecretRefs(label)
+	if err != nil {
+		refs = map[string]sous.SecretRef{}
+		for name := range deploy.Env {
+			refs[name] = sous.SecretRef{}
+		}
+	}
+	redacted := *deploy
+	redacted.Env = sous.Env(deploy.Env).Redacted(refs)
+	return &redacted
+}
+
+// redactedHistory returns a copy of dh whose deploy is safe to log.
+func redactedHistory(dh *dtos.SingularityDeployHistory) *dtos.SingularityDeployHistory {
+	if dh == nil {
+		return nil
+	}
+	redacted := *dh
+	redacted.Deploy = redactedDeploy(dh.Deploy)
+	return &redacted
+}
+
+// redactedRequestParent returns a copy of rp whose deploys are safe to log.
+func redactedRequestParent(rp *dtos.SingularityRequestParent) *dtos.SingularityRequestParent {
+	if rp == nil {
+		return nil
+	}
+	redacted := *rp
+	redacted.ActiveDeploy = redactedDeploy(rp.ActiveDeploy)
+	redacted.PendingDeploy = redactedDeploy(rp.PendingDeploy)
+	return &redacted
+}
+
 func (db *deploymentBuilder) extractDeployFromDeployHistory() error {
 	db.deploy = db.history.Deploy
 	if db.deploy == nil {
@@ -358,11 +406,21 @@ func (db *deploymentBuilder) restoreFromMetadata() error {
 }
 
 func (db *deploymentBuilder) unpackDeployConfig() error {
-	db.Target.Env = db.deploy.Env
-	messages.ReportLogFieldsMessage("UnpackDeployConfig", logging.ExtraDebug1Level, db.log, db.reqID, db.deploy.Env)
-	if db.Target.Env == nil {
-		db.Target.Env = make(map[string]string)
+	db.Target.Env = make(sous.Env, len(db.deploy.Env))
+	for name, value := range db.deploy.Env {
+		db.Target.Env[name] = value
+	}
+	// Put back the references to secrets, so that the deployment compares
+	// equal to the GDM, and the values of the secrets go no further.
+	if label, ok := db.deploy.Metadata[sous.SecretRefsLabel]; ok {
+		refs, err := sous.DecodeSecretRefs(label)
+		if err != nil {
+			return malformedResponse{err.Error()}
+		}
+		db.Target.Env.RestoreSecretRefs(refs)
 	}
+	// Only the references to secrets are left in Target.Env to be logged.
+	messages.ReportLogFieldsMessage("UnpackDeployConfig", logging.ExtraDebug1Level, db.log, db.reqID, db.Target.Env)
 
 	singRez := db.deploy.Resources
 	if singRez == nil {
@@ -426,6 +484,9 @@ func (db *deploymentBuilder) determineManifestKind() error {
 		db.Target.Kind = sous.ManifestKindOnDemand
 	case dtos.SingularityRequestRequestTypeSCHEDULED:
 		db.Target.Kind = sous.ManifestKindScheduled
+		if db.deploy != nil && db.deploy.Metadata[sous.KindLabel] == string(sous.ScheduledJob) {
+			db.Target.Kind = sous.ScheduledJob
+		}
 	case dtos.SingularityRequestRequestTypeRUN_ONCE:
 		db.Target.Kind = sous.ManifestKindOnce
 	}
@@ -433,11 +494,38 @@ func (db *deploymentBuilder) determineManifestKind() error {
 }
 
 func (db *deploymentBuilder) extractSchedule() error {
-	if db.Target.Kind == sous.ManifestKindScheduled {
+	if db.Target.Kind.IsScheduled() {
 		if db.request == nil {
 			return fmt.Errorf("request is nil")
 		}
 		db.Target.DeployConfig.Schedule = db.request.Schedule
+		db.Target.DeployConfig.ScheduleTimeZone = db.request.ScheduleTimeZone
+		db.Target.DeployConfig.MaxExecutionSeconds = int(db.request.TaskExecutionTimeLimitMillis / 1000)
+		db.Target.DeployConfig.RetriesOnFailure = int(db.request.NumRetriesOnFailure)
+	}
+	return nil
+}
+
+func (db *deploymentBuilder) extractPlacement() error {
+	if db.request == nil {
+		return fmt.Errorf("request is nil")
+	}
+	p := &db.Target.DeployConfig.Placement
+	if len(db.request.RequiredSlaveAttributes) > 0 {
+		p.RequiredAttributes = make(map[string]string, len(db.request.RequiredSlaveAttributes))
+		for k, v := range db.request.RequiredSlaveAttributes {
+			p.RequiredAttributes[k] = v
+		}
+	}
+	if len(db.request.AllowedSlaveAttributes) > 0 {
+		p.PreferredAttributes = make(map[string]string, len(db.request.AllowedSlaveAttributes))
+		for k, v := range db.request.AllowedSlaveAttributes {
+			p.PreferredAttributes[k] = v
+		}
+	}
+	p.RackSensitive = db.request.RackSensitive
+	if db.request.SlavePlacement == dtos.SingularityRequestSlavePlacementSEPARATE_BY_REQUEST {
+		p.MaxInstancesPerHost = 1
 	}
 	return nil
 }
@@ -145,7 +145,7 @@ func TestBuildDeployment(t *testing.T) {
 		},
 	}
 
-	log, _ := logging.NewLogSinkSpy()
+	log, ctrl := logging.NewLogSinkSpy()
 
 	fakeSing := &fakeSingClient{
 		cannedAnswer: &dtos.SingularityDeployHistory{
@@ -157,6 +157,11 @@ func TestBuildDeployment(t *testing.T) {
 				Metadata: map[string]string{
 					"com.opentable.sous.clustername": "left",
 					"com.opentable.sous.flavor":      "vanilla",
+					"com.opentable.sous.secret_refs": `{"PASSWORD": "secret://db/prod#password"}`,
+				},
+				Env: map[string]string{
+					"PASSWORD": "hunter2",
+					"PLAIN":    "value",
 				},
 
 				Healthcheck: &dtos.HealthcheckOptions{
@@ -209,6 +214,9 @@ func TestBuildDeployment(t *testing.T) {
 	assert.Equal(t, actual.Startup.CheckReadyURITimeout, 350)
 
 	assert.Equal(t, actual.Startup.Timeout, 700)
+
+	assert.Equal(t, sous.Env{"PASSWORD": "secret://db/prod#password", "PLAIN": "value"}, actual.Env)
+	assert.NotContains(t, loggedFields(ctrl), "hunter2", "a resolved secret was logged")
 }
 
 func TestBuildDeployment_failed_deploy(t *testing.T) {
@@ -0,0 +1,125 @@
+package singularity
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/opentable/go-singularity/dtos"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/firsterr"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	// An Import is a Singularity request which Sous does not manage, with the
+	// Deployable reconstructed from its current deploy.
+	Import struct {
+		RequestID  string
+		Deployable sous.Deployable
+	}
+
+	// A RequestLister lists the requests on a Singularity, and retrieves
+	// their deploys. *singularity.Client implements it.
+	RequestLister interface {
+		SingClient
+		GetRequests(useWebCache bool) (dtos.SingularityRequestParentList, error)
+	}
+
+	managedBySousError struct {
+		clusterName string
+	}
+)
+
+func (mbs managedBySousError) Error() string {
+	return fmt.Sprintf("already managed by Sous, as part of cluster %q", mbs.clusterName)
+}
+
+// ImportDeployment reconstructs the Deployable of a Singularity request
+// which Sous does not manage, as a deployment to cluster. It returns an
+// error if Sous already manages the request, or if the request's image lacks
+// the labels from which Sous derives a SourceID.
+func ImportDeployment(reg sous.ImageLabeller, cluster *sous.Cluster, req SingReq, log logging.LogSink) (*sous.Deployable, error) {
+	messages.ReportLogFieldsMessage("Import Deployment", logging.ExtraDebug1Level, log, redactedRequestParent(req.ReqParent))
+	db := deploymentBuilder{registry: reg, clusters: sous.Clusters{cluster.Name: cluster}, req: req, log: log}
+	if err := db.importConstruction(cluster); err != nil {
+		return nil, err
+	}
+	return &sous.Deployable{
+		Status:        db.Target.Status,
+		Deployment:    &db.Target.Deployment,
+		BuildArtifact: &sous.BuildArtifact{Name: db.imageName, Type: "docker"},
+	}, nil
+}
+
+// importConstruction is completeConstruction for requests Sous does not
+// manage, whose cluster and flavor cannot be read from their metadata.
+func (db *deploymentBuilder) importConstruction(cluster *sous.Cluster) error {
+	return firsterr.Returned(
+		wrapError(db.basics, "Failed to extract basic information from original request."),
+		wrapError(db.determineDeployStatus, "Failed to determine deploy status."),
+		wrapError(db.retrieveDeployHistory, "Failed to retrieve SingularityDeployHistory from SingularityRequestParent."),
+		wrapError(db.extractDeployFromDeployHistory, "Failed to extract SingularityDeploy from SingularityDeployHistory."),
+		db.importCheck,
+		wrapError(db.determineStatus, "Could not determine current status of SingularityDeploy"),
+		wrapError(db.extractArtifactName, "Could not extract ArtifactName (Docker image name) from SingularityDeploy."),
+		wrapError(db.retrieveImageLabels, "Could not retrieve ImageLabels (Docker image labels) from sous.Registry."),
+		func() error {
+			db.Target.ClusterName = cluster.Name
+			db.Target.Cluster = cluster
+			return nil
+		},
+		wrapError(db.unpackDeployConfig, "Could not convert data from a SingularityDeploy to a sous.Deployment."),
+		wrapError(db.determineManifestKind, "Could not determine SingularityRequestType."),
+		wrapError(db.extractSchedule, "Could not determine Singularity schedule."),
+		wrapError(db.extractPlacement, "Could not determine Singularity placement."),
+	)
+}
+
+func (db *deploymentBuilder) importCheck() error {
+	if cnl, ok := db.deploy.Metadata[sous.ClusterNameLabel]; ok {
+		return managedBySousError{cnl}
+	}
+	return nil
+}
+
+// ImportRequests reconstructs an Import of every request on cluster's
+// Singularity which Sous does not manage, ordered by request ID. The reasons
+// other requests could not be imported are returned by request ID in skipped;
+// requests Sous already manages are left out of both.
+func ImportRequests(client RequestLister, reg sous.ImageLabeller, cluster *sous.Cluster, log logging.LogSink) (imports []Import, skipped map[string]error, err error) {
+	rps, err := client.GetRequests(false)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "getting requests from %s", cluster.BaseURL)
+	}
+	sort.Slice(rps, func(i, j int) bool { return reqID(rps[i]) < reqID(rps[j]) })
+
+	skipped = map[string]error{}
+	imported := map[sous.DeploymentID]string{}
+	for _, rp := range rps {
+		id := reqID(rp)
+		d, err := ImportDeployment(reg, cluster, SingReq{SourceURL: cluster.BaseURL, Sing: client, ReqParent: rp}, log)
+		if err != nil {
+			if _, managed := errors.Cause(err).(managedBySousError); !managed {
+				skipped[id] = err
+			}
+			continue
+		}
+		did := d.ID()
+		if other, dup := imported[did]; dup {
+			skipped[id] = errors.Errorf("would be the same deployment, %s, as request %s", did, other)
+			continue
+		}
+		imported[did] = id
+		imports = append(imports, Import{RequestID: id, Deployable: *d})
+	}
+	return imports, skipped, nil
+}
+
+// Adopt brings an imported request under Sous's management, by deploying
+// its current image and configuration again with the metadata Sous uses to
+// recognise its own deploys.
+func (ra *RectiAgent) Adopt(imp Import) error {
+	return ra.Deploy(imp.Deployable, imp.RequestID)
+}
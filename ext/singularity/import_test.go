@@ -0,0 +1,118 @@
+package singularity
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/opentable/go-singularity/dtos"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/swaggering"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	fakeRequestLister struct {
+		requests dtos.SingularityRequestParentList
+		deploys  map[string]*dtos.SingularityDeployHistory
+	}
+
+	imageLabels map[string]map[string]string
+)
+
+func (f *fakeRequestLister) GetRequests(bool) (dtos.SingularityRequestParentList, error) {
+	return f.requests, nil
+}
+
+func (f *fakeRequestLister) GetDeploy(requestID, deployID string) (*dtos.SingularityDeployHistory, error) {
+	if dh, ok := f.deploys[requestID]; ok {
+		return dh, nil
+	}
+	return nil, fmt.Errorf("no deploy for %s", requestID)
+}
+
+func (f *fakeRequestLister) GetDeploys(requestID string, count, page int32) (dtos.SingularityDeployHistoryList, error) {
+	if dh, ok := f.deploys[requestID]; ok {
+		return dtos.SingularityDeployHistoryList{dh}, nil
+	}
+	return nil, nil
+}
+
+func (il imageLabels) ImageLabels(imageName string) (map[string]string, error) {
+	if labels, ok := il[imageName]; ok {
+		return labels, nil
+	}
+	return nil, fmt.Errorf("no labels for %s", imageName)
+}
+
+func (f *fakeRequestLister) add(id, image string, metadata map[string]string) {
+	f.requests = append(f.requests, &dtos.SingularityRequestParent{
+		RequestDeployState: &dtos.SingularityRequestDeployState{},
+		Request: &dtos.SingularityRequest{
+			Id:          id,
+			RequestType: dtos.SingularityRequestRequestTypeSERVICE,
+			Instances:   2,
+			Owners:      swaggering.StringList{"owner@example.com"},
+		},
+	})
+	f.deploys[id] = &dtos.SingularityDeployHistory{
+		DeployResult: &dtos.SingularityDeployResult{DeployState: dtos.SingularityDeployResultDeployStateSUCCEEDED},
+		DeployMarker: &dtos.SingularityDeployMarker{RequestId: id, DeployId: "1"},
+		Deploy: &dtos.SingularityDeploy{
+			RequestId: id,
+			Metadata:  metadata,
+			Env:       map[string]string{"GREETING": "hello"},
+			ContainerInfo: &dtos.SingularityContainerInfo{
+				Type:   "DOCKER",
+				Docker: &dtos.SingularityDockerInfo{Image: image},
+			},
+			Resources: &dtos.Resources{Cpus: 0.5, MemoryMb: 256, NumPorts: 1},
+		},
+	}
+}
+
+func TestImportRequests(t *testing.T) {
+	cluster := &sous.Cluster{Name: "left", Kind: sous.ClusterKindSingularity, BaseURL: "http://singularity.example.com"}
+	labels := func(repo string) map[string]string {
+		return map[string]string{
+			"com.opentable.sous.repo_url":    repo,
+			"com.opentable.sous.repo_offset": "",
+			"com.opentable.sous.revision":    "abc123",
+			"com.opentable.sous.version":     "1.2.3",
+		}
+	}
+	reg := imageLabels{
+		"docker.example.com/legacy:1.2.3":   labels("github.com/example/legacy"),
+		"docker.example.com/managed:1.2.3":  labels("github.com/example/managed"),
+		"docker.example.com/copy:1.2.3":     labels("github.com/example/legacy"),
+		"docker.example.com/unlabelled:1.0": {},
+	}
+	client := &fakeRequestLister{deploys: map[string]*dtos.SingularityDeployHistory{}}
+	client.add("legacy", "docker.example.com/legacy:1.2.3", nil)
+	client.add("legacy-copy", "docker.example.com/copy:1.2.3", nil)
+	client.add("managed", "docker.example.com/managed:1.2.3", map[string]string{sous.ClusterNameLabel: "left"})
+	client.add("unlabelled", "docker.example.com/unlabelled:1.0", nil)
+
+	log, _ := logging.NewLogSinkSpy()
+	imports, skipped, err := ImportRequests(client, reg, cluster, log)
+	require.NoError(t, err)
+
+	require.Len(t, imports, 1)
+	imp := imports[0]
+	assert.Equal(t, "legacy", imp.RequestID)
+	d := imp.Deployable
+	assert.Equal(t, "left", d.ClusterName)
+	assert.Equal(t, cluster, d.Cluster)
+	assert.Equal(t, "github.com/example/legacy", d.SourceID.Location.Repo)
+	assert.Equal(t, sous.ManifestKindService, d.Kind)
+	assert.Equal(t, 2, d.NumInstances)
+	assert.Equal(t, sous.Env{"GREETING": "hello"}, d.Env)
+	assert.True(t, d.Owners.Equal(sous.NewOwnerSet("owner@example.com")))
+	assert.Equal(t, "docker.example.com/legacy:1.2.3", d.BuildArtifact.Name)
+
+	assert.Len(t, skipped, 2)
+	assert.Contains(t, skipped, "legacy-copy", "a second request for the same deployment")
+	assert.Contains(t, skipped, "unlabelled")
+	assert.NotContains(t, skipped, "managed")
+}
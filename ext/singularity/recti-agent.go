@@ -39,6 +39,9 @@ type (
 		singClients map[string]*singularity.Client
 		sync.RWMutex
 		labeller sous.ImageLabeller
+		// Secrets resolves the secrets referred to by deployments' Env. If it
+		// is nil, deployments which refer to secrets fail.
+		Secrets sous.SecretProvider
 	}
 
 	singularityTaskData struct {
@@ -76,17 +79,20 @@ func (ra *RectiAgent) Deploy(d sous.Deployable, reqID string) error {
 		return err
 	}
 	messages.ReportLogFieldsMessage("Deploying instance", logging.DebugLevel, Log, d, reqID)
-	depReq, err := buildDeployRequest(d, reqID, labels)
+	depReq, err := buildDeployRequest(d, reqID, labels, ra.Secrets, Log)
 	if err != nil {
 		return err
 	}
 
-	messages.ReportLogFieldsMessage("Deploy req", logging.DebugLevel, Log, depReq)
 	_, err = ra.singularityClient(clusterURI).Deploy(depReq)
 	return err
 }
 
-func buildDeployRequest(d sous.Deployable, reqID string, metadata map[string]string) (*dtos.SingularityDeployRequest, error) {
+// buildDeployRequest builds the request to deploy d. The secrets d's Env
+// refers to are resolved with secrets, but redacted from the request until it
+// has been logged to log; the references are recorded in the deploy's
+// metadata, so that deployment_builder can restore them.
+func buildDeployRequest(d sous.Deployable, reqID string, metadata map[string]string, secrets sous.SecretProvider, log logging.LogSink) (*dtos.SingularityDeployRequest, error) {
 	var depReq swaggering.Fielder
 	depID := computeDeployID(&d)
 	dockerImage := d.BuildArtifact.Name
@@ -96,6 +102,18 @@ func buildDeployRequest(d sous.Deployable, reqID string, metadata map[string]str
 
 	metadata[sous.ClusterNameLabel] = d.Deployment.ClusterName
 	metadata[sous.FlavorLabel] = d.Deployment.Flavor
+	if d.Deployment.Kind == sous.ScheduledJob {
+		// Singularity has one request type for both scheduled kinds.
+		metadata[sous.KindLabel] = string(d.Deployment.Kind)
+	}
+
+	env, refs, err := e.ResolveSecrets(secrets)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) > 0 {
+		metadata[sous.SecretRefsLabel] = sous.EncodeSecretRefs(refs)
+	}
 
 	dockerInfo, err := swaggering.LoadMap(&dtos.SingularityDockerInfo{}, dtoMap{
 		"Image":   dockerImage,
@@ -141,7 +159,7 @@ func buildDeployRequest(d sous.Deployable, reqID string, metadata map[string]str
 		"RequestId":     reqID,
 		"Resources":     res,
 		"ContainerInfo": ci,
-		"Env":           map[string]string(e),
+		"Env":           map[string]string(env.Redacted(refs)),
 		"Metadata":      metadata,
 	}
 
@@ -153,12 +171,19 @@ func buildDeployRequest(d sous.Deployable, reqID string, metadata map[string]str
 	if err != nil {
 		return nil, err
 	}
-	messages.ReportLogFieldsMessage("Deploy", logging.DebugLevel, Log, dep, ci, dockerInfo)
+	messages.ReportLogFieldsMessage("Deploy", logging.DebugLevel, log, dep, ci, dockerInfo)
 
 	depReq, err = swaggering.LoadMap(&dtos.SingularityDeployRequest{}, dtoMap{"Deploy": dep})
 	if err != nil {
 		return nil, err
 	}
+	messages.ReportLogFieldsMessage("Deploy req", logging.DebugLevel, log, depReq)
+
+	if len(refs) > 0 {
+		if err := dep.SetField("Env", map[string]string(env)); err != nil {
+			return nil, err
+		}
+	}
 	return depReq.(*dtos.SingularityDeployRequest), nil
 }
 
@@ -170,6 +195,11 @@ func MapStartupIntoHealthcheckOptions(depMap *map[string]interface{}, startup so
 	if startup.SkipCheck {
 		return nil
 	}
+	switch p := strings.ToUpper(startup.CheckReadyProtocol); p {
+	case sous.CheckReadyTCP, sous.CheckReadyExec:
+		// Deployment.Validate should have rejected these already.
+		return fmt.Errorf("Singularity does not support %s readiness checks", p)
+	}
 
 	hcMap := dtoMap{}
 
@@ -216,9 +246,19 @@ func singRequestFromDeployment(dep *sous.Deployment, reqID string) (string, *dto
 		// until and unless someone asks
 		reqFields["ScheduleType"] = dtos.SingularityRequestScheduleTypeCRON
 
-		// also present but not addressed:
-		// taskExecutionTimeLimitMillis
+		// Unset fields take Singularity's defaults: UTC, no time limit and no
+		// retries.
+		if dep.ScheduleTimeZone != "" {
+			reqFields["ScheduleTimeZone"] = dep.ScheduleTimeZone
+		}
+		if dep.MaxExecutionSeconds > 0 {
+			reqFields["TaskExecutionTimeLimitMillis"] = int64(dep.MaxExecutionSeconds) * 1000
+		}
+		if dep.RetriesOnFailure > 0 {
+			reqFields["NumRetriesOnFailure"] = int32(dep.RetriesOnFailure)
+		}
 	}
+	placementRequestFields(reqFields, dep.Placement)
 	req, err := swaggering.LoadMap(&dtos.SingularityRequest{}, reqFields)
 
 	if err != nil {
@@ -228,6 +268,24 @@ func singRequestFromDeployment(dep *sous.Deployment, reqID string) (string, *dto
 	return cluster, req.(*dtos.SingularityRequest), nil
 }
 
+// placementRequestFields adds the request fields which enforce placement to
+// reqFields. Unset fields take Singularity's defaults.
+func placementRequestFields(reqFields dtoMap, placement sous.Placement) {
+	if len(placement.RequiredAttributes) > 0 {
+		reqFields["RequiredSlaveAttributes"] = placement.Clone().RequiredAttributes
+	}
+	if len(placement.PreferredAttributes) > 0 {
+		reqFields["AllowedSlaveAttributes"] = placement.Clone().PreferredAttributes
+	}
+	if placement.RackSensitive {
+		reqFields["RackSensitive"] = true
+	}
+	// Placement.ValidateFor rejects any other limit on Singularity clusters.
+	if placement.MaxInstancesPerHost == 1 {
+		reqFields["SlavePlacement"] = dtos.SingularityRequestSlavePlacementSEPARATE_BY_REQUEST
+	}
+}
+
 // PostRequest sends requests to Singularity to create a new Request
 func (ra *RectiAgent) PostRequest(d sous.Deployable, reqID string) error {
 	cluster, req, err := singRequestFromDeployment(d.Deployment, reqID)
@@ -250,7 +308,7 @@ func determineRequestType(kind sous.ManifestKind) (dtos.SingularityRequestReques
 		return dtos.SingularityRequestRequestTypeWORKER, nil
 	case sous.ManifestKindOnDemand:
 		return dtos.SingularityRequestRequestTypeON_DEMAND, nil
-	case sous.ManifestKindScheduled:
+	case sous.ManifestKindScheduled, sous.ScheduledJob:
 		return dtos.SingularityRequestRequestTypeSCHEDULED, nil
 	case sous.ManifestKindOnce:
 		return dtos.SingularityRequestRequestTypeRUN_ONCE, nil
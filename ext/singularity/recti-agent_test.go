@@ -5,6 +5,7 @@ import (
 
 	"github.com/opentable/go-singularity/dtos"
 	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -70,6 +71,7 @@ func TestDetermineRequestType(t *testing.T) {
 		{sous.ManifestKindWorker, dtos.SingularityRequestRequestTypeWORKER},
 		{sous.ManifestKindOnDemand, dtos.SingularityRequestRequestTypeON_DEMAND},
 		{sous.ManifestKindScheduled, dtos.SingularityRequestRequestTypeSCHEDULED},
+		{sous.ScheduledJob, dtos.SingularityRequestRequestTypeSCHEDULED},
 		{sous.ManifestKindOnce, dtos.SingularityRequestRequestTypeRUN_ONCE},
 	}
 
@@ -146,6 +148,17 @@ func TestMapStartup(t *testing.T) {
 			assert.Equal(t, int32(118), hco.MaxRetries)                                    //CheckReadyRetries
 		}
 	})
+
+	t.Run("Unsupported protocol", func(t *testing.T) {
+		depMap := dtoMap{}
+		for _, startup := range []sous.Startup{
+			{CheckReadyProtocol: sous.CheckReadyTCP},
+			{CheckReadyProtocol: sous.CheckReadyExec, CheckReadyCommand: []string{"true"}},
+		} {
+			err := MapStartupIntoHealthcheckOptions((*map[string]interface{})(&depMap), startup)
+			assert.Error(t, err, startup.CheckReadyProtocol)
+		}
+	})
 }
 
 func TestContainerStartupOptions(t *testing.T) {
@@ -163,7 +176,7 @@ func TestContainerStartupOptions(t *testing.T) {
 	d.Startup.CheckReadyURIPath = checkReadyPath
 	d.Startup.Timeout = checkReadyTimeout
 
-	dr, err := buildDeployRequest(d, "fake-request-id", map[string]string{})
+	dr, err := buildDeployRequest(d, "fake-request-id", map[string]string{}, nil, logging.SilentLogSet())
 	if err != nil {
 		t.Fatal(err)
 	}
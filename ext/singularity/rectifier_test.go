@@ -1,6 +1,8 @@
 package singularity
 
 import (
+	"bytes"
+	"fmt"
 	"log"
 	"testing"
 
@@ -38,7 +40,7 @@ func TestBuildDeployRequest(t *testing.T) {
 				BaseURL: "http://cluster",
 			},
 		},
-	}, rID, map[string]string{})
+	}, rID, map[string]string{}, nil, logging.SilentLogSet())
 	require.NoError(err)
 	assert.NotNil(dr)
 	assert.Equal(dr.Deploy.RequestId, rID)
@@ -73,7 +75,7 @@ func TestDockerMetadataSet(t *testing.T) {
 				BaseURL: "http://cluster",
 			},
 		},
-	}, rID, md)
+	}, rID, md, nil, logging.SilentLogSet())
 
 	if err != nil {
 		t.Fatal(err)
@@ -85,6 +87,59 @@ func TestDockerMetadataSet(t *testing.T) {
 	}
 }
 
+type fakeSecrets map[string]string
+
+func (fs fakeSecrets) Secret(ref sous.SecretRef) (string, error) {
+	return fs[ref.String()], nil
+}
+
+func TestBuildDeployRequestSecrets(t *testing.T) {
+	d := sous.Deployable{
+		BuildArtifact: &sous.BuildArtifact{
+			Name: "an-image",
+			Type: "docker",
+		},
+		Deployment: &sous.Deployment{
+			DeployConfig: sous.DeployConfig{
+				Resources: sous.Resources{},
+				Env: sous.Env{
+					"PASSWORD": "secret://db/prod#password",
+					"PLAIN":    "value",
+				},
+			},
+			ClusterName: "cluster",
+			Cluster:     &sous.Cluster{BaseURL: "http://cluster"},
+		},
+	}
+
+	ls, ctrl := logging.NewLogSinkSpy()
+	dr, err := buildDeployRequest(d, "rid", map[string]string{}, fakeSecrets{"secret://db/prod#password": "hunter2"}, ls)
+	require.NoError(t, err)
+	logged := loggedFields(ctrl)
+	assert.NotContains(t, logged, "hunter2", "a resolved secret was logged")
+	assert.Contains(t, logged, "redacted", "the secret should be logged as redacted")
+	assert.Equal(t, map[string]string{"PASSWORD": "hunter2", "PLAIN": "value"}, dr.Deploy.Env)
+	refs, err := sous.DecodeSecretRefs(dr.Deploy.Metadata[sous.SecretRefsLabel])
+	require.NoError(t, err)
+	assert.Equal(t, map[string]sous.SecretRef{"PASSWORD": {Path: "db/prod", Key: "password"}}, refs)
+	assert.Equal(t, "secret://db/prod#password", d.Deployment.Env["PASSWORD"], "the deployment's env should not change")
+
+	_, err = buildDeployRequest(d, "rid", map[string]string{}, nil, logging.SilentLogSet())
+	assert.Error(t, err, "secrets cannot be resolved without a provider")
+}
+
+// loggedFields returns the fields of every message logged to ctrl.
+func loggedFields(ctrl logging.LogSinkController) string {
+	buf := &bytes.Buffer{}
+	for _, call := range ctrl.CallsTo("LogMessage") {
+		msg := call.PassedArgs().Get(1).(logging.LogMessage)
+		msg.EachField(func(name string, value interface{}) {
+			fmt.Fprintf(buf, "%s: %v\n", name, value)
+		})
+	}
+	return buf.String()
+}
+
 func baseDeployablePair() *sous.DeployablePair {
 	return &sous.DeployablePair{
 		ExecutorData: &singularityTaskData{requestID: "reqid"},
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltKVStore is a KVStore kept in a BoltDB file. It suits a single Sous
+// server: Bolt locks the file, so only one process may open it at a time, and
+// servers which share state need a store they can all reach.
+//
+// Each pair is stored in the pairs bucket as its revision, eight bytes big
+// endian, followed by its value. The tombstone of a deleted key is stored in
+// the tombstones bucket as the revision it was deleted at. The revision of the
+// store is kept in the meta bucket.
+type BoltKVStore struct {
+	db      *bolt.DB
+	watches *kvWatches
+	// writing serializes writes with their notification, so that watches
+	// receive pairs in the order they were written.
+	writing sync.Mutex
+}
+
+var (
+	kvPairsBucket      = []byte("pairs")
+	kvTombstonesBucket = []byte("tombstones")
+	kvMetaBucket       = []byte("meta")
+	kvRevisionKey      = []byte("revision")
+	kvRevisionSize     = 8
+)
+
+// OpenBoltKVStore returns a KVStore kept in the Bolt file at path, which is
+// created if it does not exist. It waits for up to a second for another
+// process to close the file. The store should be closed when it is no longer
+// needed.
+func OpenBoltKVStore(path string) (*BoltKVStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening key-value store %s", path)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{kvPairsBucket, kvTombstonesBucket, kvMetaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrapf(err, "preparing key-value store %s", path)
+	}
+	return &BoltKVStore{db: db, watches: newKVWatches()}, nil
+}
+
+// Close closes the Bolt file.
+func (s *BoltKVStore) Close() error {
+	return s.db.Close()
+}
+
+// Get implements KVStore on BoltKVStore.
+func (s *BoltKVStore) Get(key string) (KVPair, bool, error) {
+	var p KVPair
+	var ok bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		p, ok, err = boltPair(tx, []byte(key))
+		return err
+	})
+	return p, ok, err
+}
+
+// List implements KVStore on BoltKVStore.
+func (s *BoltKVStore) List(prefix string) ([]KVPair, uint64, error) {
+	pairs := []KVPair{}
+	var rev uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		rev = boltRevision(tx)
+		pre := []byte(prefix)
+		c := tx.Bucket(kvPairsBucket).Cursor()
+		for k, v := c.Seek(pre); k != nil && bytes.HasPrefix(k, pre); k, v = c.Next() {
+			p, err := decodeKVPair(k, v)
+			if err != nil {
+				return err
+			}
+			pairs = append(pairs, p)
+		}
+		c = tx.Bucket(kvTombstonesBucket).Cursor()
+		for k, v := c.Seek(pre); k != nil && bytes.HasPrefix(k, pre); k, v = c.Next() {
+			p, err := decodeKVTombstone(k, v)
+			if err != nil {
+				return err
+			}
+			pairs = append(pairs, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs, rev, nil
+}
+
+// CompareAndSwap implements KVStore on BoltKVStore. The writes are made in a
+// single Bolt transaction, so they are all saved or none are.
+func (s *BoltKVStore) CompareAndSwap(writes ...KVWrite) (uint64, error) {
+	s.writing.Lock()
+	defer s.writing.Unlock()
+	var rev uint64
+	written := []KVPair{}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		pairs := tx.Bucket(kvPairsBucket)
+		tombstones := tx.Bucket(kvTombstonesBucket)
+		rev = boltRevision(tx)
+		for _, w := range writes {
+			p, _, err := boltPair(tx, []byte(w.Key))
+			if err != nil {
+				return err
+			}
+			if p.Revision != w.Revision {
+				return &KVConflictError{Key: w.Key, Expected: w.Revision, Actual: p.Revision}
+			}
+		}
+		if len(writes) == 0 {
+			return nil
+		}
+
+		next := rev + 1
+		for _, w := range writes {
+			p := KVPair{Key: w.Key, Revision: next}
+			if w.Value == nil {
+				if err := pairs.Delete([]byte(w.Key)); err != nil {
+					return err
+				}
+				if err := tombstones.Put([]byte(w.Key), encodeRevision(next)); err != nil {
+					return err
+				}
+			} else {
+				p.Value = append([]byte{}, w.Value...)
+				if err := pairs.Put([]byte(w.Key), encodeKVValue(p)); err != nil {
+					return err
+				}
+				if err := tombstones.Delete([]byte(w.Key)); err != nil {
+					return err
+				}
+			}
+			written = append(written, p)
+		}
+		if err := tx.Bucket(kvMetaBucket).Put(kvRevisionKey, encodeRevision(next)); err != nil {
+			return err
+		}
+		rev = next
+		return nil
+	})
+	if IsKVConflict(err) {
+		return rev, err
+	}
+	if err != nil {
+		return rev, errors.Wrapf(err, "saving key-value store")
+	}
+	s.watches.notify(written)
+	return rev, nil
+}
+
+// Watch implements KVStore on BoltKVStore. Only writes made through s are
+// received.
+func (s *BoltKVStore) Watch(prefix string, done <-chan struct{}) <-chan KVPair {
+	return s.watches.watch(prefix, done)
+}
+
+// boltPair returns the pair at key in tx, and false if there is none. If the
+// key was deleted, the pair returned is its tombstone.
+func boltPair(tx *bolt.Tx, key []byte) (KVPair, bool, error) {
+	if v := tx.Bucket(kvPairsBucket).Get(key); v != nil {
+		p, err := decodeKVPair(key, v)
+		return p, err == nil, err
+	}
+	if v := tx.Bucket(kvTombstonesBucket).Get(key); v != nil {
+		p, err := decodeKVTombstone(key, v)
+		return p, false, err
+	}
+	return KVPair{}, false, nil
+}
+
+// boltRevision returns the revision of the store tx belongs to.
+func boltRevision(tx *bolt.Tx) uint64 {
+	v := tx.Bucket(kvMetaBucket).Get(kvRevisionKey)
+	if len(v) != kvRevisionSize {
+		return 0
+	}
+	return binary.BigEndian.Uint64(v)
+}
+
+func encodeRevision(rev uint64) []byte {
+	bs := make([]byte, kvRevisionSize)
+	binary.BigEndian.PutUint64(bs, rev)
+	return bs
+}
+
+func encodeKVValue(p KVPair) []byte {
+	return append(encodeRevision(p.Revision), p.Value...)
+}
+
+// decodeKVPair decodes the pair stored at key. Bolt's slices are only valid
+// during their transaction, so the pair is copied out of v.
+func decodeKVPair(key, v []byte) (KVPair, error) {
+	if len(v) < kvRevisionSize {
+		return KVPair{}, errors.Errorf("key-value store entry %q is corrupt", key)
+	}
+	return KVPair{
+		Key:      string(key),
+		Revision: binary.BigEndian.Uint64(v[:kvRevisionSize]),
+		Value:    append([]byte{}, v[kvRevisionSize:]...),
+	}, nil
+}
+
+// decodeKVTombstone decodes the tombstone of the key deleted at revision v.
+func decodeKVTombstone(key, v []byte) (KVPair, error) {
+	if len(v) != kvRevisionSize {
+		return KVPair{}, errors.Errorf("key-value store tombstone %q is corrupt", key)
+	}
+	return KVPair{Key: string(key), Revision: binary.BigEndian.Uint64(v)}, nil
+}
@@ -24,13 +24,19 @@ func NewDuplexStateManager(primary, secondary sous.StateManager, log logging.Log
 	}
 }
 
-// ReadState implements StateManager on DuplexStateManager
+// SyncUser is the user recorded against the writes a DuplexStateManager
+// makes to its secondary StateManager to bring it into line with the primary,
+// so that the history of the secondary attributes them to Sous, rather than
+// to an anonymous user.
+var SyncUser = sous.User{Name: "Sous State Sync", Email: "sous-sync@localhost"}
+
+// ReadState implements StateManager on DuplexStateManager. The state read
+// from the primary StateManager is written to the secondary as SyncUser.
 func (dup *DuplexStateManager) ReadState() (*sous.State, error) {
-	user := sous.User{}
 	start := time.Now()
 	state, err := dup.primary.ReadState()
 	if err == nil {
-		if err := dup.secondary.WriteState(state, user); err != nil {
+		if err := dup.secondary.WriteState(state, SyncUser); err != nil {
 			logging.ReportError(dup.log, errors.Wrapf(err, "writing to secondary StateManager"))
 		}
 	}
@@ -38,13 +44,244 @@ func (dup *DuplexStateManager) ReadState() (*sous.State, error) {
 	return state, err
 }
 
-// WriteState implements StateManager on DuplexStateManager
+// WriteState implements StateManager on DuplexStateManager. The state is
+// written to the secondary StateManager only once the primary has accepted
+// it, so that the history the secondary records holds only changes which
+// were made. Failing to write to the secondary is logged, but the write
+// succeeds.
 func (dup *DuplexStateManager) WriteState(state *sous.State, user sous.User) error {
 	start := time.Now()
+	err := dup.primary.WriteState(state, user)
+	reportWriting(dup.log, start, state, err)
+	if err != nil {
+		return err
+	}
 	if err := dup.secondary.WriteState(state, user); err != nil {
 		logging.ReportError(dup.log, errors.Wrapf(err, "writing to secondary StateManager"))
 	}
-	err := dup.primary.WriteState(state, user)
-	reportWriting(dup.log, start, state, err)
-	return err
+	return nil
+}
+
+// ReconcileState implements sous.StateReconciler on DuplexStateManager,
+// reporting how the secondary StateManager differs from the primary. Unlike
+// ReadState, it does not write to the secondary.
+func (dup *DuplexStateManager) ReconcileState() (*sous.StateComparison, error) {
+	return CompareStateManagers(dup.primary, dup.secondary)
+}
+
+// ReadDeployment implements sous.DeploymentManager on DuplexStateManager, by
+// delegating to the primary StateManager.
+func (dup *DuplexStateManager) ReadDeployment(did sous.DeploymentID) (*sous.Deployment, error) {
+	return sous.MakeDeploymentManager(dup.primary).ReadDeployment(did)
+}
+
+// WriteDeployment implements sous.DeploymentManager on DuplexStateManager.
+// Like WriteState, it writes to the secondary StateManager only once the
+// primary has accepted the write, and only fails if the write to the primary
+// fails. The primary decides the outcome: the write is scoped to the
+// deployment, and conflicts only with writes to it, if the primary is a
+// sous.DeploymentManager, as KVStateManager is; otherwise the whole state is
+// read and written.
+func (dup *DuplexStateManager) WriteDeployment(dep *sous.Deployment, user sous.User) error {
+	if err := sous.MakeDeploymentManager(dup.primary).WriteDeployment(dep, user); err != nil {
+		return err
+	}
+	if err := sous.MakeDeploymentManager(dup.secondary).WriteDeployment(dep, user); err != nil {
+		logging.ReportError(dup.log, errors.Wrapf(err, "writing to secondary StateManager"))
+	}
+	return nil
+}
+
+// ReadCluster implements sous.ClusterManager on DuplexStateManager, by
+// delegating to the primary StateManager.
+func (dup *DuplexStateManager) ReadCluster(clusterName string) (sous.Deployments, error) {
+	return sous.MakeClusterManager(dup.primary).ReadCluster(clusterName)
+}
+
+// WriteCluster implements sous.ClusterManager on DuplexStateManager. Like
+// WriteState, it writes to the secondary StateManager only once the primary
+// has accepted the write, and only fails if the write to the primary fails.
+func (dup *DuplexStateManager) WriteCluster(clusterName string, deps sous.Deployments, user sous.User) error {
+	if err := sous.MakeClusterManager(dup.primary).WriteCluster(clusterName, deps, user); err != nil {
+		return err
+	}
+	if err := sous.MakeClusterManager(dup.secondary).WriteCluster(clusterName, deps, user); err != nil {
+		logging.ReportError(dup.log, errors.Wrapf(err, "writing to secondary StateManager"))
+	}
+	return nil
+}
+
+// WatchState implements sous.StateWatcher on DuplexStateManager. Only writes
+// to the primary StateManager are watched; if it cannot be watched, the
+// channel never receives.
+func (dup *DuplexStateManager) WatchState(done <-chan struct{}) <-chan struct{} {
+	sw, is := dup.primary.(sous.StateWatcher)
+	if !is {
+		return nil
+	}
+	return sw.WatchState(done)
+}
+
+// ReadHistory implements sous.HistoryReader on DuplexStateManager. History is
+// only recorded by the secondary StateManager, so reads are delegated there.
+func (dup *DuplexStateManager) ReadHistory(filter sous.HistoryFilter) ([]sous.StateChange, error) {
+	hr, is := dup.secondary.(sous.HistoryReader)
+	if !is {
+		return nil, errors.Errorf("secondary StateManager %T does not record history", dup.secondary)
+	}
+	return hr.ReadHistory(filter)
+}
+
+// ReadStateAsOf implements sous.StateHistorian on DuplexStateManager, by
+// delegating to the primary StateManager.
+func (dup *DuplexStateManager) ReadStateAsOf(pit sous.PointInTime) (*sous.State, error) {
+	sh, is := dup.primary.(sous.StateHistorian)
+	if !is {
+		return nil, errors.Errorf("primary StateManager %T cannot read earlier states", dup.primary)
+	}
+	return sh.ReadStateAsOf(pit)
+}
+
+// pendingChanges returns the secondary StateManager as a
+// sous.PendingChangeStore. Pending changes are only stored by the secondary,
+// since they are not part of the GDM.
+func (dup *DuplexStateManager) pendingChanges() (sous.PendingChangeStore, error) {
+	pcs, is := dup.secondary.(sous.PendingChangeStore)
+	if !is {
+		return nil, errors.Errorf("secondary StateManager %T does not store pending changes", dup.secondary)
+	}
+	return pcs, nil
+}
+
+// ProposeChange implements sous.PendingChangeStore on DuplexStateManager.
+func (dup *DuplexStateManager) ProposeChange(pc sous.PendingChange) error {
+	pcs, err := dup.pendingChanges()
+	if err != nil {
+		return err
+	}
+	return pcs.ProposeChange(pc)
+}
+
+// ReadPendingChange implements sous.PendingChangeStore on DuplexStateManager.
+func (dup *DuplexStateManager) ReadPendingChange(id sous.PendingChangeID) (*sous.PendingChange, error) {
+	pcs, err := dup.pendingChanges()
+	if err != nil {
+		return nil, err
+	}
+	return pcs.ReadPendingChange(id)
+}
+
+// ListPendingChanges implements sous.PendingChangeStore on DuplexStateManager.
+func (dup *DuplexStateManager) ListPendingChanges() ([]sous.PendingChange, error) {
+	pcs, err := dup.pendingChanges()
+	if err != nil {
+		return nil, err
+	}
+	return pcs.ListPendingChanges()
+}
+
+// ReviewPendingChange implements sous.PendingChangeStore on DuplexStateManager.
+func (dup *DuplexStateManager) ReviewPendingChange(id sous.PendingChangeID, status sous.PendingStatus, reviewer sous.User, at time.Time) error {
+	pcs, err := dup.pendingChanges()
+	if err != nil {
+		return err
+	}
+	return pcs.ReviewPendingChange(id, status, reviewer, at)
+}
+
+// r11nStore returns the secondary StateManager as a sous.R11nStore. Queued
+// rectifications are only stored by the secondary, since they are not part of
+// the GDM.
+func (dup *DuplexStateManager) r11nStore() (sous.R11nStore, error) {
+	rs, is := dup.secondary.(sous.R11nStore)
+	if !is {
+		return nil, errors.Errorf("secondary StateManager %T does not store rectifications", dup.secondary)
+	}
+	return rs, nil
+}
+
+// StoreR11n implements sous.R11nStore on DuplexStateManager.
+func (dup *DuplexStateManager) StoreR11n(sr sous.StoredR11n) error {
+	rs, err := dup.r11nStore()
+	if err != nil {
+		return err
+	}
+	return rs.StoreR11n(sr)
+}
+
+// RemoveR11n implements sous.R11nStore on DuplexStateManager.
+func (dup *DuplexStateManager) RemoveR11n(id sous.R11nID) error {
+	rs, err := dup.r11nStore()
+	if err != nil {
+		return err
+	}
+	return rs.RemoveR11n(id)
+}
+
+// LoadR11ns implements sous.R11nStore on DuplexStateManager.
+func (dup *DuplexStateManager) LoadR11ns() ([]sous.StoredR11n, error) {
+	rs, err := dup.r11nStore()
+	if err != nil {
+		return nil, err
+	}
+	return rs.LoadR11ns()
+}
+
+// LeaseStore returns the secondary StateManager as a sous.LeaseStore. Leases
+// are only held in the secondary, which all the servers share, so although
+// DuplexStateManager implements sous.LeaseStore, it can only hold leases if
+// LeaseStore returns no error.
+func (dup *DuplexStateManager) LeaseStore() (sous.LeaseStore, error) {
+	ls, is := dup.secondary.(sous.LeaseStore)
+	if !is {
+		return nil, errors.Errorf("secondary StateManager %T does not store leases", dup.secondary)
+	}
+	return ls, nil
+}
+
+// AcquireLease implements sous.LeaseStore on DuplexStateManager.
+func (dup *DuplexStateManager) AcquireLease(cluster, holder string, ttl time.Duration) (sous.Lease, error) {
+	ls, err := dup.LeaseStore()
+	if err != nil {
+		return sous.Lease{}, err
+	}
+	return ls.AcquireLease(cluster, holder, ttl)
+}
+
+// ReleaseLease implements sous.LeaseStore on DuplexStateManager.
+func (dup *DuplexStateManager) ReleaseLease(cluster, holder string) error {
+	ls, err := dup.LeaseStore()
+	if err != nil {
+		return err
+	}
+	return ls.ReleaseLease(cluster, holder)
+}
+
+// lastGoodStore returns the secondary StateManager as a sous.LastGoodStore.
+// Last good versions are only held in the secondary, which all the servers
+// share.
+func (dup *DuplexStateManager) lastGoodStore() (sous.LastGoodStore, error) {
+	lgs, is := dup.secondary.(sous.LastGoodStore)
+	if !is {
+		return nil, errors.Errorf("secondary StateManager %T does not store last good versions", dup.secondary)
+	}
+	return lgs, nil
+}
+
+// RecordLastGood implements sous.LastGoodStore on DuplexStateManager.
+func (dup *DuplexStateManager) RecordLastGood(did sous.DeploymentID, version sous.SourceID) error {
+	lgs, err := dup.lastGoodStore()
+	if err != nil {
+		return err
+	}
+	return lgs.RecordLastGood(did, version)
+}
+
+// LastGood implements sous.LastGoodStore on DuplexStateManager.
+func (dup *DuplexStateManager) LastGood(did sous.DeploymentID) (sous.SourceID, bool, error) {
+	lgs, err := dup.lastGoodStore()
+	if err != nil {
+		return sous.SourceID{}, false, err
+	}
+	return lgs.LastGood(did)
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -70,6 +71,12 @@ func TestDuplexReadState(t *testing.T) {
 	pstate, err := psm.ReadState()
 	require.NoError(t, err)
 	assertStatesEqual(t, expected, pstate)
+
+	// The sync is recorded in history as made by Sous.
+	history, err := psm.ReadHistory(sous.HistoryFilter{})
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+	assert.Equal(t, SyncUser, history[0].User)
 }
 
 func setupDB(t *testing.T) *sql.DB {
@@ -132,3 +139,34 @@ func assertStatesEqual(t *testing.T, oldState, newState *sous.State) {
 		}
 	}
 }
+
+// rejectingStateManager fails every write.
+type rejectingStateManager struct {
+	*sous.DummyStateManager
+}
+
+func (rejectingStateManager) WriteState(*sous.State, sous.User) error {
+	return fmt.Errorf("rejected")
+}
+
+func TestDuplexWrite_primaryFirst(t *testing.T) {
+	s := exampleState()
+	deps, err := s.Deployments()
+	require.NoError(t, err)
+	dep := deps.Snapshot()[deps.Keys()[0]]
+
+	log := logging.SilentLogSet()
+	primary := rejectingStateManager{sous.NewDummyStateManager()}
+	primary.State = exampleState()
+	secondary := sous.NewDummyStateManager()
+	dupsm := NewDuplexStateManager(primary, secondary, log)
+
+	assert.Error(t, dupsm.WriteState(s, testUser))
+	assert.Error(t, dupsm.WriteDeployment(dep, testUser))
+	assert.Error(t, dupsm.WriteCluster(dep.ClusterName, sous.NewDeployments(dep), testUser))
+	assert.Zero(t, secondary.WriteCount, "a write the primary rejected should not reach the secondary")
+
+	dupsm = NewDuplexStateManager(sous.NewDummyStateManager(), secondary, log)
+	require.NoError(t, dupsm.WriteState(s, testUser))
+	assert.Equal(t, 1, secondary.WriteCount)
+}
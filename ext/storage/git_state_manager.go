@@ -1,12 +1,16 @@
 package storage
 
 import (
+	"archive/tar"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
@@ -236,3 +240,103 @@ func (gsm *GitStateManager) WriteState(s *sous.State, u sous.User) error {
 	}
 	return fmt.Errorf("unable to merge changes")
 }
+
+// ReadStateAsOf implements sous.StateHistorian on GitStateManager. The state
+// is read from the commit named by the revision, or from the last commit made
+// at or before the given time.
+func (gsm *GitStateManager) ReadStateAsOf(pit sous.PointInTime) (*sous.State, error) {
+	if err := pit.Validate(); err != nil {
+		return nil, err
+	}
+
+	gsm.Lock()
+	defer gsm.Unlock()
+
+	rev, err := gsm.resolveRevision(pit)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "sous-state-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "state.tar")
+	if err := gsm.git("archive", "--format=tar", "-o", archive, rev); err != nil {
+		return nil, err
+	}
+	stateDir := filepath.Join(dir, "state")
+	if err := extractTar(archive, stateDir); err != nil {
+		return nil, errors.Wrapf(err, "extracting state at %s", rev)
+	}
+
+	state, err := NewDiskStateManager(stateDir).ReadState()
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading state at %s", rev)
+	}
+	state.SetEtag(rev)
+	return state, nil
+}
+
+func (gsm *GitStateManager) resolveRevision(pit sous.PointInTime) (string, error) {
+	if pit.Revision != "" {
+		rev, err := gsm.gitOut("rev-parse", "--verify", "--quiet", pit.Revision+"^{commit}")
+		if err != nil {
+			return "", errors.Errorf("no such revision %q", pit.Revision)
+		}
+		return strings.TrimSpace(rev), nil
+	}
+	rev, err := gsm.gitOut("rev-list", "-1", "--before="+pit.At.Format(time.RFC3339), "HEAD")
+	if err != nil {
+		return "", err
+	}
+	rev = strings.TrimSpace(rev)
+	if rev == "" {
+		return "", errors.Errorf("no state recorded at or before %s", pit)
+	}
+	return rev, nil
+}
+
+func extractTar(archive, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if !strings.HasPrefix(path, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return errors.Errorf("archive entry %q escapes %s", hdr.Name, dir)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			out, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/yaml"
@@ -327,3 +328,39 @@ func TestGitReadState_empty(t *testing.T) {
 		t.Errorf("got len %d; want %d", d.Len(), 0)
 	}
 }
+
+func TestGitStateManager_ReadStateAsOf(t *testing.T) {
+	require := require.New(t)
+
+	s := exampleState()
+	PrepareTestGitRepo(t, s, "testdata/remote", "testdata/out")
+	gsm := NewGitStateManager(NewDiskStateManager("testdata/out"))
+
+	before, err := gsm.ReadState()
+	require.NoError(err)
+	rev, err := before.GetEtag()
+	require.NoError(err)
+
+	m, ok := before.Manifests.Any(func(m *sous.Manifest) bool { return m.Source.Repo == "github.com/opentable/sous" })
+	require.True(ok)
+	m.Deployments["cluster-1"].Env["NEWVAR"] = "YOLO"
+	require.NoError(gsm.WriteState(before, testUser))
+
+	past, err := gsm.ReadStateAsOf(sous.PointInTime{Revision: rev})
+	require.NoError(err)
+	sameYAML(t, past, exampleState())
+	pastRev, err := past.GetEtag()
+	require.NoError(err)
+	assert.Equal(t, rev, pastRev)
+
+	latest, err := gsm.ReadStateAsOf(sous.PointInTime{At: time.Now().Add(time.Minute)})
+	require.NoError(err)
+	lm, ok := latest.Manifests.Get(m.ID())
+	require.True(ok)
+	assert.Equal(t, "YOLO", lm.Deployments["cluster-1"].Env["NEWVAR"])
+
+	_, err = gsm.ReadStateAsOf(sous.PointInTime{Revision: "no-such-revision"})
+	assert.Error(t, err)
+	_, err = gsm.ReadStateAsOf(sous.PointInTime{})
+	assert.Error(t, err)
+}
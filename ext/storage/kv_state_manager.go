@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/sous/util/yaml"
+	"github.com/pkg/errors"
+)
+
+// KVStateManager implements StateManager, DeploymentManager, ClusterManager
+// and StateWatcher with a KVStore. The Defs and each manifest are stored as YAML under their
+// own keys:
+//
+//	<prefix>defs
+//	<prefix>manifests/<manifest ID>
+//
+// so that a write only touches the keys which changed, and conflicts only
+// with writes to the same keys.
+type KVStateManager struct {
+	store  KVStore
+	prefix string
+	log    logging.LogSink
+}
+
+const (
+	kvDefsKey      = "defs"
+	kvManifestsKey = "manifests/"
+)
+
+// NewKVStateManager returns a KVStateManager which keeps the state in store,
+// under keys beginning with prefix.
+func NewKVStateManager(store KVStore, prefix string, log logging.LogSink) *KVStateManager {
+	return &KVStateManager{store: store, prefix: prefix, log: log}
+}
+
+// ReadState implements sous.StateReader on KVStateManager. The state's etag
+// is the revision of the store it was read at.
+func (m *KVStateManager) ReadState() (*sous.State, error) {
+	pairs, rev, err := m.store.List(m.prefix)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing state")
+	}
+	s := sous.NewState()
+	for _, p := range pairs {
+		key := strings.TrimPrefix(p.Key, m.prefix)
+		switch {
+		case p.Value == nil:
+		case key == kvDefsKey:
+			if err := yaml.Unmarshal(p.Value, &s.Defs); err != nil {
+				return nil, errors.Wrapf(err, "parsing %s", p.Key)
+			}
+		case strings.HasPrefix(key, kvManifestsKey):
+			manifest := &sous.Manifest{}
+			if err := yaml.Unmarshal(p.Value, manifest); err != nil {
+				return nil, errors.Wrapf(err, "parsing %s", p.Key)
+			}
+			s.Manifests.Add(manifest)
+		}
+	}
+	for _, manifest := range s.Manifests.Snapshot() {
+		for clusterName := range manifest.Deployments {
+			if _, ok := s.Defs.Clusters[clusterName]; !ok {
+				return s, errors.Errorf("cluster %q not defined (from manifest %q)", clusterName, manifest.ID())
+			}
+		}
+	}
+	if err := repairState(s); err != nil {
+		return nil, err
+	}
+	s.SetEtag(strconv.FormatUint(rev, 10))
+	return s, nil
+}
+
+// WriteState implements sous.StateWriter on KVStateManager. s must have been
+// read from this KVStateManager: the write fails with a *KVConflictError if
+// any key it changes was written since, and fails if s has no etag to tell.
+// To write a state whatever was written before, use ReplaceState.
+func (m *KVStateManager) WriteState(s *sous.State, u sous.User) error {
+	etag, err := s.GetEtag()
+	if err != nil {
+		return errors.Errorf("state has no etag to check for conflicting writes; read it from the key-value store first, or replace the state")
+	}
+	since, err := strconv.ParseUint(etag, 10, 64)
+	if err != nil {
+		return errors.Errorf("etag %q is not a revision of the key-value store", etag)
+	}
+	return m.write(s, u, &since)
+}
+
+// ReplaceState writes s in place of the state in the store, whatever has been
+// written since s was read, e.g. when migrating a state read from another
+// StateManager.
+func (m *KVStateManager) ReplaceState(s *sous.State, u sous.User) error {
+	return m.write(s, u, nil)
+}
+
+// write writes the keys of s which differ from the store. If since is not
+// nil, the write fails if any of them was written or deleted after revision
+// since.
+func (m *KVStateManager) write(s *sous.State, u sous.User, since *uint64) error {
+	if err := repairState(s); err != nil {
+		return err
+	}
+
+	intended, err := m.encode(s)
+	if err != nil {
+		return err
+	}
+	pairs, _, err := m.store.List(m.prefix)
+	if err != nil {
+		return errors.Wrapf(err, "listing state")
+	}
+	current := map[string]KVPair{}
+	for _, p := range pairs {
+		key := strings.TrimPrefix(p.Key, m.prefix)
+		if key == kvDefsKey || strings.HasPrefix(key, kvManifestsKey) {
+			current[p.Key] = p
+		}
+	}
+
+	writes := []KVWrite{}
+	change := func(p KVPair, value []byte) error {
+		if since != nil && p.Revision > *since {
+			return &KVConflictError{Key: p.Key, Expected: *since, Actual: p.Revision}
+		}
+		writes = append(writes, KVWrite{Key: p.Key, Revision: p.Revision, Value: value})
+		return nil
+	}
+	for key, p := range current {
+		if _, ok := intended[key]; !ok && p.Value != nil {
+			if err := change(p, nil); err != nil {
+				return err
+			}
+		}
+	}
+	for key, value := range intended {
+		p, ok := current[key]
+		if ok && p.Value != nil && bytes.Equal(p.Value, value) {
+			continue
+		}
+		p.Key = key
+		if err := change(p, value); err != nil {
+			return err
+		}
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+
+	rev, err := m.store.CompareAndSwap(writes...)
+	if err != nil {
+		return errors.Wrapf(err, "writing state")
+	}
+	messages.ReportLogFieldsMessage(fmt.Sprintf("Wrote %d keys of state for %s at revision %d", len(writes), u, rev),
+		logging.DebugLevel, m.log, u)
+	return nil
+}
+
+// encode returns the keys and values which store s.
+func (m *KVStateManager) encode(s *sous.State) (map[string][]byte, error) {
+	kvs := map[string][]byte{}
+	defs, err := yaml.Marshal(s.Defs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "encoding defs")
+	}
+	kvs[m.prefix+kvDefsKey] = defs
+	for mid, manifest := range s.Manifests.Snapshot() {
+		bs, err := yaml.Marshal(manifest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encoding manifest %q", mid)
+		}
+		kvs[m.manifestKey(mid)] = bs
+	}
+	return kvs, nil
+}
+
+func (m *KVStateManager) manifestKey(mid sous.ManifestID) string {
+	return m.prefix + kvManifestsKey + mid.String()
+}
+
+// readManifest returns a State holding the Defs and only the manifest mid,
+// if it exists, and the pair the manifest was read from, which is a
+// tombstone or empty if it does not.
+func (m *KVStateManager) readManifest(mid sous.ManifestID) (*sous.State, KVPair, error) {
+	s := sous.NewState()
+	defs, ok, err := m.store.Get(m.prefix + kvDefsKey)
+	if err != nil {
+		return nil, KVPair{}, errors.Wrapf(err, "reading defs")
+	}
+	if ok {
+		if err := yaml.Unmarshal(defs.Value, &s.Defs); err != nil {
+			return nil, KVPair{}, errors.Wrapf(err, "parsing %s", defs.Key)
+		}
+	}
+	p, ok, err := m.store.Get(m.manifestKey(mid))
+	if err != nil {
+		return nil, KVPair{}, errors.Wrapf(err, "reading manifest %q", mid)
+	}
+	if ok {
+		manifest := &sous.Manifest{}
+		if err := yaml.Unmarshal(p.Value, manifest); err != nil {
+			return nil, KVPair{}, errors.Wrapf(err, "parsing %s", p.Key)
+		}
+		s.Manifests.Add(manifest)
+	}
+	return s, p, nil
+}
+
+// ReadDeployment implements sous.DeploymentManager on KVStateManager, reading
+// only the Defs and the manifest of the deployment.
+func (m *KVStateManager) ReadDeployment(did sous.DeploymentID) (*sous.Deployment, error) {
+	s, _, err := m.readManifest(did.ManifestID)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := s.Deployments()
+	if err != nil {
+		return nil, err
+	}
+	dep, has := deps.Get(did)
+	if !has {
+		return nil, errors.Errorf("no deployment found for %s", did)
+	}
+	return dep, nil
+}
+
+// WriteDeployment implements sous.DeploymentManager on KVStateManager. Only
+// the manifest of the deployment is written, and the write fails with a
+// *KVConflictError if the manifest is written or deleted meanwhile.
+func (m *KVStateManager) WriteDeployment(dep *sous.Deployment, user sous.User) error {
+	mid := dep.ManifestID()
+	s, p, err := m.readManifest(mid)
+	if err != nil {
+		return err
+	}
+	if err := s.UpdateDeployments(dep); err != nil {
+		return err
+	}
+	if err := repairState(s); err != nil {
+		return err
+	}
+	manifest, ok := s.Manifests.Get(mid)
+	if !ok {
+		return errors.Errorf("no manifest %q after writing deployment %s", mid, dep.ID())
+	}
+	bs, err := yaml.Marshal(manifest)
+	if err != nil {
+		return errors.Wrapf(err, "encoding manifest %q", mid)
+	}
+	if p.Value != nil && bytes.Equal(p.Value, bs) {
+		return nil
+	}
+	rev, err := m.store.CompareAndSwap(KVWrite{Key: m.manifestKey(mid), Revision: p.Revision, Value: bs})
+	if err != nil {
+		return errors.Wrapf(err, "writing deployment %s", dep.ID())
+	}
+	messages.ReportLogFieldsMessage(fmt.Sprintf("Wrote deployment %s for %s at revision %d", dep.ID(), user, rev),
+		logging.DebugLevel, m.log, user)
+	return nil
+}
+
+// ReadCluster implements sous.ClusterManager on KVStateManager.
+func (m *KVStateManager) ReadCluster(clusterName string) (sous.Deployments, error) {
+	return sous.MakeClusterManager(struct{ sous.StateManager }{m}).ReadCluster(clusterName)
+}
+
+// WriteCluster implements sous.ClusterManager on KVStateManager. Only the
+// manifests whose deployments to the cluster change are written, and the
+// write fails if any of them was written meanwhile.
+func (m *KVStateManager) WriteCluster(clusterName string, deps sous.Deployments, user sous.User) error {
+	return sous.MakeClusterManager(struct{ sous.StateManager }{m}).WriteCluster(clusterName, deps, user)
+}
+
+// WatchState implements sous.StateWatcher on KVStateManager.
+func (m *KVStateManager) WatchState(done <-chan struct{}) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	pairs := m.store.Watch(m.prefix, done)
+	go func() {
+		for range pairs {
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return changes
+}
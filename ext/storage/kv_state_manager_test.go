@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVStateManager_roundTrip(t *testing.T) {
+	store := NewMemKVStore()
+	sm := NewKVStateManager(store, "sous/", logging.SilentLogSet())
+
+	require.NoError(t, sm.ReplaceState(exampleState(), sous.User{}))
+
+	pairs, _, err := store.List("sous/")
+	require.NoError(t, err)
+	keys := []string{}
+	for _, p := range pairs {
+		keys = append(keys, p.Key)
+	}
+	assert.Equal(t, []string{
+		"sous/defs",
+		"sous/manifests/github.com/opentable/sous",
+		"sous/manifests/github.com/user/project",
+	}, keys)
+
+	actual, err := sm.ReadState()
+	require.NoError(t, err)
+	actualYAML, err := yaml.Marshal(actual)
+	require.NoError(t, err)
+	expectedYAML, err := yaml.Marshal(exampleState())
+	require.NoError(t, err)
+	assert.Equal(t, string(expectedYAML), string(actualYAML))
+}
+
+func TestKVStateManager_WriteState_changedKeys(t *testing.T) {
+	store := NewMemKVStore()
+	sm := NewKVStateManager(store, "", logging.SilentLogSet())
+	require.NoError(t, sm.ReplaceState(exampleState(), sous.User{}))
+	before, _, err := store.Get("defs")
+	require.NoError(t, err)
+
+	s, err := sm.ReadState()
+	require.NoError(t, err)
+	mid := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/project"}}
+	m, ok := s.Manifests.Get(mid)
+	require.True(t, ok)
+	m.Owners = []string{"Someone Else"}
+	s.Manifests.Set(mid, m)
+	s.Manifests.Remove(sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/sous"}})
+	require.NoError(t, sm.WriteState(s, sous.User{}))
+
+	after, _, err := store.Get("defs")
+	require.NoError(t, err)
+	assert.Equal(t, before.Revision, after.Revision, "unchanged keys should not be written")
+	_, ok, err = store.Get("manifests/github.com/opentable/sous")
+	require.NoError(t, err)
+	assert.False(t, ok, "the key of a removed manifest should be deleted")
+
+	s, err = sm.ReadState()
+	require.NoError(t, err)
+	m, ok = s.Manifests.Get(mid)
+	require.True(t, ok)
+	assert.Equal(t, []string{"Someone Else"}, m.Owners)
+}
+
+func TestKVStateManager_WriteState_conflict(t *testing.T) {
+	sm := NewKVStateManager(NewMemKVStore(), "", logging.SilentLogSet())
+	require.NoError(t, sm.ReplaceState(exampleState(), sous.User{}))
+
+	first, err := sm.ReadState()
+	require.NoError(t, err)
+	second, err := sm.ReadState()
+	require.NoError(t, err)
+
+	mid := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/project"}}
+	m, _ := first.Manifests.Get(mid)
+	m.Owners = []string{"First"}
+	require.NoError(t, sm.WriteState(first, sous.User{}))
+
+	m, _ = second.Manifests.Get(mid)
+	m.Owners = []string{"Second"}
+	err = sm.WriteState(second, sous.User{})
+	assert.True(t, IsKVConflict(err), "a write of a stale manifest should conflict, got %v", err)
+
+	second, err = sm.ReadState()
+	require.NoError(t, err)
+	second.Defs.DockerRepo = "docker.example.com"
+	assert.NoError(t, sm.WriteState(second, sous.User{}), "a write after reading again should succeed")
+
+	other, err := sm.ReadState()
+	require.NoError(t, err)
+	other.SetEtag("deadbeef")
+	assert.Error(t, sm.WriteState(other, sous.User{}))
+	assert.Error(t, sm.WriteState(exampleState(), sous.User{}), "a state without an etag should not be written unchecked")
+	assert.NoError(t, sm.ReplaceState(exampleState(), sous.User{}))
+}
+
+func TestKVStateManager_WriteState_deleted(t *testing.T) {
+	sm := NewKVStateManager(NewMemKVStore(), "", logging.SilentLogSet())
+	require.NoError(t, sm.ReplaceState(exampleState(), sous.User{}))
+
+	stale, err := sm.ReadState()
+	require.NoError(t, err)
+	s, err := sm.ReadState()
+	require.NoError(t, err)
+	mid := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/sous"}}
+	s.Manifests.Remove(mid)
+	require.NoError(t, sm.WriteState(s, sous.User{}))
+
+	err = sm.WriteState(stale, sous.User{})
+	assert.True(t, IsKVConflict(err), "a stale write should not bring back a deleted manifest, got %v", err)
+	s, err = sm.ReadState()
+	require.NoError(t, err)
+	_, ok := s.Manifests.Get(mid)
+	assert.False(t, ok)
+
+	s.Manifests.Add(exampleState().Manifests.Snapshot()[mid])
+	assert.NoError(t, sm.WriteState(s, sous.User{}), "a manifest should be added again after reading the deletion")
+}
+
+func TestKVStateManager_WriteCluster(t *testing.T) {
+	sm := NewKVStateManager(NewMemKVStore(), "", logging.SilentLogSet())
+	require.NoError(t, sm.ReplaceState(exampleState(), sous.User{}))
+
+	deps, err := sm.ReadCluster("cluster-1")
+	require.NoError(t, err)
+	require.NotZero(t, deps.Len())
+	for _, d := range deps.Snapshot() {
+		d.NumInstances = 12
+	}
+	require.NoError(t, sm.WriteCluster("cluster-1", deps, sous.User{}))
+
+	deps, err = sm.ReadCluster("cluster-1")
+	require.NoError(t, err)
+	for id, d := range deps.Snapshot() {
+		assert.Equal(t, 12, d.NumInstances, "%s", id)
+	}
+	others, err := sm.ReadCluster("other-cluster")
+	require.NoError(t, err)
+	for id, d := range others.Snapshot() {
+		assert.NotEqual(t, 12, d.NumInstances, "%s", id)
+	}
+}
+
+func TestKVStateManager_WatchState(t *testing.T) {
+	store := NewMemKVStore()
+	sm := NewKVStateManager(store, "sous/", logging.SilentLogSet())
+	done := make(chan struct{})
+	changes := sm.WatchState(done)
+
+	_, err := store.CompareAndSwap(KVWrite{Key: "other/key", Value: []byte("x")})
+	require.NoError(t, err)
+	select {
+	case <-changes:
+		t.Fatal("a write outside the prefix should not be received")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	require.NoError(t, sm.ReplaceState(exampleState(), sous.User{}))
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("a write of the state should be received")
+	}
+
+	close(done)
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case <-changes:
+	default:
+	}
+	select {
+	case <-changes:
+		t.Fatal("the channel should not be closed when the watch ends")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// racingKVStore runs race once, just before the first compare-and-swap made
+// through it.
+type racingKVStore struct {
+	KVStore
+	race func()
+}
+
+func (s *racingKVStore) CompareAndSwap(writes ...KVWrite) (uint64, error) {
+	if race := s.race; race != nil {
+		s.race = nil
+		race()
+	}
+	return s.KVStore.CompareAndSwap(writes...)
+}
+
+func TestKVStateManager_WriteDeployment(t *testing.T) {
+	store := NewMemKVStore()
+	other := NewKVStateManager(store, "", logging.SilentLogSet())
+	require.NoError(t, other.ReplaceState(exampleState(), sous.User{}))
+	racing := &racingKVStore{KVStore: store}
+	sm := NewKVStateManager(racing, "", logging.SilentLogSet())
+
+	did := sous.DeploymentID{
+		ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/project"}},
+		Cluster:    "cluster-1",
+	}
+	before, _, err := store.Get("defs")
+	require.NoError(t, err)
+	dep, err := sm.ReadDeployment(did)
+	require.NoError(t, err)
+	dep.NumInstances = 12
+	require.NoError(t, sm.WriteDeployment(dep, sous.User{}))
+
+	after, _, err := store.Get("defs")
+	require.NoError(t, err)
+	assert.Equal(t, before.Revision, after.Revision, "only the manifest should be written")
+	read, err := other.ReadDeployment(did)
+	require.NoError(t, err)
+	assert.Equal(t, 12, read.NumInstances)
+
+	racing.race = func() {
+		d, err := other.ReadDeployment(did)
+		require.NoError(t, err)
+		d.NumInstances = 3
+		require.NoError(t, other.WriteDeployment(d, sous.User{}))
+	}
+	dep.NumInstances = 7
+	err = sm.WriteDeployment(dep, sous.User{})
+	assert.True(t, IsKVConflict(err), "a write racing another to the same manifest should conflict, got %v", err)
+	read, err = other.ReadDeployment(did)
+	require.NoError(t, err)
+	assert.Equal(t, 3, read.NumInstances)
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// A KVStore is a key-value store with compare-and-swap writes and
+	// watches, in the manner of etcd. Every write advances the store's
+	// revision, and records it as the revision of the key written.
+	KVStore interface {
+		// Get returns the pair at key, and false if there is none. If the key
+		// was deleted, the pair returned is its tombstone.
+		Get(key string) (KVPair, bool, error)
+		// List returns the pairs whose keys begin with prefix, ordered by key,
+		// and the revision of the store they were read at. Deleted keys are
+		// listed as their tombstones.
+		List(prefix string) ([]KVPair, uint64, error)
+		// CompareAndSwap makes all of writes, or none of them if the revision
+		// of any key differs from the revision its write expects, in which
+		// case it returns a *KVConflictError. The revision of a deleted key
+		// is the revision of its tombstone. It returns the revision of the
+		// store after the writes.
+		CompareAndSwap(writes ...KVWrite) (uint64, error)
+		// Watch returns a channel which receives the pairs written under
+		// prefix, until done is closed. A deleted key is received with a nil
+		// Value. If the receiver falls behind, pairs are dropped, so it
+		// should List again rather than rely on every pair arriving.
+		Watch(prefix string, done <-chan struct{}) <-chan KVPair
+	}
+
+	// A KVPair is a key in a KVStore, its value and revision. A deleted key
+	// is kept as a tombstone, a pair with a nil Value at the revision it was
+	// deleted, so that a compare-and-swap made before the deletion conflicts
+	// with it rather than bringing the key back.
+	KVPair struct {
+		Key      string
+		Value    []byte
+		Revision uint64
+	}
+
+	// A KVWrite sets or deletes a key, if the key is at Revision. Revision 0
+	// means the key has never been written.
+	KVWrite struct {
+		Key      string
+		Revision uint64
+		// Value is the new value of the key. If it is nil, the key is
+		// deleted.
+		Value []byte
+	}
+
+	// A KVConflictError is returned when a compare-and-swap fails because the
+	// key was written since the revision given.
+	KVConflictError struct {
+		Key              string
+		Expected, Actual uint64
+	}
+
+	// MemKVStore is a KVStore held in memory, e.g. for tests. See
+	// BoltKVStore for one which persists.
+	MemKVStore struct {
+		revision uint64
+		pairs    map[string]KVPair
+		watches  *kvWatches
+		sync.Mutex
+	}
+
+	// kvWatches passes the pairs written to a KVStore on to its watches.
+	kvWatches struct {
+		watches map[*kvWatch]struct{}
+		sync.Mutex
+	}
+
+	kvWatch struct {
+		prefix string
+		ch     chan KVPair
+	}
+)
+
+// kvWatchBuffer is how many pairs a watch holds for its receiver before
+// dropping them.
+const kvWatchBuffer = 64
+
+func (e *KVConflictError) Error() string {
+	return fmt.Sprintf("key %q is at revision %d, not %d", e.Key, e.Actual, e.Expected)
+}
+
+// IsKVConflict returns true if err was caused by a failed compare-and-swap.
+func IsKVConflict(err error) bool {
+	_, is := errors.Cause(err).(*KVConflictError)
+	return is
+}
+
+// NewMemKVStore returns an empty KVStore held in memory.
+func NewMemKVStore() *MemKVStore {
+	return &MemKVStore{
+		pairs:   map[string]KVPair{},
+		watches: newKVWatches(),
+	}
+}
+
+// Get implements KVStore on MemKVStore.
+func (s *MemKVStore) Get(key string) (KVPair, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	p, ok := s.pairs[key]
+	return p, ok && p.Value != nil, nil
+}
+
+// List implements KVStore on MemKVStore.
+func (s *MemKVStore) List(prefix string) ([]KVPair, uint64, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.sorted(prefix), s.revision, nil
+}
+
+// sorted assumes s is locked.
+func (s *MemKVStore) sorted(prefix string) []KVPair {
+	pairs := []KVPair{}
+	for key, p := range s.pairs {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, p)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs
+}
+
+// CompareAndSwap implements KVStore on MemKVStore.
+func (s *MemKVStore) CompareAndSwap(writes ...KVWrite) (uint64, error) {
+	s.Lock()
+	defer s.Unlock()
+	for _, w := range writes {
+		if actual := s.pairs[w.Key].Revision; actual != w.Revision {
+			return s.revision, &KVConflictError{Key: w.Key, Expected: w.Revision, Actual: actual}
+		}
+	}
+	if len(writes) == 0 {
+		return s.revision, nil
+	}
+
+	s.revision++
+	written := []KVPair{}
+	for _, w := range writes {
+		p := KVPair{Key: w.Key, Revision: s.revision}
+		if w.Value != nil {
+			p.Value = append([]byte{}, w.Value...)
+		}
+		s.pairs[w.Key] = p
+		written = append(written, p)
+	}
+	s.watches.notify(written)
+	return s.revision, nil
+}
+
+// Watch implements KVStore on MemKVStore.
+func (s *MemKVStore) Watch(prefix string, done <-chan struct{}) <-chan KVPair {
+	return s.watches.watch(prefix, done)
+}
+
+func newKVWatches() *kvWatches {
+	return &kvWatches{watches: map[*kvWatch]struct{}{}}
+}
+
+// watch returns a channel which receives the pairs written under prefix,
+// until done is closed.
+func (ws *kvWatches) watch(prefix string, done <-chan struct{}) <-chan KVPair {
+	w := &kvWatch{prefix: prefix, ch: make(chan KVPair, kvWatchBuffer)}
+	ws.Lock()
+	ws.watches[w] = struct{}{}
+	ws.Unlock()
+	go func() {
+		<-done
+		ws.Lock()
+		delete(ws.watches, w)
+		close(w.ch)
+		ws.Unlock()
+	}()
+	return w.ch
+}
+
+// notify passes the pairs written on to the watches of their keys, dropping
+// them for any watch whose receiver has fallen behind.
+func (ws *kvWatches) notify(written []KVPair) {
+	ws.Lock()
+	defer ws.Unlock()
+	for w := range ws.watches {
+		for _, p := range written {
+			if !strings.HasPrefix(p.Key, w.prefix) {
+				continue
+			}
+			select {
+			case w.ch <- p:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemKVStore_CompareAndSwap(t *testing.T) {
+	s := NewMemKVStore()
+
+	rev, err := s.CompareAndSwap(KVWrite{Key: "a", Value: []byte("1")}, KVWrite{Key: "b", Value: []byte("2")})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), rev)
+
+	_, err = s.CompareAndSwap(KVWrite{Key: "a", Value: []byte("3")})
+	assert.True(t, IsKVConflict(err), "a write expecting a missing key should conflict, got %v", err)
+
+	_, err = s.CompareAndSwap(KVWrite{Key: "a", Revision: 1, Value: []byte("3")}, KVWrite{Key: "b", Revision: 7})
+	assert.True(t, IsKVConflict(err))
+	p, ok, err := s.Get("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "1", string(p.Value), "no write should be made if any conflicts")
+
+	rev, err = s.CompareAndSwap(KVWrite{Key: "a", Revision: 1, Value: []byte("3")}, KVWrite{Key: "b", Revision: 1})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), rev)
+
+	pairs, rev, err := s.List("")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), rev)
+	assert.Equal(t, []KVPair{{Key: "a", Value: []byte("3"), Revision: 2}, {Key: "b", Revision: 2}}, pairs,
+		"a deleted key should be listed as its tombstone")
+
+	_, ok, err = s.Get("b")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, err = s.CompareAndSwap(KVWrite{Key: "b", Value: []byte("4")})
+	assert.True(t, IsKVConflict(err), "a write from before a deletion should conflict, got %v", err)
+	_, err = s.CompareAndSwap(KVWrite{Key: "b", Revision: 2, Value: []byte("4")})
+	assert.NoError(t, err, "a write from after a deletion should succeed")
+}
+
+func TestMemKVStore_List(t *testing.T) {
+	s := NewMemKVStore()
+	_, err := s.CompareAndSwap(
+		KVWrite{Key: "x/2", Value: []byte("2")},
+		KVWrite{Key: "x/1", Value: []byte("1")},
+		KVWrite{Key: "y/1", Value: []byte("3")},
+	)
+	require.NoError(t, err)
+
+	pairs, _, err := s.List("x/")
+	require.NoError(t, err)
+	require.Len(t, pairs, 2)
+	assert.Equal(t, "x/1", pairs[0].Key)
+	assert.Equal(t, "x/2", pairs[1].Key)
+}
+
+func TestMemKVStore_Watch(t *testing.T) {
+	s := NewMemKVStore()
+	done := make(chan struct{})
+	watch := s.Watch("x/", done)
+
+	_, err := s.CompareAndSwap(KVWrite{Key: "y/1", Value: []byte("ignored")})
+	require.NoError(t, err)
+	_, err = s.CompareAndSwap(KVWrite{Key: "x/1", Value: []byte("1")})
+	require.NoError(t, err)
+	_, err = s.CompareAndSwap(KVWrite{Key: "x/1", Revision: 2})
+	require.NoError(t, err)
+
+	assert.Equal(t, KVPair{Key: "x/1", Value: []byte("1"), Revision: 2}, <-watch)
+	assert.Equal(t, KVPair{Key: "x/1", Revision: 3}, <-watch, "a deletion should be received with a nil value")
+
+	close(done)
+	for range watch {
+	}
+}
+
+func TestBoltKVStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-kv")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.db")
+
+	s, err := OpenBoltKVStore(path)
+	require.NoError(t, err)
+	done := make(chan struct{})
+	watch := s.Watch("b", done)
+	rev, err := s.CompareAndSwap(KVWrite{Key: "a", Value: []byte("1")}, KVWrite{Key: "b", Value: []byte("2")})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), rev)
+	_, err = s.CompareAndSwap(KVWrite{Key: "a", Value: []byte("3")}, KVWrite{Key: "b", Revision: 1})
+	assert.True(t, IsKVConflict(err), "a write expecting a missing key should conflict, got %v", err)
+	_, err = s.CompareAndSwap(KVWrite{Key: "b", Revision: 1})
+	require.NoError(t, err)
+	assert.Equal(t, KVPair{Key: "b", Value: []byte("2"), Revision: 1}, <-watch)
+	assert.Equal(t, KVPair{Key: "b", Revision: 2}, <-watch, "a deletion should be received with a nil value")
+	close(done)
+	require.NoError(t, s.Close())
+
+	reopened, err := OpenBoltKVStore(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	pairs, rev, err := reopened.List("")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), rev)
+	assert.Equal(t, []KVPair{{Key: "a", Value: []byte("1"), Revision: 1}, {Key: "b", Revision: 2}}, pairs,
+		"no write should be made if any conflicts, and a deleted key should be listed as its tombstone")
+	p, ok, err := reopened.Get("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "1", string(p.Value))
+	_, ok, err = reopened.Get("b")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, err = reopened.CompareAndSwap(KVWrite{Key: "b", Value: []byte("4")})
+	assert.True(t, IsKVConflict(err), "a write from before a deletion should conflict, got %v", err)
+	_, err = reopened.CompareAndSwap(KVWrite{Key: "b", Revision: 2, Value: []byte("4")})
+	require.NoError(t, err)
+	p, ok, err = reopened.Get("b")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "4", string(p.Value))
+
+	_, err = OpenBoltKVStore(path)
+	assert.Error(t, err, "the file should be locked while it is open")
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+// A stateReplacer is a StateManager which only writes states it has not read
+// itself when asked explicitly.
+type stateReplacer interface {
+	ReplaceState(*sous.State, sous.User) error
+}
+
+// A StateMigration reports the copying of the state from one StateManager to
+// another.
+type StateMigration struct {
+	// Repaired lists the flaws found in the state read, which were repaired
+	// before it was written.
+	Repaired []string
+	// Comparison compares the state written with the state read back from
+	// the destination.
+	Comparison *sous.StateComparison
+}
+
+// OpenStateManager returns the StateManager for the state at loc, which is
+// one of:
+//
+//	git:<dir>           a GDM committed to the git repository at dir
+//	disk:<dir>          a GDM in dir, not committed to git
+//	kv:<path>           a key-value store in the Bolt file at path
+//	postgres:<conn>     a Postgres database, given by its connection string
+func OpenStateManager(loc string, log logging.LogSink) (sous.StateManager, error) {
+	parts := strings.SplitN(loc, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, errors.Errorf("state location %q is not <kind>:<location>", loc)
+	}
+	switch kind, where := parts[0], parts[1]; kind {
+	default:
+		return nil, errors.Errorf("state location %q is not one of git, disk, kv or postgres", loc)
+	case "git":
+		return NewGitStateManager(NewDiskStateManager(where)), nil
+	case "disk":
+		return NewDiskStateManager(where), nil
+	case "kv":
+		kv, err := OpenBoltKVStore(where)
+		if err != nil {
+			return nil, err
+		}
+		return NewKVStateManager(kv, "", log.Child("kv-state")), nil
+	case "postgres":
+		db, err := sql.Open("postgres", where)
+		if err != nil {
+			return nil, errors.Wrapf(err, "opening database")
+		}
+		if err := db.Ping(); err != nil {
+			return nil, errors.Wrapf(err, "connecting to database")
+		}
+		return NewPostgresStateManager(db, log.Child("database")), nil
+	}
+}
+
+// MigrateState copies the state from one StateManager to another. The state
+// read is validated, and its flaws repaired, before it is written; if any
+// cannot be, nothing is written. The state is then read back from to, and
+// compared with the state written, so that anything lost by to is reported
+// in the Comparison.
+func MigrateState(from, to sous.StateManager, user sous.User) (*StateMigration, error) {
+	state, err := from.ReadState()
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading state to migrate")
+	}
+
+	migration := &StateMigration{Repaired: []string{}}
+	flaws := state.Validate()
+	if unrepaired, errs := sous.RepairAll(flaws); len(errs) > 0 {
+		descs := make([]string, len(unrepaired))
+		for i, f := range unrepaired {
+			descs[i] = fmt.Sprint(f)
+		}
+		return nil, errors.Errorf("cannot repair state: %s", strings.Join(descs, "; "))
+	}
+	for _, f := range flaws {
+		migration.Repaired = append(migration.Repaired, fmt.Sprint(f))
+	}
+
+	// The source's etag means nothing to the destination, so the state
+	// replaces whatever the destination holds.
+	state = &sous.State{Defs: state.Defs, Manifests: state.Manifests}
+	write := to.WriteState
+	if r, ok := to.(stateReplacer); ok {
+		write = r.ReplaceState
+	}
+	if err := write(state, user); err != nil {
+		return nil, errors.Wrapf(err, "writing migrated state")
+	}
+
+	written, err := to.ReadState()
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading back migrated state")
+	}
+	if migration.Comparison, err = sous.CompareStates(state, written); err != nil {
+		return nil, err
+	}
+	return migration, nil
+}
+
+// CompareStateManagers reads the state from both StateManagers, and reports
+// how the state in b differs from that in a.
+func CompareStateManagers(a, b sous.StateManager) (*sous.StateComparison, error) {
+	aState, err := a.ReadState()
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading first state")
+	}
+	bState, err := b.ReadState()
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading second state")
+	}
+	return sous.CompareStates(aState, bState)
+}
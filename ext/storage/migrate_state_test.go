@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenStateManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-migrate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ls := logging.SilentLogSet()
+
+	sm, err := OpenStateManager("git:"+dir, ls)
+	require.NoError(t, err)
+	assert.IsType(t, &GitStateManager{}, sm)
+	sm, err = OpenStateManager("disk:"+dir, ls)
+	require.NoError(t, err)
+	assert.IsType(t, &DiskStateManager{}, sm)
+	sm, err = OpenStateManager("kv:"+filepath.Join(dir, "state.db"), ls)
+	require.NoError(t, err)
+	assert.IsType(t, &KVStateManager{}, sm)
+
+	for _, loc := range []string{"", dir, "git:", "bolt:" + dir} {
+		_, err := OpenStateManager(loc, ls)
+		assert.Error(t, err, "%q", loc)
+	}
+}
+
+func TestMigrateState(t *testing.T) {
+	gsm, _ := setupManagers(t)
+	kv := NewKVStateManager(NewMemKVStore(), "", logging.SilentLogSet())
+
+	migration, err := MigrateState(gsm, kv, testUser)
+	require.NoError(t, err)
+	assert.True(t, migration.Comparison.Same(), "%#v", migration.Comparison)
+
+	expected, err := gsm.ReadState()
+	require.NoError(t, err)
+	actual, err := kv.ReadState()
+	require.NoError(t, err)
+	sameYAML(t, actual, expected)
+
+	cmp, err := CompareStateManagers(gsm, kv)
+	require.NoError(t, err)
+	assert.True(t, cmp.Same(), "%#v", cmp)
+}
+
+func TestMigrateState_fullyPopulated(t *testing.T) {
+	from := NewKVStateManager(NewMemKVStore(), "", logging.SilentLogSet())
+	require.NoError(t, from.ReplaceState(fullyPopulatedState(), testUser))
+	to := NewKVStateManager(NewMemKVStore(), "", logging.SilentLogSet())
+
+	migration, err := MigrateState(from, to, testUser)
+	require.NoError(t, err)
+	assert.True(t, migration.Comparison.Same(), "%v %v", migration.Comparison.Defs, migration.Comparison.Changes)
+}
+
+func TestMigrateState_postgres(t *testing.T) {
+	from := NewKVStateManager(NewMemKVStore(), "", logging.SilentLogSet())
+	require.NoError(t, from.ReplaceState(fullyPopulatedState(), testUser))
+	to := NewPostgresStateManager(setupDB(t), logging.SilentLogSet())
+
+	migration, err := MigrateState(from, to, testUser)
+	require.NoError(t, err)
+	assert.True(t, migration.Comparison.Same(), "%v %v", migration.Comparison.Defs, migration.Comparison.Changes)
+}
+
+func TestCompareStateManagers(t *testing.T) {
+	a := NewKVStateManager(NewMemKVStore(), "", logging.SilentLogSet())
+	b := NewKVStateManager(NewMemKVStore(), "", logging.SilentLogSet())
+	require.NoError(t, a.ReplaceState(exampleState(), testUser))
+	changed := exampleState()
+	changed.Manifests.Remove(sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/project"}})
+	require.NoError(t, b.ReplaceState(changed, testUser))
+
+	cmp, err := CompareStateManagers(a, b)
+	require.NoError(t, err)
+	assert.Empty(t, cmp.Defs)
+	require.NotEmpty(t, cmp.Changes)
+	for _, dc := range cmp.Changes {
+		assert.Equal(t, "removed", dc.Kind)
+		assert.Equal(t, "github.com/user/project", dc.DeploymentID.ManifestID.Source.Repo)
+	}
+
+	dup := NewDuplexStateManager(a, b, logging.SilentLogSet())
+	reconciled, err := dup.ReconcileState()
+	require.NoError(t, err)
+	assert.Equal(t, cmp, reconciled)
+	unchanged, err := b.ReadState()
+	require.NoError(t, err)
+	assert.Equal(t, 1, unchanged.Manifests.Len(), "reconciling should not write to the secondary")
+}
@@ -1,12 +1,14 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 
 	"github.com/lib/pq"
 	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
 )
 
 type (
@@ -33,6 +35,26 @@ func NewPostgresStateManager(db *sql.DB, log logging.LogSink) *PostgresStateMana
 	return &PostgresStateManager{db: db, log: log}
 }
 
+// withTx calls f in a repeatable read transaction, which is committed if f
+// succeeds and rolled back otherwise.
+func (m PostgresStateManager) withTx(readOnly bool, f func(context.Context, *sql.Tx) error) error {
+	ctx := context.TODO()
+
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: readOnly})
+	if err != nil {
+		return errors.Wrapf(err, "opening transaction")
+	}
+	defer func(tx *sql.Tx) {
+		// ignoring error - since if the Tx is committed, we would expect an error on rollback
+		tx.Rollback()
+	}(tx)
+
+	if err := f(ctx, tx); err != nil {
+		return err
+	}
+	return errors.Wrapf(tx.Commit(), "committing transaction")
+}
+
 func (c PostgresConfig) connStr() string {
 	conn := []string{}
 	if c.Host != "" {
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+// deploymentCond and clusterCond scope loadManifestsWhere to a single
+// deployment, and to the deployments to a single cluster.
+const (
+	deploymentCond = `repo = $1 and dir = $2 and flavor = $3 and clusters.name = $4`
+	clusterCond    = `clusters.name = $1`
+)
+
+// ReadDeployment implements sous.DeploymentManager on PostgresStateManager,
+// loading only the deployment requested.
+func (m PostgresStateManager) ReadDeployment(did sous.DeploymentID) (*sous.Deployment, error) {
+	var dep *sous.Deployment
+	err := m.withTx(true, func(ctx context.Context, tx *sql.Tx) error {
+		_, deps, err := m.loadScoped(ctx, tx, deploymentCond, deploymentArgs(did)...)
+		if err != nil {
+			return err
+		}
+		var has bool
+		if dep, has = deps.Get(did); !has {
+			return errors.Errorf("no deployment found for %s", did)
+		}
+		return nil
+	})
+	return dep, err
+}
+
+// WriteDeployment implements sous.DeploymentManager on PostgresStateManager.
+// If another write to the same deployment commits first, it fails and
+// changes nothing.
+func (m PostgresStateManager) WriteDeployment(dep *sous.Deployment, user sous.User) error {
+	did := dep.ID()
+	err := m.withTx(false, func(ctx context.Context, tx *sql.Tx) error {
+		return m.storeScoped(ctx, tx, user, sous.NewDeployments(dep), deploymentCond, deploymentArgs(did)...)
+	})
+	return concurrentWriteError(err, "deployment %s", did)
+}
+
+// ReadCluster implements sous.ClusterManager on PostgresStateManager,
+// loading only the deployments to clusterName.
+func (m PostgresStateManager) ReadCluster(clusterName string) (sous.Deployments, error) {
+	deps := sous.NewDeployments()
+	err := m.withTx(true, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		_, deps, err = m.loadScoped(ctx, tx, clusterCond, clusterName)
+		return err
+	})
+	return deps, err
+}
+
+// WriteCluster implements sous.ClusterManager on PostgresStateManager. Like
+// the ClusterManager made by sous.MakeClusterManager, it adds and updates
+// deployments, but does not remove those missing from deps. If another write
+// to any of the same deployments commits first, it fails and changes
+// nothing.
+func (m PostgresStateManager) WriteCluster(clusterName string, deps sous.Deployments, user sous.User) error {
+	err := m.withTx(false, func(ctx context.Context, tx *sql.Tx) error {
+		return m.storeScoped(ctx, tx, user, deps, clusterCond, clusterName)
+	})
+	return concurrentWriteError(err, "cluster %q", clusterName)
+}
+
+func deploymentArgs(did sous.DeploymentID) []interface{} {
+	mid := did.ManifestID
+	return []interface{}{mid.Source.Repo, mid.Source.Dir, mid.Flavor, did.Cluster}
+}
+
+// loadScoped returns a State with all of its Defs, but only the deployments
+// matching cond, and those deployments.
+func (m PostgresStateManager) loadScoped(ctx context.Context, tx *sql.Tx, cond string, args ...interface{}) (*sous.State, sous.Deployments, error) {
+	state, err := loadDefs(ctx, m.log, tx)
+	if err != nil {
+		return nil, sous.NewDeployments(), err
+	}
+	if err := loadManifestsWhere(ctx, m.log, tx, state, cond, args...); err != nil {
+		return nil, sous.NewDeployments(), err
+	}
+	deps, err := state.Deployments()
+	if err != nil {
+		return nil, sous.NewDeployments(), err
+	}
+	return state, deps, nil
+}
+
+// storeScoped writes deps over the deployments matching cond. They are
+// validated against the Defs as a whole State would be.
+func (m PostgresStateManager) storeScoped(ctx context.Context, tx *sql.Tx, user sous.User, deps sous.Deployments, cond string, args ...interface{}) error {
+	state, currentDeps, err := m.loadScoped(ctx, tx, cond, args...)
+	if err != nil {
+		return err
+	}
+	ds := []*sous.Deployment{}
+	for _, d := range deps.Snapshot() {
+		ds = append(ds, d)
+	}
+	if err := state.UpdateDeployments(ds...); err != nil {
+		return err
+	}
+	newDeps, err := state.Deployments()
+	if err != nil {
+		return err
+	}
+	return storeDiffs(ctx, m.log, tx, user, currentDeps.Diff(newDeps).Collect())
+}
+
+// concurrentWriteError explains err if it was caused by a concurrent write
+// to the same deployment: either the unique constraint over
+// prior_deployment_id, or a serialization failure.
+func concurrentWriteError(err error, format string, args ...interface{}) error {
+	if pqerr, is := errors.Cause(err).(*pq.Error); is {
+		switch pqerr.Code {
+		case "23505", "40001":
+			return errors.Wrapf(err, format+" changed concurrently; read it and try again", args...)
+		}
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+// ReadHistory implements sous.HistoryReader on PostgresStateManager
+func (m PostgresStateManager) ReadHistory(filter sous.HistoryFilter) ([]sous.StateChange, error) {
+	context := context.TODO()
+
+	tx, err := m.db.BeginTx(context, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening transaction")
+	}
+	defer func(tx *sql.Tx) {
+		// ignoring error - since if the Tx is committed, we would expect an error on rollback
+		tx.Rollback()
+	}(tx)
+
+	changes, err := loadHistory(context, m.log, tx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "committing transaction")
+	}
+	return changes, nil
+}
+
+func loadHistory(ctx context.Context, log logging.LogSink, tx *sql.Tx, filter sous.HistoryFilter) ([]sous.StateChange, error) {
+	conds := []string{}
+	args := []interface{}{}
+	where := func(cond string, vals ...interface{}) {
+		for _, v := range vals {
+			args = append(args, v)
+			cond = strings.Replace(cond, "?", fmt.Sprintf("$%d", len(args)), 1)
+		}
+		conds = append(conds, cond)
+	}
+
+	if filter.Manifest != nil {
+		where("repo = ? and dir = ? and flavor = ?",
+			filter.Manifest.Source.Repo, filter.Manifest.Source.Dir, filter.Manifest.Flavor)
+	}
+	if filter.Cluster != "" {
+		where("cluster = ?", filter.Cluster)
+	}
+	if filter.User != "" {
+		where("(user_name = ? or user_email = ?)", filter.User, filter.User)
+	}
+	if !filter.Since.IsZero() {
+		where("changed_at >= ?", filter.Since)
+	}
+
+	// The Limit applies to changes, not to their rows, so the changes are
+	// chosen first, and their rows joined in after.
+	from := `
+	from
+		gdm_changes
+		join gdm_change_deployments using (change_id)`
+	if filter.Limit > 0 {
+		matching := ""
+		if len(conds) > 0 {
+			matching = "\n\twhere " + strings.Join(conds, " and ")
+		}
+		args = append(args, filter.Limit)
+		conds = append(conds, fmt.Sprintf(`change_id in (
+		select distinct change_id%s%s
+		order by change_id desc
+		limit $%d)`, from, matching, len(args)))
+	}
+
+	query := `select
+		change_id, changed_at, user_name, user_email,
+		repo, dir, flavor, cluster, change_kind, diffs` + from
+	if len(conds) > 0 {
+		query += "\n\twhere " + strings.Join(conds, " and ")
+	}
+	query += "\n\torder by change_id desc, repo, dir, flavor, cluster;"
+
+	changes := []sous.StateChange{}
+	err := loadTableWithArgs(ctx, log, tx, "gdm_changes", query, args,
+		func(rows *sql.Rows) error {
+			var changeID int64
+			var changedAt time.Time
+			user := sous.User{}
+			dc := sous.DeploymentChange{}
+			diffs := pq.StringArray{}
+			if err := rows.Scan(
+				&changeID, &changedAt, &user.Name, &user.Email,
+				&dc.DeploymentID.ManifestID.Source.Repo,
+				&dc.DeploymentID.ManifestID.Source.Dir,
+				&dc.DeploymentID.ManifestID.Flavor,
+				&dc.DeploymentID.Cluster,
+				&dc.Kind,
+				&diffs,
+			); err != nil {
+				return errors.Wrapf(err, "loadHistory")
+			}
+			if len(diffs) > 0 {
+				dc.Diffs = sous.Differences(diffs)
+			}
+
+			last := len(changes) - 1
+			if last < 0 || changes[last].ID != changeID {
+				changes = append(changes, sous.StateChange{
+					ID:   changeID,
+					User: user,
+					At:   changedAt,
+				})
+				last++
+			}
+			changes[last].Deployments = append(changes[last].Deployments, dc)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return filter.FilterAll(changes), nil
+}
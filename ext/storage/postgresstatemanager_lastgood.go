@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+// RecordLastGood implements sous.LastGoodStore on PostgresStateManager.
+func (m PostgresStateManager) RecordLastGood(did sous.DeploymentID, version sous.SourceID) error {
+	return m.withTx(false, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `insert into last_good_versions
+			(repo, dir, flavor, cluster, versionstring, recorded_at)
+			values ($1, $2, $3, $4, $5, now())
+			on conflict (repo, dir, flavor, cluster) do update
+			set versionstring = excluded.versionstring, recorded_at = excluded.recorded_at;`,
+			append(deploymentArgs(did), version.Version.String())...,
+		); err != nil {
+			return errors.Wrapf(err, "recording last good version of %s", did)
+		}
+		return nil
+	})
+}
+
+// LastGood implements sous.LastGoodStore on PostgresStateManager. Versions
+// are always of the deployment's own source location.
+func (m PostgresStateManager) LastGood(did sous.DeploymentID) (sous.SourceID, bool, error) {
+	var versionString string
+	err := m.withTx(true, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `select versionstring
+		from last_good_versions
+		where repo = $1 and dir = $2 and flavor = $3 and cluster = $4;`,
+			deploymentArgs(did)...).Scan(&versionString)
+	})
+	if err == sql.ErrNoRows {
+		return sous.SourceID{}, false, nil
+	}
+	if err != nil {
+		return sous.SourceID{}, false, errors.Wrapf(err, "reading last good version of %s", did)
+	}
+	version, err := semv.Parse(versionString)
+	if err != nil {
+		return sous.SourceID{}, false, errors.Wrapf(err, "parsing last good version of %s", did)
+	}
+	return sous.SourceID{Location: did.ManifestID.Source, Version: version}, true, nil
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+// AcquireLease implements sous.LeaseStore on PostgresStateManager. Leases
+// are timed by the database's clock, so that the servers sharing them need
+// not agree on the time.
+func (m PostgresStateManager) AcquireLease(cluster, holder string, ttl time.Duration) (sous.Lease, error) {
+	lease := sous.Lease{Cluster: cluster}
+	err := m.withTx(false, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `insert into cluster_leases
+			(cluster, holder, expires_at)
+			values ($1, $2, now() + $3 * interval '1 millisecond')
+			on conflict (cluster) do update
+			set holder = excluded.holder, expires_at = excluded.expires_at
+			where cluster_leases.holder = excluded.holder
+				or cluster_leases.expires_at < now();`,
+			cluster, holder, ttl.Nanoseconds()/int64(time.Millisecond),
+		); err != nil {
+			return errors.Wrapf(err, "acquiring lease on %q", cluster)
+		}
+		var remaining float64
+		if err := tx.QueryRowContext(ctx, `select
+			holder, extract(epoch from expires_at - now())
+		from cluster_leases
+		where cluster = $1;`, cluster).Scan(&lease.Holder, &remaining); err != nil {
+			return errors.Wrapf(err, "reading lease on %q", cluster)
+		}
+		lease.Expires = time.Now().Add(time.Duration(remaining * float64(time.Second)))
+		return nil
+	})
+	return lease, err
+}
+
+// ReleaseLease implements sous.LeaseStore on PostgresStateManager.
+func (m PostgresStateManager) ReleaseLease(cluster, holder string) error {
+	return m.withTx(false, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `delete from cluster_leases
+			where cluster = $1 and holder = $2;`, cluster, holder); err != nil {
+			return errors.Wrapf(err, "releasing lease on %q", cluster)
+		}
+		return nil
+	})
+}
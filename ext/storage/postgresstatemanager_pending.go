@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+// ProposeChange implements sous.PendingChangeStore on PostgresStateManager.
+func (m PostgresStateManager) ProposeChange(pc sous.PendingChange) error {
+	return m.withTx(false, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `insert into pending_changes
+			(change_id, author_name, author_email, proposed_at, status)
+			values ($1, $2, $3, $4, $5);`,
+			string(pc.ID), pc.Author.Name, pc.Author.Email, pc.Proposed, string(pc.Status),
+		); err != nil {
+			return errors.Wrapf(err, "inserting pending change")
+		}
+
+		for _, pd := range pc.Deployments {
+			// A removal is stored as a null deployment, and an addition as a
+			// null prior.
+			dep, err := nullableJSON(pd.Deployment)
+			if err != nil {
+				return errors.Wrapf(err, "encoding proposed deployment %s", pd.DeploymentID)
+			}
+			prior, err := nullableJSON(pd.Prior)
+			if err != nil {
+				return errors.Wrapf(err, "encoding prior deployment %s", pd.DeploymentID)
+			}
+			did := pd.DeploymentID
+			if _, err := tx.ExecContext(ctx, `insert into pending_change_deployments
+				(change_id, repo, dir, flavor, cluster, deployment, prior)
+				values ($1, $2, $3, $4, $5, $6, $7);`,
+				string(pc.ID), did.ManifestID.Source.Repo, did.ManifestID.Source.Dir,
+				did.ManifestID.Flavor, did.Cluster, dep, prior,
+			); err != nil {
+				return errors.Wrapf(err, "inserting proposed deployment %s", did)
+			}
+		}
+		return nil
+	})
+}
+
+// ReadPendingChange implements sous.PendingChangeStore on PostgresStateManager.
+func (m PostgresStateManager) ReadPendingChange(id sous.PendingChangeID) (*sous.PendingChange, error) {
+	var changes []sous.PendingChange
+	err := m.withTx(true, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		changes, err = loadPendingChanges(ctx, m.log, tx, "pending_changes.change_id = $1", string(id))
+		return err
+	})
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+	return &changes[0], nil
+}
+
+// ListPendingChanges implements sous.PendingChangeStore on PostgresStateManager.
+func (m PostgresStateManager) ListPendingChanges() ([]sous.PendingChange, error) {
+	var changes []sous.PendingChange
+	err := m.withTx(true, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		changes, err = loadPendingChanges(ctx, m.log, tx, "status = $1", string(sous.PendingStatusPending))
+		return err
+	})
+	return changes, err
+}
+
+// ReviewPendingChange implements sous.PendingChangeStore on PostgresStateManager.
+func (m PostgresStateManager) ReviewPendingChange(id sous.PendingChangeID, status sous.PendingStatus, reviewer sous.User, at time.Time) error {
+	return m.withTx(false, func(ctx context.Context, tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `update pending_changes
+			set status = $2, reviewer_name = $3, reviewer_email = $4, reviewed_at = $5
+			where change_id = $1 and status = $6;`,
+			string(id), string(status), reviewer.Name, reviewer.Email, at, string(sous.PendingStatusPending))
+		if err != nil {
+			return errors.Wrapf(err, "reviewing pending change %s", id)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return errors.Wrapf(err, "reviewing pending change %s", id)
+		}
+		if n != 1 {
+			return errors.Errorf("no pending change %s awaiting review", id)
+		}
+		return nil
+	})
+}
+
+func loadPendingChanges(ctx context.Context, log logging.LogSink, tx *sql.Tx, cond string, args ...interface{}) ([]sous.PendingChange, error) {
+	query := `select
+		pending_changes.change_id, author_name, author_email, proposed_at, status,
+		reviewer_name, reviewer_email, reviewed_at,
+		repo, dir, flavor, cluster, deployment, prior
+	from
+		pending_changes
+		join pending_change_deployments using (change_id)
+	where ` + cond + `
+	order by proposed_at, pending_changes.change_id, repo, dir, flavor, cluster;`
+
+	changes := []sous.PendingChange{}
+	err := loadTableWithArgs(ctx, log, tx, "pending_changes", query, args,
+		func(rows *sql.Rows) error {
+			var id, status string
+			pc := sous.PendingChange{}
+			var reviewerName, reviewerEmail sql.NullString
+			var reviewed pq.NullTime
+			pd := sous.ProposedDeployment{}
+			var dep, prior []byte
+			if err := rows.Scan(
+				&id, &pc.Author.Name, &pc.Author.Email, &pc.Proposed, &status,
+				&reviewerName, &reviewerEmail, &reviewed,
+				&pd.DeploymentID.ManifestID.Source.Repo,
+				&pd.DeploymentID.ManifestID.Source.Dir,
+				&pd.DeploymentID.ManifestID.Flavor,
+				&pd.DeploymentID.Cluster,
+				&dep, &prior,
+			); err != nil {
+				return errors.Wrapf(err, "loadPendingChanges")
+			}
+			if len(dep) > 0 {
+				pd.Deployment = &sous.Deployment{}
+				if err := json.Unmarshal(dep, pd.Deployment); err != nil {
+					return errors.Wrapf(err, "decoding proposed deployment %s", pd.DeploymentID)
+				}
+			}
+			if len(prior) > 0 {
+				pd.Prior = &sous.Deployment{}
+				if err := json.Unmarshal(prior, pd.Prior); err != nil {
+					return errors.Wrapf(err, "decoding prior deployment %s", pd.DeploymentID)
+				}
+			}
+
+			last := len(changes) - 1
+			if last < 0 || changes[last].ID != sous.PendingChangeID(id) {
+				pc.ID = sous.PendingChangeID(id)
+				pc.Status = sous.PendingStatus(status)
+				pc.Reviewer = sous.User{Name: reviewerName.String, Email: reviewerEmail.String}
+				pc.Reviewed = reviewed.Time
+				changes = append(changes, pc)
+				last++
+			}
+			changes[last].Deployments = append(changes[last].Deployments, pd)
+			return nil
+		})
+	return changes, err
+}
+
+// nullableJSON encodes dep as JSON, or as null if it is nil.
+func nullableJSON(dep *sous.Deployment) (interface{}, error) {
+	if dep == nil {
+		return nil, nil
+	}
+	js, err := json.Marshal(dep)
+	if err != nil {
+		return nil, err
+	}
+	return string(js), nil
+}
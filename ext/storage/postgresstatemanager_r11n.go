@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+// StoreR11n implements sous.R11nStore on PostgresStateManager. Storing a
+// rectification again records the rollout stage it has reached.
+func (m PostgresStateManager) StoreR11n(sr sous.StoredR11n) error {
+	prior, err := encodeDeployable(sr.Prior)
+	if err != nil {
+		return errors.Wrapf(err, "encoding prior deployment of rectification %s", sr.ID)
+	}
+	post, err := encodeDeployable(sr.Post)
+	if err != nil {
+		return errors.Wrapf(err, "encoding post deployment of rectification %s", sr.ID)
+	}
+	var executorData interface{}
+	if sr.ExecutorData != nil {
+		js, err := json.Marshal(sr.ExecutorData)
+		if err != nil {
+			return errors.Wrapf(err, "encoding executor data of rectification %s", sr.ID)
+		}
+		executorData = string(js)
+	}
+	var stage interface{}
+	if sr.Stage != nil {
+		js, err := json.Marshal(sr.Stage)
+		if err != nil {
+			return errors.Wrapf(err, "encoding rollout stage of rectification %s", sr.ID)
+		}
+		stage = string(js)
+	}
+	did := sr.DeploymentID
+	return m.withTx(false, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `insert into r11n_queue
+			(r11n_id, repo, dir, flavor, cluster, priority, queued_at, prior, post, executor_data, rollout_stage)
+			values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			on conflict (r11n_id) do update set rollout_stage = excluded.rollout_stage;`,
+			string(sr.ID), did.ManifestID.Source.Repo, did.ManifestID.Source.Dir,
+			did.ManifestID.Flavor, did.Cluster, int(sr.Priority), sr.QueuedAt, prior, post, executorData, stage,
+		); err != nil {
+			return errors.Wrapf(err, "inserting rectification %s", sr.ID)
+		}
+		return nil
+	})
+}
+
+// RemoveR11n implements sous.R11nStore on PostgresStateManager.
+func (m PostgresStateManager) RemoveR11n(id sous.R11nID) error {
+	return m.withTx(false, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `delete from r11n_queue where r11n_id = $1;`, string(id)); err != nil {
+			return errors.Wrapf(err, "deleting rectification %s", id)
+		}
+		return nil
+	})
+}
+
+// LoadR11ns implements sous.R11nStore on PostgresStateManager.
+func (m PostgresStateManager) LoadR11ns() ([]sous.StoredR11n, error) {
+	query := `select
+		r11n_id, repo, dir, flavor, cluster, priority, queued_at, prior, post, executor_data, rollout_stage
+	from r11n_queue
+	order by seq;`
+
+	stored := []sous.StoredR11n{}
+	err := m.withTx(true, func(ctx context.Context, tx *sql.Tx) error {
+		return loadTable(ctx, m.log, tx, "r11n_queue", query, func(rows *sql.Rows) error {
+			var id string
+			var priority int
+			var prior, post, executorData, stage []byte
+			sr := sous.StoredR11n{}
+			if err := rows.Scan(
+				&id,
+				&sr.DeploymentID.ManifestID.Source.Repo,
+				&sr.DeploymentID.ManifestID.Source.Dir,
+				&sr.DeploymentID.ManifestID.Flavor,
+				&sr.DeploymentID.Cluster,
+				&priority, &sr.QueuedAt, &prior, &post, &executorData, &stage,
+			); err != nil {
+				return errors.Wrapf(err, "LoadR11ns")
+			}
+			sr.ID = sous.R11nID(id)
+			sr.Priority = sous.R11nPriority(priority)
+			var err error
+			if sr.Prior, err = decodeDeployable(prior); err != nil {
+				return errors.Wrapf(err, "decoding prior deployment of rectification %s", id)
+			}
+			if sr.Post, err = decodeDeployable(post); err != nil {
+				return errors.Wrapf(err, "decoding post deployment of rectification %s", id)
+			}
+			if len(executorData) > 0 {
+				if err := json.Unmarshal(executorData, &sr.ExecutorData); err != nil {
+					return errors.Wrapf(err, "decoding executor data of rectification %s", id)
+				}
+			}
+			if len(stage) > 0 {
+				sr.Stage = &sous.RolloutStage{}
+				if err := json.Unmarshal(stage, sr.Stage); err != nil {
+					return errors.Wrapf(err, "decoding rollout stage of rectification %s", id)
+				}
+			}
+			stored = append(stored, sr)
+			return nil
+		})
+	})
+	return stored, err
+}
+
+// encodeDeployable encodes d as JSON for a JSONB column; a nil Deployable is
+// stored as null.
+func encodeDeployable(d *sous.Deployable) (interface{}, error) {
+	if d == nil {
+		return nil, nil
+	}
+	js, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return string(js), nil
+}
+
+func decodeDeployable(js []byte) (*sous.Deployable, error) {
+	if len(js) == 0 {
+		return nil, nil
+	}
+	d := &sous.Deployable{}
+	if err := json.Unmarshal(js, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
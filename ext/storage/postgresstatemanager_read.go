@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/lib/pq"
@@ -44,6 +45,19 @@ func (m PostgresStateManager) ReadState() (*sous.State, error) {
 }
 
 func loadState(ctx context.Context, log logging.LogSink, tx *sql.Tx) (*sous.State, error) {
+	state, err := loadDefs(ctx, log, tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadManifests(ctx, log, tx, state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// loadDefs returns a State with its Defs loaded, but no manifests.
+func loadDefs(ctx context.Context, log logging.LogSink, tx *sql.Tx) (*sous.State, error) {
 	state := sous.NewState()
 
 	if err := loadEnvDefs(ctx, log, tx, state); err != nil {
@@ -58,7 +72,7 @@ func loadState(ctx context.Context, log logging.LogSink, tx *sql.Tx) (*sous.Stat
 	if err := loadClusters(ctx, log, tx, state); err != nil {
 		return nil, err
 	}
-	if err := loadManifests(ctx, log, tx, state); err != nil {
+	if err := loadGlobalDefs(ctx, log, tx, state); err != nil {
 		return nil, err
 	}
 
@@ -67,12 +81,16 @@ func loadState(ctx context.Context, log logging.LogSink, tx *sql.Tx) (*sous.Stat
 
 func loadEnvDefs(context context.Context, log logging.LogSink, tx *sql.Tx, state *sous.State) error {
 	return loadTable(context, log, tx, "env_var_defs",
-		`select "name", "desc", "scope", "type" from env_var_defs;`,
+		`select "name", "desc", "scope", "type", "allowed_values", "pattern" from env_var_defs;`,
 		func(rows *sql.Rows) error {
 			d := sous.EnvDef{}
-			if err := rows.Scan(&d.Name, &d.Desc, &d.Scope, &d.Type); err != nil {
+			var values pq.StringArray
+			if err := rows.Scan(&d.Name, &d.Desc, &d.Scope, &d.Type, &values, &d.Pattern); err != nil {
 				return errors.Wrapf(err, "loadEnvDefs")
 			}
+			if len(values) > 0 {
+				d.Values = values
+			}
 			state.Defs.EnvVars = append(state.Defs.EnvVars, d)
 			return nil
 		})
@@ -80,10 +98,10 @@ func loadEnvDefs(context context.Context, log logging.LogSink, tx *sql.Tx, state
 
 func loadResourceDefs(context context.Context, log logging.LogSink, tx *sql.Tx, state *sous.State) error {
 	return loadTable(context, log, tx, "resource_fdefs",
-		`select "field_name", "var_type", "default_value" from resource_fdefs;`,
+		`select "field_name", "var_type", "default_value", "optional" from resource_fdefs;`,
 		func(rows *sql.Rows) error {
 			d := sous.FieldDefinition{}
-			if err := rows.Scan(&d.Name, &d.Type, &d.Default); err != nil {
+			if err := rows.Scan(&d.Name, &d.Type, &d.Default, &d.Optional); err != nil {
 				return errors.Wrapf(err, "loadResourceDefs")
 			}
 			state.Defs.Resources = append(state.Defs.Resources, d)
@@ -93,10 +111,10 @@ func loadResourceDefs(context context.Context, log logging.LogSink, tx *sql.Tx,
 
 func loadMetadataDefs(context context.Context, log logging.LogSink, tx *sql.Tx, state *sous.State) error {
 	return loadTable(context, log, tx, "metadata_fdefs",
-		`select "field_name", "var_type", "default_value" from metadata_fdefs;`,
+		`select "field_name", "var_type", "default_value", "optional" from metadata_fdefs;`,
 		func(rows *sql.Rows) error {
 			d := sous.FieldDefinition{}
-			if err := rows.Scan(&d.Name, &d.Type, &d.Default); err != nil {
+			if err := rows.Scan(&d.Name, &d.Type, &d.Default, &d.Optional); err != nil {
 				return errors.Wrapf(err, "loadMetadataDefs")
 			}
 			state.Defs.Metadata = append(state.Defs.Metadata, d)
@@ -111,40 +129,62 @@ func loadClusters(context context.Context, log logging.LogSink, tx *sql.Tx, stat
 		clusters.cluster_id, clusters.name, clusters.kind, "base_url",
 		"crdef_skip", "crdef_connect_delay", "crdef_timeout", "crdef_connect_interval",
 		"crdef_proto", "crdef_path", "crdef_port_index", "crdef_failure_statuses",
-		"crdef_uri_timeout", "crdef_interval", "crdef_retries",
+		"crdef_uri_timeout", "crdef_interval", "crdef_retries", "crdef_command",
+		"require_approval", "approvers", "writers", "env",
 		qualities.name
 		from
 			clusters
 			left join cluster_qualities using (cluster_id)
 			left join qualities
 		    on cluster_qualities.quality_id = qualities.quality_id
-				and qualities.kind = 'advisory';
+				and qualities.kind = 'advisory'
+		order by cluster_qualities.cluster_quality_id;
 		`,
 		func(rows *sql.Rows) error {
 			var cid int
 			c := &sous.Cluster{}
 			var qname sql.NullString
 			failStates := make(pq.Int64Array, 10)
+			var command, approvers, writers pq.StringArray
+			var env []byte
 			if err := rows.Scan(
 				&cid, &c.Name, &c.Kind, &c.BaseURL,
 				&c.Startup.SkipCheck, &c.Startup.ConnectDelay, &c.Startup.Timeout, &c.Startup.ConnectInterval,
 				&c.Startup.CheckReadyProtocol, &c.Startup.CheckReadyURIPath, &c.Startup.CheckReadyPortIndex, &failStates,
 				&c.Startup.CheckReadyURITimeout, &c.Startup.CheckReadyInterval, &c.Startup.CheckReadyRetries,
+				&command,
+				&c.RequireApproval, &approvers, &writers, &env,
 				&qname,
 			); err != nil {
 				return errors.Wrapf(err, "loadClusters")
 			}
+			// Each cluster has a row for every advisory it allows.
 			if newC, has := clusters[cid]; has {
 				c = newC
 			} else {
 				clusters[cid] = c
+				for _, s := range failStates {
+					c.Startup.CheckReadyFailureStatuses = append(c.Startup.CheckReadyFailureStatuses, int(s))
+				}
+				if len(command) > 0 {
+					c.Startup.CheckReadyCommand = command
+				}
+				if len(approvers) > 0 {
+					c.Approvers = approvers
+				}
+				if len(writers) > 0 {
+					c.Writers = writers
+				}
+				if err := json.Unmarshal(env, &c.Env); err != nil {
+					return errors.Wrapf(err, "loadClusters parsing env %q", env)
+				}
+				if len(c.Env) == 0 {
+					c.Env = nil
+				}
 			}
 			if qname.Valid {
 				c.AllowedAdvisories = append(c.AllowedAdvisories, qname.String)
 			}
-			for _, s := range failStates {
-				c.Startup.CheckReadyFailureStatuses = append(c.Startup.CheckReadyFailureStatuses, int(s))
-			}
 			return nil
 		}); err != nil {
 		return err
@@ -158,8 +198,46 @@ func loadClusters(context context.Context, log logging.LogSink, tx *sql.Tx, stat
 	return nil
 }
 
+// loadGlobalDefs loads the Defs which are not collections of named
+// definitions: the docker repo, freeze windows, secrets and lint rules, each
+// stored as a JSON document.
+func loadGlobalDefs(context context.Context, log logging.LogSink, tx *sql.Tx, state *sous.State) error {
+	return loadTable(context, log, tx, "global_defs",
+		`select "name", "definition" from global_defs;`,
+		func(rows *sql.Rows) error {
+			var name string
+			var definition []byte
+			if err := rows.Scan(&name, &definition); err != nil {
+				return errors.Wrapf(err, "loadGlobalDefs")
+			}
+			var into interface{}
+			switch name {
+			default:
+				return errors.Errorf("loadGlobalDefs: unknown definition %q", name)
+			case "docker_repo":
+				into = &state.Defs.DockerRepo
+			case "freeze_windows":
+				into = &state.Defs.FreezeWindows
+			case "secrets":
+				into = &state.Defs.Secrets
+			case "lint":
+				into = &state.Defs.Lint
+			}
+			if err := json.Unmarshal(definition, into); err != nil {
+				return errors.Wrapf(err, "loadGlobalDefs parsing %s", name)
+			}
+			return nil
+		})
+}
+
 func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, state *sous.State) error {
-	return loadTable(context, log, tx, "components",
+	return loadManifestsWhere(context, log, tx, state, "true")
+}
+
+// loadManifestsWhere loads only the deployments matching cond, which may
+// refer to the components and clusters tables, with args for its parameters.
+func loadManifestsWhere(context context.Context, log logging.LogSink, tx *sql.Tx, state *sous.State, cond string, args ...interface{}) error {
+	return loadTableWithArgs(context, log, tx, "components",
 		// This query is somewhat naive and returns many more rows than we need
 		// specifically, every possible combination of env/resource/volume/metadata
 		// results in its own row. Maybe that could be reduced?
@@ -167,9 +245,12 @@ func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, sta
 			"repo", "dir", "flavor", components.kind,
 			"email",
 			"versionstring", "num_instances", "schedule_string",
+			"schedule_time_zone", "max_execution_seconds", "retries_on_failure",
 			"cr_skip", "cr_connect_delay", "cr_timeout", "cr_connect_interval",
 			"cr_proto", "cr_path", "cr_port_index", "cr_failure_statuses",
-			"cr_uri_timeout", "cr_interval", "cr_retries",
+			"cr_uri_timeout", "cr_interval", "cr_retries", "cr_command",
+			"placement",
+			"rollout", "pause", "freeze_override", "auto_rollback",
 			clusters.name,
 			envs.key, envs.value,
 			"resource_name", "resource_value",
@@ -189,7 +270,8 @@ func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, sta
 			select max(deployment_id) from deployments group by cluster_id, component_id
 		)
 		and deployments.lifecycle != 'decommissioned'
-		`,
+		and (`+cond+`)
+		`, args,
 		func(rows *sql.Rows) error {
 			m := &sous.Manifest{
 				Owners:      []string{},
@@ -212,14 +294,21 @@ func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, sta
 				volHost, volContainer, volMode sql.NullString
 
 			failStates := make(pq.Int64Array, 0)
+			var command pq.StringArray
+			var placement, rollout, pause, override []byte
+			var autoRollback bool
 
 			if err := rows.Scan(
 				&m.Source.Repo, &m.Source.Dir, &m.Flavor, &m.Kind,
 				&ownerEmail,
 				&versionString, &ds.NumInstances, &ds.Schedule,
+				&ds.ScheduleTimeZone, &ds.MaxExecutionSeconds, &ds.RetriesOnFailure,
 				&ds.Startup.SkipCheck, &ds.Startup.ConnectDelay, &ds.Startup.Timeout, &ds.Startup.ConnectInterval,
 				&ds.Startup.CheckReadyProtocol, &ds.Startup.CheckReadyURIPath, &ds.Startup.CheckReadyPortIndex, &failStates,
 				&ds.Startup.CheckReadyURITimeout, &ds.Startup.CheckReadyInterval, &ds.Startup.CheckReadyRetries,
+				&command,
+				&placement,
+				&rollout, &pause, &override, &autoRollback,
 				&clusterName,
 				&envKey, &envValue,
 				&resName, &resValue,
@@ -233,6 +322,7 @@ func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, sta
 			} else {
 				state.Manifests.Add(m)
 			}
+			m.AutoRollback = autoRollback
 			set := sous.NewOwnerSet(m.Owners...)
 			set.Add(ownerEmail)
 			m.Owners = set.Slice()
@@ -246,6 +336,27 @@ func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, sta
 				for _, s := range failStates {
 					ds.Startup.CheckReadyFailureStatuses = append(ds.Startup.CheckReadyFailureStatuses, int(s))
 				}
+				if len(command) > 0 {
+					ds.Startup.CheckReadyCommand = command
+				}
+				if err := json.Unmarshal(placement, &ds.Placement); err != nil {
+					return errors.Wrapf(err, "loadManifests parsing placement %q", placement)
+				}
+				if err := json.Unmarshal(rollout, &ds.Rollout); err != nil {
+					return errors.Wrapf(err, "loadManifests parsing rollout %q", rollout)
+				}
+				if len(pause) > 0 {
+					ds.Pause = &sous.Pause{}
+					if err := json.Unmarshal(pause, ds.Pause); err != nil {
+						return errors.Wrapf(err, "loadManifests parsing pause %q", pause)
+					}
+				}
+				if len(override) > 0 {
+					ds.FreezeOverride = &sous.FreezeOverride{}
+					if err := json.Unmarshal(override, ds.FreezeOverride); err != nil {
+						return errors.Wrapf(err, "loadManifests parsing freeze override %q", override)
+					}
+				}
 			}
 			if envKey.Valid && envValue.Valid {
 				ds.Env[envKey.String] = envValue.String
@@ -279,9 +390,13 @@ func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, sta
 }
 
 func loadTable(ctx context.Context, log logging.LogSink, tx *sql.Tx, mainTable string, sql string, pack func(*sql.Rows) error) error {
+	return loadTableWithArgs(ctx, log, tx, mainTable, sql, nil, pack)
+}
+
+func loadTableWithArgs(ctx context.Context, log logging.LogSink, tx *sql.Tx, mainTable string, sql string, args []interface{}, pack func(*sql.Rows) error) error {
 	rowcount := 0
 	start := time.Now()
-	rows, err := tx.QueryContext(ctx, sql)
+	rows, err := tx.QueryContext(ctx, sql, args...)
 	if err != nil {
 		reportSQLMessage(log, start, mainTable, read, sql, rowcount, err)
 		return errors.Wrapf(err, "loadTable %q", sql)
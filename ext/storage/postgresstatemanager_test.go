@@ -1,13 +1,18 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
+	"reflect"
+	"sort"
 	"testing"
+	"time"
 
 	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	// it's a SQL db driver. This is how you do that.
@@ -64,7 +69,7 @@ func TestPostgresStateManagerWriteState_success(t *testing.T) {
 	}
 	suite.Equal(int64(4), suite.pluckSQL("select count(*) from deployments"))
 
-	assert.Len(t, suite.logs.CallsTo("LogMessage"), 13)
+	assert.Len(t, suite.logs.CallsTo("LogMessage"), 20)
 	message := suite.logs.CallsTo("LogMessage")[0].PassedArgs().Get(1).(logging.LogMessage)
 	// XXX This message deserves its own test
 	logging.AssertMessageFields(t, message, append(
@@ -113,6 +118,270 @@ func TestPostgresStateManagerWriteState_success(t *testing.T) {
 	}
 }
 
+// fullyPopulatedState returns exampleState with every field of its Defs set,
+// and with a deployment, fullyPopulatedDeploymentID, that sets every field of
+// sous.Deployment.
+var fullyPopulatedDeploymentID = sous.DeploymentID{
+	ManifestID: sous.MustParseManifestID("github.com/opentable/sous,cmd/nightly~nightly"),
+	Cluster:    "cluster-1",
+}
+
+func fullyPopulatedState() *sous.State {
+	s := exampleState()
+	at := time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC)
+	user := sous.User{Name: "Judson", Email: "judson@example.com"}
+
+	for name, c := range s.Defs.Clusters {
+		c.Name = name
+	}
+	c := s.Defs.Clusters["cluster-1"]
+	c.Env = sous.EnvDefaults{"REGION": "east"}
+	c.AllowedAdvisories = []string{"ephemeral_tag", "is_snapshot"}
+	c.RequireApproval = true
+	c.Approvers = []string{"approver@example.com"}
+	c.Writers = []string{"writer@example.com"}
+
+	s.Defs.EnvVars = sous.EnvDefs{
+		{Name: "LOG_LEVEL", Desc: "how much to log", Scope: sous.EnvScopeAny, Type: sous.VarTypeEnum, Values: []string{"debug", "info"}},
+		{Name: "REGION", Desc: "where it runs", Scope: sous.EnvScopeCluster, Type: sous.VarTypeRegex, Pattern: "[a-z]+"},
+	}
+	s.Defs.Resources = sous.FieldDefinitions{{Name: "cpus", Type: "Float", Default: "0.1"}}
+	s.Defs.Metadata = sous.FieldDefinitions{{Name: "team", Type: "String", Optional: true}}
+	s.Defs.FreezeWindows = sous.FreezeWindows{{
+		Start:    at,
+		End:      at.Add(48 * time.Hour),
+		Clusters: []string{"cluster-1"},
+		Exempt:   []sous.ManifestID{sous.MustParseManifestID("github.com/user/project")},
+		Reason:   "holidays",
+	}}
+	s.Defs.Secrets = sous.SecretDefs{{Path: "db/main", Desc: "the database", Keys: []string{"password"}, Clusters: []string{"cluster-1"}}}
+	s.Defs.Lint = sous.LintRules{{
+		Name:     "enough-instances",
+		Desc:     "production runs at least two instances",
+		Severity: sous.LintWarning,
+		Clusters: []string{"cluster-1"},
+		Kinds:    []sous.ManifestKind{sous.ManifestKindService},
+		Field:    "NumInstances",
+		Op:       ">=",
+		Value:    "2",
+	}}
+
+	s.Manifests.MustAdd(&sous.Manifest{
+		Source:       sous.SourceLocation{Repo: "github.com/opentable/sous", Dir: "cmd/nightly"},
+		Flavor:       "nightly",
+		Owners:       []string{"Judson"},
+		Kind:         sous.ManifestKindScheduled,
+		AutoRollback: true,
+		Deployments: sous.DeploySpecs{
+			"cluster-1": {
+				DeployConfig: sous.DeployConfig{
+					Resources:    sous.Resources{"cpus": "0.1", "memory": "256", "ports": "1"},
+					Metadata:     sous.Metadata{"team": "sous"},
+					Env:          sous.Env{"LOG_LEVEL": "debug"},
+					NumInstances: 1,
+					Volumes:      sous.Volumes{{Host: "/var/log", Container: "/logs", Mode: sous.ReadWrite}},
+					Startup: sous.Startup{
+						SkipCheck:                 true,
+						ConnectDelay:              5,
+						Timeout:                   60,
+						ConnectInterval:           2,
+						CheckReadyProtocol:        "HTTP",
+						CheckReadyURIPath:         "/health",
+						CheckReadyPortIndex:       1,
+						CheckReadyFailureStatuses: []int{500, 503},
+						CheckReadyURITimeout:      3,
+						CheckReadyInterval:        10,
+						CheckReadyRetries:         4,
+						CheckReadyCommand:         []string{"true"},
+					},
+					Placement: sous.Placement{
+						RequiredAttributes:  map[string]string{"rack": "a"},
+						PreferredAttributes: map[string]string{"disk": "ssd"},
+						RackSensitive:       true,
+						MaxInstancesPerHost: 1,
+					},
+					Schedule:            "0 3 * * *",
+					ScheduleTimeZone:    "America/Los_Angeles",
+					MaxExecutionSeconds: 3600,
+					RetriesOnFailure:    2,
+					Rollout: sous.Rollout{
+						Strategy:           sous.RolloutCanary,
+						CanaryInstances:    1,
+						Steps:              []int{25, 50},
+						PauseSeconds:       30,
+						HealthGate:         true,
+						GateTimeoutSeconds: 300,
+					},
+					FreezeOverride: &sous.FreezeOverride{User: user, Reason: "hotfix", At: at},
+					Pause:          &sous.Pause{User: user, Reason: "incident", At: at, Until: at.Add(time.Hour)},
+				},
+				Version: semv.MustParse("2.0.0-rc.1+deadbeef"),
+			},
+		},
+	})
+	return s
+}
+
+// deploymentFields flattens v into its fields by path, so that a field added to
+// sous.Deployment, or to any struct within it, is visited without changes
+// here. Types from outside package sous, like time.Time, are not flattened.
+// The Cluster is skipped: it comes from the Defs, which CompareStates covers.
+// So are fields tagged json:"-", like User.Token, which are never stored.
+func deploymentFields(v reflect.Value, path string, fields map[string]reflect.Value) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			fields[path] = v
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || v.Type().PkgPath() != reflect.TypeOf(sous.Deployment{}).PkgPath() {
+		fields[path] = v
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" || f.Tag.Get("json") == "-" || f.Type == reflect.TypeOf(&sous.Cluster{}) {
+			continue
+		}
+		deploymentFields(v.Field(i), path+"."+f.Name, fields)
+	}
+}
+
+// deploymentFieldDiffs returns the path of every field that differs between a
+// and b. Unlike Deployment.Diff it compares every field, including those only
+// significant for some kinds of deployment.
+func deploymentFieldDiffs(a, b *sous.Deployment) []string {
+	af, bf := map[string]reflect.Value{}, map[string]reflect.Value{}
+	deploymentFields(reflect.ValueOf(a), "Deployment", af)
+	deploymentFields(reflect.ValueOf(b), "Deployment", bf)
+	diffs := []string{}
+	for path, av := range af {
+		bv, has := bf[path]
+		if !has || !sameField(av, bv) {
+			diffs = append(diffs, fmt.Sprintf("%s: %v != %v", path, av, bv))
+		}
+	}
+	for path, bv := range bf {
+		if _, has := af[path]; !has {
+			diffs = append(diffs, fmt.Sprintf("%s: <missing> != %v", path, bv))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs
+}
+
+func sameField(a, b reflect.Value) bool {
+	switch av := a.Interface().(type) {
+	case time.Time:
+		return av.Equal(b.Interface().(time.Time))
+	case semv.Version:
+		return av.String() == b.Interface().(semv.Version).String()
+	}
+	switch a.Kind() {
+	case reflect.Map, reflect.Slice:
+		if a.Len() == 0 && b.Len() == 0 {
+			return true
+		}
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// TestFullyPopulatedState guards the round trip tests that use
+// fullyPopulatedState: a field added to sous.Deployment fails here until the
+// fixture sets it, and so until those tests cover it.
+func TestFullyPopulatedState(t *testing.T) {
+	ds, err := fullyPopulatedState().Deployments()
+	require.NoError(t, err)
+	dep, has := ds.Get(fullyPopulatedDeploymentID)
+	require.True(t, has)
+
+	fields := map[string]reflect.Value{}
+	deploymentFields(reflect.ValueOf(dep), "Deployment", fields)
+	for path, v := range fields {
+		assert.False(t, v.IsZero(), "fullyPopulatedState does not set %s", path)
+	}
+	assert.Empty(t, deploymentFieldDiffs(dep, dep.Clone()))
+}
+
+func TestPostgresStateManagerWriteState_fullyPopulated(t *testing.T) {
+	suite := SetupTest(t)
+
+	s := fullyPopulatedState()
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+
+	read, err := suite.manager.ReadState()
+	suite.require.NoError(err)
+	comparison, err := sous.CompareStates(s, read)
+	suite.require.NoError(err)
+	suite.True(comparison.Same(), "the state read back differs from that written: %v %v", comparison.Defs, comparison.Changes)
+
+	did := fullyPopulatedDeploymentID
+	written, err := s.Deployments()
+	suite.require.NoError(err)
+	want, _ := written.Get(did)
+	dep, err := suite.manager.ReadDeployment(did)
+	suite.require.NoError(err)
+	_, diffs := want.Diff(dep)
+	suite.Empty(diffs)
+	suite.Empty(want.PolicyDiff(dep))
+	suite.Empty(deploymentFieldDiffs(want, dep))
+	suite.Equal(s.Defs.Clusters["cluster-1"], dep.Cluster)
+
+	readDeps, err := read.Deployments()
+	suite.require.NoError(err)
+	fromState, has := readDeps.Get(did)
+	suite.require.True(has)
+	suite.Empty(deploymentFieldDiffs(want, fromState))
+
+	// Writing the same state again changes nothing.
+	suite.require.NoError(suite.manager.WriteState(read, testUser))
+	suite.Equal(int64(1), suite.pluckSQL("select count(*) from gdm_changes"))
+	suite.Equal(int64(2), suite.pluckSQL("select count(*) from cluster_qualities"))
+}
+
+func TestPostgresStateManagerReadHistory(t *testing.T) {
+	suite := SetupTest(t)
+
+	s := exampleState()
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+	// An unchanged state records no history.
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+	suite.Equal(int64(1), suite.pluckSQL("select count(*) from gdm_changes"))
+
+	changes, err := suite.manager.ReadHistory(sous.HistoryFilter{})
+	suite.require.NoError(err)
+	suite.require.Len(changes, 1)
+	suite.Equal(testUser.Email, changes[0].User.Email)
+	suite.Len(changes[0].Deployments, 4)
+	for _, dc := range changes[0].Deployments {
+		suite.Equal("added", dc.Kind)
+	}
+
+	changes, err = suite.manager.ReadHistory(sous.HistoryFilter{User: "nobody@example.com"})
+	suite.require.NoError(err)
+	suite.Len(changes, 0)
+
+	mid := sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/project"}}
+	m, ok := s.Manifests.Get(mid)
+	suite.require.True(ok)
+	for cluster, spec := range m.Deployments {
+		spec.NumInstances++
+		m.Deployments[cluster] = spec
+	}
+	s.Manifests.Set(mid, m)
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+
+	all, err := suite.manager.ReadHistory(sous.HistoryFilter{})
+	suite.require.NoError(err)
+	suite.require.Len(all, 2)
+	changes, err = suite.manager.ReadHistory(sous.HistoryFilter{Limit: 1})
+	suite.require.NoError(err)
+	suite.require.Len(changes, 1, "the limit should count changes, not their deployments")
+	suite.Equal(all[0].ID, changes[0].ID)
+	suite.Equal(all[0].Deployments, changes[0].Deployments)
+}
+
 func (suite *PostgresStateManagerSuite) pluckSQL(sql string) interface{} {
 	var v interface{}
 
@@ -124,3 +393,236 @@ func (suite *PostgresStateManagerSuite) pluckSQL(sql string) interface{} {
 
 	return v
 }
+
+func TestPostgresStateManagerPendingChanges(t *testing.T) {
+	suite := SetupTest(t)
+
+	s := exampleState()
+	deps, err := s.Deployments()
+	suite.require.NoError(err)
+	prior := deps.Snapshot()[deps.Keys()[0]]
+	dep := prior.Clone()
+	dep.NumInstances++
+
+	pc := sous.NewPendingChange(testUser, []sous.ProposedDeployment{
+		{DeploymentID: dep.ID(), Deployment: dep, Prior: prior},
+		{DeploymentID: deps.Keys()[1]},
+	}, time.Now())
+	suite.require.NoError(suite.manager.ProposeChange(pc))
+
+	pending, err := suite.manager.ListPendingChanges()
+	suite.require.NoError(err)
+	suite.require.Len(pending, 1)
+	suite.Equal(pc.ID, pending[0].ID)
+	suite.require.Len(pending[0].Deployments, 2)
+	for _, pd := range pending[0].Deployments {
+		if pd.DeploymentID != dep.ID() {
+			continue
+		}
+		suite.require.NotNil(pd.Prior)
+		suite.Equal(prior.NumInstances, pd.Prior.NumInstances)
+	}
+
+	reviewer := sous.User{Name: "Reviewer", Email: "reviewer@example.com"}
+	suite.require.NoError(suite.manager.ReviewPendingChange(pc.ID, sous.PendingStatusApproved, reviewer, time.Now()))
+	suite.Error(suite.manager.ReviewPendingChange(pc.ID, sous.PendingStatusRejected, reviewer, time.Now()))
+
+	read, err := suite.manager.ReadPendingChange(pc.ID)
+	suite.require.NoError(err)
+	suite.require.NotNil(read)
+	suite.Equal(sous.PendingStatusApproved, read.Status)
+	suite.Equal(reviewer.Email, read.Reviewer.Email)
+
+	pending, err = suite.manager.ListPendingChanges()
+	suite.require.NoError(err)
+	suite.Len(pending, 0)
+}
+
+func TestPostgresStateManagerR11ns(t *testing.T) {
+	suite := SetupTest(t)
+
+	s := exampleState()
+	deps, err := s.Deployments()
+	suite.require.NoError(err)
+	dep := deps.Snapshot()[deps.Keys()[0]]
+
+	queuedAt := time.Now().Truncate(time.Second)
+	first := sous.StoredR11n{
+		ID:           sous.NewR11nID(),
+		DeploymentID: dep.ID(),
+		Priority:     sous.R11nPriorityManual,
+		QueuedAt:     queuedAt,
+		Post:         &sous.Deployable{Status: sous.DeployStatusActive, Deployment: dep},
+		ExecutorData: "some-request",
+	}
+	second := sous.StoredR11n{
+		ID:           sous.NewR11nID(),
+		DeploymentID: deps.Keys()[1],
+		QueuedAt:     queuedAt,
+		Prior:        &sous.Deployable{Deployment: dep},
+	}
+	suite.require.NoError(suite.manager.StoreR11n(first))
+	suite.require.NoError(suite.manager.StoreR11n(second))
+
+	stored, err := suite.manager.LoadR11ns()
+	suite.require.NoError(err)
+	suite.require.Len(stored, 2)
+	suite.Equal(first.ID, stored[0].ID)
+	suite.Equal(first.DeploymentID, stored[0].DeploymentID)
+	suite.Equal(sous.R11nPriorityManual, stored[0].Priority)
+	suite.True(queuedAt.Equal(stored[0].QueuedAt))
+	suite.Nil(stored[0].Prior)
+	suite.require.NotNil(stored[0].Post)
+	suite.Equal(dep.NumInstances, stored[0].Post.NumInstances)
+	suite.Equal("some-request", stored[0].ExecutorData)
+	suite.Nil(stored[0].Stage)
+	suite.Nil(stored[1].Post)
+
+	// Storing it again records the stage its rollout has reached.
+	stage := sous.RolloutStage{Index: 1, Count: 3, Instances: 5}
+	first.Stage = &stage
+	suite.require.NoError(suite.manager.StoreR11n(first))
+	stored, err = suite.manager.LoadR11ns()
+	suite.require.NoError(err)
+	suite.require.Len(stored, 2)
+	suite.Equal(&stage, stored[0].Stage)
+
+	suite.require.NoError(suite.manager.RemoveR11n(first.ID))
+	stored, err = suite.manager.LoadR11ns()
+	suite.require.NoError(err)
+	suite.require.Len(stored, 1)
+	suite.Equal(second.ID, stored[0].ID)
+}
+
+func TestPostgresStateManagerLastGood(t *testing.T) {
+	suite := SetupTest(t)
+
+	did := sous.DeploymentID{
+		ManifestID: sous.MustParseManifestID("github.com/opentable/example"),
+		Cluster:    "cluster-1",
+	}
+	_, known, err := suite.manager.LastGood(did)
+	suite.require.NoError(err)
+	suite.False(known)
+
+	for _, v := range []string{"1.0.0", "1.1.0"} {
+		version := sous.SourceID{Location: did.ManifestID.Source, Version: semv.MustParse(v)}
+		suite.require.NoError(suite.manager.RecordLastGood(did, version))
+		good, known, err := suite.manager.LastGood(did)
+		suite.require.NoError(err)
+		suite.True(known)
+		suite.Equal(version, good)
+	}
+}
+
+func TestPostgresStateManagerLeases(t *testing.T) {
+	suite := SetupTest(t)
+
+	lease, err := suite.manager.AcquireLease("cluster-1", "http://one", time.Minute)
+	suite.require.NoError(err)
+	suite.Equal("http://one", lease.Holder)
+	suite.True(lease.Expires.After(time.Now()))
+
+	lease, err = suite.manager.AcquireLease("cluster-1", "http://two", time.Minute)
+	suite.require.NoError(err)
+	suite.Equal("http://one", lease.Holder, "a held lease should not be taken over")
+
+	lease, err = suite.manager.AcquireLease("cluster-2", "http://two", time.Minute)
+	suite.require.NoError(err)
+	suite.Equal("http://two", lease.Holder)
+
+	suite.require.NoError(suite.manager.ReleaseLease("cluster-1", "http://two"))
+	lease, err = suite.manager.AcquireLease("cluster-1", "http://two", time.Minute)
+	suite.require.NoError(err)
+	suite.Equal("http://one", lease.Holder, "only the holder should release a lease")
+
+	suite.require.NoError(suite.manager.ReleaseLease("cluster-1", "http://one"))
+	lease, err = suite.manager.AcquireLease("cluster-1", "http://two", time.Millisecond)
+	suite.require.NoError(err)
+	suite.Equal("http://two", lease.Holder)
+
+	time.Sleep(10 * time.Millisecond)
+	lease, err = suite.manager.AcquireLease("cluster-1", "http://one", time.Minute)
+	suite.require.NoError(err)
+	suite.Equal("http://one", lease.Holder, "an expired lease should be taken over")
+}
+
+func TestPostgresStateManagerDeployments(t *testing.T) {
+	suite := SetupTest(t)
+	suite.require.NoError(suite.manager.WriteState(exampleState(), testUser))
+
+	did := sous.DeploymentID{
+		ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/sous"}},
+		Cluster:    "cluster-1",
+	}
+	dep, err := suite.manager.ReadDeployment(did)
+	suite.require.NoError(err)
+	suite.Equal(6, dep.NumInstances)
+
+	_, err = suite.manager.ReadDeployment(sous.DeploymentID{ManifestID: did.ManifestID, Cluster: "no-such-cluster"})
+	suite.Error(err)
+
+	dep.NumInstances = 3
+	suite.require.NoError(suite.manager.WriteDeployment(dep, testUser))
+	dep, err = suite.manager.ReadDeployment(did)
+	suite.require.NoError(err)
+	suite.Equal(3, dep.NumInstances)
+	suite.Equal(int64(5), suite.pluckSQL("select count(*) from deployments"))
+
+	deps, err := suite.manager.ReadCluster("cluster-1")
+	suite.require.NoError(err)
+	for _, d := range deps.Snapshot() {
+		suite.Equal("cluster-1", d.ClusterName)
+	}
+	clustered, has := deps.Get(did)
+	suite.require.True(has)
+	suite.Equal(3, clustered.NumInstances)
+
+	clustered.NumInstances = 4
+	suite.require.NoError(suite.manager.WriteCluster("cluster-1", deps, testUser))
+	state, err := suite.manager.ReadState()
+	suite.require.NoError(err)
+	all, err := state.Deployments()
+	suite.require.NoError(err)
+	written, has := all.Get(did)
+	suite.require.True(has)
+	suite.Equal(4, written.NumInstances)
+	other, has := all.Get(sous.DeploymentID{ManifestID: did.ManifestID, Cluster: "other-cluster"})
+	suite.require.True(has)
+	suite.Equal(6, other.NumInstances, "deployments to other clusters should be untouched")
+}
+
+func TestPostgresStateManagerWriteDeployment_concurrent(t *testing.T) {
+	suite := SetupTest(t)
+	suite.require.NoError(suite.manager.WriteState(exampleState(), testUser))
+
+	did := sous.DeploymentID{
+		ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/sous"}},
+		Cluster:    "cluster-1",
+	}
+	dep, err := suite.manager.ReadDeployment(did)
+	suite.require.NoError(err)
+
+	// The first write is held open while the second is made.
+	ctx := context.Background()
+	tx, err := suite.manager.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	suite.require.NoError(err)
+	first := dep.Clone()
+	first.NumInstances = 1
+	suite.require.NoError(suite.manager.storeScoped(ctx, tx, testUser, sous.NewDeployments(first), deploymentCond, deploymentArgs(did)...))
+
+	second := dep.Clone()
+	second.NumInstances = 2
+	errs := make(chan error)
+	go func() { errs <- suite.manager.WriteDeployment(second, testUser) }()
+	time.Sleep(100 * time.Millisecond)
+	suite.require.NoError(tx.Commit())
+
+	err = <-errs
+	suite.require.Error(err)
+	suite.Contains(err.Error(), "changed concurrently")
+
+	dep, err = suite.manager.ReadDeployment(did)
+	suite.require.NoError(err)
+	suite.Equal(1, dep.NumInstances)
+}
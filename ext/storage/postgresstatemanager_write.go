@@ -3,6 +3,8 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"reflect"
 	"time"
 
 	"github.com/lib/pq"
@@ -26,7 +28,7 @@ func (m PostgresStateManager) WriteState(state *sous.State, user sous.User) erro
 		tx.Rollback()
 	}(tx)
 
-	if err := storeManifests(context, m.log, state, tx); err != nil {
+	if err := storeState(context, m.log, state, user, tx); err != nil {
 		reportWriting(m.log, start, state, errors.Wrapf(err, "storing state"))
 		return err
 	}
@@ -39,7 +41,8 @@ func (m PostgresStateManager) WriteState(state *sous.State, user sous.User) erro
 	return nil
 }
 
-func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State, tx *sql.Tx) error {
+// storeState writes the Defs and deployments of state over those stored.
+func storeState(ctx context.Context, log logging.LogSink, state *sous.State, user sous.User, tx *sql.Tx) error {
 	newDeps, err := state.Deployments()
 	if err != nil {
 		return err
@@ -54,14 +57,161 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 		return err
 	}
 
-	diffs := currentDeps.Diff(newDeps).Collect()
+	if err := storeDefs(ctx, log, tx, currentState.Defs, state.Defs); err != nil {
+		return err
+	}
+	return storeDiffs(ctx, log, tx, user, currentDeps.Diff(newDeps).Collect())
+}
+
+// storeDefs writes the parts of defs which differ from current, which was
+// read in the same transaction. Definitions missing from defs are removed,
+// except for clusters, which deployment rows refer to.
+func storeDefs(ctx context.Context, log logging.LogSink, tx *sql.Tx, current, defs sous.Defs) error {
+	clusters := sqlgen.NewFieldset()
+	changed := sous.Clusters{}
+	for name, c := range defs.Clusters {
+		stored := c.Clone()
+		stored.Name = name
+		if cur, has := current.Clusters[name]; has && reflect.DeepEqual(cur, stored) {
+			continue
+		}
+		changed[name] = stored
+		clusters.Row(func(r sqlgen.RowDef) {
+			clusterFields(r, name, stored)
+		})
+	}
+	if err := execInsert(ctx, log, tx, clusters, "clusters", upsert); err != nil {
+		return err
+	}
+	for name, c := range changed {
+		if err := storeAdvisories(ctx, log, tx, name, c.AllowedAdvisories); err != nil {
+			return err
+		}
+	}
+
+	if !sameDefs(current.EnvVars, defs.EnvVars) {
+		fields := sqlgen.NewFieldset()
+		for _, d := range defs.EnvVars {
+			fields.Row(func(r sqlgen.RowDef) {
+				r.FD("?", "name", d.Name)
+				r.FD("?", "desc", d.Desc)
+				r.FD("?", "scope", d.Scope)
+				r.FD("?", "type", d.Type)
+				r.FD("?", "allowed_values", pq.Array(d.Values))
+				r.FD("?", "pattern", d.Pattern)
+			})
+		}
+		if err := execReplace(ctx, log, tx, fields, "env_var_defs"); err != nil {
+			return err
+		}
+	}
+
+	for table, fdefs := range map[string][2]sous.FieldDefinitions{
+		"resource_fdefs": {current.Resources, defs.Resources},
+		"metadata_fdefs": {current.Metadata, defs.Metadata},
+	} {
+		if sameDefs(fdefs[0], fdefs[1]) {
+			continue
+		}
+		fields := sqlgen.NewFieldset()
+		for _, d := range fdefs[1] {
+			fields.Row(func(r sqlgen.RowDef) {
+				r.FD("?", "field_name", d.Name)
+				r.FD("?", "var_type", d.Type)
+				r.FD("?", "default_value", d.Default)
+				r.FD("?", "optional", d.Optional)
+			})
+		}
+		if err := execReplace(ctx, log, tx, fields, table); err != nil {
+			return err
+		}
+	}
+
+	globals := sqlgen.NewFieldset()
+	for name, gs := range map[string][2]interface{}{
+		"docker_repo":    {current.DockerRepo, defs.DockerRepo},
+		"freeze_windows": {current.FreezeWindows, defs.FreezeWindows},
+		"secrets":        {current.Secrets, defs.Secrets},
+		"lint":           {current.Lint, defs.Lint},
+	} {
+		if sameDefs(gs[0], gs[1]) {
+			continue
+		}
+		js, err := json.Marshal(gs[1])
+		if err != nil {
+			return errors.Wrapf(err, "encoding %s", name)
+		}
+		globals.Row(func(r sqlgen.RowDef) {
+			r.CF("?", "name", name)
+			r.FD("?", "definition", string(js))
+		})
+	}
+	return execInsert(ctx, log, tx, globals, "global_defs", upsert)
+}
+
+// storeAdvisories replaces the advisories allowed in the named cluster.
+func storeAdvisories(ctx context.Context, log logging.LogSink, tx *sql.Tx, cluster string, advisories []string) error {
+	start := time.Now()
+	sql := `delete from cluster_qualities
+		where cluster_id = (select cluster_id from clusters where name = $1)
+		and quality_id in (select quality_id from qualities where kind = 'advisory');`
+	_, err := tx.ExecContext(ctx, sql, cluster)
+	reportSQLMessage(log, start, "cluster_qualities", write, sql, 0, err)
+	if err != nil {
+		return err
+	}
+	for _, advisory := range advisories {
+		start := time.Now()
+		sql := `insert into qualities (name, kind)
+			select $1, 'advisory'
+			where not exists (select 1 from qualities where name = $1 and kind = 'advisory');`
+		_, err := tx.ExecContext(ctx, sql, advisory)
+		reportSQLMessage(log, start, "qualities", write, sql, 1, err)
+		if err != nil {
+			return err
+		}
+
+		start = time.Now()
+		sql = `insert into cluster_qualities (cluster_id, quality_id)
+			select cluster_id, quality_id from clusters, qualities
+			where clusters.name = $1 and qualities.name = $2 and qualities.kind = 'advisory'
+			on conflict do nothing;`
+		_, err = tx.ExecContext(ctx, sql, cluster, advisory)
+		reportSQLMessage(log, start, "cluster_qualities", write, sql, 1, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameDefs returns true if this and other, which are strings or slices of
+// the same type of definition, are both empty or are equal.
+func sameDefs(this, other interface{}) bool {
+	if reflect.ValueOf(this).Len() == 0 && reflect.ValueOf(other).Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(this, other)
+}
+
+// storeDiffs records the deployments which diffs add, modify or remove. Each
+// deployment row names the row it supersedes, so that if two transactions
+// change the same deployment at once, one fails on the unique constraint
+// over prior_deployment_id rather than silently overwriting the other.
+func storeDiffs(ctx context.Context, log logging.LogSink, tx *sql.Tx, user sous.User, diffs sous.DeployablePairs) error {
 	updates := sous.NewDeployments()
 	deletes := sous.NewDeployments()
 	alldeps := sous.NewDeployments()
 
 	for _, diff := range diffs {
 		switch diff.Kind() {
-		default: //do nothing for Same
+		case sous.SameKind:
+			// Changes only to how Sous manages the deployment still need
+			// storing.
+			if len(diff.IntendedDiffs()) != 0 {
+				updates.Add(diff.Post.Deployment)
+				alldeps.Add(diff.Post.Deployment)
+			}
 		case sous.AddedKind, sous.ModifiedKind:
 			updates.Add(diff.Post.Deployment)
 			alldeps.Add(diff.Post.Deployment)
@@ -71,6 +221,10 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 		}
 	}
 
+	if err := storeHistory(ctx, log, tx, user, sous.GDMChanges(diffs)); err != nil {
+		return err
+	}
+
 	if err := execInsertDeployments(ctx, log, tx, alldeps, "components", "on conflict do nothing", func(fields sqlgen.FieldSet, dep *sous.Deployment) {
 		fields.Row(func(r sqlgen.RowDef) {
 			r.FD("?", "repo", dep.SourceID.Location.Repo)
@@ -82,14 +236,9 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 		return nil
 	}
 
-	if err := execInsertDeployments(ctx, log, tx, alldeps, "clusters", `on conflict {{.Candidates}} do update set {{.NonCandidates}} = {{.NSNonCandidates "excluded"}}`, func(fields sqlgen.FieldSet, dep *sous.Deployment) {
-		c := dep.Cluster
-		s := c.Startup
+	if err := execInsertDeployments(ctx, log, tx, alldeps, "clusters", upsert, func(fields sqlgen.FieldSet, dep *sous.Deployment) {
 		fields.Row(func(r sqlgen.RowDef) {
-			r.CF("?", "name", dep.ClusterName)
-			r.FD("?", "kind", c.Kind)
-			r.FD("?", "base_url", c.BaseURL)
-			startupFields(r, "crdef", s)
+			clusterFields(r, dep.ClusterName, dep.Cluster)
 		})
 	}); err != nil {
 		return nil
@@ -104,11 +253,17 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 		fields.Row(func(r sqlgen.RowDef) {
 			compID(r, dep)
 			clusterID(r, dep)
+			priorID(r, dep)
 			r.FD("?", "versionstring", dep.SourceID.Version.String())
 			r.FD("?", "num_instances", dep.NumInstances)
 			r.FD("?", "schedule_string", dep.Schedule)
+			r.FD("?", "schedule_time_zone", dep.ScheduleTimeZone)
+			r.FD("?", "max_execution_seconds", dep.MaxExecutionSeconds)
+			r.FD("?", "retries_on_failure", dep.RetriesOnFailure)
 			r.FD("?", "lifecycle", "active")
 			startupFields(r, "cr", s)
+			r.FD("?", "placement", placementJSON(dep.Placement))
+			policyFields(r, dep)
 		})
 	}); err != nil {
 		return err
@@ -121,11 +276,17 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 		fields.Row(func(r sqlgen.RowDef) {
 			compID(r, dep)
 			clusterID(r, dep)
+			priorID(r, dep)
 			r.FD("?", "versionstring", dep.SourceID.Version.String())
 			r.FD("?", "num_instances", dep.NumInstances)
 			r.FD("?", "schedule_string", dep.Schedule)
+			r.FD("?", "schedule_time_zone", dep.ScheduleTimeZone)
+			r.FD("?", "max_execution_seconds", dep.MaxExecutionSeconds)
+			r.FD("?", "retries_on_failure", dep.RetriesOnFailure)
 			r.FD("?", "lifecycle", "decommisioned")
 			startupFields(r, "cr", s)
+			r.FD("?", "placement", placementJSON(dep.Placement))
+			policyFields(r, dep)
 		})
 	}); err != nil {
 		return err
@@ -204,6 +365,43 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 	return nil
 }
 
+// storeHistory records a change to the GDM in the gdm_changes and
+// gdm_change_deployments tables, in the same transaction as the change itself.
+func storeHistory(ctx context.Context, log logging.LogSink, tx *sql.Tx, user sous.User, changes []sous.DeploymentChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	var changeID int64
+	insert := `insert into gdm_changes (user_name, user_email) values ($1, $2) returning change_id`
+	err := tx.QueryRowContext(ctx, insert, user.Name, user.Email).Scan(&changeID)
+	reportSQLMessage(log, start, "gdm_changes", write, insert, 1, err)
+	if err != nil {
+		return errors.Wrapf(err, "recording GDM change")
+	}
+
+	fields := sqlgen.NewFieldset()
+	for _, change := range changes {
+		did := change.DeploymentID
+		fields.Row(func(r sqlgen.RowDef) {
+			r.FD("?", "change_id", changeID)
+			r.FD("?", "repo", did.ManifestID.Source.Repo)
+			r.FD("?", "dir", did.ManifestID.Source.Dir)
+			r.FD("?", "flavor", did.ManifestID.Flavor)
+			r.FD("?", "cluster", did.Cluster)
+			r.FD("?", "change_kind", change.Kind)
+			r.FD("?", "diffs", pq.Array([]string(change.Diffs)))
+		})
+	}
+
+	start = time.Now()
+	sql := fields.InsertSQL("gdm_change_deployments", "")
+	_, err = tx.ExecContext(ctx, sql, fields.InsertValues()...)
+	reportSQLMessage(log, start, "gdm_change_deployments", write, sql, fields.RowCount(), err)
+	return err
+}
+
 func depID(row sqlgen.RowDef, dep *sous.Deployment) {
 	sid := dep.SourceID
 	row.FD(`(select max(deployment_id)
@@ -217,6 +415,20 @@ func depID(row sqlgen.RowDef, dep *sous.Deployment) {
 		"deployment_id", sid.Location.Repo, sid.Location.Dir, dep.Flavor, dep.Kind, dep.ClusterName)
 }
 
+// priorID is the latest deployment row for dep's component and cluster, which
+// a new row supersedes, or 0 if there is none.
+func priorID(row sqlgen.RowDef, dep *sous.Deployment) {
+	sid := dep.SourceID
+	row.FD(`(select coalesce(max(deployment_id), 0)
+	from
+		deployments
+		join components using (component_id)
+		join clusters using (cluster_id)
+	where
+	  repo = ? and dir = ? and flavor = ? and components.kind = ? and clusters.name = ?)`,
+		"prior_deployment_id", sid.Location.Repo, sid.Location.Dir, dep.Flavor, dep.Kind, dep.ClusterName)
+}
+
 func compID(row sqlgen.RowDef, dep *sous.Deployment) {
 	sid := dep.SourceID
 	row.FD(`(select component_id from components
@@ -232,6 +444,28 @@ func ownerID(row sqlgen.RowDef, ownername string) {
 	row.FD("(select owner_id from owners where email = ?)", "owner_id", ownername)
 }
 
+// upsert updates the stored row with the same candidate fields, if there is
+// one.
+const upsert = `on conflict {{.Candidates}} do update set {{.NonCandidates}} = {{.NSNonCandidates "excluded"}}`
+
+func clusterFields(r sqlgen.RowDef, name string, c *sous.Cluster) {
+	r.CF("?", "name", name)
+	r.FD("?", "kind", c.Kind)
+	r.FD("?", "base_url", c.BaseURL)
+	startupFields(r, "crdef", c.Startup)
+	r.FD("?", "require_approval", c.RequireApproval)
+	r.FD("?", "approvers", pq.Array(c.Approvers))
+	r.FD("?", "writers", pq.Array(c.Writers))
+	r.FD("?", "env", envDefaultsJSON(c.Env))
+}
+
+// envDefaultsJSON encodes env for the env column of clusters. It is a map of
+// strings, so encoding cannot fail.
+func envDefaultsJSON(env sous.EnvDefaults) string {
+	b, _ := json.Marshal(env)
+	return string(b)
+}
+
 func startupFields(r sqlgen.RowDef, prefix string, s sous.Startup) {
 	statuses := []int64{}
 	for _, n := range s.CheckReadyFailureStatuses {
@@ -248,6 +482,58 @@ func startupFields(r sqlgen.RowDef, prefix string, s sous.Startup) {
 	r.FD("?", prefix+"_interval", s.CheckReadyInterval)
 	r.FD("?", prefix+"_retries", s.CheckReadyRetries)
 	r.FD("?", prefix+"_failure_statuses", pq.Array(statuses))
+	r.FD("?", prefix+"_command", pq.Array(s.CheckReadyCommand))
+}
+
+// placementJSON encodes p for the placement column. A Placement has only
+// maps of strings, a bool and an int, so encoding cannot fail.
+func placementJSON(p sous.Placement) string {
+	b, _ := json.Marshal(p)
+	return string(b)
+}
+
+// policyFields records how Sous manages dep. Its Rollout, Pause and
+// FreezeOverride have only strings, ints, bools and times, so encoding cannot
+// fail.
+func policyFields(r sqlgen.RowDef, dep *sous.Deployment) {
+	rollout, _ := json.Marshal(dep.Rollout)
+	r.FD("?", "rollout", string(rollout))
+	var pause, override interface{}
+	if dep.Pause != nil {
+		js, _ := json.Marshal(dep.Pause)
+		pause = string(js)
+	}
+	if dep.FreezeOverride != nil {
+		js, _ := json.Marshal(dep.FreezeOverride)
+		override = string(js)
+	}
+	r.FD("?", "pause", pause)
+	r.FD("?", "freeze_override", override)
+	r.FD("?", "auto_rollback", dep.AutoRollback)
+}
+
+// execInsert inserts the rows of fields into table, if there are any.
+func execInsert(ctx context.Context, log logging.LogSink, tx *sql.Tx, fields sqlgen.FieldSet, table, conflict string) error {
+	if !fields.Potent() {
+		return nil
+	}
+	start := time.Now()
+	sql := fields.InsertSQL(table, conflict)
+	_, err := tx.ExecContext(ctx, sql, fields.InsertValues()...)
+	reportSQLMessage(log, start, table, write, sql, fields.RowCount(), err)
+	return err
+}
+
+// execReplace replaces every row of table with the rows of fields.
+func execReplace(ctx context.Context, log logging.LogSink, tx *sql.Tx, fields sqlgen.FieldSet, table string) error {
+	start := time.Now()
+	sql := `delete from ` + table
+	_, err := tx.ExecContext(ctx, sql)
+	reportSQLMessage(log, start, table, write, sql, 0, err)
+	if err != nil {
+		return err
+	}
+	return execInsert(ctx, log, tx, fields, table, "")
 }
 
 func execInsertDeployments(
@@ -265,14 +551,5 @@ func execInsertDeployments(
 	for _, d := range ds.Snapshot() {
 		fn(fields, d)
 	}
-	if !fields.Potent() {
-		return nil
-	}
-	start := time.Now()
-
-	sql := fields.InsertSQL(table, conflict)
-	_, err := tx.ExecContext(ctx, sql, fields.InsertValues()...)
-	reportSQLMessage(log, start, table, write, sql, fields.RowCount(), err)
-
-	return err
+	return execInsert(ctx, log, tx, fields, table, conflict)
 }
@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Config configures the webhooks a server notifies of deploy events.
+	Config struct {
+		// Hooks are the webhooks to notify.
+		Hooks []Hook `yaml:",omitempty"`
+		// Attempts is the number of times delivery of each event is attempted
+		// before it is dead-lettered.
+		Attempts int `env:"SOUS_WEBHOOK_ATTEMPTS"`
+		// DeadLetterFile is a file to which events which could not be
+		// delivered are appended, one JSON object per line. If it is empty,
+		// they are only logged.
+		DeadLetterFile string `env:"SOUS_WEBHOOK_DEAD_LETTERS"`
+	}
+
+	// A Hook is a URL to POST events to, and the events it subscribes to.
+	Hook struct {
+		// URL receives each event as a JSON POST.
+		URL string
+		// Secret, if set, is used to sign each event: the Sous-Signature
+		// header is "sha256=" and the hex HMAC-SHA256 of the body.
+		Secret string `yaml:",omitempty"`
+		// Events lists the event types to send. If it is empty, every type is
+		// sent.
+		Events []EventType `yaml:",omitempty"`
+		// Clusters lists the clusters to send events about. If it is empty,
+		// events about every cluster are sent.
+		Clusters []string `yaml:",omitempty"`
+		// Manifests lists the manifests to send events about, by manifest ID
+		// (e.g. github.com/opentable/example~flavor) or by source location, to
+		// include every flavor. If it is empty, events about every manifest
+		// are sent.
+		Manifests []string `yaml:",omitempty"`
+	}
+)
+
+// DefaultAttempts is the number of attempts to deliver each event if
+// Config.Attempts is not set.
+const DefaultAttempts = 5
+
+// DefaultConfig returns the default webhook configuration, with no hooks.
+func DefaultConfig() Config {
+	return Config{Attempts: DefaultAttempts}
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c Config) Validate() error {
+	if c.Attempts < 0 {
+		return errors.Errorf("Attempts must not be negative, got %d", c.Attempts)
+	}
+	for i, h := range c.Hooks {
+		if err := h.Validate(); err != nil {
+			return errors.Wrapf(err, "Hooks[%d]", i)
+		}
+	}
+	return nil
+}
+
+// Validate returns an error if the hook is invalid.
+func (h Hook) Validate() error {
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		return errors.Wrapf(err, "%q is not a valid URL", h.URL)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("URL %q must begin with http:// or https://", h.URL)
+	}
+	for _, et := range h.Events {
+		if !et.valid() {
+			return errors.Errorf("unknown event type %q (want one of %s)", et, eventTypes)
+		}
+	}
+	return nil
+}
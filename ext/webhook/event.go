@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/pborman/uuid"
+)
+
+type (
+	// EventType is the type of an Event.
+	EventType string
+
+	// An Event is the body POSTed to webhooks.
+	Event struct {
+		// ID is unique to the event, and is the same on every attempt to
+		// deliver it.
+		ID   string
+		Type EventType
+		At   time.Time
+		// Cluster, ManifestID and DeploymentID identify the deployment.
+		Cluster      string
+		ManifestID   string
+		DeploymentID string
+		// R11nID identifies the rectification.
+		R11nID sous.R11nID
+		// Resolution is the result of the rectification, for every type but
+		// Queued.
+		Resolution *sous.DiffResolution `json:",omitempty"`
+
+		mid sous.ManifestID
+	}
+)
+
+const (
+	// Queued events are sent when a change to a deployment is queued.
+	Queued = EventType("queued")
+	// Rectified events are sent when a deployment is created or updated.
+	Rectified = EventType("rectified")
+	// Failed events are sent when a change to a deployment fails.
+	Failed = EventType("failed")
+	// Deleted events are sent when a deployment is deleted.
+	Deleted = EventType("deleted")
+)
+
+var eventTypes = []EventType{Queued, Rectified, Failed, Deleted}
+
+func (et EventType) valid() bool {
+	for _, t := range eventTypes {
+		if et == t {
+			return true
+		}
+	}
+	return false
+}
+
+// eventFor returns the Event to send for ev, or false if ev is not about a
+// change to a deployment. Rectifications which change nothing are ignored, as
+// every deployment is rectified on every resolve cycle.
+func eventFor(ev sous.ResolveEvent) (Event, bool) {
+	if ev.DeploymentID == nil || ev.Expected == sous.StableDiff {
+		return Event{}, false
+	}
+	e := Event{
+		ID:           uuid.New(),
+		At:           ev.At,
+		Cluster:      ev.DeploymentID.Cluster,
+		ManifestID:   ev.DeploymentID.ManifestID.String(),
+		DeploymentID: ev.DeploymentID.String(),
+		R11nID:       ev.R11nID,
+		Resolution:   ev.Resolution,
+		mid:          ev.DeploymentID.ManifestID,
+	}
+	switch ev.Kind {
+	default:
+		return Event{}, false
+	case sous.R11nQueuedEvent:
+		e.Type = Queued
+	case sous.R11nResolvedEvent:
+		switch {
+		case ev.Resolution == nil:
+			return Event{}, false
+		case ev.Resolution.Error != nil:
+			e.Type = Failed
+		case ev.Resolution.Desc == sous.DeleteDiff:
+			e.Type = Deleted
+		case ev.Resolution.Desc == sous.CreateDiff, ev.Resolution.Desc == sous.ModifyDiff, ev.Resolution.Desc == sous.ComingDiff:
+			e.Type = Rectified
+		default:
+			return Event{}, false
+		}
+	}
+	return e, true
+}
+
+// Wants returns true if the hook subscribes to e.
+func (h Hook) Wants(e Event) bool {
+	return (len(h.Events) == 0 || containsType(h.Events, e.Type)) &&
+		(len(h.Clusters) == 0 || contains(h.Clusters, e.Cluster)) &&
+		(len(h.Manifests) == 0 || contains(h.Manifests, e.ManifestID) || contains(h.Manifests, e.mid.Source.String()))
+}
+
+func containsType(types []EventType, et EventType) bool {
+	for _, t := range types {
+		if t == et {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,236 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A Notifier POSTs deploy events to webhooks. Each hook receives its
+	// events in order; a failed delivery is retried with exponential backoff
+	// and, once the attempts are used up, dead-lettered.
+	Notifier struct {
+		Config Config
+		Client *http.Client
+		// Backoff is the delay before the first retry; each later retry waits
+		// twice as long as the last.
+		Backoff time.Duration
+		events  *sous.ResolveEvents
+		log     logging.LogSink
+		queues  []chan Event
+		deadMu  sync.Mutex
+	}
+
+	// A DeadLetter records an event which could not be delivered.
+	DeadLetter struct {
+		At       time.Time
+		URL      string
+		Attempts int
+		Error    string
+		Event    Event
+	}
+
+	// permanentError is a failed delivery which should not be retried.
+	permanentError struct {
+		error
+	}
+)
+
+// QueueLength is the number of events queued for each hook. Events for a hook
+// with a full queue are dead-lettered.
+const QueueLength = 1000
+
+// DefaultBackoff is the delay before the first retry of a delivery.
+const DefaultBackoff = time.Second
+
+// SignatureHeader is the header carrying the signature of a signed event.
+const SignatureHeader = "Sous-Signature"
+
+// NewNotifier returns a Notifier which sends the events published to events
+// to the webhooks in cfg, once it is started.
+func NewNotifier(cfg Config, events *sous.ResolveEvents, ls logging.LogSink) *Notifier {
+	if cfg.Attempts == 0 {
+		cfg.Attempts = DefaultAttempts
+	}
+	return &Notifier{
+		Config:  cfg,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+		Backoff: DefaultBackoff,
+		events:  events,
+		log:     ls,
+	}
+}
+
+// Start starts sending events to the webhooks, until done is closed. If there
+// are no webhooks, it does nothing.
+func (n *Notifier) Start(done <-chan struct{}) {
+	if n == nil || len(n.Config.Hooks) == 0 || n.events == nil {
+		return
+	}
+	n.queues = make([]chan Event, len(n.Config.Hooks))
+	for i, h := range n.Config.Hooks {
+		n.queues[i] = make(chan Event, QueueLength)
+		go n.deliverAll(h, n.queues[i], done)
+	}
+	events, unsubscribe := n.events.Subscribe()
+	go n.dispatch(events, unsubscribe, done)
+}
+
+// dispatch queues each event for the hooks which want it. If it falls too far
+// behind and loses its subscription, it subscribes again.
+func (n *Notifier) dispatch(events <-chan sous.ResolveEvent, unsubscribe func(), done <-chan struct{}) {
+	for n.dispatchFrom(events, done) {
+		messages.ReportLogFieldsMessage("Webhook notifier fell behind; some events were not sent", logging.WarningLevel, n.log)
+		events, unsubscribe = n.events.Subscribe()
+	}
+	unsubscribe()
+}
+
+// dispatchFrom returns true if events is closed, or false if done is.
+func (n *Notifier) dispatchFrom(events <-chan sous.ResolveEvent, done <-chan struct{}) bool {
+	for {
+		select {
+		case <-done:
+			return false
+		case ev, open := <-events:
+			if !open {
+				return true
+			}
+			e, ok := eventFor(ev)
+			if !ok {
+				continue
+			}
+			for i, h := range n.Config.Hooks {
+				if !h.Wants(e) {
+					continue
+				}
+				select {
+				case n.queues[i] <- e:
+				default:
+					n.deadLetter(h, e, 0, errors.New("too many events queued"))
+				}
+			}
+		}
+	}
+}
+
+func (n *Notifier) deliverAll(h Hook, queue <-chan Event, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case e := <-queue:
+			n.deliver(h, e, done)
+		}
+	}
+}
+
+// deliver sends e to h, retrying until it succeeds or the attempts are used up,
+// in which case e is dead-lettered.
+func (n *Notifier) deliver(h Hook, e Event, done <-chan struct{}) {
+	backoff := n.Backoff
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		err = n.post(h, e)
+		if err == nil {
+			return
+		}
+		if _, permanent := err.(permanentError); permanent || attempt >= n.Config.Attempts {
+			break
+		}
+		select {
+		case <-done:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	n.deadLetter(h, e, attempt, err)
+}
+
+func (n *Notifier) post(h Hook, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return permanentError{errors.Wrapf(err, "encoding %s event", e.Type)}
+	}
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Sous-Event", string(e.Type))
+	req.Header.Set("Sous-Delivery", e.ID)
+	if h.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(body, h.Secret))
+	}
+	res, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+	switch {
+	case res.StatusCode < 300:
+		return nil
+	case res.StatusCode >= 500, res.StatusCode == http.StatusRequestTimeout, res.StatusCode == http.StatusTooManyRequests:
+		return errors.Errorf("POST %s: %s", h.URL, res.Status)
+	default:
+		return permanentError{errors.Errorf("POST %s: %s", h.URL, res.Status)}
+	}
+}
+
+// Sign returns the signature of body with secret, as sent in the
+// Sous-Signature header.
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deadLetter logs that e could not be delivered to h, and appends it to the
+// dead letter file, if there is one.
+func (n *Notifier) deadLetter(h Hook, e Event, attempts int, cause error) {
+	dl := DeadLetter{At: time.Now(), URL: h.URL, Attempts: attempts, Error: cause.Error(), Event: e}
+	messages.ReportLogFieldsMessage(fmt.Sprintf("Webhook %s event %s for %s not delivered to %s after %d attempts: %s",
+		e.Type, e.ID, e.DeploymentID, h.URL, attempts, cause), logging.WarningLevel, n.log)
+	if n.Config.DeadLetterFile == "" {
+		return
+	}
+	if err := n.appendDeadLetter(dl); err != nil {
+		logging.ReportError(n.log, errors.Wrapf(err, "recording dead letter"))
+	}
+}
+
+func (n *Notifier) appendDeadLetter(dl DeadLetter) error {
+	line, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+	n.deadMu.Lock()
+	defer n.deadMu.Unlock()
+	f, err := os.OpenFile(n.Config.DeadLetterFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
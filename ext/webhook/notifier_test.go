@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+)
+
+func testDeploymentID() *sous.DeploymentID {
+	return &sous.DeploymentID{
+		Cluster: "cluster-1",
+		ManifestID: sous.ManifestID{
+			Source: sous.SourceLocation{Repo: "github.com/opentable/example"},
+			Flavor: "blue",
+		},
+	}
+}
+
+func TestEventFor(t *testing.T) {
+	did := testDeploymentID()
+	testCases := []struct {
+		desc string
+		ev   sous.ResolveEvent
+		want EventType
+	}{
+		{"queued", sous.ResolveEvent{Kind: sous.R11nQueuedEvent, Expected: sous.ModifyDiff}, Queued},
+		{"queued no-op", sous.ResolveEvent{Kind: sous.R11nQueuedEvent, Expected: sous.StableDiff}, ""},
+		{"started", sous.ResolveEvent{Kind: sous.R11nStartedEvent, Expected: sous.ModifyDiff}, ""},
+		{"created", sous.ResolveEvent{Kind: sous.R11nResolvedEvent, Resolution: &sous.DiffResolution{Desc: sous.CreateDiff}}, Rectified},
+		{"updated", sous.ResolveEvent{Kind: sous.R11nResolvedEvent, Resolution: &sous.DiffResolution{Desc: sous.ModifyDiff}}, Rectified},
+		{"deleted", sous.ResolveEvent{Kind: sous.R11nResolvedEvent, Resolution: &sous.DiffResolution{Desc: sous.DeleteDiff}}, Deleted},
+		{"failed", sous.ResolveEvent{Kind: sous.R11nResolvedEvent, Resolution: &sous.DiffResolution{Desc: sous.ModifyDiff, Error: &sous.ErrorWrapper{}}}, Failed},
+		{"frozen", sous.ResolveEvent{Kind: sous.R11nResolvedEvent, Resolution: &sous.DiffResolution{Desc: sous.FrozenDiff}}, ""},
+		{"cycle", sous.ResolveEvent{Kind: sous.DiffResolvedEvent, Resolution: &sous.DiffResolution{Desc: sous.ModifyDiff}}, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tc.ev.DeploymentID = did
+			e, ok := eventFor(tc.ev)
+			if tc.want == "" {
+				if ok {
+					t.Errorf("got %q event; want none", e.Type)
+				}
+				return
+			}
+			if !ok || e.Type != tc.want {
+				t.Fatalf("got %q event (%t); want %q", e.Type, ok, tc.want)
+			}
+			if e.Cluster != "cluster-1" || e.ManifestID != "github.com/opentable/example~blue" {
+				t.Errorf("got cluster %q, manifest %q", e.Cluster, e.ManifestID)
+			}
+		})
+	}
+}
+
+func TestHook_Wants(t *testing.T) {
+	e, _ := eventFor(sous.ResolveEvent{Kind: sous.R11nQueuedEvent, DeploymentID: testDeploymentID()})
+	testCases := []struct {
+		hook Hook
+		want bool
+	}{
+		{Hook{}, true},
+		{Hook{Events: []EventType{Queued}}, true},
+		{Hook{Events: []EventType{Failed, Deleted}}, false},
+		{Hook{Clusters: []string{"cluster-1"}}, true},
+		{Hook{Clusters: []string{"cluster-2"}}, false},
+		{Hook{Manifests: []string{"github.com/opentable/example~blue"}}, true},
+		{Hook{Manifests: []string{"github.com/opentable/example"}}, true},
+		{Hook{Manifests: []string{"github.com/opentable/example~green"}}, false},
+		{Hook{Clusters: []string{"cluster-1"}, Manifests: []string{"github.com/opentable/other"}}, false},
+	}
+	for _, tc := range testCases {
+		if got := tc.hook.Wants(e); got != tc.want {
+			t.Errorf("%+v.Wants: got %t; want %t", tc.hook, got, tc.want)
+		}
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := Config{Hooks: []Hook{{URL: "https://chat.example.com/hook", Events: []EventType{Failed}}}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+	for _, invalid := range []Config{
+		{Attempts: -1},
+		{Hooks: []Hook{{URL: "chat.example.com/hook"}}},
+		{Hooks: []Hook{{URL: "https://chat.example.com/hook", Events: []EventType{"exploded"}}}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("%+v: no error", invalid)
+		}
+	}
+}
+
+type hookServer struct {
+	*httptest.Server
+	sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+	received chan struct{}
+}
+
+// newHookServer returns a server which responds to each request with the next
+// of statuses, and then with 200.
+func newHookServer(statuses ...int) *hookServer {
+	hs := &hookServer{statuses: statuses, received: make(chan struct{}, 10)}
+	hs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		hs.Lock()
+		hs.requests = append(hs.requests, r)
+		hs.bodies = append(hs.bodies, body)
+		status := http.StatusOK
+		if len(hs.statuses) > 0 {
+			status, hs.statuses = hs.statuses[0], hs.statuses[1:]
+		}
+		hs.Unlock()
+		w.WriteHeader(status)
+		hs.received <- struct{}{}
+	}))
+	return hs
+}
+
+func (hs *hookServer) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-hs.received:
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for request %d", i+1)
+		}
+	}
+}
+
+func startNotifier(t *testing.T, cfg Config) (*sous.ResolveEvents, func()) {
+	events := sous.NewResolveEvents()
+	n := NewNotifier(cfg, events, logging.SilentLogSet())
+	n.Backoff = time.Millisecond
+	done := make(chan struct{})
+	n.Start(done)
+	return events, func() { close(done) }
+}
+
+func TestNotifier_retries(t *testing.T) {
+	hs := newHookServer(http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	defer hs.Close()
+	events, stop := startNotifier(t, Config{Hooks: []Hook{{URL: hs.URL, Secret: "s3cret"}}})
+	defer stop()
+
+	events.Publish(sous.ResolveEvent{Kind: sous.R11nQueuedEvent, DeploymentID: testDeploymentID(), Expected: sous.CreateDiff})
+	hs.wait(t, 3)
+
+	hs.Lock()
+	defer hs.Unlock()
+	ids := map[string]bool{}
+	for i, r := range hs.requests {
+		ids[r.Header.Get("Sous-Delivery")] = true
+		if got, want := r.Header.Get(SignatureHeader), Sign(hs.bodies[i], "s3cret"); got != want {
+			t.Errorf("request %d: got signature %q; want %q", i, got, want)
+		}
+	}
+	if len(ids) != 1 {
+		t.Errorf("retries should have the same delivery ID; got %v", ids)
+	}
+	e := Event{}
+	if err := json.Unmarshal(hs.bodies[2], &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != Queued || e.DeploymentID != testDeploymentID().String() {
+		t.Errorf("got event %+v", e)
+	}
+}
+
+func TestNotifier_deadLetter(t *testing.T) {
+	hs := newHookServer(http.StatusBadRequest)
+	defer hs.Close()
+	dir, err := ioutil.TempDir("", "sous-webhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	deadLetters := filepath.Join(dir, "dead-letters")
+	events, stop := startNotifier(t, Config{
+		Hooks:          []Hook{{URL: hs.URL, Events: []EventType{Failed}}},
+		DeadLetterFile: deadLetters,
+	})
+	defer stop()
+
+	did := testDeploymentID()
+	// Not wanted by the hook.
+	events.Publish(sous.ResolveEvent{Kind: sous.R11nQueuedEvent, DeploymentID: did, Expected: sous.CreateDiff})
+	events.Publish(sous.ResolveEvent{Kind: sous.R11nResolvedEvent, DeploymentID: did, Expected: sous.CreateDiff,
+		Resolution: &sous.DiffResolution{Desc: sous.CreateDiff, Error: &sous.ErrorWrapper{}}})
+	hs.wait(t, 1)
+
+	var data []byte
+	for i := 0; i < 100 && len(data) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		data, _ = ioutil.ReadFile(deadLetters)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d dead letters; want 1: %q", len(lines), data)
+	}
+	dl := DeadLetter{}
+	if err := json.Unmarshal([]byte(lines[0]), &dl); err != nil {
+		t.Fatal(err)
+	}
+	if dl.URL != hs.URL || dl.Attempts != 1 || dl.Event.Type != Failed {
+		t.Errorf("got dead letter %+v", dl)
+	}
+	select {
+	case <-hs.received:
+		t.Errorf("rejected event was retried")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
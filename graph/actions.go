@@ -5,6 +5,7 @@ import (
 
 	"github.com/opentable/sous/cli/actions"
 	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/ext/webhook"
 	sous "github.com/opentable/sous/lib"
 	"github.com/samsalisbury/semv"
 )
@@ -99,12 +100,18 @@ func (di *SousGraph) GetServer(dff config.DeployFilterFlags, dryrun string, ladd
 		Config        *config.Config
 		ServerHandler ServerHandler
 		AutoResolver  *sous.AutoResolver
+		Webhooks      *webhook.Notifier
+		StateManager  *ServerStateManager
+		Membership    *sous.Membership
+		Leadership    *sous.Leadership
 	}{}
 
 	if err := di.Inject(&scoop); err != nil {
 		return nil, err
 	}
 
+	r11nStore, _ := scoop.StateManager.StateManager.(sous.R11nStore)
+
 	return &actions.Server{
 		DeployFilterFlags: dff,
 		GDMRepo:           gdmRepo,
@@ -114,5 +121,9 @@ func (di *SousGraph) GetServer(dff config.DeployFilterFlags, dryrun string, ladd
 		Config:            scoop.Config,
 		ServerHandler:     scoop.ServerHandler.Handler,
 		AutoResolver:      scoop.AutoResolver,
+		Webhooks:          scoop.Webhooks,
+		R11nStore:         r11nStore,
+		Membership:        scoop.Membership,
+		Leadership:        scoop.Leadership,
 	}, nil
 }
@@ -12,8 +12,11 @@ import (
 	"github.com/opentable/sous/ext/docker"
 	"github.com/opentable/sous/ext/git"
 	"github.com/opentable/sous/ext/github"
+	"github.com/opentable/sous/ext/kubernetes"
+	"github.com/opentable/sous/ext/secrets"
 	"github.com/opentable/sous/ext/singularity"
 	"github.com/opentable/sous/ext/storage"
+	"github.com/opentable/sous/ext/webhook"
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/server"
 	"github.com/opentable/sous/util/docker_registry"
@@ -276,7 +279,11 @@ func AddInternals(graph adder) {
 		newServerComponentLocator,
 		newHTTPClient,
 		newClusterSpecificHTTPClient,
-		NewR11nQueueSet,
+		newR11nQueueSet,
+		newResolveEvents,
+		newWebhookNotifier,
+		newMembership,
+		newLeadership,
 	)
 }
 
@@ -284,12 +291,82 @@ func newResolveFilter(sf *config.DeployFilterFlags, shc sous.SourceHostChooser)
 	return sf.BuildFilter(shc.ParseSourceLocation)
 }
 
-func newResolver(filter *sous.ResolveFilter, d sous.Deployer, r sous.Registry, ls LogSink, qs *sous.R11nQueueSet) *sous.Resolver {
-	return sous.NewResolver(d, r, filter, ls.Child("resolver"), qs)
+func newResolver(filter *sous.ResolveFilter, d sous.Deployer, r sous.Registry, ls LogSink, qs *sous.R11nQueueSet, events *sous.ResolveEvents) *sous.Resolver {
+	rez := sous.NewResolver(d, r, filter, ls.Child("resolver"), qs)
+	rez.Events = events
+	return rez
 }
 
-func newAutoResolver(rez *sous.Resolver, sr *ServerStateManager, ls LogSink) *sous.AutoResolver {
-	return sous.NewAutoResolver(rez, sr, ls.Child("autoresolver"))
+func newAutoResolver(rez *sous.Resolver, sr *ServerStateManager, l *sous.Leadership, ls LogSink) *sous.AutoResolver {
+	ar := sous.NewAutoResolver(rez, sr, ls.Child("autoresolver"))
+	ar.DeploymentManager = sous.MakeDeploymentManager(sr.StateManager)
+	if lgs, ok := sr.StateManager.(sous.LastGoodStore); ok {
+		ar.LastGood = lgs
+	}
+	ar.Leadership = l
+	if sw, ok := sr.StateManager.(sous.StateWatcher); ok {
+		ar.StateChanges = sw.WatchState(nil)
+	}
+	return ar
+}
+
+func newWebhookNotifier(cfg LocalSousConfig, events *sous.ResolveEvents, ls LogSink) *webhook.Notifier {
+	return webhook.NewNotifier(cfg.Webhooks, events, ls.Child("webhooks"))
+}
+
+// newMembership returns the Membership through which the server gossips with
+// its siblings, or nil if the server is not configured to take part in gossip,
+// or does not resolve a single cluster it could announce.
+func newMembership(cfg LocalSousConfig, dff *config.DeployFilterFlags, ls LogSink) *sous.Membership {
+	mc := cfg.Membership
+	if mc.URL == "" || dff.Cluster == "" {
+		return nil
+	}
+	seeds := []string{}
+	for _, url := range cfg.SiblingURLs {
+		seeds = append(seeds, url)
+	}
+	self := sous.Member{ClusterName: dff.Cluster, URL: mc.URL}
+	log := ls.Child("membership")
+	m := sous.NewMembership(self, seeds, sous.NewHTTPMembershipTransport(log), log)
+	m.Interval = mc.Interval(sous.DefaultMembershipInterval)
+	m.FailAfter = mc.FailAfter(sous.DefaultMembershipFailAfter)
+	m.Key = []byte(mc.Key)
+	return m
+}
+
+// newLeadership returns the Leadership which elects the server that rectifies
+// each cluster, or nil if the server is not configured to take part in leader
+// election, in which case it rectifies every cluster it resolves.
+func newLeadership(cfg LocalSousConfig, sm *ServerStateManager, ls LogSink) *sous.Leadership {
+	mc := cfg.Membership
+	if mc.URL == "" || mc.LeaseTTL() == 0 {
+		return nil
+	}
+	store, err := leaseStore(sm.StateManager)
+	if err != nil {
+		messages.ReportLogFieldsMessage(fmt.Sprintf("Not electing leaders: %s", err),
+			logging.WarningLevel, ls)
+		return nil
+	}
+	l := sous.NewLeadership(mc.URL, store, ls.Child("leadership"))
+	l.TTL = mc.LeaseTTL()
+	l.Key = []byte(mc.Key)
+	return l
+}
+
+// leaseStore returns the store which holds the leases of sm. A
+// DuplexStateManager holds them in its secondary StateManager, which may not
+// be able to, e.g. if there is no database.
+func leaseStore(sm sous.StateManager) (sous.LeaseStore, error) {
+	if dup, is := sm.(*storage.DuplexStateManager); is {
+		return dup.LeaseStore()
+	}
+	store, is := sm.(sous.LeaseStore)
+	if !is {
+		return nil, errors.Errorf("%T does not store leases", sm)
+	}
+	return store, nil
 }
 
 func newSourceHostChooser() sous.SourceHostChooser {
@@ -495,22 +572,32 @@ func newDeployer(dryrun DryrunOption, nc lazyNameCache, ls LogSink, c LocalSousC
 	if dryrun == DryrunBoth || dryrun == DryrunScheduler {
 		drc := sous.NewDummyRectificationClient()
 		drc.SetLogger(ls.Child("rectify"))
-		return singularity.NewDeployer(
-			drc,
-			ls.Child("singularity-deployer"),
-			singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
-		), nil
+		return sous.NewDeployerSet(map[string]sous.Deployer{
+			sous.ClusterKindSingularity: singularity.NewDeployer(
+				drc,
+				ls.Child("singularity-deployer"),
+				singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
+			),
+			sous.ClusterKindKubernetes: sous.NewDummyDeployer(),
+		}), nil
 	}
 	// We need the real name cache.
 	nameCache, err := nc()
 	if err != nil {
 		return nil, err
 	}
-	return singularity.NewDeployer(
-		singularity.NewRectiAgent(nameCache),
-		ls,
-		singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
-	), nil
+	kc := kubernetes.NewHTTPClient(nil, c.Kubernetes.Namespace)
+	kc.BearerToken = c.Kubernetes.BearerToken
+	ra := singularity.NewRectiAgent(nameCache)
+	ra.Secrets = secrets.NewProvider(c.Secrets)
+	return sous.NewDeployerSet(map[string]sous.Deployer{
+		sous.ClusterKindSingularity: singularity.NewDeployer(
+			ra,
+			ls,
+			singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
+		),
+		sous.ClusterKindKubernetes: kubernetes.NewDeployer(kc, ls.Child("kubernetes-deployer")),
+	}), nil
 }
 
 func newDockerClient(ls LogSink) LocalDockerClient {
@@ -583,7 +670,7 @@ func newHTTPClient(c LocalSousConfig, user sous.User, srvr ServerHandler, log Lo
 	return HTTPClient{HTTPClient: cl}, err
 }
 
-func newServerStateManager(c LocalSousConfig, log LogSink) *ServerStateManager {
+func newServerStateManager(c LocalSousConfig, log LogSink) (*ServerStateManager, error) {
 	var secondary sous.StateManager
 	db, err := c.Database.DB()
 	if err == nil {
@@ -593,22 +680,44 @@ func newServerStateManager(c LocalSousConfig, log LogSink) *ServerStateManager {
 		secondary = storage.NewLogOnlyStateManager(log.Child("database"))
 	}
 
-	dm := storage.NewDiskStateManager(c.StateLocation)
-	gm := storage.NewGitStateManager(dm)
-	duplex := storage.NewDuplexStateManager(gm, secondary, log.Child("duplex-state"))
-	return &ServerStateManager{StateManager: duplex}
+	primary, err := newPrimaryStateManager(c, log)
+	if err != nil {
+		return nil, err
+	}
+	duplex := storage.NewDuplexStateManager(primary, secondary, log.Child("duplex-state"))
+	return &ServerStateManager{StateManager: duplex}, nil
+}
+
+// newPrimaryStateManager returns the StateManager which holds the state: a
+// key-value store if KVStatePath is set, or else the git repository at
+// StateLocation. Writes of single deployments are only scoped to the
+// deployment with a key-value store; with git, each one rewrites the state.
+func newPrimaryStateManager(c LocalSousConfig, log LogSink) (sous.StateManager, error) {
+	if c.KVStatePath == "" {
+		dm := storage.NewDiskStateManager(c.StateLocation)
+		return storage.NewGitStateManager(dm), nil
+	}
+	kv, err := storage.OpenBoltKVStore(c.KVStatePath)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewKVStateManager(kv, "", log.Child("kv-state")), nil
 }
 
 // newStateManager returns a wrapped sous.HTTPStateManager if cl is not nil.
 // Otherwise it returns a wrapped sous.GitStateManager, for local git based GDM.
 // If it returns a sous.GitStateManager, it emits a warning log.
-func newStateManager(cl HTTPClient, c LocalSousConfig, log LogSink) *StateManager {
+func newStateManager(cl HTTPClient, c LocalSousConfig, log LogSink) (*StateManager, error) {
 	if c.Server == "" {
 		messages.ReportLogFieldsMessageToConsole("Using local state stored at", logging.WarningLevel, log, c.StateLocation)
-		return &StateManager{StateManager: newServerStateManager(c, log).StateManager}
+		ssm, err := newServerStateManager(c, log)
+		if err != nil {
+			return nil, err
+		}
+		return &StateManager{StateManager: ssm.StateManager}, nil
 	}
 	hsm := sous.NewHTTPStateManager(cl)
-	return &StateManager{StateManager: hsm}
+	return &StateManager{StateManager: hsm}, nil
 }
 
 func newStatusPoller(cl HTTPClient, rf *RefinedResolveFilter, user sous.User, logs LogSink) *sous.StatusPoller {
@@ -147,7 +147,10 @@ func injectedStateManager(t *testing.T, cfg *config.Config) *StateManager {
 	g.Add(newAutoResolver)
 	g.Add(newServerHandler)
 	g.Add(newHTTPClient)
-	g.Add(NewR11nQueueSet)
+	g.Add(newR11nQueueSet)
+	g.Add(newResolveEvents)
+	g.Add(newMembership)
+	g.Add(newLeadership)
 	g.Add(g)
 
 	smRcvr := struct {
@@ -185,6 +188,21 @@ func TestStateManagerSelectsDuplex(t *testing.T) {
 	}
 }
 
+func TestNewLeadership(t *testing.T) {
+	ls := LogSink{logging.SilentLogSet()}
+	cfg := LocalSousConfig{&config.Config{Membership: config.MembershipConfig{URL: "http://sous.example.com", LeaseSeconds: 30}}}
+	primary := storage.NewDiskStateManager("/tmp/sous")
+
+	logOnly := storage.NewDuplexStateManager(primary, storage.NewLogOnlyStateManager(ls), ls)
+	assert.Nil(t, newLeadership(cfg, &ServerStateManager{StateManager: logOnly}, ls),
+		"leaders should not be elected without a database to hold their leases")
+
+	postgres := storage.NewDuplexStateManager(primary, storage.NewPostgresStateManager(nil, ls), ls)
+	l := newLeadership(cfg, &ServerStateManager{StateManager: postgres}, ls)
+	require.NotNil(t, l)
+	assert.Equal(t, "http://sous.example.com", l.Holder)
+}
+
 func TestNewBuildConfig(t *testing.T) {
 	f := &config.DeployFilterFlags{}
 	p := &config.PolicyFlags{}
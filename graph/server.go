@@ -6,9 +6,13 @@ import (
 	"github.com/samsalisbury/semv"
 )
 
-func newServerComponentLocator(ls LogSink, cfg LocalSousConfig, ins sous.Inserter, sm *ServerStateManager, rf *sous.ResolveFilter, ar *sous.AutoResolver, v semv.Version, qs *sous.R11nQueueSet) server.ComponentLocator {
+func newServerComponentLocator(ls LogSink, cfg LocalSousConfig, ins sous.Inserter, sm *ServerStateManager, rf *sous.ResolveFilter, ar *sous.AutoResolver, v semv.Version, qs *sous.R11nQueueSet, events *sous.ResolveEvents, m *sous.Membership, l *sous.Leadership) server.ComponentLocator {
 	cm := sous.MakeClusterManager(sm.StateManager)
 	dm := sous.MakeDeploymentManager(sm.StateManager)
+	hr, _ := sm.StateManager.(sous.HistoryReader)
+	sh, _ := sm.StateManager.(sous.StateHistorian)
+	pcs, _ := sm.StateManager.(sous.PendingChangeStore)
+	sr, _ := sm.StateManager.(sous.StateReconciler)
 	return server.ComponentLocator{
 
 		LogSink:           ls.LogSink,
@@ -21,6 +25,15 @@ func newServerComponentLocator(ls LogSink, cfg LocalSousConfig, ins sous.Inserte
 		AutoResolver:      ar,
 		Version:           v,
 		QueueSet:          qs,
+		HistoryReader:     hr,
+		StateHistorian:    sh,
+		PendingChanges:    pcs,
+		StateReconciler:   sr,
+		Identifier:        server.NewIdentifier(cfg.Config.Authorization),
+		Authorizer:        server.NewAuthorizer(cfg.Config.Authorization),
+		Events:            events,
+		Membership:        m,
+		Leadership:        l,
 	}
 
 }
@@ -28,9 +41,21 @@ func newServerComponentLocator(ls LogSink, cfg LocalSousConfig, ins sous.Inserte
 // NewR11nQueueSet returns a new queue set configured to start processing r11ns
 // immediately.
 func NewR11nQueueSet(d sous.Deployer) *sous.R11nQueueSet {
-	return sous.NewR11nQueueSet(sous.R11nQueueStartWithHandler(
-		func(qr *sous.QueuedR11n) sous.DiffResolution {
-			qr.Rectification.Begin(d)
-			return qr.Rectification.Wait()
-		}))
+	return newR11nQueueSet(d, nil)
+}
+
+// newR11nQueueSet is like NewR11nQueueSet, but also publishes the progress of
+// each r11n to events.
+func newR11nQueueSet(d sous.Deployer, events *sous.ResolveEvents) *sous.R11nQueueSet {
+	return sous.NewR11nQueueSet(
+		sous.R11nQueueEvents(events),
+		sous.R11nQueueStartWithHandler(
+			func(qr *sous.QueuedR11n) sous.DiffResolution {
+				qr.Rectification.Begin(d)
+				return qr.Rectification.Wait()
+			}))
+}
+
+func newResolveEvents() *sous.ResolveEvents {
+	return sous.NewResolveEvents()
 }
@@ -168,7 +168,7 @@ func TestNewTargetManifest_Existing(t *testing.T) {
 	s.Manifests.Add(m)
 	tm := newTargetManifest(detected, tmid, s)
 	if tm.Source != sl {
-		t.Errorf("unexpected manifest %q", m)
+		t.Errorf("unexpected manifest %v", m)
 	}
 	flaws := tm.Manifest.Validate()
 	if len(flaws) > 0 {
@@ -190,7 +190,7 @@ func TestNewTargetManifest_Existing_withOffset(t *testing.T) {
 	s.Manifests.Add(m)
 	tm := newTargetManifest(detected, tmid, s)
 	if tm.Source != sl {
-		t.Errorf("unexpected manifest %q", m)
+		t.Errorf("unexpected manifest %v", m)
 	}
 	flaws := tm.Manifest.Validate()
 	if len(flaws) > 0 {
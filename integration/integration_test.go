@@ -412,7 +412,7 @@ func (suite *integrationSuite) TestMissingImage() {
 	deploymentsOne, err := stateOne.Deployments()
 	suite.Require().NoError(err)
 
-	err = r.Begin(deploymentsOne, clusterDefs.Clusters).Wait()
+	err = r.Begin(deploymentsOne, clusterDefs.Clusters, nil).Wait()
 
 	suite.T().Logf("Missing Image Error: %v", err)
 	suite.Error(err, "should report 'missing image' for opentable/one")
@@ -467,7 +467,7 @@ func (suite *integrationSuite) TestResolve() {
 	r := sous.NewResolver(suite.deployer, suite.nameCache, &sous.ResolveFilter{}, logsink, qs)
 
 	suite.T().Log("Begining OneTwo")
-	err = r.Begin(deploymentsOneTwo, clusterDefs.Clusters).Wait()
+	err = r.Begin(deploymentsOneTwo, clusterDefs.Clusters, nil).Wait()
 	suite.T().Log("Finished OneTwo")
 	if err != nil {
 		suite.Fail(err.Error())
@@ -532,7 +532,7 @@ func (suite *integrationSuite) TestResolve() {
 		qs := graph.NewR11nQueueSet(suite.deployer)
 		r := sous.NewResolver(deployer, suite.nameCache, &sous.ResolveFilter{}, logging.SilentLogSet(), qs)
 
-		err = r.Begin(deploymentsTwoThree, clusterDefs.Clusters).Wait()
+		err = r.Begin(deploymentsTwoThree, clusterDefs.Clusters, nil).Wait()
 		if err != nil {
 			//suite.Require().NotRegexp(`Pending deploy already in progress`, err.Error())
 			suffix := `           this is dumb but it would suck to panic during tests
@@ -0,0 +1,307 @@
+package sous
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+)
+
+type (
+	// PendingChangeID identifies a PendingChange.
+	PendingChangeID string
+
+	// PendingStatus is the status of a PendingChange.
+	PendingStatus string
+
+	// A ProposedDeployment is a change to a single deployment which is
+	// awaiting approval.
+	ProposedDeployment struct {
+		DeploymentID DeploymentID
+		// Deployment is the proposed deployment. It is nil if the proposal is
+		// to remove the deployment.
+		Deployment *Deployment `json:",omitempty"`
+		// Prior is the deployment as it was when the change was proposed. It
+		// is nil if the deployment did not exist.
+		Prior *Deployment `json:",omitempty"`
+	}
+
+	// A PendingChange is a change to deployments in clusters which require
+	// approval. It is only written to the GDM once it has been approved by
+	// someone other than its Author.
+	PendingChange struct {
+		ID          PendingChangeID
+		Author      User
+		Proposed    time.Time
+		Deployments []ProposedDeployment
+		Status      PendingStatus
+		// Reviewer is the user who approved or rejected the change.
+		Reviewer User
+		// Reviewed is when the change was approved or rejected.
+		Reviewed time.Time
+	}
+
+	// A PendingChangeStore stores changes which are awaiting approval.
+	PendingChangeStore interface {
+		// ProposeChange stores a new pending change.
+		ProposeChange(PendingChange) error
+		// ReadPendingChange returns the change with the given ID, or nil if
+		// there is no such change.
+		ReadPendingChange(PendingChangeID) (*PendingChange, error)
+		// ListPendingChanges returns all the changes which are still pending,
+		// oldest first.
+		ListPendingChanges() ([]PendingChange, error)
+		// ReviewPendingChange marks a pending change as approved or rejected.
+		// It returns an error if the change is not pending.
+		ReviewPendingChange(id PendingChangeID, status PendingStatus, reviewer User, at time.Time) error
+	}
+
+	// A PendingApprovalError is returned when a write to the GDM was held for
+	// approval instead of being made.
+	PendingApprovalError struct {
+		ID PendingChangeID
+	}
+
+	// A StaleChangeError is returned when approving a pending change to a
+	// deployment which has changed since the change was proposed.
+	StaleChangeError struct {
+		ID           PendingChangeID
+		DeploymentID DeploymentID
+	}
+
+	// MemoryPendingChangeStore is a PendingChangeStore which keeps changes in
+	// memory. It is intended for testing.
+	MemoryPendingChangeStore struct {
+		sync.Mutex
+		changes []PendingChange
+	}
+)
+
+const (
+	// PendingStatusPending means the change is awaiting approval.
+	PendingStatusPending = PendingStatus("pending")
+	// PendingStatusApproved means the change was approved and written to the
+	// GDM.
+	PendingStatusApproved = PendingStatus("approved")
+	// PendingStatusRejected means the change was rejected.
+	PendingStatusRejected = PendingStatus("rejected")
+)
+
+func (err *PendingApprovalError) Error() string {
+	return fmt.Sprintf("changes to protected clusters are awaiting approval as pending change %s", err.ID)
+}
+
+func (err *StaleChangeError) Error() string {
+	return fmt.Sprintf("pending change %s is stale: %s has changed since it was proposed; reject it and propose it again",
+		err.ID, err.DeploymentID)
+}
+
+// PendingApprovalFromLocation returns a *PendingApprovalError if loc is the
+// location of a pending change, and nil otherwise.
+func PendingApprovalFromLocation(loc string) *PendingApprovalError {
+	if loc == "" {
+		return nil
+	}
+	u, err := url.Parse(loc)
+	if err != nil {
+		return nil
+	}
+	id := u.Query().Get("id")
+	if !strings.HasSuffix(u.Path, "/pending-change") || id == "" {
+		return nil
+	}
+	return &PendingApprovalError{ID: PendingChangeID(id)}
+}
+
+// RequiresApproval returns true if changes to the deployment in the named
+// cluster require approval.
+func (cs Clusters) RequiresApproval(did DeploymentID) bool {
+	c, ok := cs[did.Cluster]
+	return ok && c.RequireApproval
+}
+
+// SplitProtected divides the changes from current to intended deployments into
+// those which may be made immediately, and those which require approval. It
+// returns a copy of intended with every change requiring approval undone, and
+// the undone changes as proposals.
+func SplitProtected(current, intended Deployments, clusters Clusters) (Deployments, []ProposedDeployment) {
+	allowed := intended.Clone()
+	var proposed []ProposedDeployment
+	for _, pair := range current.Diff(intended).Collect() {
+		did := pair.ID()
+		if pair.Kind() == SameKind || !clusters.RequiresApproval(did) {
+			continue
+		}
+		pd := ProposedDeployment{DeploymentID: did}
+		if dep, has := intended.Get(did); has {
+			pd.Deployment = dep.Clone()
+		}
+		if dep, had := current.Get(did); had {
+			pd.Prior = dep.Clone()
+		}
+		proposed = append(proposed, pd)
+		if dep, had := current.Get(did); had {
+			allowed.Set(did, dep.Clone())
+		} else {
+			allowed.Remove(did)
+		}
+	}
+	return allowed, proposed
+}
+
+// NewPendingChange returns a new pending change proposing deployments.
+func NewPendingChange(author User, proposed []ProposedDeployment, at time.Time) PendingChange {
+	return PendingChange{
+		ID:          PendingChangeID(uuid.New()),
+		Author:      author,
+		Proposed:    at,
+		Deployments: proposed,
+		Status:      PendingStatusPending,
+	}
+}
+
+// sameUser returns true if a and b identify the same user.
+func sameUser(a, b User) bool {
+	if a.Email != "" || b.Email != "" {
+		return strings.EqualFold(a.Email, b.Email)
+	}
+	return a.Name != "" && a.Name == b.Name
+}
+
+// CheckReview returns an error unless reviewer may give the change the status
+// in the context of state. The author of a change may reject it, but not
+// approve it. Anyone else must, for each proposed deployment, either be an
+// owner of its manifest or an approver for its cluster.
+func (pc PendingChange) CheckReview(reviewer User, status PendingStatus, state *State) error {
+	if status != PendingStatusApproved && status != PendingStatusRejected {
+		return errors.Errorf("a change can only be %s or %s, not %q",
+			PendingStatusApproved, PendingStatusRejected, status)
+	}
+	if reviewer.Email == "" && reviewer.Name == "" {
+		return errors.New("an anonymous user cannot review changes")
+	}
+	if sameUser(pc.Author, reviewer) {
+		if status == PendingStatusRejected {
+			return nil
+		}
+		return errors.Errorf("%s cannot approve their own change", reviewer)
+	}
+	for _, pd := range pc.Deployments {
+		var approvers []string
+		if c, ok := state.Defs.Clusters[pd.DeploymentID.Cluster]; ok {
+			approvers = append(approvers, c.Approvers...)
+		}
+		if m, ok := state.Manifests.Get(pd.DeploymentID.ManifestID); ok {
+			approvers = append(approvers, m.Owners...)
+		} else if pd.Deployment != nil {
+			approvers = append(approvers, pd.Deployment.Owners.Slice()...)
+		}
+		if !reviewer.ListedIn(approvers) {
+			return errors.Errorf("%s is neither an owner of %s nor an approver for cluster %s",
+				reviewer, pd.DeploymentID.ManifestID, pd.DeploymentID.Cluster)
+		}
+	}
+	return nil
+}
+
+// Intended returns a copy of current with the proposed changes made.
+func (pc PendingChange) Intended(current Deployments) Deployments {
+	proposed := current.Clone()
+	for _, pd := range pc.Deployments {
+		if pd.Deployment == nil {
+			proposed.Remove(pd.DeploymentID)
+			continue
+		}
+		proposed.Set(pd.DeploymentID, pd.Deployment.Clone())
+	}
+	return proposed
+}
+
+// CheckCurrent returns a *StaleChangeError if any deployment pc changes is no
+// longer as it was when pc was proposed. Writing such a change would silently
+// undo whatever changed the deployment in the meantime.
+func (pc PendingChange) CheckCurrent(current Deployments) error {
+	for _, pd := range pc.Deployments {
+		dep, has := current.Get(pd.DeploymentID)
+		stale := has != (pd.Prior != nil)
+		if has && !stale {
+			different, _ := pd.Prior.Diff(dep)
+			stale = different || len(pd.Prior.PolicyDiff(dep)) > 0
+		}
+		if stale {
+			return &StaleChangeError{ID: pc.ID, DeploymentID: pd.DeploymentID}
+		}
+	}
+	return nil
+}
+
+// Changes describes the proposed changes relative to current.
+func (pc PendingChange) Changes(current Deployments) []DeploymentChange {
+	return GDMChanges(current.Diff(pc.Intended(current)).Collect())
+}
+
+// NewMemoryPendingChangeStore returns an empty MemoryPendingChangeStore.
+func NewMemoryPendingChangeStore() *MemoryPendingChangeStore {
+	return &MemoryPendingChangeStore{}
+}
+
+// ProposeChange implements PendingChangeStore on MemoryPendingChangeStore.
+func (s *MemoryPendingChangeStore) ProposeChange(pc PendingChange) error {
+	s.Lock()
+	defer s.Unlock()
+	for _, c := range s.changes {
+		if c.ID == pc.ID {
+			return errors.Errorf("pending change %s already exists", pc.ID)
+		}
+	}
+	s.changes = append(s.changes, pc)
+	return nil
+}
+
+// ReadPendingChange implements PendingChangeStore on MemoryPendingChangeStore.
+func (s *MemoryPendingChangeStore) ReadPendingChange(id PendingChangeID) (*PendingChange, error) {
+	s.Lock()
+	defer s.Unlock()
+	for _, c := range s.changes {
+		if c.ID == id {
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListPendingChanges implements PendingChangeStore on MemoryPendingChangeStore.
+func (s *MemoryPendingChangeStore) ListPendingChanges() ([]PendingChange, error) {
+	s.Lock()
+	defer s.Unlock()
+	var pending []PendingChange
+	for _, c := range s.changes {
+		if c.Status == PendingStatusPending {
+			pending = append(pending, c)
+		}
+	}
+	return pending, nil
+}
+
+// ReviewPendingChange implements PendingChangeStore on MemoryPendingChangeStore.
+func (s *MemoryPendingChangeStore) ReviewPendingChange(id PendingChangeID, status PendingStatus, reviewer User, at time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+	for i, c := range s.changes {
+		if c.ID != id {
+			continue
+		}
+		if c.Status != PendingStatusPending {
+			return errors.Errorf("pending change %s is already %s", id, c.Status)
+		}
+		s.changes[i].Status = status
+		s.changes[i].Reviewer = reviewer
+		s.changes[i].Reviewed = at
+		return nil
+	}
+	return errors.Errorf("no pending change %s", id)
+}
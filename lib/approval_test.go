@@ -0,0 +1,187 @@
+package sous
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func approvalFixture() (*State, *Deployment) {
+	dep := DeploymentFixture("")
+	state := NewState()
+	state.Defs.Clusters = Clusters{
+		dep.ClusterName: &Cluster{
+			Name:            dep.ClusterName,
+			RequireApproval: true,
+			Approvers:       []string{"approver@example.com"},
+		},
+		"open": &Cluster{Name: "open"},
+	}
+	state.Manifests = NewManifests(&Manifest{
+		Source: dep.SourceID.Location,
+		Flavor: dep.Flavor,
+		Owners: []string{"owner@example.com"},
+	})
+	return state, dep
+}
+
+func TestClusters_RequiresApproval(t *testing.T) {
+	state, dep := approvalFixture()
+	assert.True(t, state.Defs.Clusters.RequiresApproval(dep.ID()))
+
+	open := dep.Clone()
+	open.ClusterName = "open"
+	assert.False(t, state.Defs.Clusters.RequiresApproval(open.ID()))
+
+	missing := dep.Clone()
+	missing.ClusterName = "missing"
+	assert.False(t, state.Defs.Clusters.RequiresApproval(missing.ID()))
+}
+
+func TestSplitProtected(t *testing.T) {
+	state, dep := approvalFixture()
+	open := dep.Clone()
+	open.ClusterName = "open"
+	current := NewDeployments(dep, open)
+
+	changed, changedOpen := dep.Clone(), open.Clone()
+	changed.NumInstances++
+	changedOpen.NumInstances++
+	added := dep.Clone()
+	added.Flavor = "added"
+
+	allowed, proposed := SplitProtected(current, NewDeployments(changed, changedOpen, added), state.Defs.Clusters)
+
+	require.Len(t, proposed, 2)
+	for _, pd := range proposed {
+		require.NotNil(t, pd.Deployment)
+		assert.Equal(t, pd.DeploymentID, pd.Deployment.ID())
+	}
+
+	got, has := allowed.Get(dep.ID())
+	require.True(t, has)
+	assert.Equal(t, dep.NumInstances, got.NumInstances, "change to protected cluster was not held")
+	got, has = allowed.Get(open.ID())
+	require.True(t, has)
+	assert.Equal(t, changedOpen.NumInstances, got.NumInstances, "change to open cluster was held")
+	_, has = allowed.Get(added.ID())
+	assert.False(t, has, "addition to protected cluster was not held")
+
+	t.Run("removal", func(t *testing.T) {
+		allowed, proposed := SplitProtected(current, NewDeployments(open), state.Defs.Clusters)
+		require.Len(t, proposed, 1)
+		assert.Nil(t, proposed[0].Deployment)
+		_, has := allowed.Get(dep.ID())
+		assert.True(t, has, "removal from protected cluster was not held")
+	})
+}
+
+func TestPendingChange_CheckReview(t *testing.T) {
+	state, dep := approvalFixture()
+	author := User{Name: "Author", Email: "author@example.com"}
+	pc := NewPendingChange(author, []ProposedDeployment{{DeploymentID: dep.ID(), Deployment: dep}}, time.Now())
+
+	assert.Error(t, pc.CheckReview(author, PendingStatusApproved, state), "author approved own change")
+	assert.NoError(t, pc.CheckReview(User{Email: "AUTHOR@example.com"}, PendingStatusRejected, state))
+
+	assert.NoError(t, pc.CheckReview(User{Email: "owner@example.com"}, PendingStatusApproved, state))
+	assert.NoError(t, pc.CheckReview(User{Email: "approver@example.com"}, PendingStatusApproved, state))
+
+	assert.Error(t, pc.CheckReview(User{Email: "someone@example.com"}, PendingStatusApproved, state))
+	assert.Error(t, pc.CheckReview(User{}, PendingStatusRejected, state))
+	assert.Error(t, pc.CheckReview(User{Email: "owner@example.com"}, PendingStatusPending, state))
+}
+
+func TestPendingChange_CheckCurrent(t *testing.T) {
+	state, dep := approvalFixture()
+	changed := dep.Clone()
+	changed.NumInstances++
+	_, proposed := SplitProtected(NewDeployments(dep), NewDeployments(changed), state.Defs.Clusters)
+	require.Len(t, proposed, 1)
+	require.NotNil(t, proposed[0].Prior)
+	pc := NewPendingChange(User{Name: "Author"}, proposed, time.Now())
+
+	assert.NoError(t, pc.CheckCurrent(NewDeployments(dep)))
+
+	meanwhile := dep.Clone()
+	meanwhile.SourceID.Version = semv.MustParse("9.9.9")
+	err := pc.CheckCurrent(NewDeployments(meanwhile))
+	require.IsType(t, &StaleChangeError{}, err)
+	assert.Equal(t, dep.ID(), err.(*StaleChangeError).DeploymentID)
+
+	paused := dep.Clone()
+	paused.Pause = &Pause{Reason: "incident"}
+	assert.Error(t, pc.CheckCurrent(NewDeployments(paused)), "a policy change should make the change stale")
+
+	assert.Error(t, pc.CheckCurrent(NewDeployments()), "removing the deployment should make the change stale")
+}
+
+func TestPendingChange_Intended(t *testing.T) {
+	_, dep := approvalFixture()
+	other := dep.Clone()
+	other.Flavor = "other"
+	changed := dep.Clone()
+	changed.NumInstances++
+
+	pc := NewPendingChange(User{}, []ProposedDeployment{
+		{DeploymentID: changed.ID(), Deployment: changed},
+		{DeploymentID: other.ID()},
+	}, time.Now())
+	current := NewDeployments(dep, other)
+
+	intended := pc.Intended(current)
+	got, has := intended.Get(dep.ID())
+	require.True(t, has)
+	assert.Equal(t, changed.NumInstances, got.NumInstances)
+	_, has = intended.Get(other.ID())
+	assert.False(t, has)
+	assert.Equal(t, 2, current.Len(), "current deployments were modified")
+
+	assert.Len(t, pc.Changes(current), 2)
+}
+
+func TestMemoryPendingChangeStore(t *testing.T) {
+	_, dep := approvalFixture()
+	store := NewMemoryPendingChangeStore()
+	pc := NewPendingChange(User{Email: "author@example.com"}, []ProposedDeployment{{DeploymentID: dep.ID(), Deployment: dep}}, time.Now())
+
+	require.NoError(t, store.ProposeChange(pc))
+	assert.Error(t, store.ProposeChange(pc))
+
+	pending, err := store.ListPendingChanges()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, pc.ID, pending[0].ID)
+
+	reviewer := User{Email: "owner@example.com"}
+	require.NoError(t, store.ReviewPendingChange(pc.ID, PendingStatusRejected, reviewer, time.Now()))
+	assert.Error(t, store.ReviewPendingChange(pc.ID, PendingStatusApproved, reviewer, time.Now()))
+	assert.Error(t, store.ReviewPendingChange("missing", PendingStatusApproved, reviewer, time.Now()))
+
+	read, err := store.ReadPendingChange(pc.ID)
+	require.NoError(t, err)
+	require.NotNil(t, read)
+	assert.Equal(t, PendingStatusRejected, read.Status)
+	assert.Equal(t, reviewer, read.Reviewer)
+
+	pending, err = store.ListPendingChanges()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	read, err = store.ReadPendingChange("missing")
+	assert.NoError(t, err)
+	assert.Nil(t, read)
+}
+
+func TestPendingApprovalFromLocation(t *testing.T) {
+	assert.Nil(t, PendingApprovalFromLocation(""))
+	assert.Nil(t, PendingApprovalFromLocation("/gdm"))
+	assert.Nil(t, PendingApprovalFromLocation("/pending-change"))
+
+	err := PendingApprovalFromLocation("http://sous.example.com/pending-change?id=abc")
+	require.NotNil(t, err)
+	assert.Equal(t, PendingChangeID("abc"), err.ID)
+}
@@ -31,6 +31,24 @@ type (
 		sync.RWMutex
 		stableStatus, liveStatus *ResolveStatus
 		currentRecorder          *ResolveRecorder
+		// DeploymentManager is used to write automatic rollbacks to the GDM.
+		// If it is nil, no rollbacks are performed.
+		DeploymentManager DeploymentManager
+		// LastGood records the last version of each deployment seen to be
+		// stable, as the target of automatic rollbacks. If it is nil, they
+		// are held in memory only.
+		LastGood LastGoodStore
+		// lastGood caches the versions recorded in LastGood, so that they
+		// are only written when they change.
+		lastGood     map[DeploymentID]SourceID
+		lastGoodLock sync.Mutex
+		// Leadership decides which clusters this server rectifies, when
+		// several servers share them. If it is nil, every cluster passing
+		// the ResolveFilter is rectified.
+		Leadership *Leadership
+		// StateChanges, if not nil, receives when the state is written, so
+		// that a resolution begins without waiting for UpdateTime.
+		StateChanges <-chan struct{}
 	}
 )
 
@@ -46,6 +64,7 @@ func NewAutoResolver(rez *Resolver, sr StateReader, ls logging.LogSink) *AutoRes
 		StateReader: sr,
 		LogSink:     ls,
 		listeners:   make([]autoResolveListener, 0),
+		lastGood:    map[DeploymentID]SourceID{},
 	}
 	ar.StandardListeners()
 	return ar
@@ -177,23 +196,50 @@ func (ar *AutoResolver) resolveOnce(ac announceChannel) {
 		return
 	}
 
+	intended, clusters := ar.leading(ar.GDM, state.Defs.Clusters)
+
 	ar.write(func() {
-		ar.currentRecorder = ar.Resolver.Begin(ar.GDM, state.Defs.Clusters)
+		ar.currentRecorder = ar.Resolver.Begin(intended, clusters, state.Defs.FreezeWindows)
 	})
 	defer ar.write(func() {
 		ar.currentRecorder = nil
 	})
 	ac <- ar.currentRecorder.Wait()
+	ss := ar.currentRecorder.CurrentStatus()
+	ar.rollbackFailures(intended, state.Defs.FreezeWindows, &ss)
 	ar.write(func() {
-		ss := ar.currentRecorder.CurrentStatus()
 
 		reportResolverStatus(ar.LogSink, &ss)
 
 		ar.stableStatus = &ss
 	})
+	completed := ss
+	ar.Resolver.Events.Publish(ResolveEvent{Kind: ResolveCompletedEvent, Status: &completed})
 	ar.Statuses() // XXX this is debugging
 }
 
+// leading returns the clusters this server leads, and the intended
+// deployments to them. Deployments to clusters led by other servers are left
+// out, lest they be taken as new deployments to create.
+func (ar *AutoResolver) leading(gdm Deployments, clusters Clusters) (Deployments, Clusters) {
+	if ar.Leadership == nil {
+		return gdm, clusters
+	}
+	candidates := ar.Resolver.FilteredClusters(clusters)
+	ar.Leadership.Contend(candidates.Names()...)
+	led := Clusters{}
+	for name, cluster := range candidates {
+		if ar.Leadership.Leads(name) {
+			led[name] = cluster
+		}
+	}
+	logging.ReportMsg(ar.LogSink, logging.DebugLevel, fmt.Sprintf("Leading clusters %v of %v", led.Names(), candidates.Names()))
+	return gdm.Filter(func(d *Deployment) bool {
+		_, ok := led[d.ClusterName]
+		return ok
+	}), led
+}
+
 func (ar *AutoResolver) afterDone(tc, done TriggerChannel, ac announceChannel) {
 	select {
 	case <-done:
@@ -204,6 +250,8 @@ func (ar *AutoResolver) afterDone(tc, done TriggerChannel, ac announceChannel) {
 	case <-done:
 		return
 	case <-time.After(ar.UpdateTime):
+	case <-ar.StateChanges:
+		logging.ReportMsg(ar.LogSink, logging.DebugLevel, "State written: resolving")
 	}
 	tc.trigger()
 }
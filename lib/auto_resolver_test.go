@@ -75,6 +75,26 @@ func TestAfterDone(t *testing.T) {
 	}
 }
 
+func TestAfterDone_StateChanges(t *testing.T) {
+	ar := setupAR()
+	ar.UpdateTime = time.Hour
+	changes := make(chan struct{}, 1)
+	ar.StateChanges = changes
+
+	tc := make(TriggerChannel, 1)
+	ac := make(announceChannel, 1)
+	done := make(TriggerChannel, 1)
+
+	ac <- nil
+	changes <- struct{}{}
+	ar.afterDone(tc, done, ac)
+	select {
+	case <-tc:
+	default:
+		t.Error("Trigger channel not triggered by a change to the state")
+	}
+}
+
 func TestResolveLoop(t *testing.T) {
 	ar := setupAR()
 
@@ -35,8 +35,11 @@ func (cm clusterManagerSpy) WriteCluster(clusterName string, deps Deployments, u
 }
 
 // MakeClusterManager wraps a StateManager in a ClusterManager. This is the easy way to get a ClusterManager;
-// It's assumed that more effecient ClusterManager implementations could be added to specific StateManagers.
+// StateManagers which implement ClusterManager more efficiently themselves are returned as is.
 func MakeClusterManager(sm StateManager) ClusterManager {
+	if cm, is := sm.(ClusterManager); is {
+		return cm
+	}
 	return &clusterManagerDecorator{sm: sm}
 }
 
@@ -10,6 +10,10 @@ const ClusterNameLabel = "com.opentable.sous.clustername"
 // SingularityDeployMetadataFlavor defines the namespace for storing a Sous Flavor in SingularityDeploy metadata.
 const FlavorLabel = "com.opentable.sous.flavor"
 
+// KindLabel is the metadata fieldname that records the ManifestKind of a
+// deployment, where the executor cannot otherwise distinguish it.
+const KindLabel = "com.opentable.sous.kind"
+
 // RepoLabel is the metadata fieldname that records the version control repository URL of a Sous-controlled service.
 const RepoLabel = "com.opentable.sous.repo_url"
 
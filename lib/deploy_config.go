@@ -21,7 +21,8 @@ type (
 		// Env is a list of environment variables to set for each instance of
 		// of this deployment. It will be checked for conflict with the
 		// definitions found in State.Defs.EnvVars, and if not in conflict
-		// assumes the greatest priority.
+		// assumes the greatest priority. Values may be SecretRefs, which must
+		// be declared in State.Defs.Secrets.
 		Env `yaml:",omitempty" validate:"keys=nonempty,values=nonempty"`
 
 		// No manifest uses this, it doesn't get sent to Singularity. If we want it we should bring it back.
@@ -35,8 +36,29 @@ type (
 		Volumes Volumes
 		// Startup containts healthcheck options for this deploy.
 		Startup Startup `yaml:",omitempty"`
+		// Placement constrains the hosts this deploy's instances run on.
+		Placement Placement `yaml:",omitempty"`
 		// Schedule is a cronjob-format schedule for jobs.
 		Schedule string
+		// ScheduleTimeZone is the time zone in which Schedule is interpreted,
+		// e.g. "America/Los_Angeles". Empty means UTC.
+		ScheduleTimeZone string `yaml:",omitempty"`
+		// MaxExecutionSeconds limits how long each run of a scheduled job may
+		// take before it is killed. Zero means no limit.
+		MaxExecutionSeconds int `yaml:",omitempty"`
+		// RetriesOnFailure is the number of times a failed run of a scheduled
+		// job is retried before the next scheduled run.
+		RetriesOnFailure int `yaml:",omitempty"`
+		// Rollout describes how changes to this deployment are rolled out. It
+		// is an instruction to Sous rather than part of the deployment itself,
+		// so it is not considered by Diff.
+		Rollout Rollout `yaml:",omitempty"`
+		// FreezeOverride records that this deployment was deliberately changed
+		// during a freeze. Like Rollout, it is not considered by Diff.
+		FreezeOverride *FreezeOverride `yaml:",omitempty" json:",omitempty"`
+		// Pause, while it is in effect, stops Sous from rectifying this
+		// deployment. Like Rollout, it is not considered by Diff.
+		Pause *Pause `yaml:",omitempty" json:",omitempty"`
 	}
 
 	// A DeployConfigs is a map from cluster name to DeployConfig
@@ -76,7 +98,13 @@ func (dc *DeployConfig) Validate() []Flaw {
 
 	flaws = append(flaws, rezs.Validate()...)
 
+	if _, err := dc.Env.SecretRefs(); err != nil {
+		flaws = append(flaws, FatalFlaw("invalid secret reference: %s", err))
+	}
+
 	flaws = append(flaws, dc.Startup.Validate()...)
+	flaws = append(flaws, dc.Placement.Validate()...)
+	flaws = append(flaws, dc.Rollout.Validate()...)
 
 	for _, f := range flaws {
 		f.AddContext("deploy config", dc)
@@ -150,6 +178,7 @@ func (dc *DeployConfig) Diff(o DeployConfig) (bool, []string) {
 		}
 	}
 	diffs = append(diffs, dc.Startup.diff(o.Startup)...)
+	diffs = append(diffs, dc.Placement.diff(o.Placement)...)
 	// TODO: Compare Args
 	return len(diffs) == 0, diffs
 }
@@ -173,7 +202,20 @@ func (dc DeployConfig) Clone() (c DeployConfig) {
 	}
 	c.Volumes = dc.Volumes.Clone()
 	c.Startup = dc.Startup
+	c.Placement = dc.Placement.Clone()
 	c.Schedule = dc.Schedule
+	c.ScheduleTimeZone = dc.ScheduleTimeZone
+	c.MaxExecutionSeconds = dc.MaxExecutionSeconds
+	c.RetriesOnFailure = dc.RetriesOnFailure
+	c.Rollout = dc.Rollout.Clone()
+	if dc.FreezeOverride != nil {
+		fo := *dc.FreezeOverride
+		c.FreezeOverride = &fo
+	}
+	if dc.Pause != nil {
+		p := *dc.Pause
+		c.Pause = &p
+	}
 
 	return
 }
@@ -232,25 +274,40 @@ func flattenDeployConfigs(dcs []DeployConfig) DeployConfig {
 		Env:       make(Env),
 		Metadata:  make(Metadata),
 	}
+	// Each field is taken from the first of dcs that sets it.
 	for _, c := range dcs {
-		if c.NumInstances != 0 {
+		if dc.NumInstances == 0 {
 			dc.NumInstances = c.NumInstances
-			break
 		}
-	}
-	for _, c := range dcs {
-		if len(c.Volumes) != 0 {
+		if len(dc.Volumes) == 0 && len(c.Volumes) != 0 {
 			dc.Volumes = c.Volumes
-			break
 		}
-	}
-	for _, c := range dcs {
-		if c.Schedule != "" {
+		if dc.Schedule == "" {
 			dc.Schedule = c.Schedule
-			break
 		}
-	}
-	for _, c := range dcs {
+		if dc.ScheduleTimeZone == "" {
+			dc.ScheduleTimeZone = c.ScheduleTimeZone
+		}
+		if dc.MaxExecutionSeconds == 0 {
+			dc.MaxExecutionSeconds = c.MaxExecutionSeconds
+		}
+		if dc.RetriesOnFailure == 0 {
+			dc.RetriesOnFailure = c.RetriesOnFailure
+		}
+		if dc.Placement.IsZero() && !c.Placement.IsZero() {
+			dc.Placement = c.Placement.Clone()
+		}
+		if !dc.Rollout.Staged() && c.Rollout.Staged() {
+			dc.Rollout = c.Rollout.Clone()
+		}
+		if dc.FreezeOverride == nil && c.FreezeOverride != nil {
+			fo := *c.FreezeOverride
+			dc.FreezeOverride = &fo
+		}
+		if dc.Pause == nil && c.Pause != nil {
+			p := *c.Pause
+			dc.Pause = &p
+		}
 		for n, v := range c.Resources {
 			if _, set := dc.Resources[n]; !set {
 				dc.Resources[n] = v
@@ -267,7 +324,7 @@ func flattenDeployConfigs(dcs []DeployConfig) DeployConfig {
 			}
 		}
 
-		dc.Startup = c.Startup
+		dc.Startup = c.Startup.MergeDefaults(dc.Startup)
 	}
 	return dc
 }
@@ -54,6 +54,13 @@ func (dp *DeployablePair) Diffs() Differences {
 	return diffs
 }
 
+// IntendedDiffs returns the differences from Prior to Post, including those
+// in how Sous manages the deployment (see Deployment.PolicyDiff). Only pairs
+// of intended deployments differ in those.
+func (dp *DeployablePair) IntendedDiffs() Differences {
+	return append(dp.Diffs(), dp.Prior.Deployment.PolicyDiff(dp.Post.Deployment)...)
+}
+
 // SameResolution returns a DiffResolution indicating that there is no intended
 // change. The deployment may either be stable or in the process of being
 // deployed.
@@ -73,3 +80,14 @@ func (dp *DeployablePair) SameResolution() DiffResolution {
 		Error:        err,
 	}
 }
+
+// canaryAt returns the canary deployment of dp.Post for stage: a copy of
+// dp.Post, identified by CanaryID, running stage.Instances instances.
+func (dp *DeployablePair) canaryAt(stage RolloutStage) *Deployable {
+	canary := *dp.Post
+	dep := canary.Deployment.Clone()
+	dep.Flavor = CanaryID(dp.ID()).ManifestID.Flavor
+	dep.NumInstances = stage.Instances
+	canary.Deployment = dep
+	return &canary
+}
@@ -0,0 +1,111 @@
+package sous
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ClusterKindSingularity is the Cluster.Kind for Singularity clusters. It
+	// is also assumed for clusters which do not specify a Kind.
+	ClusterKindSingularity = "singularity"
+	// ClusterKindKubernetes is the Cluster.Kind for Kubernetes clusters.
+	ClusterKindKubernetes = "kubernetes"
+)
+
+type (
+	// DeployerSet is a Deployer which dispatches to other Deployers according
+	// to the Kind of the cluster each deployment targets.
+	DeployerSet struct {
+		deployers map[string]Deployer
+		sync.RWMutex
+		// clusterKinds records the kind of each cluster seen by
+		// RunningDeployments, so that removals (which carry no Post deployment)
+		// can be routed.
+		clusterKinds map[string]string
+	}
+)
+
+// NormalizedKind returns the Kind of this cluster, defaulting to
+// ClusterKindSingularity.
+func (c *Cluster) NormalizedKind() string {
+	if c == nil || c.Kind == "" {
+		return ClusterKindSingularity
+	}
+	return c.Kind
+}
+
+// NewDeployerSet returns a DeployerSet which uses the deployers given, keyed by
+// cluster kind.
+func NewDeployerSet(deployers map[string]Deployer) *DeployerSet {
+	ds := &DeployerSet{
+		deployers:    map[string]Deployer{},
+		clusterKinds: map[string]string{},
+	}
+	for k, d := range deployers {
+		ds.deployers[k] = d
+	}
+	return ds
+}
+
+// RunningDeployments implements Deployer. It groups clusters by kind and
+// collects the running deployments from each kind's deployer.
+func (ds *DeployerSet) RunningDeployments(reg Registry, from Clusters) (DeployStates, error) {
+	byKind := map[string]Clusters{}
+	ds.Lock()
+	for name, c := range from {
+		kind := c.NormalizedKind()
+		ds.clusterKinds[name] = kind
+		if byKind[kind] == nil {
+			byKind[kind] = Clusters{}
+		}
+		byKind[kind][name] = c
+	}
+	ds.Unlock()
+
+	all := NewDeployStates()
+	for kind, clusters := range byKind {
+		d, ok := ds.deployers[kind]
+		if !ok {
+			return all, errors.Errorf("no deployer for cluster kind %q (clusters: %v)", kind, clusters.Names())
+		}
+		states, err := d.RunningDeployments(reg, clusters)
+		if err != nil {
+			return all, err
+		}
+		if conflict, ok := all.AddAll(states); !ok {
+			return all, errors.Errorf("deployment %q reported by more than one deployer", conflict)
+		}
+	}
+	return all, nil
+}
+
+// Rectify implements Deployer. It passes the pair to the deployer for the
+// kind of the pair's cluster.
+func (ds *DeployerSet) Rectify(pair *DeployablePair) DiffResolution {
+	kind := ds.kindFor(pair)
+	d, ok := ds.deployers[kind]
+	if !ok {
+		return DiffResolution{
+			DeploymentID: pair.ID(),
+			Desc:         "not rectified",
+			Error:        WrapResolveError(errors.Errorf("no deployer for cluster kind %q", kind)),
+		}
+	}
+	return d.Rectify(pair)
+}
+
+func (ds *DeployerSet) kindFor(pair *DeployablePair) string {
+	for _, d := range []*Deployable{pair.Post, pair.Prior} {
+		if d != nil && d.Deployment != nil && d.Deployment.Cluster != nil {
+			return d.Deployment.Cluster.NormalizedKind()
+		}
+	}
+	ds.RLock()
+	defer ds.RUnlock()
+	if kind, ok := ds.clusterKinds[pair.ID().Cluster]; ok {
+		return kind
+	}
+	return ClusterKindSingularity
+}
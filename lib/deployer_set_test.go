@@ -0,0 +1,69 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type kindDeployer struct {
+	states    DeployStates
+	clusters  Clusters
+	rectified []DeploymentID
+}
+
+func (kd *kindDeployer) RunningDeployments(reg Registry, from Clusters) (DeployStates, error) {
+	kd.clusters = from
+	return kd.states, nil
+}
+
+func (kd *kindDeployer) Rectify(pair *DeployablePair) DiffResolution {
+	kd.rectified = append(kd.rectified, pair.ID())
+	return DiffResolution{DeploymentID: pair.ID()}
+}
+
+func TestDeployerSet(t *testing.T) {
+	sing := &Cluster{Name: "sing"}
+	kube := &Cluster{Name: "kube", Kind: ClusterKindKubernetes}
+	clusters := Clusters{"sing": sing, "kube": kube}
+
+	singState := &DeployState{Deployment: Deployment{ClusterName: "sing", Cluster: sing}}
+	kubeState := &DeployState{Deployment: Deployment{ClusterName: "kube", Cluster: kube}}
+
+	sd := &kindDeployer{states: NewDeployStates(singState)}
+	kd := &kindDeployer{states: NewDeployStates(kubeState)}
+	ds := NewDeployerSet(map[string]Deployer{
+		ClusterKindSingularity: sd,
+		ClusterKindKubernetes:  kd,
+	})
+
+	states, err := ds.RunningDeployments(nil, clusters)
+	require.NoError(t, err)
+	assert.Equal(t, 2, states.Len())
+	assert.Equal(t, Clusters{"sing": sing}, sd.clusters)
+	assert.Equal(t, Clusters{"kube": kube}, kd.clusters)
+
+	ds.Rectify(&DeployablePair{Post: &Deployable{Deployment: &kubeState.Deployment}})
+	ds.Rectify(&DeployablePair{Post: &Deployable{Deployment: &singState.Deployment}})
+	// A removal whose deployment has lost its Cluster is routed by name.
+	removal := &DeployablePair{Prior: &Deployable{Deployment: &Deployment{ClusterName: "kube"}}}
+	removal.SetID(DeploymentID{Cluster: "kube"})
+	ds.Rectify(removal)
+
+	assert.Len(t, sd.rectified, 1)
+	assert.Len(t, kd.rectified, 2)
+}
+
+func TestDeployerSet_UnknownKind(t *testing.T) {
+	ds := NewDeployerSet(map[string]Deployer{ClusterKindSingularity: NewDummyDeployer()})
+
+	_, err := ds.RunningDeployments(nil, Clusters{"mesos": &Cluster{Name: "mesos", Kind: "marathon"}})
+	assert.Error(t, err)
+
+	rez := ds.Rectify(&DeployablePair{Post: &Deployable{Deployment: &Deployment{
+		ClusterName: "mesos",
+		Cluster:     &Cluster{Name: "mesos", Kind: "marathon"},
+	}}})
+	assert.NotNil(t, rez.Error)
+}
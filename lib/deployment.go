@@ -36,6 +36,8 @@ type (
 		Owners OwnerSet
 		// Kind is the kind of software that SourceRepo represents.
 		Kind ManifestKind
+		// AutoRollback is copied from the Manifest; see Manifest.AutoRollback.
+		AutoRollback bool `yaml:",omitempty"`
 	}
 )
 
@@ -93,6 +95,13 @@ func (d *Deployment) Validate() []Flaw {
 	cf := d.DeployConfig.Validate()
 	flaws = append(flaws, cf...)
 
+	if d.Kind.IsScheduled() {
+		flaws = append(flaws, d.DeployConfig.validateSchedule()...)
+	}
+	flaws = append(flaws, d.Startup.ValidateFor(d.Cluster)...)
+	flaws = append(flaws, d.Placement.ValidateFor(d.Cluster)...)
+	flaws = append(flaws, d.Env.ValidateFor(d.Cluster)...)
+
 	for _, f := range flaws {
 		f.AddContext("deployment", d)
 		f.AddContext("cluster", d.ClusterName)
@@ -208,10 +217,19 @@ func (d *Deployment) Diff(o *Deployment) (bool, Differences) {
 	}
 
 	// Schedule is only significant for Scheduled Jobs
-	if d.Kind == ManifestKindScheduled {
+	if d.Kind.IsScheduled() {
 		if d.Schedule != o.Schedule {
 			diff("schedule; this: %q, other: %q", d.Schedule, o.Schedule)
 		}
+		if d.ScheduleTimeZone != o.ScheduleTimeZone {
+			diff("schedule time zone; this: %q, other: %q", d.ScheduleTimeZone, o.ScheduleTimeZone)
+		}
+		if d.MaxExecutionSeconds != o.MaxExecutionSeconds {
+			diff("max execution seconds; this: %d, other: %d", d.MaxExecutionSeconds, o.MaxExecutionSeconds)
+		}
+		if d.RetriesOnFailure != o.RetriesOnFailure {
+			diff("retries on failure; this: %d, other: %d", d.RetriesOnFailure, o.RetriesOnFailure)
+		}
 	}
 
 	if len(d.Owners) != len(o.Owners) {
@@ -227,3 +245,26 @@ func (d *Deployment) Diff(o *Deployment) (bool, Differences) {
 	diffs = append(diffs, configDiffs...)
 	return len(diffs) != 0, diffs
 }
+
+// PolicyDiff returns the differences between d and o in how Sous manages
+// them: their Rollout, Pause, FreezeOverride and AutoRollback. These are left
+// out of Diff, since they are not part of a running deployment, and changing
+// them alone must not cause it to be rectified; but they are changes to the
+// GDM, and are recorded in its history.
+func (d *Deployment) PolicyDiff(o *Deployment) Differences {
+	var diffs Differences
+	diff := func(format string, a ...interface{}) { diffs = append(diffs, fmt.Sprintf(format, a...)) }
+	if !d.Rollout.Equal(o.Rollout) {
+		diff("rollout; this: %+v; other: %+v", d.Rollout, o.Rollout)
+	}
+	if !d.Pause.Equal(o.Pause) {
+		diff("pause; this: %v; other: %v", d.Pause, o.Pause)
+	}
+	if !d.FreezeOverride.Equal(o.FreezeOverride) {
+		diff("freeze override; this: %v; other: %v", d.FreezeOverride, o.FreezeOverride)
+	}
+	if d.AutoRollback != o.AutoRollback {
+		diff("auto rollback; this: %t; other: %t", d.AutoRollback, o.AutoRollback)
+	}
+	return diffs
+}
@@ -161,10 +161,12 @@ func (d *differ) diff(existing Deployments) {
 			}
 			continue
 		}
+		// Post is the existing deployment, so that differences Diff ignores,
+		// in how Sous manages it, are still seen; see PolicyDiff.
 		d.Pairs <- &DeployablePair{
 			name:  id,
 			Prior: &Deployable{Deployment: &intendedDeployment.Deployment, Status: intendedDeployment.Status},
-			Post:  &Deployable{Deployment: &intendedDeployment.Deployment, Status: intendedDeployment.Status},
+			Post:  &Deployable{Deployment: existingDeployment, Status: intendedDeployment.Status},
 		}
 	}
 
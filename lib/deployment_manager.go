@@ -43,8 +43,12 @@ func (spy deploymentManagerSpy) WriteDeployment(dep *Deployment, user User) erro
 	return res.Error(0)
 }
 
-// MakeDeploymentManager wraps a StateManager such that it fulfills the DeploymentManager interface
+// MakeDeploymentManager wraps a StateManager such that it fulfills the DeploymentManager interface.
+// If sm is already a DeploymentManager, it is returned as is.
 func MakeDeploymentManager(sm StateManager) DeploymentManager {
+	if dm, is := sm.(DeploymentManager); is {
+		return dm
+	}
 	return &deploymentManagerDecorator{StateManager: sm}
 }
 
@@ -74,11 +78,9 @@ func (dm *deploymentManagerDecorator) WriteDeployment(dep *Deployment, user User
 		return err
 	}
 
-	deps, err := state.Deployments()
-	if err != nil {
+	if err := state.UpdateDeployments(dep); err != nil {
 		return err
 	}
 
-	deps.Add(dep)
-	return nil
+	return dm.WriteState(state, user)
 }
@@ -40,3 +40,59 @@ func TestDeploymentManager_ReadDeployment(t *testing.T) {
 		t.Errorf("ReadDeployment returned different deployment (diffs: %#v)", diffs)
 	}
 }
+
+func TestDeploymentManager_WriteDeployment(t *testing.T) {
+	dummy := &DummyStateManager{
+		State: DefaultStateFixture(),
+	}
+	dm := MakeDeploymentManager(dummy)
+
+	did := DeploymentID{
+		ManifestID: ManifestID{
+			Source: SourceLocation{
+				Repo: "github.com/user1/repo1",
+				Dir:  "dir1",
+			},
+			Flavor: "flavor1",
+		},
+		Cluster: "cluster1",
+	}
+	deployment, err := dm.ReadDeployment(did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment.NumInstances = 17
+
+	if err := dm.WriteDeployment(deployment, User{Name: "Test User"}); err != nil {
+		t.Fatal(err)
+	}
+	if dummy.WriteCount != 1 {
+		t.Errorf("got %d state writes, want 1", dummy.WriteCount)
+	}
+
+	written, err := dm.ReadDeployment(did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written.NumInstances != 17 {
+		t.Errorf("got NumInstances %d after write, want 17", written.NumInstances)
+	}
+}
+
+// nativeDeploymentManager is a StateManager which also reads and writes
+// individual Deployments itself.
+type nativeDeploymentManager struct {
+	*DummyStateManager
+	DeploymentManager
+}
+
+func TestMakeDeploymentManager_native(t *testing.T) {
+	dm, _ := NewDeploymentManagerSpy()
+	native := nativeDeploymentManager{
+		DummyStateManager: &DummyStateManager{State: DefaultStateFixture()},
+		DeploymentManager: dm,
+	}
+	if got := MakeDeploymentManager(native); got != DeploymentManager(native) {
+		t.Errorf("got %#v, want the StateManager itself", got)
+	}
+}
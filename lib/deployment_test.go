@@ -19,6 +19,9 @@ func TestDeploymentDiffAnalysis(t *testing.T) {
 		"Deployment.Cluster.BaseURL",
 		"Deployment.Cluster.Env",
 		"Deployment.Cluster.AllowedAdvisories",
+		"Deployment.Cluster.RequireApproval",
+		"Deployment.Cluster.Approvers",
+		"Deployment.Cluster.Writers",
 		"Deployment.Cluster.Startup",
 		"Deployment.Cluster.Startup.SkipCheck",
 		"Deployment.Cluster.Startup.CheckReadyURIPath",
@@ -31,6 +34,56 @@ func TestDeploymentDiffAnalysis(t *testing.T) {
 		"Deployment.Cluster.Startup.CheckReadyInterval",
 		"Deployment.Cluster.Startup.ConnectDelay",
 		"Deployment.Cluster.Startup.CheckReadyPortIndex",
+		"Deployment.Cluster.Startup.CheckReadyCommand",
+		// Rollout says how to apply changes, and isn't part of the deployment.
+		"Deployment.DeployConfig.Rollout",
+		"Deployment.DeployConfig.Rollout.Strategy",
+		"Deployment.DeployConfig.Rollout.CanaryInstances",
+		"Deployment.DeployConfig.Rollout.Steps",
+		"Deployment.DeployConfig.Rollout.PauseSeconds",
+		"Deployment.DeployConfig.Rollout.HealthGate",
+		"Deployment.DeployConfig.Rollout.GateTimeoutSeconds",
+		"Deployment.Rollout",
+		"Deployment.Rollout.Strategy",
+		"Deployment.Rollout.CanaryInstances",
+		"Deployment.Rollout.Steps",
+		"Deployment.Rollout.PauseSeconds",
+		"Deployment.Rollout.HealthGate",
+		"Deployment.Rollout.GateTimeoutSeconds",
+		// FreezeOverride is an audit record, and isn't part of the deployment.
+		"Deployment.DeployConfig.FreezeOverride",
+		"Deployment.DeployConfig.FreezeOverride.User",
+		"Deployment.DeployConfig.FreezeOverride.User.Name",
+		"Deployment.DeployConfig.FreezeOverride.User.Email",
+		"Deployment.DeployConfig.FreezeOverride.User.Token",
+		"Deployment.DeployConfig.FreezeOverride.Reason",
+		"Deployment.DeployConfig.FreezeOverride.At",
+		"Deployment.FreezeOverride",
+		"Deployment.FreezeOverride.User",
+		"Deployment.FreezeOverride.User.Name",
+		"Deployment.FreezeOverride.User.Email",
+		"Deployment.FreezeOverride.User.Token",
+		"Deployment.FreezeOverride.Reason",
+		"Deployment.FreezeOverride.At",
+		// Pause is an instruction to Sous, and isn't part of the deployment.
+		"Deployment.DeployConfig.Pause",
+		"Deployment.DeployConfig.Pause.User",
+		"Deployment.DeployConfig.Pause.User.Name",
+		"Deployment.DeployConfig.Pause.User.Email",
+		"Deployment.DeployConfig.Pause.User.Token",
+		"Deployment.DeployConfig.Pause.Reason",
+		"Deployment.DeployConfig.Pause.At",
+		"Deployment.DeployConfig.Pause.Until",
+		"Deployment.Pause",
+		"Deployment.Pause.User",
+		"Deployment.Pause.User.Name",
+		"Deployment.Pause.User.Email",
+		"Deployment.Pause.User.Token",
+		"Deployment.Pause.Reason",
+		"Deployment.Pause.At",
+		"Deployment.Pause.Until",
+		// AutoRollback is a policy for Sous, not part of the deployment.
+		"Deployment.AutoRollback",
 		// SourceID.Location is incorporated into the value of ID(),
 		// is is compared directly - Repo and Dir are compared implicitly thereby
 		"Deployment.SourceID.Location.Repo",
@@ -153,7 +206,7 @@ func TestDeployment_String(t *testing.T) {
 			in: &Deployment{},
 			// Current value of want reflects current reality.
 			// I think we can do better than this representation...
-			want: ",0.0.0 \"\" @ <unknown> #0 {false 0 0 0   0 <nil> 0 0 0} map[] : map[] []",
+			want: ",0.0.0 \"\" @ <unknown> #0 {false 0 0 0   0 <nil> 0 0 0 <nil>} map[] : map[] []",
 		},
 	}
 	for name, tc := range testCases {
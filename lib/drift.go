@@ -0,0 +1,118 @@
+package sous
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/opentable/sous/util/logging"
+)
+
+type (
+	// A DriftReport lists the ways the deployments running on a set of
+	// clusters differ from the GDM, found without rectifying them.
+	DriftReport struct {
+		// Clusters are the names of the clusters compared with the GDM.
+		Clusters []string
+		// Changes are the changes rectifying would make: "added" deployments
+		// are in the GDM but not running, "removed" deployments are running
+		// but not in the GDM, and "modified" deployments are running
+		// differently from the GDM.
+		Changes []DeploymentChange
+	}
+
+	// DriftCounts counts the drifted deployments of each kind in a cluster.
+	DriftCounts struct {
+		Added, Removed, Modified int
+	}
+
+	driftMessage struct {
+		logging.CallerInfo
+		report *DriftReport
+	}
+)
+
+// Drift compares the intended deployments with those running in clusters,
+// both narrowed by the Resolver's filter, and reports how they differ
+// without rectifying anything.
+func (r *Resolver) Drift(intended Deployments, clusters Clusters) (*DriftReport, error) {
+	intended = intended.Filter(r.FilterDeployment)
+	clusters = r.FilteredClusters(clusters)
+	actual, err := r.Deployer.RunningDeployments(r.Registry, clusters)
+	if err != nil {
+		return nil, err
+	}
+	actual = actual.Filter(r.FilterDeployStates)
+	report := newDriftReport(actual, intended, clusters)
+	reportDrift(r.ls, report)
+	return report, nil
+}
+
+func newDriftReport(actual DeployStates, intended Deployments, clusters Clusters) *DriftReport {
+	names := clusters.Names()
+	sort.Strings(names)
+	changes := DeploymentChanges(actual.Diff(intended).Collect())
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].DeploymentID.String() < changes[j].DeploymentID.String()
+	})
+	return &DriftReport{Clusters: names, Changes: changes}
+}
+
+// Counts returns the DriftCounts of each cluster in the report, including
+// those which have not drifted.
+func (dr *DriftReport) Counts() map[string]DriftCounts {
+	counts := make(map[string]DriftCounts, len(dr.Clusters))
+	for _, name := range dr.Clusters {
+		counts[name] = DriftCounts{}
+	}
+	for _, dc := range dr.Changes {
+		c := counts[dc.DeploymentID.Cluster]
+		switch dc.Kind {
+		case AddedKind.String():
+			c.Added++
+		case RemovedKind.String():
+			c.Removed++
+		case ModifiedKind.String():
+			c.Modified++
+		}
+		counts[dc.DeploymentID.Cluster] = c
+	}
+	return counts
+}
+
+// Total returns the number of drifted deployments counted.
+func (c DriftCounts) Total() int {
+	return c.Added + c.Removed + c.Modified
+}
+
+func reportDrift(logger logging.LogSink, report *DriftReport) {
+	msg := driftMessage{
+		CallerInfo: logging.GetCallerInfo(logging.NotHere()),
+		report:     report,
+	}
+	logging.Deliver(msg, logger)
+}
+
+func (msg driftMessage) MetricsTo(m logging.MetricsSink) {
+	for cluster, c := range msg.report.Counts() {
+		m.UpdateSample("drift."+cluster+".added", int64(c.Added))
+		m.UpdateSample("drift."+cluster+".removed", int64(c.Removed))
+		m.UpdateSample("drift."+cluster+".modified", int64(c.Modified))
+	}
+}
+
+func (msg driftMessage) DefaultLevel() logging.Level {
+	if len(msg.report.Changes) > 0 {
+		return logging.InformationLevel
+	}
+	return logging.DebugLevel
+}
+
+func (msg driftMessage) Message() string {
+	return fmt.Sprintf("%d deployments drifted from the GDM", len(msg.report.Changes))
+}
+
+func (msg driftMessage) EachField(f logging.FieldReportFn) {
+	f("@loglov3-otl", "sous-generic-v1")
+	f("drift-count", len(msg.report.Changes))
+	msg.CallerInfo.EachField(f)
+}
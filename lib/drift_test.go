@@ -0,0 +1,73 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver_Drift(t *testing.T) {
+	east := &Cluster{Name: "east"}
+	west := &Cluster{Name: "west"}
+	clusters := Clusters{"east": east, "west": west}
+
+	dep := func(repo string, cluster *Cluster, instances int) *Deployment {
+		return &Deployment{
+			SourceID:     MustNewSourceID(repo, "", "1.0.0"),
+			ClusterName:  cluster.Name,
+			Cluster:      cluster,
+			DeployConfig: DeployConfig{NumInstances: instances},
+		}
+	}
+	running := func(d *Deployment) *DeployState {
+		return &DeployState{Deployment: *d, Status: DeployStatusActive}
+	}
+
+	intended := NewDeployments(
+		dep("github.com/example/same", east, 1),
+		dep("github.com/example/scaled", east, 3),
+		dep("github.com/example/missing", west, 1),
+	)
+	kd := &kindDeployer{states: NewDeployStates(
+		running(dep("github.com/example/same", east, 1)),
+		running(dep("github.com/example/scaled", east, 5)),
+		running(dep("github.com/example/manual", west, 1)),
+	)}
+
+	ls, control := logging.NewLogSinkSpy()
+	r := NewResolver(kd, NewDummyRegistry(), &ResolveFilter{}, ls, NewR11nQueueSet())
+
+	report, err := r.Drift(intended, clusters)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"east", "west"}, report.Clusters)
+	assert.Empty(t, kd.rectified)
+
+	kinds := map[string]string{}
+	for _, dc := range report.Changes {
+		kinds[dc.DeploymentID.ManifestID.Source.Repo] = dc.Kind
+		if dc.Kind == "modified" {
+			assert.Len(t, dc.Diffs, 1)
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"github.com/example/scaled":  "modified",
+		"github.com/example/missing": "added",
+		"github.com/example/manual":  "removed",
+	}, kinds)
+
+	assert.Equal(t, map[string]DriftCounts{
+		"east": {Modified: 1},
+		"west": {Added: 1, Removed: 1},
+	}, report.Counts())
+	assert.Len(t, control.Metrics.CallsTo("UpdateSample"), 6)
+
+	r.ResolveFilter = &ResolveFilter{Cluster: NewResolveFieldMatcher("east")}
+	report, err = r.Drift(intended, clusters)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"east"}, report.Clusters)
+	if assert.Len(t, report.Changes, 1) {
+		assert.Equal(t, "modified", report.Changes[0].Kind)
+	}
+}
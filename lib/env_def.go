@@ -0,0 +1,230 @@
+package sous
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The VarTypes of environment variables.
+const (
+	// VarTypeString allows any value. An empty Type means the same.
+	VarTypeString = VarType("string")
+	// VarTypeInt allows decimal integers.
+	VarTypeInt = VarType("int")
+	// VarTypeBool allows the values accepted by strconv.ParseBool.
+	VarTypeBool = VarType("bool")
+	// VarTypeURL allows absolute URLs.
+	VarTypeURL = VarType("url")
+	// VarTypeDuration allows durations like "1m30s".
+	VarTypeDuration = VarType("duration")
+	// VarTypeEnum allows the values listed in the EnvDef's Values.
+	VarTypeEnum = VarType("enum")
+	// VarTypeRegex allows values matching the EnvDef's Pattern.
+	VarTypeRegex = VarType("regex")
+)
+
+// The Scopes of environment variables.
+const (
+	// EnvScopeAny means a variable may be set by clusters and by manifests,
+	// whose values take precedence. An empty Scope means the same.
+	EnvScopeAny = "any"
+	// EnvScopeCluster means a variable may only be set in Cluster.Env.
+	// Manifests may not give it a different value.
+	EnvScopeCluster = "cluster"
+	// EnvScopeManifest means a variable may only be set by manifests, and
+	// not in Cluster.Env.
+	EnvScopeManifest = "manifest"
+)
+
+// Validate returns a Flaw for each EnvDef in evs which is itself invalid.
+func (evs EnvDefs) Validate() []Flaw {
+	var flaws []Flaw
+	seen := map[string]bool{}
+	for _, ev := range evs {
+		if seen[ev.Name] {
+			flaws = append(flaws, FatalFlaw("env var %s is defined more than once", ev.Name))
+		}
+		seen[ev.Name] = true
+		if err := ev.validate(); err != nil {
+			flaws = append(flaws, FatalFlaw("env var %s: %s", ev.Name, err))
+		}
+	}
+	return flaws
+}
+
+func (ev EnvDef) validate() error {
+	switch ev.Scope {
+	default:
+		return errors.Errorf("Scope %q is not %q, %q or %q", ev.Scope, EnvScopeAny, EnvScopeCluster, EnvScopeManifest)
+	case "", EnvScopeAny, EnvScopeCluster, EnvScopeManifest:
+	}
+	switch ev.Type {
+	default:
+		return errors.Errorf("unknown Type %q", ev.Type)
+	case "", VarTypeString, VarTypeInt, VarTypeBool, VarTypeURL, VarTypeDuration:
+	case VarTypeEnum:
+		if len(ev.Values) == 0 {
+			return errors.Errorf("Type %s requires Values", ev.Type)
+		}
+	case VarTypeRegex:
+		if _, err := ev.pattern(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ev EnvDef) pattern() (*regexp.Regexp, error) {
+	if ev.Pattern == "" {
+		return nil, errors.Errorf("Type %s requires a Pattern", ev.Type)
+	}
+	re, err := regexp.Compile("^(?:" + ev.Pattern + ")$")
+	if err != nil {
+		return nil, errors.Wrapf(err, "Pattern %q", ev.Pattern)
+	}
+	return re, nil
+}
+
+// Check returns an error if value is not a valid value of the variable ev
+// defines.
+func (ev EnvDef) Check(value string) error {
+	var err error
+	switch ev.Type {
+	default:
+		return ev.validate()
+	case "", VarTypeString:
+	case VarTypeInt:
+		_, err = strconv.Atoi(value)
+	case VarTypeBool:
+		_, err = strconv.ParseBool(value)
+	case VarTypeURL:
+		var u *url.URL
+		if u, err = url.Parse(value); err == nil && (u.Scheme == "" || u.Host == "") {
+			err = errors.New("not an absolute URL")
+		}
+	case VarTypeDuration:
+		_, err = time.ParseDuration(value)
+	case VarTypeEnum:
+		if !containsString(ev.Values, value) {
+			return errors.Errorf("%q is not one of %q", value, ev.Values)
+		}
+	case VarTypeRegex:
+		re, perr := ev.pattern()
+		if perr != nil {
+			return perr
+		}
+		if !re.MatchString(value) {
+			return errors.Errorf("%q does not match %q", value, ev.Pattern)
+		}
+	}
+	if err != nil {
+		return errors.Errorf("%q is not a valid %s", value, ev.Type)
+	}
+	return nil
+}
+
+// Get returns the definition of the variable called name, if there is one.
+func (evs EnvDefs) Get(name string) (EnvDef, bool) {
+	for _, ev := range evs {
+		if ev.Name == name {
+			return ev, true
+		}
+	}
+	return EnvDef{}, false
+}
+
+// Check returns a Flaw for each variable in d's Env which breaks its
+// definition in evs. Values inherited unchanged from d's Cluster are not
+// checked here; CheckCluster reports those once for the cluster. Variables
+// without a definition, and SecretRefs, are not checked.
+func (evs EnvDefs) Check(d *Deployment) []Flaw {
+	var flaws []Flaw
+	for _, name := range d.Env.names() {
+		value := d.Env[name]
+		ev, defined := evs.Get(name)
+		if !defined || IsSecretRef(value) {
+			continue
+		}
+		var clusterValue Var
+		var inherited bool
+		if d.Cluster != nil {
+			clusterValue, inherited = d.Cluster.Env[name]
+		}
+		if inherited && string(clusterValue) == value {
+			continue
+		}
+		if ev.Scope == EnvScopeCluster {
+			if inherited {
+				flaws = append(flaws, FatalFlaw("%s env %s: %q conflicts with the value %q set by cluster %s", d.ID(), name, value, clusterValue, d.ClusterName))
+			} else {
+				flaws = append(flaws, FatalFlaw("%s env %s: may only be set by clusters", d.ID(), name))
+			}
+			continue
+		}
+		if err := ev.Check(value); err != nil {
+			flaws = append(flaws, FatalFlaw("%s env %s: %s", d.ID(), name, err))
+		}
+	}
+	return flaws
+}
+
+// CheckCluster returns a Flaw for each variable in c's Env which breaks its
+// definition in evs.
+func (evs EnvDefs) CheckCluster(c *Cluster) []Flaw {
+	var flaws []Flaw
+	names := make([]string, 0, len(c.Env))
+	for name := range c.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ev, defined := evs.Get(name)
+		if !defined {
+			continue
+		}
+		if ev.Scope == EnvScopeManifest {
+			flaws = append(flaws, FatalFlaw("cluster %s env %s: may only be set by manifests", c.Name, name))
+			continue
+		}
+		if err := ev.Check(string(c.Env[name])); err != nil {
+			flaws = append(flaws, FatalFlaw("cluster %s env %s: %s", c.Name, name, err))
+		}
+	}
+	return flaws
+}
+
+// ValidateEnv returns a Flaw for every breach of s.Defs.EnvVars: by the
+// definitions themselves, by the clusters' Env defaults, and by the Env of
+// every deployment. Unlike Validate, it is not used when reading state, so
+// that existing breaches do not prevent Sous from working.
+func (s *State) ValidateEnv() []Flaw {
+	flaws := s.Defs.EnvVars.Validate()
+
+	clusterNames := s.Defs.Clusters.Names()
+	sort.Strings(clusterNames)
+	for _, name := range clusterNames {
+		c := s.Defs.Clusters[name]
+		if c.Name == "" {
+			c = c.Clone()
+			c.Name = name
+		}
+		flaws = append(flaws, s.Defs.EnvVars.CheckCluster(c)...)
+	}
+
+	ds, err := s.Deployments()
+	if err != nil {
+		return append(flaws, FatalFlaw("Cannot merge a set of deployments to validate: %v", err))
+	}
+	ids := DeploymentIDSlice(ds.Keys())
+	sort.Sort(ids)
+	for _, id := range ids {
+		d, _ := ds.Get(id)
+		flaws = append(flaws, s.Defs.EnvVars.Check(d)...)
+	}
+	return flaws
+}
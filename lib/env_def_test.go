@@ -0,0 +1,96 @@
+package sous
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnvDef_Check(t *testing.T) {
+	testCases := []struct {
+		def           EnvDef
+		valid, broken []string
+	}{
+		{EnvDef{}, []string{"", "anything"}, nil},
+		{EnvDef{Type: VarTypeInt}, []string{"0", "-12"}, []string{"", "1.5", "many"}},
+		{EnvDef{Type: VarTypeBool}, []string{"true", "0", "F"}, []string{"yes"}},
+		{EnvDef{Type: VarTypeURL}, []string{"https://example.com/x"}, []string{"example.com", "/x", "http://%zz"}},
+		{EnvDef{Type: VarTypeDuration}, []string{"10s", "1h30m"}, []string{"10"}},
+		{EnvDef{Type: VarTypeEnum, Values: []string{"debug", "info"}}, []string{"info"}, []string{"warn", ""}},
+		{EnvDef{Type: VarTypeRegex, Pattern: "[a-z]+"}, []string{"abc"}, []string{"abc1", ""}},
+		{EnvDef{Type: VarType("float")}, nil, []string{"1.5"}},
+	}
+	for _, tc := range testCases {
+		for _, v := range tc.valid {
+			if err := tc.def.Check(v); err != nil {
+				t.Errorf("%+v.Check(%q): %v", tc.def, v, err)
+			}
+		}
+		for _, v := range tc.broken {
+			if err := tc.def.Check(v); err == nil {
+				t.Errorf("%+v.Check(%q): no error", tc.def, v)
+			}
+		}
+	}
+}
+
+func TestEnvDefs_Validate(t *testing.T) {
+	evs := EnvDefs{
+		{Name: "OK", Scope: EnvScopeCluster, Type: VarTypeInt},
+		{Name: "OK", Type: VarTypeInt},
+		{Name: "SCOPE", Scope: "region"},
+		{Name: "TYPE", Type: "float"},
+		{Name: "ENUM", Type: VarTypeEnum},
+		{Name: "REGEX", Type: VarTypeRegex, Pattern: "("},
+	}
+	if flaws := evs.Validate(); len(flaws) != 5 {
+		t.Errorf("got flaws %v; want 5", flaws)
+	}
+}
+
+func TestState_ValidateEnv(t *testing.T) {
+	s := NewState()
+	s.Defs.Clusters = Clusters{
+		"one": &Cluster{Env: EnvDefaults{
+			"REGION":    "us-west",
+			"LOG_LEVEL": "loud",
+		}},
+		"two": &Cluster{Env: EnvDefaults{
+			"REGION":  "eu-west",
+			"WORKERS": "4",
+		}},
+	}
+	s.Defs.EnvVars = EnvDefs{
+		{Name: "REGION", Scope: EnvScopeCluster},
+		{Name: "LOG_LEVEL", Type: VarTypeEnum, Values: []string{"debug", "info"}},
+		{Name: "WORKERS", Scope: EnvScopeManifest, Type: VarTypeInt},
+		{Name: "TIMEOUT", Type: VarTypeDuration},
+	}
+	dc := func(env Env) DeploySpec {
+		return DeploySpec{DeployConfig: DeployConfig{Env: env}}
+	}
+	s.Manifests.Add(&Manifest{
+		Source: SourceLocation{Repo: "github.com/opentable/sous"},
+		Kind:   ManifestKindService,
+		Deployments: DeploySpecs{
+			"one": dc(Env{"REGION": "us-west", "WORKERS": "many", "TIMEOUT": "5s"}),
+			"two": dc(Env{"REGION": "elsewhere", "TIMEOUT": "5", "UNDEFINED": "x"}),
+		},
+	})
+
+	flaws := s.ValidateEnv()
+	want := []string{
+		"cluster one env LOG_LEVEL",
+		"cluster two env WORKERS",
+		"one:github.com/opentable/sous env WORKERS",
+		"two:github.com/opentable/sous env REGION",
+		"two:github.com/opentable/sous env TIMEOUT",
+	}
+	if len(flaws) != len(want) {
+		t.Fatalf("got flaws %v; want %d", flaws, len(want))
+	}
+	for i, f := range flaws {
+		if desc := f.(GenericFlaw).Desc; !strings.HasPrefix(desc, want[i]) {
+			t.Errorf("flaw %d: got %q; want it to begin %q", i, desc, want[i])
+		}
+	}
+}
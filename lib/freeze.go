@@ -0,0 +1,296 @@
+package sous
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	// FreezeWindows is a list of FreezeWindow.
+	FreezeWindows []FreezeWindow
+
+	// A FreezeWindow is a period during which deployments to some clusters
+	// must not change, e.g. a holiday freeze or an ongoing incident.
+	FreezeWindow struct {
+		// Start and End bound the freeze: it is in effect from Start up to,
+		// but not including, End.
+		Start, End time.Time
+		// Clusters lists the names of the clusters which are frozen. If it is
+		// empty, all clusters are frozen.
+		Clusters []string `yaml:",omitempty"`
+		// Exempt lists manifests which may still change during the freeze.
+		Exempt []ManifestID `yaml:",omitempty"`
+		// Reason explains the freeze to anyone whose change is rejected.
+		Reason string
+	}
+
+	// A FreezeOverride records that a deployment was deliberately changed
+	// during a freeze.
+	FreezeOverride struct {
+		// User is the user who overrode the freeze.
+		User User
+		// Reason explains why the freeze was overridden.
+		Reason string
+		// At is when the freeze was overridden.
+		At time.Time
+	}
+
+	// A FrozenError is returned when a change to a deployment is rejected
+	// because of a freeze.
+	FrozenError struct {
+		DeploymentID DeploymentID
+		Window       FreezeWindow
+	}
+
+	// A FreezeBaseline describes what was intended for frozen deployments
+	// before their freezes began. During a freeze, drift from that intent is
+	// still repaired, but changes to it are not rectified.
+	FreezeBaseline struct {
+		// Intents maps the ID of each frozen deployment to what was intended
+		// for it before its freeze.
+		Intents map[DeploymentID]*Deployment
+		// Since is when intent was first recorded. A deployment missing from
+		// Intents whose freeze began later was not intended before its
+		// freeze; otherwise, what was intended for it is unknown.
+		Since time.Time
+	}
+
+	freezeProcessor struct {
+		windows  FreezeWindows
+		baseline FreezeBaseline
+		at       time.Time
+		ls       logging.LogSink
+	}
+
+	// preFreezeIntents remembers what was intended for each deployment before
+	// its freeze began.
+	preFreezeIntents struct {
+		sync.Mutex
+		since   time.Time
+		intents map[DeploymentID]*Deployment
+	}
+)
+
+// FreezeOverrideHeader is the HTTP header used to override a freeze when
+// writing to the GDM. Its value is the reason for the override.
+const FreezeOverrideHeader = "Sous-Freeze-Override"
+
+func (err *FrozenError) Error() string {
+	return fmt.Sprintf("%s is frozen until %s: %s", err.DeploymentID, err.Window.End.Format(time.RFC3339), err.Window.Reason)
+}
+
+// ActiveAt returns true if the freeze is in effect at the given time. A
+// window which ends before it starts is never in effect.
+func (fw FreezeWindow) ActiveAt(at time.Time) bool {
+	return !at.Before(fw.Start) && at.Before(fw.End)
+}
+
+// Freezes returns true if the freeze applies to the given deployment, whether
+// or not it is active.
+func (fw FreezeWindow) Freezes(did DeploymentID) bool {
+	for _, mid := range fw.Exempt {
+		if mid == did.ManifestID {
+			return false
+		}
+	}
+	if len(fw.Clusters) == 0 {
+		return true
+	}
+	for _, c := range fw.Clusters {
+		if c == did.Cluster {
+			return true
+		}
+	}
+	return false
+}
+
+// Clone returns a deep copy of this FreezeWindow.
+func (fw FreezeWindow) Clone() FreezeWindow {
+	fw.Clusters = append([]string(nil), fw.Clusters...)
+	fw.Exempt = append([]ManifestID(nil), fw.Exempt...)
+	return fw
+}
+
+// Clone returns a deep copy of these FreezeWindows.
+func (fws FreezeWindows) Clone() FreezeWindows {
+	if fws == nil {
+		return nil
+	}
+	c := make(FreezeWindows, len(fws))
+	for i, fw := range fws {
+		c[i] = fw.Clone()
+	}
+	return c
+}
+
+// FrozenAt returns the freeze in effect for a deployment at a time, if any.
+func (fws FreezeWindows) FrozenAt(did DeploymentID, at time.Time) (FreezeWindow, bool) {
+	for _, fw := range fws {
+		if fw.ActiveAt(at) && fw.Freezes(did) {
+			return fw, true
+		}
+	}
+	return FreezeWindow{}, false
+}
+
+// Equal returns true if fo and o are both nil, or are the same override.
+func (fo *FreezeOverride) Equal(o *FreezeOverride) bool {
+	if fo == nil || o == nil {
+		return fo == o
+	}
+	return fo.User == o.User && fo.Reason == o.Reason && fo.At.Equal(o.At)
+}
+
+// Permits returns true if a change to dep at the given time is allowed:
+// either no freeze applies, or dep carries an override made during that
+// freeze.
+func (fws FreezeWindows) Permits(dep *Deployment, at time.Time) bool {
+	fw, frozen := fws.FrozenAt(dep.ID(), at)
+	if !frozen {
+		return true
+	}
+	fo := dep.FreezeOverride
+	return fo != nil && fw.ActiveAt(fo.At)
+}
+
+// Guard checks the changes from current to intended deployments against the
+// freezes in effect at the given time. If override is nil, it returns a
+// *FrozenError for the first frozen change. Otherwise, each frozen change is
+// allowed, and the override is recorded on the intended deployment so the
+// resolver will rectify it. Guard returns the IDs of deployments whose freeze
+// was overridden.
+func (fws FreezeWindows) Guard(current, intended Deployments, override *FreezeOverride, at time.Time) ([]DeploymentID, error) {
+	var overridden []DeploymentID
+	for _, pair := range current.Diff(intended).Collect() {
+		if pair.Kind() == SameKind {
+			continue
+		}
+		did := pair.ID()
+		fw, frozen := fws.FrozenAt(did, at)
+		if !frozen {
+			continue
+		}
+		if override == nil {
+			return nil, &FrozenError{DeploymentID: did, Window: fw}
+		}
+		dep, present := intended.Get(did)
+		if !present {
+			return nil, errors.Wrapf(&FrozenError{DeploymentID: did, Window: fw},
+				"removing a deployment during a freeze cannot be overridden; set its instances to 0 instead")
+		}
+		fo := *override
+		dep.FreezeOverride = &fo
+		overridden = append(overridden, did)
+	}
+	return overridden, nil
+}
+
+// ReportFreezeOverrides logs an audit message for each overridden freeze.
+func ReportFreezeOverrides(ls logging.LogSink, override FreezeOverride, dids []DeploymentID) {
+	for _, did := range dids {
+		messages.ReportLogFieldsMessage(
+			fmt.Sprintf("Freeze overridden for %s by %s: %s", did, override.User, override.Reason),
+			logging.WarningLevel, ls, did, override)
+	}
+}
+
+// update records intended as the pre-freeze intent of each deployment not
+// frozen at the given time, and of each frozen deployment whose freeze was
+// overridden. Frozen deployments keep the intent recorded before their
+// freeze. It returns the baseline for the deployments frozen at that time.
+func (pf *preFreezeIntents) update(windows FreezeWindows, intended Deployments, at time.Time) FreezeBaseline {
+	pf.Lock()
+	defer pf.Unlock()
+
+	if pf.since.IsZero() {
+		pf.since = at
+	}
+	intents := map[DeploymentID]*Deployment{}
+	for did, dep := range pf.intents {
+		if _, frozen := windows.FrozenAt(did, at); frozen {
+			intents[did] = dep
+		}
+	}
+	for _, dep := range intended.Snapshot() {
+		did := dep.ID()
+		if _, frozen := windows.FrozenAt(did, at); !frozen || windows.Permits(dep, at) {
+			intents[did] = dep.Clone()
+		}
+	}
+	pf.intents = intents
+
+	baseline := FreezeBaseline{Intents: map[DeploymentID]*Deployment{}, Since: pf.since}
+	for did, dep := range intents {
+		if _, frozen := windows.FrozenAt(did, at); frozen {
+			baseline.Intents[did] = dep
+		}
+	}
+	return baseline
+}
+
+// EnforceFreezes adds a pipeline step which stops any change frozen at the
+// given time, unless the freeze was overridden, or the change only restores
+// the deployment to its baseline.
+func (d *DeployableChans) EnforceFreezes(ctx context.Context, windows FreezeWindows, baseline FreezeBaseline, at time.Time, ls logging.LogSink) *DeployableChans {
+	return d.Pipeline(ctx, freezeProcessor{windows: windows, baseline: baseline, at: at, ls: ls})
+}
+
+func (fp freezeProcessor) HandlePairs(dp *DeployablePair) (*DeployablePair, *DiffResolution) {
+	if dp.Kind() == SameKind || len(fp.windows) == 0 {
+		return dp, nil
+	}
+	fw, frozen := fp.windows.FrozenAt(dp.ID(), fp.at)
+	if !frozen {
+		return dp, nil
+	}
+	if dp.Post != nil && dp.Post.Deployment != nil && fp.windows.Permits(dp.Post.Deployment, fp.at) {
+		fo := dp.Post.Deployment.FreezeOverride
+		messages.ReportLogFieldsMessage(
+			fmt.Sprintf("Rectifying %s during freeze, overridden by %s: %s", dp.ID(), fo.User, fo.Reason),
+			logging.InformationLevel, fp.ls, dp.ID())
+		return dp, nil
+	}
+	if fp.repairsDrift(dp, fw) {
+		messages.ReportLogFieldsMessage(
+			fmt.Sprintf("Repairing drift of %s during freeze", dp.ID()),
+			logging.InformationLevel, fp.ls, dp.ID())
+		return dp, nil
+	}
+	return nil, &DiffResolution{
+		DeploymentID: dp.ID(),
+		Desc:         FrozenDiff,
+		Error:        WrapResolveError(&FrozenError{DeploymentID: dp.ID(), Window: fw}),
+	}
+}
+
+// repairsDrift returns true if dp would only restore its deployment to what
+// was intended before fw began.
+func (fp freezeProcessor) repairsDrift(dp *DeployablePair, fw FreezeWindow) bool {
+	base, had := fp.baseline.Intents[dp.ID()]
+	if !had {
+		// Only a deployment known not to have been intended may be removed.
+		known := !fp.baseline.Since.IsZero() && fw.Start.After(fp.baseline.Since)
+		return known && (dp.Post == nil || dp.Post.Deployment == nil)
+	}
+	if dp.Post == nil || dp.Post.Deployment == nil {
+		return false
+	}
+	different, _ := base.Diff(dp.Post.Deployment)
+	return !different
+}
+
+func (fw FreezeWindow) String() string {
+	clusters := "all clusters"
+	if len(fw.Clusters) > 0 {
+		clusters = strings.Join(fw.Clusters, ", ")
+	}
+	return fmt.Sprintf("%s from %s to %s: %s", clusters,
+		fw.Start.Format(time.RFC3339), fw.End.Format(time.RFC3339), fw.Reason)
+}
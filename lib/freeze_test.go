@@ -0,0 +1,211 @@
+package sous
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var freezeTestNow = time.Date(2017, 12, 24, 12, 0, 0, 0, time.UTC)
+
+func freezeFixture(clusters ...string) FreezeWindow {
+	return FreezeWindow{
+		Start:    freezeTestNow.Add(-time.Hour),
+		End:      freezeTestNow.Add(time.Hour),
+		Clusters: clusters,
+		Reason:   "holiday",
+	}
+}
+
+func TestFreezeWindow_ActiveAt(t *testing.T) {
+	fw := freezeFixture()
+	assert.True(t, fw.ActiveAt(fw.Start))
+	assert.True(t, fw.ActiveAt(freezeTestNow))
+	assert.False(t, fw.ActiveAt(fw.End))
+	assert.False(t, fw.ActiveAt(fw.Start.Add(-time.Second)))
+
+	inverted := FreezeWindow{Start: fw.End, End: fw.Start}
+	assert.False(t, inverted.ActiveAt(freezeTestNow))
+}
+
+func TestFreezeWindow_Freezes(t *testing.T) {
+	did := DeploymentFixture("").ID()
+
+	assert.True(t, freezeFixture().Freezes(did))
+	assert.True(t, freezeFixture("other", did.Cluster).Freezes(did))
+	assert.False(t, freezeFixture("other").Freezes(did))
+
+	exempt := freezeFixture()
+	exempt.Exempt = []ManifestID{did.ManifestID}
+	assert.False(t, exempt.Freezes(did))
+}
+
+func TestFreezeWindows_YAML(t *testing.T) {
+	fw := freezeFixture("cluster-1")
+	fw.Exempt = []ManifestID{MustParseManifestID("github.com/opentable/example,dir~flavor")}
+	fws := FreezeWindows{fw}
+
+	bs, err := yaml.Marshal(fws)
+	require.NoError(t, err)
+	var got FreezeWindows
+	require.NoError(t, yaml.Unmarshal(bs, &got))
+	assert.Equal(t, fws, got)
+}
+
+func TestFreezeWindows_Guard(t *testing.T) {
+	fws := FreezeWindows{freezeFixture()}
+	dep := DeploymentFixture("")
+	changed := dep.Clone()
+	changed.NumInstances++
+
+	t.Run("unchanged", func(t *testing.T) {
+		overridden, err := fws.Guard(NewDeployments(dep), NewDeployments(dep.Clone()), nil, freezeTestNow)
+		assert.NoError(t, err)
+		assert.Empty(t, overridden)
+	})
+
+	t.Run("outside the freeze", func(t *testing.T) {
+		_, err := fws.Guard(NewDeployments(dep), NewDeployments(changed.Clone()), nil, freezeTestNow.Add(2*time.Hour))
+		assert.NoError(t, err)
+	})
+
+	t.Run("frozen", func(t *testing.T) {
+		_, err := fws.Guard(NewDeployments(dep), NewDeployments(changed.Clone()), nil, freezeTestNow)
+		require.Error(t, err)
+		fe, is := err.(*FrozenError)
+		require.True(t, is, "%T is not a *FrozenError", err)
+		assert.Equal(t, dep.ID(), fe.DeploymentID)
+		assert.Contains(t, err.Error(), "holiday")
+	})
+
+	t.Run("overridden", func(t *testing.T) {
+		override := &FreezeOverride{User: User{Email: "ops@example.com"}, Reason: "urgent fix", At: freezeTestNow}
+		intended := changed.Clone()
+		overridden, err := fws.Guard(NewDeployments(dep), NewDeployments(intended), override, freezeTestNow)
+		require.NoError(t, err)
+		assert.Equal(t, []DeploymentID{dep.ID()}, overridden)
+		require.NotNil(t, intended.FreezeOverride)
+		assert.Equal(t, *override, *intended.FreezeOverride)
+		assert.True(t, fws.Permits(intended, freezeTestNow))
+	})
+
+	t.Run("removal cannot be overridden", func(t *testing.T) {
+		override := &FreezeOverride{Reason: "urgent fix", At: freezeTestNow}
+		_, err := fws.Guard(NewDeployments(dep), NewDeployments(), override, freezeTestNow)
+		assert.Error(t, err)
+	})
+}
+
+func TestFreezeWindows_Permits(t *testing.T) {
+	fws := FreezeWindows{freezeFixture()}
+	dep := DeploymentFixture("")
+	assert.False(t, fws.Permits(dep, freezeTestNow))
+	assert.True(t, fws.Permits(dep, freezeTestNow.Add(2*time.Hour)))
+
+	dep.FreezeOverride = &FreezeOverride{At: freezeTestNow.Add(-2 * time.Hour)}
+	assert.False(t, fws.Permits(dep, freezeTestNow), "an override from before the freeze should not apply")
+
+	dep.FreezeOverride.At = freezeTestNow
+	assert.True(t, fws.Permits(dep, freezeTestNow))
+}
+
+func TestDeployableChans_EnforceFreezes(t *testing.T) {
+	ls, _ := logging.NewLogSinkSpy()
+	fws := FreezeWindows{freezeFixture()}
+
+	frozen := DeploymentFixture("")
+	permitted := DeploymentFixture("")
+	permitted.ClusterName = "cluster-2"
+	permitted.FreezeOverride = &FreezeOverride{Reason: "urgent fix", At: freezeTestNow}
+	require.NotEqual(t, frozen.ID(), permitted.ID())
+
+	dc := NewDeployableChans(2)
+	for _, dep := range []*Deployment{frozen, permitted} {
+		dc.Pairs <- &DeployablePair{name: dep.ID(), Post: &Deployable{Deployment: dep}}
+	}
+	dc.Close()
+
+	out := dc.EnforceFreezes(context.Background(), fws, FreezeBaseline{}, freezeTestNow, ls)
+	var passed []DeploymentID
+	for p := range out.Pairs {
+		passed = append(passed, p.ID())
+	}
+	var stopped []DiffResolution
+	for rez := range out.Errs {
+		stopped = append(stopped, *rez)
+	}
+
+	assert.Equal(t, []DeploymentID{permitted.ID()}, passed)
+	require.Len(t, stopped, 1)
+	assert.Equal(t, frozen.ID(), stopped[0].DeploymentID)
+	assert.Equal(t, FrozenDiff, stopped[0].Desc)
+	assert.Error(t, stopped[0].Error)
+}
+
+func TestDeployableChans_EnforceFreezes_driftRepair(t *testing.T) {
+	ls, _ := logging.NewLogSinkSpy()
+	fws := FreezeWindows{freezeFixture()}
+	before := freezeTestNow.Add(-2 * time.Hour)
+
+	drifted := DeploymentFixture("")
+	changed := DeploymentFixture("")
+	changed.ClusterName = "cluster-2"
+	added := DeploymentFixture("")
+	added.ClusterName = "cluster-3"
+	stray := DeploymentFixture("")
+	stray.ClusterName = "cluster-4"
+
+	pf := &preFreezeIntents{}
+	pf.update(fws, NewDeployments(drifted, changed), before)
+
+	changed = changed.Clone()
+	changed.NumInstances++
+	baseline := pf.update(fws, NewDeployments(drifted, changed, added), freezeTestNow)
+	assert.Len(t, baseline.Intents, 2)
+
+	dc := NewDeployableChans(4)
+	for _, dep := range []*Deployment{drifted, changed, added} {
+		dc.Pairs <- &DeployablePair{name: dep.ID(), Post: &Deployable{Deployment: dep}}
+	}
+	dc.Pairs <- &DeployablePair{name: stray.ID(), Prior: &Deployable{Deployment: stray}}
+	dc.Close()
+
+	out := dc.EnforceFreezes(context.Background(), fws, baseline, freezeTestNow, ls)
+	var passed []DeploymentID
+	for p := range out.Pairs {
+		passed = append(passed, p.ID())
+	}
+	var stopped []DeploymentID
+	for rez := range out.Errs {
+		stopped = append(stopped, rez.DeploymentID)
+	}
+
+	assert.Equal(t, []DeploymentID{drifted.ID(), stray.ID()}, passed)
+	assert.Equal(t, []DeploymentID{changed.ID(), added.ID()}, stopped)
+
+	t.Run("unknown intent", func(t *testing.T) {
+		pf := &preFreezeIntents{}
+		baseline := pf.update(fws, NewDeployments(drifted), freezeTestNow)
+		assert.Empty(t, baseline.Intents)
+
+		dc := NewDeployableChans(2)
+		dc.Pairs <- &DeployablePair{name: drifted.ID(), Post: &Deployable{Deployment: drifted}}
+		dc.Pairs <- &DeployablePair{name: stray.ID(), Prior: &Deployable{Deployment: stray}}
+		dc.Close()
+
+		out := dc.EnforceFreezes(context.Background(), fws, baseline, freezeTestNow, ls)
+		for p := range out.Pairs {
+			t.Errorf("%s was rectified without a known pre-freeze intent", p.ID())
+		}
+		n := 0
+		for range out.Errs {
+			n++
+		}
+		assert.Equal(t, 2, n)
+	})
+}
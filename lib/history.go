@@ -0,0 +1,121 @@
+package sous
+
+import "time"
+
+type (
+	// A StateChange records a single write to the GDM: who made it, when, and
+	// which deployments it affected.
+	StateChange struct {
+		ID          int64
+		User        User
+		At          time.Time
+		Deployments []DeploymentChange
+	}
+
+	// A DeploymentChange records how a single deployment was affected by a
+	// StateChange.
+	DeploymentChange struct {
+		DeploymentID DeploymentID
+		// Kind is one of "added", "removed" or "modified".
+		Kind  string
+		Diffs Differences `json:",omitempty"`
+	}
+
+	// A HistoryFilter selects StateChanges from a HistoryReader.
+	// Zero valued fields match everything.
+	HistoryFilter struct {
+		Manifest *ManifestID
+		Cluster  string
+		// User matches either the name or the email of the changing user.
+		User  string
+		Since time.Time
+		// Limit caps the number of StateChanges returned; 0 means no limit.
+		Limit int
+	}
+
+	// A HistoryReader can report the recorded changes to the GDM.
+	HistoryReader interface {
+		// ReadHistory returns StateChanges matching the filter, most recent first.
+		ReadHistory(HistoryFilter) ([]StateChange, error)
+	}
+)
+
+// DeploymentChanges builds DeploymentChanges from a set of DeployablePairs,
+// omitting pairs which are unchanged.
+func DeploymentChanges(pairs DeployablePairs) []DeploymentChange {
+	return deploymentChanges(pairs, (*DeployablePair).Diffs)
+}
+
+// GDMChanges is like DeploymentChanges, but for pairs of intended
+// deployments, e.g. from two versions of the GDM. It also records changes to
+// how Sous manages each deployment (see Deployment.PolicyDiff), which
+// DeploymentChanges leaves out, since they are never seen in a running
+// deployment.
+func GDMChanges(pairs DeployablePairs) []DeploymentChange {
+	return deploymentChanges(pairs, (*DeployablePair).IntendedDiffs)
+}
+
+func deploymentChanges(pairs DeployablePairs, diff func(*DeployablePair) Differences) []DeploymentChange {
+	changes := []DeploymentChange{}
+	for _, pair := range pairs {
+		kind := pair.Kind()
+		dc := DeploymentChange{DeploymentID: pair.ID()}
+		if kind == SameKind || kind == ModifiedKind {
+			if dc.Diffs = diff(pair); len(dc.Diffs) == 0 {
+				continue
+			}
+			kind = ModifiedKind
+		}
+		dc.Kind = kind.String()
+		changes = append(changes, dc)
+	}
+	return changes
+}
+
+// matchesDeployment returns true if the DeploymentChange is selected by the filter.
+// Filtering on user or time is the concern of the StateChange.
+func (f HistoryFilter) matchesDeployment(dc DeploymentChange) bool {
+	if f.Manifest != nil && dc.DeploymentID.ManifestID != *f.Manifest {
+		return false
+	}
+	if f.Cluster != "" && dc.DeploymentID.Cluster != f.Cluster {
+		return false
+	}
+	return true
+}
+
+// Filter returns a copy of the StateChange with only the deployments matched
+// by the filter, and false if the change is not selected at all.
+func (f HistoryFilter) Filter(sc StateChange) (StateChange, bool) {
+	if f.User != "" && sc.User.Name != f.User && sc.User.Email != f.User {
+		return sc, false
+	}
+	if !f.Since.IsZero() && sc.At.Before(f.Since) {
+		return sc, false
+	}
+	deps := []DeploymentChange{}
+	for _, dc := range sc.Deployments {
+		if f.matchesDeployment(dc) {
+			deps = append(deps, dc)
+		}
+	}
+	if len(deps) == 0 {
+		return sc, false
+	}
+	sc.Deployments = deps
+	return sc, true
+}
+
+// FilterAll applies the filter to a list of StateChanges, respecting Limit.
+func (f HistoryFilter) FilterAll(changes []StateChange) []StateChange {
+	selected := []StateChange{}
+	for _, sc := range changes {
+		if f.Limit > 0 && len(selected) >= f.Limit {
+			break
+		}
+		if fsc, ok := f.Filter(sc); ok {
+			selected = append(selected, fsc)
+		}
+	}
+	return selected
+}
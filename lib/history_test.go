@@ -0,0 +1,98 @@
+package sous
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploymentChanges(t *testing.T) {
+	prior := makeDepl("github.com/example/repo", 1)
+	post := makeDepl("github.com/example/repo", 2)
+	same := makeDepl("github.com/example/repo", 1)
+
+	modified := &DeployablePair{Prior: &Deployable{Deployment: prior}, Post: &Deployable{Deployment: post}}
+	modified.SetID(post.ID())
+	unchanged := &DeployablePair{Prior: &Deployable{Deployment: same}, Post: &Deployable{Deployment: same}}
+	unchanged.SetID(same.ID())
+	added := &DeployablePair{Post: &Deployable{Deployment: post}}
+	added.SetID(post.ID())
+
+	changes := DeploymentChanges(DeployablePairs{modified, unchanged, added})
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, "modified", changes[0].Kind)
+		assert.NotEmpty(t, changes[0].Diffs)
+		assert.Equal(t, "added", changes[1].Kind)
+		assert.Empty(t, changes[1].Diffs)
+	}
+}
+
+func TestGDMChanges(t *testing.T) {
+	prior := makeDepl("github.com/example/repo", 1)
+	paused := prior.Clone()
+	paused.Pause = &Pause{User: User{Name: "ops"}, Reason: "incident", Until: time.Now().Add(time.Hour)}
+	rolled := prior.Clone()
+	rolled.Rollout = Rollout{Strategy: RolloutCanary, Steps: []int{50}}
+
+	var pairs DeployablePairs
+	for _, post := range []*Deployment{paused, rolled, prior.Clone()} {
+		pair := &DeployablePair{Prior: &Deployable{Deployment: prior}, Post: &Deployable{Deployment: post}}
+		pair.SetID(post.ID())
+		pairs = append(pairs, pair)
+	}
+
+	// A running deployment is not changed by a pause or a rollout...
+	assert.Empty(t, DeploymentChanges(pairs))
+	// ...but the GDM is.
+	changes := GDMChanges(pairs)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, "modified", changes[0].Kind)
+		assert.Len(t, changes[0].Diffs, 1)
+		assert.Contains(t, changes[0].Diffs[0], "pause")
+		assert.Len(t, changes[1].Diffs, 1)
+		assert.Contains(t, changes[1].Diffs[0], "rollout")
+	}
+}
+
+func TestHistoryFilter_FilterAll(t *testing.T) {
+	mid := func(repo string) ManifestID {
+		return ManifestID{Source: SourceLocation{Repo: repo}}
+	}
+	dc := func(repo, cluster string) DeploymentChange {
+		return DeploymentChange{
+			DeploymentID: DeploymentID{ManifestID: mid(repo), Cluster: cluster},
+			Kind:         "modified",
+		}
+	}
+	now := time.Now()
+	changes := []StateChange{
+		{ID: 3, User: User{Name: "ann", Email: "ann@example.com"}, At: now,
+			Deployments: []DeploymentChange{dc("one", "east"), dc("two", "west")}},
+		{ID: 2, User: User{Name: "bob", Email: "bob@example.com"}, At: now.Add(-time.Hour),
+			Deployments: []DeploymentChange{dc("two", "east")}},
+		{ID: 1, User: User{Name: "ann", Email: "ann@example.com"}, At: now.Add(-2 * time.Hour),
+			Deployments: []DeploymentChange{dc("one", "west")}},
+	}
+
+	ids := func(scs []StateChange) []int64 {
+		ids := []int64{}
+		for _, sc := range scs {
+			ids = append(ids, sc.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []int64{3, 2, 1}, ids(HistoryFilter{}.FilterAll(changes)))
+	assert.Equal(t, []int64{3, 2}, ids(HistoryFilter{Limit: 2}.FilterAll(changes)))
+	assert.Equal(t, []int64{3, 1}, ids(HistoryFilter{User: "ann"}.FilterAll(changes)))
+	assert.Equal(t, []int64{2}, ids(HistoryFilter{User: "bob@example.com"}.FilterAll(changes)))
+	assert.Equal(t, []int64{3, 2}, ids(HistoryFilter{Since: now.Add(-90 * time.Minute)}.FilterAll(changes)))
+
+	one := mid("one")
+	byManifest := HistoryFilter{Manifest: &one}.FilterAll(changes)
+	assert.Equal(t, []int64{3, 1}, ids(byManifest))
+	assert.Len(t, byManifest[0].Deployments, 1, "unmatched deployments are dropped")
+
+	assert.Equal(t, []int64{3, 2}, ids(HistoryFilter{Cluster: "east"}.FilterAll(changes)))
+}
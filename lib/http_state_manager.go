@@ -16,6 +16,9 @@ type (
 		gdmState restful.Updater
 		restful.HTTPClient
 		User User
+		// FreezeOverride, if set, is sent with writes to override any freeze
+		// on the deployments being changed. It is the reason for the override.
+		FreezeOverride string
 	}
 
 	gdmWrapper struct {
@@ -125,8 +128,23 @@ func (hsm *HTTPStateManager) getManifests(defs Defs) (Manifests, error) {
 
 func (hsm *HTTPStateManager) putDeployments(new Deployments) error {
 	wNew := wrapDeployments(new)
-	_, err := hsm.gdmState.Update(&wNew, hsm.User.HTTPHeaders())
-	return errors.Wrapf(err, "putting GDM")
+	headers := hsm.User.HTTPHeaders()
+	if hsm.FreezeOverride != "" {
+		headers[FreezeOverrideHeader] = hsm.FreezeOverride
+	}
+	up, err := hsm.gdmState.Update(&wNew, headers)
+	if err != nil {
+		return errors.Wrapf(err, "putting GDM")
+	}
+	// The server holds changes to clusters requiring approval, and tells us
+	// where to find them.
+	if up == nil {
+		return nil
+	}
+	if pa := PendingApprovalFromLocation(up.Location()); pa != nil {
+		return pa
+	}
+	return nil
 }
 
 // EmptyReceiver implements Comparable on Manifest
@@ -0,0 +1,226 @@
+package sous
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+)
+
+type (
+	// A Lease grants a Sous server the right to rectify a cluster until it
+	// expires.
+	Lease struct {
+		// Cluster is the name of the cluster the lease is for.
+		Cluster string
+		// Holder identifies the server which holds the lease, by its URL.
+		Holder string
+		// Expires is when the lease lapses unless it is renewed.
+		Expires time.Time
+	}
+
+	// A LeaseStore records which server holds the lease on each cluster, so
+	// that servers which share it agree on a single leader per cluster.
+	LeaseStore interface {
+		// AcquireLease grants the lease on cluster to holder for ttl, if it is
+		// free, has expired or is already held by holder. It returns the lease
+		// as it stands afterwards, which belongs to another server if holder
+		// did not get it.
+		AcquireLease(cluster, holder string, ttl time.Duration) (Lease, error)
+		// ReleaseLease gives up the lease on cluster, if holder holds it.
+		ReleaseLease(cluster, holder string) error
+	}
+
+	// Leadership elects a leader for each cluster from the Sous servers
+	// sharing a LeaseStore. Only the leader of a cluster should rectify it;
+	// the others follow, and pass rectifications on to the leader.
+	Leadership struct {
+		// Holder identifies this server in leases, by its URL.
+		Holder string
+		// TTL is how long a lease lasts unless it is renewed. Leases are
+		// renewed every third of TTL.
+		TTL time.Duration
+		// Key is the secret the servers share, with which they sign the
+		// requests they forward to each other's leaders.
+		Key       []byte
+		store     LeaseStore
+		contended map[string]struct{}
+		leases    map[string]Lease
+		ls        logging.LogSink
+		now       func() time.Time
+		sync.Mutex
+	}
+)
+
+// DefaultLeaseTTL is how long a lease lasts if Leadership.TTL is not set.
+const DefaultLeaseTTL = 30 * time.Second
+
+// NewLeadership returns a Leadership for the server at holder, which contends
+// for leases recorded in store.
+func NewLeadership(holder string, store LeaseStore, ls logging.LogSink) *Leadership {
+	return &Leadership{
+		Holder:    holder,
+		TTL:       DefaultLeaseTTL,
+		store:     store,
+		contended: map[string]struct{}{},
+		leases:    map[string]Lease{},
+		ls:        ls,
+		now:       time.Now,
+	}
+}
+
+// Contend adds clusters for this server to contend for the lease on, and
+// tries to acquire any it was not contending for already.
+func (l *Leadership) Contend(clusters ...string) {
+	fresh := []string{}
+	l.Lock()
+	for _, cluster := range clusters {
+		if _, ok := l.contended[cluster]; !ok {
+			l.contended[cluster] = struct{}{}
+			fresh = append(fresh, cluster)
+		}
+	}
+	l.Unlock()
+	for _, cluster := range fresh {
+		l.acquire(cluster)
+	}
+}
+
+// Renew acquires or renews the lease on each cluster this server contends
+// for.
+func (l *Leadership) Renew() {
+	l.Lock()
+	clusters := make([]string, 0, len(l.contended))
+	for cluster := range l.contended {
+		clusters = append(clusters, cluster)
+	}
+	l.Unlock()
+	sort.Strings(clusters)
+	for _, cluster := range clusters {
+		l.acquire(cluster)
+	}
+}
+
+// Resign releases the leases this server holds, so that another server can
+// take over without waiting for them to expire.
+func (l *Leadership) Resign() {
+	if l == nil {
+		return
+	}
+	l.Lock()
+	held := []string{}
+	for cluster, lease := range l.leases {
+		if lease.Holder == l.Holder {
+			held = append(held, cluster)
+		}
+	}
+	l.contended = map[string]struct{}{}
+	l.leases = map[string]Lease{}
+	l.Unlock()
+	for _, cluster := range held {
+		if err := l.store.ReleaseLease(cluster, l.Holder); err != nil {
+			reportLeaseError(l.ls, "release", cluster, err)
+		}
+	}
+}
+
+func (l *Leadership) acquire(cluster string) {
+	lease, err := l.store.AcquireLease(cluster, l.Holder, l.TTL)
+	l.Lock()
+	defer l.Unlock()
+	prior, known := l.leases[cluster]
+	if err != nil {
+		// What is known stays good until it expires.
+		reportLeaseError(l.ls, "acquire", cluster, err)
+		return
+	}
+	l.leases[cluster] = lease
+	if !known || prior.Holder != lease.Holder {
+		reportLeaderChange(l.ls, lease)
+	}
+}
+
+// Leads returns true if this server holds an unexpired lease on cluster. A nil
+// Leadership leads every cluster, as a server with no peers to elect among.
+func (l *Leadership) Leads(cluster string) bool {
+	if l == nil {
+		return true
+	}
+	leader, ok := l.Leader(cluster)
+	return ok && leader == l.Holder
+}
+
+// Leader returns the URL of the server leading cluster, if this server knows
+// of an unexpired lease on it.
+func (l *Leadership) Leader(cluster string) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+	l.Lock()
+	defer l.Unlock()
+	lease, ok := l.leases[cluster]
+	if !ok || !l.now().Before(lease.Expires) {
+		return "", false
+	}
+	return lease.Holder, true
+}
+
+// Leaders returns the URL of the leader of each cluster this server contends
+// for, where it knows of an unexpired lease.
+func (l *Leadership) Leaders() map[string]string {
+	leaders := map[string]string{}
+	if l == nil {
+		return leaders
+	}
+	l.Lock()
+	defer l.Unlock()
+	now := l.now()
+	for cluster, lease := range l.leases {
+		if now.Before(lease.Expires) {
+			leaders[cluster] = lease.Holder
+		}
+	}
+	return leaders
+}
+
+// Start renews leases every third of TTL until done is closed, and then
+// resigns them. Start does nothing on a nil Leadership.
+func (l *Leadership) Start(done <-chan struct{}) {
+	if l == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(l.TTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				l.Resign()
+				return
+			case <-ticker.C:
+				l.Renew()
+			}
+		}
+	}()
+}
+
+func reportLeaderChange(ls logging.LogSink, lease Lease) {
+	if ls == nil {
+		return
+	}
+	messages.ReportLogFieldsMessage(
+		fmt.Sprintf("Sous server %s leads cluster %q", lease.Holder, lease.Cluster),
+		logging.InformationLevel, ls, lease)
+}
+
+func reportLeaseError(ls logging.LogSink, action, cluster string, err error) {
+	if ls == nil {
+		return
+	}
+	messages.ReportLogFieldsMessage(
+		fmt.Sprintf("Failed to %s the lease on cluster %q: %v", action, cluster, err),
+		logging.WarningLevel, ls, cluster, err)
+}
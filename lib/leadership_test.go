@@ -0,0 +1,163 @@
+package sous
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLeaseStore is an in-memory LeaseStore, timed by a clock shared with
+// the Leaderships using it.
+type testLeaseStore struct {
+	now    *time.Time
+	leases map[string]Lease
+	down   bool
+	sync.Mutex
+}
+
+func newTestLeaseStore(now *time.Time) *testLeaseStore {
+	return &testLeaseStore{now: now, leases: map[string]Lease{}}
+}
+
+func (s *testLeaseStore) AcquireLease(cluster, holder string, ttl time.Duration) (Lease, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.down {
+		return Lease{}, errors.New("lease store down")
+	}
+	lease, ok := s.leases[cluster]
+	if !ok || lease.Holder == holder || !s.now.Before(lease.Expires) {
+		lease = Lease{Cluster: cluster, Holder: holder, Expires: s.now.Add(ttl)}
+		s.leases[cluster] = lease
+	}
+	return lease, nil
+}
+
+func (s *testLeaseStore) ReleaseLease(cluster, holder string) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.leases[cluster].Holder == holder {
+		delete(s.leases, cluster)
+	}
+	return nil
+}
+
+func testLeaderships(now *time.Time, store LeaseStore, holders ...string) []*Leadership {
+	ls := []*Leadership{}
+	for _, holder := range holders {
+		l := NewLeadership(holder, store, logging.SilentLogSet())
+		l.now = func() time.Time { return *now }
+		ls = append(ls, l)
+	}
+	return ls
+}
+
+func TestLeadership_elects(t *testing.T) {
+	now := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestLeaseStore(&now)
+	ls := testLeaderships(&now, store, "http://one", "http://two")
+	one, two := ls[0], ls[1]
+
+	one.Contend("cluster-1")
+	two.Contend("cluster-1", "cluster-2")
+
+	assert.True(t, one.Leads("cluster-1"))
+	assert.False(t, two.Leads("cluster-1"))
+	assert.True(t, two.Leads("cluster-2"))
+	assert.False(t, one.Leads("cluster-2"), "one does not contend for cluster-2")
+
+	leader, ok := two.Leader("cluster-1")
+	assert.True(t, ok)
+	assert.Equal(t, "http://one", leader)
+	assert.Equal(t, map[string]string{"cluster-1": "http://one", "cluster-2": "http://two"}, two.Leaders())
+
+	// Renewing keeps the leases where they are.
+	now = now.Add(DefaultLeaseTTL / 3)
+	two.Renew()
+	one.Renew()
+	assert.True(t, one.Leads("cluster-1"))
+	assert.False(t, two.Leads("cluster-1"))
+}
+
+func TestLeadership_failover(t *testing.T) {
+	now := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestLeaseStore(&now)
+	ls := testLeaderships(&now, store, "http://one", "http://two")
+	one, two := ls[0], ls[1]
+
+	one.Contend("cluster-1")
+	two.Contend("cluster-1")
+	require.True(t, one.Leads("cluster-1"))
+
+	// one stops renewing, e.g. because it has crashed.
+	now = now.Add(DefaultLeaseTTL)
+	assert.False(t, one.Leads("cluster-1"), "an expired lease should not be led")
+	two.Renew()
+	assert.True(t, two.Leads("cluster-1"))
+	one.Renew()
+	assert.False(t, one.Leads("cluster-1"))
+
+	// two resigns, and one takes over straight away.
+	two.Resign()
+	assert.False(t, two.Leads("cluster-1"))
+	one.Renew()
+	assert.True(t, one.Leads("cluster-1"))
+}
+
+func TestLeadership_storeDown(t *testing.T) {
+	now := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestLeaseStore(&now)
+	one := testLeaderships(&now, store, "http://one")[0]
+
+	one.Contend("cluster-1")
+	store.down = true
+	now = now.Add(DefaultLeaseTTL / 3)
+	one.Renew()
+	assert.True(t, one.Leads("cluster-1"), "a lease should be kept until it expires")
+	now = now.Add(DefaultLeaseTTL)
+	one.Renew()
+	assert.False(t, one.Leads("cluster-1"), "a lease which could not be renewed should lapse")
+}
+
+func TestLeadership_nil(t *testing.T) {
+	var l *Leadership
+	assert.True(t, l.Leads("cluster-1"))
+	_, ok := l.Leader("cluster-1")
+	assert.False(t, ok)
+	assert.Empty(t, l.Leaders())
+	l.Start(nil)
+	l.Resign()
+}
+
+func TestAutoResolver_leading(t *testing.T) {
+	now := time.Now()
+	store := newTestLeaseStore(&now)
+	ls := testLeaderships(&now, store, "http://one", "http://two")
+	ls[1].Contend("cluster-2")
+
+	ar := setupAR()
+	ar.Leadership = ls[0]
+
+	led := DeploymentFixture("")
+	led.ClusterName = "cluster-1"
+	followed := DeploymentFixture("")
+	followed.ClusterName = "cluster-2"
+	clusters := Clusters{"cluster-1": &Cluster{Name: "cluster-1"}, "cluster-2": &Cluster{Name: "cluster-2"}}
+
+	intended, leading := ar.leading(NewDeployments(led, followed), clusters)
+	assert.Equal(t, []DeploymentID{led.ID()}, intended.Keys())
+	names := leading.Names()
+	sort.Strings(names)
+	assert.Equal(t, []string{"cluster-1"}, names)
+
+	ar.Leadership = nil
+	intended, leading = ar.leading(NewDeployments(led, followed), clusters)
+	assert.Equal(t, 2, intended.Len())
+	assert.Len(t, leading, 2)
+}
@@ -0,0 +1,326 @@
+package sous
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// LintRules is a collection of LintRule.
+	LintRules []LintRule
+
+	// A LintRule is an organisation's policy about the deployments in its
+	// GDM, e.g. that deployments to production clusters must have at least
+	// two instances. Every value of Field in each deployment the rule applies
+	// to must satisfy Op Value.
+	LintRule struct {
+		// Name identifies the rule in the flaws it reports.
+		Name string
+		// Desc describes the rule, and is included in its flaws.
+		Desc string `yaml:",omitempty"`
+		// Severity is LintError (the default), which means deployments which
+		// break the rule may not be written to the GDM, or LintWarning, which
+		// means they are only reported.
+		Severity LintSeverity `yaml:",omitempty"`
+		// Clusters limits the rule to deployments to these clusters. If it is
+		// empty, the rule applies to every cluster.
+		Clusters []string `yaml:",omitempty"`
+		// Kinds limits the rule to deployments of these kinds. If it is
+		// empty, the rule applies to every kind.
+		Kinds []ManifestKind `yaml:",omitempty"`
+		// Field is the field of each deployment to check. It is one of
+		// NumInstances, Kind, Flavor, Owners, Schedule, a field of Startup
+		// (e.g. Startup.SkipCheck), or Resources.<name>, Env.<name> or
+		// Metadata.<name>. A deployment without the field does not break the
+		// rule; nor does one with no Owners.
+		Field string
+		// Op is the comparison: one of ==, !=, <, <=, >, >= or matches. The
+		// ordering comparisons are numeric, and matches takes a regular
+		// expression which the whole value must match.
+		Op string
+		// Value is compared to the field's value.
+		Value string
+	}
+
+	// LintSeverity is the severity of a LintRule.
+	LintSeverity string
+
+	// A LintFlaw is a breach of a LintRule by a deployment.
+	LintFlaw struct {
+		Rule         string
+		Severity     LintSeverity
+		DeploymentID DeploymentID
+		Desc         string
+	}
+)
+
+const (
+	// LintError is the severity of rules that must not be broken.
+	LintError = LintSeverity("error")
+	// LintWarning is the severity of rules that should not be broken.
+	LintWarning = LintSeverity("warning")
+)
+
+var lintOps = []string{"==", "!=", "<", "<=", ">", ">=", "matches"}
+
+var startupLintFields = map[string]func(Startup) string{
+	"SkipCheck":            func(s Startup) string { return strconv.FormatBool(s.SkipCheck) },
+	"ConnectDelay":         func(s Startup) string { return strconv.Itoa(s.ConnectDelay) },
+	"Timeout":              func(s Startup) string { return strconv.Itoa(s.Timeout) },
+	"ConnectInterval":      func(s Startup) string { return strconv.Itoa(s.ConnectInterval) },
+	"CheckReadyProtocol":   func(s Startup) string { return s.CheckReadyProtocol },
+	"CheckReadyURIPath":    func(s Startup) string { return s.CheckReadyURIPath },
+	"CheckReadyPortIndex":  func(s Startup) string { return strconv.Itoa(s.CheckReadyPortIndex) },
+	"CheckReadyURITimeout": func(s Startup) string { return strconv.Itoa(s.CheckReadyURITimeout) },
+	"CheckReadyInterval":   func(s Startup) string { return strconv.Itoa(s.CheckReadyInterval) },
+	"CheckReadyRetries":    func(s Startup) string { return strconv.Itoa(s.CheckReadyRetries) },
+}
+
+// Clone returns a deep copy of this LintRules.
+func (lrs LintRules) Clone() LintRules {
+	if lrs == nil {
+		return nil
+	}
+	c := make(LintRules, len(lrs))
+	for i, lr := range lrs {
+		lr.Clusters = append([]string(nil), lr.Clusters...)
+		lr.Kinds = append([]ManifestKind(nil), lr.Kinds...)
+		c[i] = lr
+	}
+	return c
+}
+
+// Validate returns a Flaw for each LintRule in lrs which cannot be applied.
+func (lrs LintRules) Validate() []Flaw {
+	var flaws []Flaw
+	for _, lr := range lrs {
+		if err := lr.validate(); err != nil {
+			flaws = append(flaws, FatalFlaw("lint rule %q: %s", lr.Name, err))
+		}
+	}
+	return flaws
+}
+
+func (lr LintRule) validate() error {
+	if lr.Name == "" {
+		return errors.New("Name is required")
+	}
+	switch lr.Severity {
+	default:
+		return errors.Errorf("Severity %q is not %q or %q", lr.Severity, LintError, LintWarning)
+	case "", LintError, LintWarning:
+	}
+	if !lintField(lr.Field) {
+		return errors.Errorf("unknown Field %q", lr.Field)
+	}
+	switch lr.Op {
+	default:
+		return errors.Errorf("Op %q is not one of %s", lr.Op, strings.Join(lintOps, " "))
+	case "==", "!=":
+	case "<", "<=", ">", ">=":
+		if _, err := strconv.ParseFloat(lr.Value, 64); err != nil {
+			return errors.Errorf("Op %s needs a numeric Value, not %q", lr.Op, lr.Value)
+		}
+	case "matches":
+		if _, err := lr.pattern(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (lr LintRule) pattern() (*regexp.Regexp, error) {
+	re, err := regexp.Compile("^(?:" + lr.Value + ")$")
+	return re, errors.Wrapf(err, "Value %q", lr.Value)
+}
+
+func lintField(field string) bool {
+	switch field {
+	case "NumInstances", "Kind", "Flavor", "Owners", "Schedule":
+		return true
+	}
+	parts := strings.SplitN(field, ".", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return false
+	}
+	switch parts[0] {
+	case "Resources", "Env", "Metadata":
+		return true
+	case "Startup":
+		_, ok := startupLintFields[parts[1]]
+		return ok
+	}
+	return false
+}
+
+// lintValues returns the values of field in d.
+func lintValues(d *Deployment, field string) []string {
+	switch field {
+	case "NumInstances":
+		return []string{strconv.Itoa(d.NumInstances)}
+	case "Kind":
+		return []string{string(d.Kind)}
+	case "Flavor":
+		return []string{d.Flavor}
+	case "Owners":
+		owners := d.Owners.Slice()
+		sort.Strings(owners)
+		return owners
+	case "Schedule":
+		return []string{d.Schedule}
+	}
+	parts := strings.SplitN(field, ".", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	var m map[string]string
+	switch parts[0] {
+	case "Resources":
+		m = d.Resources
+	case "Env":
+		m = d.Env
+	case "Metadata":
+		m = d.Metadata
+	case "Startup":
+		if f, ok := startupLintFields[parts[1]]; ok {
+			return []string{f(d.Startup)}
+		}
+		return nil
+	}
+	if v, ok := m[parts[1]]; ok {
+		return []string{v}
+	}
+	return nil
+}
+
+// AppliesTo returns true if lr should be checked against d.
+func (lr LintRule) AppliesTo(d *Deployment) bool {
+	if len(lr.Clusters) > 0 && !containsString(lr.Clusters, d.ClusterName) {
+		return false
+	}
+	if len(lr.Kinds) == 0 {
+		return true
+	}
+	for _, k := range lr.Kinds {
+		if k == d.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an error describing the first value of lr.Field in d which
+// breaks lr, or nil if lr does not apply to d or d satisfies it.
+func (lr LintRule) Check(d *Deployment) error {
+	if !lr.AppliesTo(d) {
+		return nil
+	}
+	if err := lr.validate(); err != nil {
+		return err
+	}
+	for _, v := range lintValues(d, lr.Field) {
+		if !lr.satisfied(v) {
+			return errors.Errorf("%s is %q, but must be %s %s", lr.Field, v, lr.Op, lr.Value)
+		}
+	}
+	return nil
+}
+
+func (lr LintRule) satisfied(v string) bool {
+	switch lr.Op {
+	case "==":
+		return v == lr.Value
+	case "!=":
+		return v != lr.Value
+	case "matches":
+		re, err := lr.pattern()
+		return err == nil && re.MatchString(v)
+	}
+	have, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return false
+	}
+	want, _ := strconv.ParseFloat(lr.Value, 64)
+	switch lr.Op {
+	default:
+		return false
+	case "<":
+		return have < want
+	case "<=":
+		return have <= want
+	case ">":
+		return have > want
+	case ">=":
+		return have >= want
+	}
+}
+
+// Lint returns a LintFlaw for each rule in lrs which d breaks.
+func (lrs LintRules) Lint(d *Deployment) []LintFlaw {
+	var flaws []LintFlaw
+	for _, lr := range lrs {
+		if err := lr.Check(d); err != nil {
+			severity := lr.Severity
+			if severity == "" {
+				severity = LintError
+			}
+			desc := err.Error()
+			if lr.Desc != "" {
+				desc = fmt.Sprintf("%s (%s)", desc, lr.Desc)
+			}
+			flaws = append(flaws, LintFlaw{
+				Rule:         lr.Name,
+				Severity:     severity,
+				DeploymentID: d.ID(),
+				Desc:         desc,
+			})
+		}
+	}
+	return flaws
+}
+
+// Errors returns a Flaw for each rule of severity LintError in lrs which d
+// breaks.
+func (lrs LintRules) Errors(d *Deployment) []Flaw {
+	var flaws []Flaw
+	for _, lf := range lrs.Lint(d) {
+		if lf.Severity == LintError {
+			flaws = append(flaws, lf)
+		}
+	}
+	return flaws
+}
+
+// Lint returns a LintFlaw for each breach of s.Defs.Lint by a deployment in
+// s, ordered by deployment.
+func (s *State) Lint() ([]LintFlaw, error) {
+	ds, err := s.Deployments()
+	if err != nil {
+		return nil, err
+	}
+	ids := DeploymentIDSlice(ds.Keys())
+	sort.Sort(ids)
+	var flaws []LintFlaw
+	for _, id := range ids {
+		d, _ := ds.Get(id)
+		flaws = append(flaws, s.Defs.Lint.Lint(d)...)
+	}
+	return flaws, nil
+}
+
+func (lf LintFlaw) String() string {
+	return fmt.Sprintf("%s: %s %s: %s", lf.Severity, lf.DeploymentID, lf.Rule, lf.Desc)
+}
+
+// AddContext implements Flaw on LintFlaw. It discards the context.
+func (lf LintFlaw) AddContext(string, interface{}) {}
+
+// Repair implements Flaw on LintFlaw. LintFlaws cannot be repaired.
+func (lf LintFlaw) Repair() error {
+	return errors.Errorf("%s: cannot be repaired", lf)
+}
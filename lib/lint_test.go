@@ -0,0 +1,79 @@
+package sous
+
+import (
+	"testing"
+)
+
+func TestLintRules(t *testing.T) {
+	rules := LintRules{
+		{Name: "ha", Clusters: []string{"prod"}, Field: "NumInstances", Op: ">=", Value: "2"},
+		{Name: "checked", Kinds: []ManifestKind{ManifestKindService}, Field: "Startup.SkipCheck", Op: "==", Value: "false"},
+		{Name: "emails", Field: "Owners", Op: "matches", Value: `[^@\s]+@[^@\s]+`},
+		{Name: "memory", Severity: LintWarning, Clusters: []string{"dev"}, Field: "Resources.memory", Op: "<=", Value: "1024"},
+	}
+	if flaws := rules.Validate(); len(flaws) != 0 {
+		t.Fatalf("got flaws %v in valid rules", flaws)
+	}
+
+	dep := func(cluster string, kind ManifestKind, instances int, skip bool, memory string, owners ...string) *Deployment {
+		return &Deployment{
+			ClusterName: cluster,
+			Kind:        kind,
+			Owners:      NewOwnerSet(owners...),
+			DeployConfig: DeployConfig{
+				NumInstances: instances,
+				Resources:    Resources{"memory": memory},
+				Startup:      Startup{SkipCheck: skip},
+			},
+		}
+	}
+	testCases := []struct {
+		name   string
+		d      *Deployment
+		broken []string
+		errors int
+	}{
+		{"compliant", dep("prod", ManifestKindService, 3, false, "4096", "sam@example.com"), nil, 0},
+		{"single instance", dep("prod", ManifestKindService, 1, false, "512", "sam@example.com"), []string{"ha"}, 1},
+		{"single instance in dev", dep("dev", ManifestKindService, 1, false, "512"), nil, 0},
+		{"skipped check", dep("dev", ManifestKindService, 1, true, "512"), []string{"checked"}, 1},
+		{"skipped worker check", dep("dev", ManifestKindWorker, 1, true, "512"), nil, 0},
+		{"bad owner", dep("dev", ManifestKindWorker, 1, false, "512", "sam@example.com", "judson"), []string{"emails"}, 1},
+		{"big", dep("dev", ManifestKindWorker, 1, false, "2048"), []string{"memory"}, 0},
+	}
+	for _, tc := range testCases {
+		flaws := rules.Lint(tc.d)
+		var broken []string
+		for _, f := range flaws {
+			broken = append(broken, f.Rule)
+		}
+		if len(broken) != len(tc.broken) {
+			t.Errorf("%s: got flaws %v; want rules %v broken", tc.name, flaws, tc.broken)
+			continue
+		}
+		for i := range broken {
+			if broken[i] != tc.broken[i] {
+				t.Errorf("%s: got rules %v broken; want %v", tc.name, broken, tc.broken)
+			}
+		}
+		if errs := rules.Errors(tc.d); len(errs) != tc.errors {
+			t.Errorf("%s: got errors %v; want %d", tc.name, errs, tc.errors)
+		}
+	}
+}
+
+func TestLintRules_Validate(t *testing.T) {
+	rules := LintRules{
+		{Field: "NumInstances", Op: "==", Value: "1"},
+		{Name: "severity", Severity: "fatal", Field: "NumInstances", Op: "==", Value: "1"},
+		{Name: "field", Field: "Replicas", Op: "==", Value: "1"},
+		{Name: "startup", Field: "Startup.Bogus", Op: "==", Value: "1"},
+		{Name: "op", Field: "NumInstances", Op: "=~", Value: "1"},
+		{Name: "numeric", Field: "NumInstances", Op: ">", Value: "one"},
+		{Name: "regex", Field: "Owners", Op: "matches", Value: "("},
+		{Name: "ok", Field: "Env.LOG_LEVEL", Op: "!=", Value: "debug"},
+	}
+	if flaws := rules.Validate(); len(flaws) != 7 {
+		t.Errorf("got flaws %v; want 7", flaws)
+	}
+}
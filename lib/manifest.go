@@ -31,6 +31,10 @@ type (
 		Kind ManifestKind `validate:"nonzero"`
 		// Deployments is a map of cluster names to DeploymentSpecs
 		Deployments DeploySpecs `validate:"keys=nonempty,values=nonzero"`
+		// AutoRollback opts this manifest in to automatic rollback: when a
+		// deployment of it fails, the server writes the last version known to
+		// have deployed successfully back to the GDM.
+		AutoRollback bool `yaml:",omitempty"`
 	}
 )
 
@@ -82,6 +86,9 @@ func (m *Manifest) Diff(o *Manifest) (bool, []string) {
 	if m.Kind != o.Kind {
 		diff("kind; this: %q; other: %q", m.Kind, o.Kind)
 	}
+	if m.AutoRollback != o.AutoRollback {
+		diff("auto rollback; this: %t; other: %t", m.AutoRollback, o.AutoRollback)
+	}
 	if len(m.Owners) != len(o.Owners) {
 		diff("number of owners; this: %d; other: %d", len(m.Owners), len(o.Owners))
 	} else {
@@ -43,3 +43,8 @@ func (mk ManifestKind) Validate() []Flaw {
 		return nil
 	}
 }
+
+// IsScheduled returns true if software of this kind runs on a Schedule.
+func (mk ManifestKind) IsScheduled() bool {
+	return mk == ManifestKindScheduled || mk == ScheduledJob
+}
@@ -92,6 +92,7 @@ func (ds Deployments) PutbackManifests(defs Defs, olds Manifests) (Manifests, er
 		}
 		m.Deployments[d.ClusterName] = spec
 		m.Kind = d.Kind
+		m.AutoRollback = d.AutoRollback
 
 		ms.Set(mid, m)
 	}
@@ -147,6 +148,7 @@ func (ds Deployments) RawManifests(defs Defs) (Manifests, error) {
 		}
 		m.Deployments[d.ClusterName] = spec
 		m.Kind = d.Kind
+		m.AutoRollback = d.AutoRollback
 
 		ms.Set(mid, m)
 	}
@@ -206,6 +208,7 @@ func BuildDeployment(m *Manifest, nick string, cluster *Cluster, spec DeploySpec
 		Owners:       ownMap,
 		Kind:         m.Kind,
 		SourceID:     m.Source.SourceID(ds.Version),
+		AutoRollback: m.AutoRollback,
 	}, nil
 }
 
@@ -0,0 +1,462 @@
+package sous
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A Member is a Sous server taking part in membership gossip.
+	Member struct {
+		// ClusterName is the name of the cluster the server resolves.
+		ClusterName string
+		// URL is where the other servers can reach the server.
+		URL string
+		// Incarnation distinguishes successive runs of the server, so that a
+		// restarted server is not mistaken for a stale one. It is the time the
+		// server started, in nanoseconds since the epoch.
+		Incarnation int64
+		// Heartbeat is advanced by the server itself every gossip round, so
+		// that the others can tell it is alive.
+		Heartbeat uint64
+	}
+
+	// An Announcement is what a Member says about itself when it gossips
+	// with another server.
+	Announcement struct {
+		Member
+		// Signature signs Member with the key the servers share, if they
+		// share one.
+		Signature string
+	}
+
+	// A MembershipTransport carries gossip between Sous servers.
+	MembershipTransport interface {
+		// Gossip announces self to the server at url, and returns the live
+		// members that server knows of, including itself.
+		Gossip(url string, self Announcement) ([]Member, error)
+	}
+
+	// Membership keeps track of the live Sous servers by gossip. Each round,
+	// it announces itself to a server chosen at random from the members and
+	// seeds it knows, and learns the members that server knows in return. A
+	// member whose heartbeat stops advancing for FailAfter is considered to
+	// have failed.
+	Membership struct {
+		// Interval is the time between gossip rounds.
+		Interval time.Duration
+		// FailAfter is how long a member's heartbeat may go without advancing
+		// before the member is considered to have failed. Failed members are
+		// forgotten after three times FailAfter, unless they are seeds.
+		FailAfter time.Duration
+		// Key is the secret the servers share to sign their announcements.
+		// Servers only accept announcements from seeds and members they know
+		// of already, unless the announcement is signed with Key.
+		Key       []byte
+		self      Member
+		seeds     map[string]struct{}
+		members   map[string]*memberState
+		transport MembershipTransport
+		ls        logging.LogSink
+		now       func() time.Time
+		pick      func(n int) int
+		sync.Mutex
+	}
+
+	memberState struct {
+		Member
+		// seen is when the member's heartbeat last advanced.
+		seen time.Time
+		// failed is true once the member has been reported as failed.
+		failed bool
+	}
+
+	// httpMembershipTransport is a MembershipTransport which gossips with
+	// the /gossip endpoint of each server.
+	httpMembershipTransport struct {
+		client *http.Client
+		ls     logging.LogSink
+	}
+
+	// copied from server - avoiding coupling to server implemention
+	gossipData struct {
+		Members []Member
+	}
+)
+
+const (
+	// DefaultMembershipInterval is the time between gossip rounds if
+	// Membership.Interval is not set.
+	DefaultMembershipInterval = time.Second
+	// DefaultMembershipFailAfter is how long a member may go unheard from
+	// before it is considered failed, if Membership.FailAfter is not set.
+	DefaultMembershipFailAfter = 10 * time.Second
+)
+
+// NewMembership returns a Membership for the server self, which begins by
+// gossiping with the servers at seeds. If self.Incarnation is zero, it is set
+// to the current time.
+func NewMembership(self Member, seeds []string, transport MembershipTransport, ls logging.LogSink) *Membership {
+	if self.Incarnation == 0 {
+		self.Incarnation = time.Now().UnixNano()
+	}
+	m := &Membership{
+		Interval:  DefaultMembershipInterval,
+		FailAfter: DefaultMembershipFailAfter,
+		self:      self,
+		seeds:     map[string]struct{}{},
+		members:   map[string]*memberState{},
+		transport: transport,
+		ls:        ls,
+		now:       time.Now,
+		pick:      rand.Intn,
+	}
+	m.AddSeeds(seeds...)
+	return m
+}
+
+// NewHTTPMembershipTransport returns a MembershipTransport which gossips
+// over HTTP with the /gossip endpoint of other Sous servers.
+func NewHTTPMembershipTransport(ls logging.LogSink) MembershipTransport {
+	return httpMembershipTransport{client: &http.Client{Timeout: SiblingTimeout}, ls: ls}
+}
+
+// SiblingTimeout bounds each request one Sous server makes of another.
+const SiblingTimeout = 10 * time.Second
+
+// Gossip implements MembershipTransport on httpMembershipTransport, by a PUT
+// of self to /gossip.
+func (t httpMembershipTransport) Gossip(url string, self Announcement) ([]Member, error) {
+	body, err := json.Marshal(self)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("PUT", strings.TrimSuffix(url, "/")+"/gossip", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Every PUT must state a precondition; /gossip has no GET, so none match.
+	req.Header.Set("If-None-Match", "*")
+	rz, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rz.Body.Close()
+	rzBody, err := ioutil.ReadAll(rz.Body)
+	if err != nil {
+		return nil, err
+	}
+	if rz.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("gossip with %s: %s: %s", url, rz.Status, bytes.TrimSpace(rzBody))
+	}
+	data := gossipData{}
+	if err := json.Unmarshal(rzBody, &data); err != nil {
+		return nil, errors.Wrapf(err, "gossip with %s", url)
+	}
+	return data.Members, nil
+}
+
+// SignAnnouncement returns the announcement of m, signed with key if it is
+// not empty.
+func SignAnnouncement(m Member, key []byte) Announcement {
+	a := Announcement{Member: m}
+	if len(key) > 0 {
+		a.Signature = SiblingSignature(key, m.signedParts()...)
+	}
+	return a
+}
+
+// Verify returns true if a is signed with key. It returns false if key is
+// empty.
+func (a Announcement) Verify(key []byte) bool {
+	return VerifySiblingSignature(key, a.Signature, a.Member.signedParts()...)
+}
+
+// signedParts returns the fields of m covered by the signature of its
+// announcement.
+func (m Member) signedParts() []string {
+	return []string{
+		m.ClusterName,
+		m.URL,
+		strconv.FormatInt(m.Incarnation, 10),
+		strconv.FormatUint(m.Heartbeat, 10),
+	}
+}
+
+// SiblingSignature signs parts with key, the secret which Sous servers share
+// to authenticate each other.
+func SiblingSignature(key []byte, parts ...string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySiblingSignature returns true if sig is the signature of parts with
+// key. It returns false if key is empty, since then nothing can be signed.
+func VerifySiblingSignature(key []byte, sig string, parts ...string) bool {
+	if len(key) == 0 || sig == "" {
+		return false
+	}
+	want, err := base64.RawURLEncoding.DecodeString(SiblingSignature(key, parts...))
+	if err != nil {
+		return false
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(got, want)
+}
+
+// newerThan returns true if m is a later report of a server than other.
+func (m Member) newerThan(other Member) bool {
+	if m.Incarnation != other.Incarnation {
+		return m.Incarnation > other.Incarnation
+	}
+	return m.Heartbeat > other.Heartbeat
+}
+
+// Self returns the member describing this server.
+func (m *Membership) Self() Member {
+	m.Lock()
+	defer m.Unlock()
+	return m.self
+}
+
+// AddSeeds adds servers to gossip with, whether or not they are live. Seeds
+// are never forgotten, so that a server which was partitioned from its seeds
+// rejoins them.
+func (m *Membership) AddSeeds(urls ...string) {
+	m.Lock()
+	defer m.Unlock()
+	for _, url := range urls {
+		if url != "" && url != m.self.URL {
+			m.seeds[url] = struct{}{}
+		}
+	}
+}
+
+// Announce records what a server says about itself when it gossips with this
+// server. It returns an error if the announcement is not accepted: only seeds
+// and members already known may announce themselves, unless the announcement
+// is signed with Key, and no member may claim a heartbeat or incarnation
+// beyond what could have passed since it was last heard from.
+func (m *Membership) Announce(a Announcement) error {
+	m.Lock()
+	defer m.Unlock()
+	member := a.Member
+	_, seed := m.seeds[member.URL]
+	_, known := m.members[member.URL]
+	if !seed && !known && !a.Verify(m.Key) {
+		return errors.Errorf("%s is not a known member, and its announcement is not signed", member.URL)
+	}
+	return m.merge(member, m.now())
+}
+
+// Merge records the members reported by another server.
+func (m *Membership) Merge(members []Member) {
+	m.Lock()
+	defer m.Unlock()
+	now := m.now()
+	for _, member := range members {
+		m.merge(member, now)
+	}
+}
+
+// merge assumes m is locked. It returns an error if member is implausible,
+// but not if it is merely older than what is known.
+func (m *Membership) merge(member Member, now time.Time) error {
+	if member.URL == "" || member.URL == m.self.URL {
+		return nil
+	}
+	known, ok := m.members[member.URL]
+	if ok && !member.newerThan(known.Member) {
+		return nil
+	}
+	var last *memberState
+	if ok {
+		last = known
+	}
+	if err := m.plausible(member, last, now); err != nil {
+		messages.ReportLogFieldsMessage(fmt.Sprintf("Ignoring gossip about %s: %v", member.URL, err),
+			logging.WarningLevel, m.ls, member)
+		return err
+	}
+	m.members[member.URL] = &memberState{Member: member, seen: now}
+	if !ok || known.failed {
+		reportMembershipChange(m.ls, "joined", member)
+	}
+	return nil
+}
+
+// plausible returns an error if member's incarnation or heartbeat has
+// advanced beyond what could have passed since last, or since the member
+// started if it is new or restarted. Members are allowed twice the rounds
+// which could have passed, and FailAfter of clock skew. It assumes m is
+// locked.
+func (m *Membership) plausible(member Member, last *memberState, now time.Time) error {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = DefaultMembershipInterval
+	}
+	rounds := func(since time.Time) uint64 {
+		elapsed := now.Sub(since) + m.FailAfter
+		if elapsed < 0 {
+			return 1
+		}
+		return 1 + 2*uint64(elapsed/interval)
+	}
+	since := time.Unix(0, member.Incarnation)
+	if last != nil && member.Incarnation == last.Incarnation {
+		if limit := last.Heartbeat + rounds(last.seen); member.Heartbeat > limit {
+			return errors.Errorf("heartbeat %d is beyond %d", member.Heartbeat, limit)
+		}
+		return nil
+	}
+	if since.After(now.Add(m.FailAfter)) {
+		return errors.Errorf("incarnation %s is in the future", since)
+	}
+	if limit := rounds(since); member.Heartbeat > limit {
+		return errors.Errorf("heartbeat %d is beyond %d", member.Heartbeat, limit)
+	}
+	return nil
+}
+
+// Live returns this server and the members it believes to be live, ordered by
+// cluster name and URL.
+func (m *Membership) Live() []Member {
+	m.Lock()
+	defer m.Unlock()
+	live := []Member{m.self}
+	for _, ms := range m.members {
+		if !ms.failed {
+			live = append(live, ms.Member)
+		}
+	}
+	sort.Slice(live, func(i, j int) bool {
+		if live[i].ClusterName != live[j].ClusterName {
+			return live[i].ClusterName < live[j].ClusterName
+		}
+		return live[i].URL < live[j].URL
+	})
+	return live
+}
+
+// Siblings returns the URL of a live server for each cluster, including this
+// server's own. If several live servers resolve the same cluster, the first by
+// URL is chosen.
+func (m *Membership) Siblings() map[string]string {
+	siblings := map[string]string{}
+	for _, member := range m.Live() {
+		if _, ok := siblings[member.ClusterName]; !ok {
+			siblings[member.ClusterName] = member.URL
+		}
+	}
+	return siblings
+}
+
+// Start runs gossip rounds every Interval until done is closed. Start does
+// nothing on a nil Membership.
+func (m *Membership) Start(done <-chan struct{}) {
+	if m == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(m.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				m.round()
+			}
+		}
+	}()
+}
+
+// round advances this server's heartbeat, marks members which have gone
+// quiet as failed, and gossips with one other server.
+func (m *Membership) round() {
+	m.Lock()
+	now := m.now()
+	m.self.Heartbeat++
+	self := SignAnnouncement(m.self, m.Key)
+	m.expire(now)
+	target := m.target()
+	m.Unlock()
+
+	if target == "" {
+		return
+	}
+	members, err := m.transport.Gossip(target, self)
+	if err != nil {
+		messages.ReportLogFieldsMessage(fmt.Sprintf("Gossip with %s failed: %v", target, err),
+			logging.DebugLevel, m.ls, target, err)
+		return
+	}
+	m.Merge(members)
+}
+
+// expire assumes m is locked.
+func (m *Membership) expire(now time.Time) {
+	for url, ms := range m.members {
+		quiet := now.Sub(ms.seen)
+		if !ms.failed && quiet >= m.FailAfter {
+			ms.failed = true
+			reportMembershipChange(m.ls, "failed", ms.Member)
+		}
+		if quiet >= 3*m.FailAfter {
+			delete(m.members, url)
+		}
+	}
+}
+
+// target chooses a server to gossip with from the live members and the seeds.
+// It assumes m is locked.
+func (m *Membership) target() string {
+	candidates := map[string]struct{}{}
+	for url := range m.seeds {
+		candidates[url] = struct{}{}
+	}
+	for url, ms := range m.members {
+		if !ms.failed {
+			candidates[url] = struct{}{}
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	urls := make([]string, 0, len(candidates))
+	for url := range candidates {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls[m.pick(len(urls))]
+}
+
+func reportMembershipChange(ls logging.LogSink, change string, member Member) {
+	if ls == nil {
+		return
+	}
+	messages.ReportLogFieldsMessage(
+		fmt.Sprintf("Sous server %s for cluster %q %s", member.URL, member.ClusterName, change),
+		logging.InformationLevel, ls, member)
+}
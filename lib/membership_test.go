@@ -0,0 +1,152 @@
+package sous
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testMembershipNet connects Memberships in memory, and can partition them.
+type testMembershipNet struct {
+	members map[string]*Membership
+	down    map[string]bool
+}
+
+func (n *testMembershipNet) Gossip(url string, self Announcement) ([]Member, error) {
+	m, ok := n.members[url]
+	if !ok || n.down[url] || n.down[self.URL] {
+		return nil, fmt.Errorf("%s unreachable", url)
+	}
+	if err := m.Announce(self); err != nil {
+		return nil, err
+	}
+	return m.Live(), nil
+}
+
+var testMembershipKey = []byte("shared secret")
+
+func testMemberships(now *time.Time, clusters ...string) (*testMembershipNet, []*Membership) {
+	net := &testMembershipNet{members: map[string]*Membership{}, down: map[string]bool{}}
+	var ms []*Membership
+	for i, cluster := range clusters {
+		self := Member{ClusterName: cluster, URL: fmt.Sprintf("http://sous-%d", i), Incarnation: 1}
+		// Every server is seeded with the first.
+		m := NewMembership(self, []string{"http://sous-0"}, net, logging.SilentLogSet())
+		m.FailAfter = 5 * time.Second
+		m.Key = testMembershipKey
+		m.now = func() time.Time { return *now }
+		m.pick = func(n int) int { return (i + int(m.self.Heartbeat)) % n }
+		net.members[self.URL] = m
+		ms = append(ms, m)
+	}
+	return net, ms
+}
+
+func gossipRounds(now *time.Time, rounds int, ms ...*Membership) {
+	for r := 0; r < rounds; r++ {
+		*now = now.Add(time.Second)
+		for _, m := range ms {
+			m.round()
+		}
+	}
+}
+
+func TestMembership_converges(t *testing.T) {
+	now := time.Unix(1000, 0)
+	_, ms := testMemberships(&now, "left", "right", "center")
+
+	gossipRounds(&now, 3, ms...)
+
+	want := map[string]string{"left": "http://sous-0", "right": "http://sous-1", "center": "http://sous-2"}
+	for _, m := range ms {
+		assert.Equal(t, want, m.Siblings(), "siblings known to %s", m.Self().URL)
+	}
+}
+
+func TestMembership_detectsFailure(t *testing.T) {
+	now := time.Unix(1000, 0)
+	net, ms := testMemberships(&now, "left", "right", "center")
+	gossipRounds(&now, 3, ms...)
+
+	net.down["http://sous-2"] = true
+	gossipRounds(&now, 6, ms[0], ms[1])
+	for _, m := range ms[:2] {
+		assert.Equal(t, map[string]string{"left": "http://sous-0", "right": "http://sous-1"}, m.Siblings(),
+			"siblings known to %s", m.Self().URL)
+	}
+
+	// The failed server restarts, with its heartbeat starting again.
+	restarted := ms[2].Self()
+	restarted.Incarnation++
+	restarted.Heartbeat = 0
+	ms[2] = NewMembership(restarted, []string{"http://sous-0"}, net, logging.SilentLogSet())
+	ms[2].Key = testMembershipKey
+	ms[2].now = func() time.Time { return now }
+	net.members[restarted.URL] = ms[2]
+	delete(net.down, restarted.URL)
+	gossipRounds(&now, 3, ms...)
+	for _, m := range ms {
+		assert.Len(t, m.Siblings(), 3, "siblings known to %s", m.Self().URL)
+	}
+}
+
+func TestMembership_merge(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := NewMembership(Member{ClusterName: "left", URL: "http://self"}, nil, nil, logging.SilentLogSet())
+	m.now = func() time.Time { return now }
+
+	m.Merge([]Member{
+		{ClusterName: "left", URL: "http://self", Incarnation: 99, Heartbeat: 99},
+		{ClusterName: "right", URL: "http://right", Incarnation: 2, Heartbeat: 5},
+	})
+	assert.NoError(t, m.Announce(Announcement{Member: Member{ClusterName: "right", URL: "http://right", Incarnation: 2, Heartbeat: 4}}))
+	assert.NoError(t, m.Announce(Announcement{Member: Member{ClusterName: "right", URL: "http://right", Incarnation: 1, Heartbeat: 50}}))
+
+	live := m.Live()
+	require.Len(t, live, 2)
+	assert.Equal(t, m.Self(), live[0], "gossip about this server should be ignored")
+	assert.Equal(t, Member{ClusterName: "right", URL: "http://right", Incarnation: 2, Heartbeat: 5}, live[1],
+		"older reports should be ignored")
+}
+
+func TestMembership_Announce(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := NewMembership(Member{ClusterName: "left", URL: "http://self"}, []string{"http://seed"}, nil, logging.SilentLogSet())
+	m.Key = testMembershipKey
+	m.now = func() time.Time { return now }
+	started := now.Add(-time.Minute).UnixNano()
+	stranger := Member{ClusterName: "right", URL: "http://stranger", Incarnation: started, Heartbeat: 1}
+
+	assert.Error(t, m.Announce(Announcement{Member: stranger}), "unsigned announcement by a stranger")
+	assert.Error(t, m.Announce(SignAnnouncement(stranger, []byte("wrong key"))))
+	forged := SignAnnouncement(stranger, testMembershipKey)
+	forged.URL = "http://forged"
+	assert.Error(t, m.Announce(forged))
+	assert.Len(t, m.Live(), 1)
+
+	assert.NoError(t, m.Announce(Announcement{Member: Member{ClusterName: "center", URL: "http://seed", Incarnation: started, Heartbeat: 1}}),
+		"seeds need not sign")
+	assert.NoError(t, m.Announce(SignAnnouncement(stranger, testMembershipKey)))
+	assert.Len(t, m.Live(), 3)
+
+	// Known members no longer need to sign, but may only advance their
+	// heartbeat as far as time allows.
+	now = now.Add(2 * time.Second)
+	stranger.Heartbeat = 1 << 40
+	assert.Error(t, m.Announce(Announcement{Member: stranger}))
+	stranger.Heartbeat = 3
+	assert.NoError(t, m.Announce(Announcement{Member: stranger}))
+
+	future := stranger
+	future.Incarnation = now.Add(time.Hour).UnixNano()
+	future.Heartbeat = 0
+	assert.Error(t, m.Announce(Announcement{Member: future}), "incarnation in the future")
+
+	live := m.Live()
+	require.Len(t, live, 3)
+	assert.Equal(t, stranger, live[2])
+}
@@ -0,0 +1,89 @@
+package sous
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type (
+	// A Pause stops Sous from rectifying a deployment for a while, e.g. so
+	// that it can be fixed by hand during an incident without Sous reverting
+	// the fix.
+	Pause struct {
+		// User is the user who paused the deployment.
+		User User
+		// Reason explains the pause to anyone whose deployment is held up.
+		Reason string
+		// At is when the deployment was paused.
+		At time.Time
+		// Until is when the pause lapses, and Sous rectifies the deployment
+		// again.
+		Until time.Time
+	}
+
+	// A PausedError is returned when a deployment is not rectified because
+	// it is paused.
+	PausedError struct {
+		DeploymentID DeploymentID
+		Pause        Pause
+	}
+
+	pauseProcessor struct {
+		at time.Time
+	}
+)
+
+func (err *PausedError) Error() string {
+	return fmt.Sprintf("%s is paused by %s until %s: %s", err.DeploymentID,
+		err.Pause.User, err.Pause.Until.Format(time.RFC3339), err.Pause.Reason)
+}
+
+// ActiveAt returns true if the pause is in effect at the given time. A nil
+// Pause is never in effect.
+func (p *Pause) ActiveAt(at time.Time) bool {
+	return p != nil && at.Before(p.Until)
+}
+
+// Equal returns true if p and o are both nil, or are the same pause.
+func (p *Pause) Equal(o *Pause) bool {
+	if p == nil || o == nil {
+		return p == o
+	}
+	return p.User == o.User && p.Reason == o.Reason && p.At.Equal(o.At) && p.Until.Equal(o.Until)
+}
+
+// Validate returns flaws if the pause cannot be set at the given time:
+// it must give a reason, and must not have lapsed already.
+func (p Pause) Validate(at time.Time) []Flaw {
+	var flaws []Flaw
+	if p.Reason == "" {
+		flaws = append(flaws, FatalFlaw("A pause must give a reason."))
+	}
+	if !p.Until.After(at) {
+		flaws = append(flaws, FatalFlaw("A pause must last until after %s, not %s.",
+			at.Format(time.RFC3339), p.Until.Format(time.RFC3339)))
+	}
+	return flaws
+}
+
+// SkipPaused adds a pipeline step which stops any deployment paused at the
+// given time from being rectified, whether or not it has changed.
+func (d *DeployableChans) SkipPaused(ctx context.Context, at time.Time) *DeployableChans {
+	return d.Pipeline(ctx, pauseProcessor{at: at})
+}
+
+func (pp pauseProcessor) HandlePairs(dp *DeployablePair) (*DeployablePair, *DiffResolution) {
+	if dp.Post == nil || dp.Post.Deployment == nil {
+		return dp, nil
+	}
+	pause := dp.Post.Deployment.Pause
+	if !pause.ActiveAt(pp.at) {
+		return dp, nil
+	}
+	return nil, &DiffResolution{
+		DeploymentID: dp.ID(),
+		Desc:         PausedDiff,
+		Error:        WrapResolveError(&PausedError{DeploymentID: dp.ID(), Pause: *pause}),
+	}
+}
@@ -0,0 +1,67 @@
+package sous
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pauseTestNow = time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestPause_ActiveAt(t *testing.T) {
+	var none *Pause
+	assert.False(t, none.ActiveAt(pauseTestNow))
+
+	p := &Pause{Reason: "incident", At: pauseTestNow, Until: pauseTestNow.Add(time.Hour)}
+	assert.True(t, p.ActiveAt(pauseTestNow))
+	assert.True(t, p.ActiveAt(pauseTestNow.Add(59*time.Minute)))
+	assert.False(t, p.ActiveAt(pauseTestNow.Add(time.Hour)), "a pause should lapse at Until")
+}
+
+func TestPause_Validate(t *testing.T) {
+	p := Pause{Reason: "incident", Until: pauseTestNow.Add(time.Hour)}
+	assert.Empty(t, p.Validate(pauseTestNow))
+
+	p.Reason = ""
+	assert.Len(t, p.Validate(pauseTestNow), 1)
+
+	p = Pause{Reason: "incident", Until: pauseTestNow}
+	assert.Len(t, p.Validate(pauseTestNow), 1, "a pause which has already lapsed")
+}
+
+func TestDeployableChans_SkipPaused(t *testing.T) {
+	paused := DeploymentFixture("")
+	paused.Pause = &Pause{User: User{Email: "ops@example.com"}, Reason: "incident", Until: pauseTestNow.Add(time.Hour)}
+	lapsed := DeploymentFixture("")
+	lapsed.ClusterName = "cluster-2"
+	lapsed.Pause = &Pause{Reason: "old incident", Until: pauseTestNow.Add(-time.Hour)}
+	removed := DeploymentFixture("")
+	removed.ClusterName = "cluster-3"
+	require.NotEqual(t, paused.ID(), lapsed.ID())
+
+	dc := NewDeployableChans(3)
+	for _, dep := range []*Deployment{paused, lapsed} {
+		dc.Pairs <- &DeployablePair{name: dep.ID(), Prior: &Deployable{Deployment: dep}, Post: &Deployable{Deployment: dep}}
+	}
+	dc.Pairs <- &DeployablePair{name: removed.ID(), Prior: &Deployable{Deployment: removed}}
+	dc.Close()
+
+	out := dc.SkipPaused(context.Background(), pauseTestNow)
+	var passed []DeploymentID
+	for p := range out.Pairs {
+		passed = append(passed, p.ID())
+	}
+	var stopped []DiffResolution
+	for rez := range out.Errs {
+		stopped = append(stopped, *rez)
+	}
+
+	assert.Equal(t, []DeploymentID{lapsed.ID(), removed.ID()}, passed)
+	require.Len(t, stopped, 1)
+	assert.Equal(t, paused.ID(), stopped[0].DeploymentID)
+	assert.Equal(t, PausedDiff, stopped[0].Desc)
+	assert.Contains(t, stopped[0].Error.Error(), "ops@example.com")
+}
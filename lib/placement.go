@@ -0,0 +1,110 @@
+package sous
+
+import "fmt"
+
+// Placement constrains the hosts a deployment's instances are run on.
+// c.f. DeployConfig for use.
+type Placement struct { //                                Singularity fields
+	// RequiredAttributes are host attributes which each instance's host must
+	// have, with these values.
+	RequiredAttributes map[string]string `yaml:",omitempty"` // requiredSlaveAttributes
+	// PreferredAttributes are host attributes which instances should be run
+	// on where possible. Singularity has no soft constraints, and instead
+	// allows instances onto hosts reserved for these attributes.
+	PreferredAttributes map[string]string `yaml:",omitempty"` // allowedSlaveAttributes
+	// RackSensitive spreads instances evenly across racks.
+	RackSensitive bool `yaml:",omitempty"` // rackSensitive
+	// MaxInstancesPerHost limits the number of instances on any one host.
+	// Zero means no limit. Singularity can only enforce a limit of 1.
+	MaxInstancesPerHost int `yaml:",omitempty"` // slavePlacement
+}
+
+// Validate implements Flawed on Placement.
+func (p *Placement) Validate() []Flaw {
+	var flaws []Flaw
+	if p.MaxInstancesPerHost < 0 {
+		flaws = append(flaws, FatalFlaw("MaxInstancesPerHost less than zero: %d!", p.MaxInstancesPerHost))
+	}
+	for name, attrs := range map[string]map[string]string{
+		"RequiredAttributes":  p.RequiredAttributes,
+		"PreferredAttributes": p.PreferredAttributes,
+	} {
+		for k := range attrs {
+			if k == "" {
+				flaws = append(flaws, FatalFlaw("%s includes an empty attribute name", name))
+			}
+		}
+	}
+	return flaws
+}
+
+// ValidateFor returns a Flaw if p requires a constraint which deployments to
+// cluster cannot enforce. Kubernetes clusters only support attributes, as
+// node labels. Singularity clusters only support a MaxInstancesPerHost of 1,
+// since Singularity has no other per host limit.
+func (p Placement) ValidateFor(cluster *Cluster) []Flaw {
+	if cluster == nil {
+		return nil
+	}
+	var flaws []Flaw
+	switch cluster.NormalizedKind() {
+	case ClusterKindKubernetes:
+		if p.RackSensitive {
+			flaws = append(flaws, FatalFlaw("RackSensitive is not supported by kubernetes cluster %s.", cluster.Name))
+		}
+		if p.MaxInstancesPerHost != 0 {
+			flaws = append(flaws, FatalFlaw("MaxInstancesPerHost is not supported by kubernetes cluster %s.", cluster.Name))
+		}
+	case ClusterKindSingularity:
+		if p.MaxInstancesPerHost > 1 {
+			flaws = append(flaws, FatalFlaw("MaxInstancesPerHost %d is not supported by singularity cluster %s; use 1 or 0.", p.MaxInstancesPerHost, cluster.Name))
+		}
+	}
+	return flaws
+}
+
+// Clone returns a deep copy of this Placement.
+func (p Placement) Clone() Placement {
+	p.RequiredAttributes = cloneAttributes(p.RequiredAttributes)
+	p.PreferredAttributes = cloneAttributes(p.PreferredAttributes)
+	return p
+}
+
+func cloneAttributes(attrs map[string]string) map[string]string {
+	if attrs == nil {
+		return nil
+	}
+	c := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		c[k] = v
+	}
+	return c
+}
+
+// IsZero returns true if p places no constraints.
+func (p Placement) IsZero() bool {
+	return len(p.RequiredAttributes) == 0 && len(p.PreferredAttributes) == 0 &&
+		!p.RackSensitive && p.MaxInstancesPerHost == 0
+}
+
+// Equal returns true if p and o place the same constraints.
+func (p Placement) Equal(o Placement) bool {
+	return len(p.diff(o)) == 0
+}
+
+func (p Placement) diff(o Placement) []string {
+	var diffs []string
+	if !Metadata(p.RequiredAttributes).Equal(o.RequiredAttributes) {
+		diffs = append(diffs, fmt.Sprintf("required attributes; this: %v; other: %v", p.RequiredAttributes, o.RequiredAttributes))
+	}
+	if !Metadata(p.PreferredAttributes).Equal(o.PreferredAttributes) {
+		diffs = append(diffs, fmt.Sprintf("preferred attributes; this: %v; other: %v", p.PreferredAttributes, o.PreferredAttributes))
+	}
+	if p.RackSensitive != o.RackSensitive {
+		diffs = append(diffs, fmt.Sprintf("rack sensitive; this: %t; other: %t", p.RackSensitive, o.RackSensitive))
+	}
+	if p.MaxInstancesPerHost != o.MaxInstancesPerHost {
+		diffs = append(diffs, fmt.Sprintf("max instances per host; this: %d; other: %d", p.MaxInstancesPerHost, o.MaxInstancesPerHost))
+	}
+	return diffs
+}
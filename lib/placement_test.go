@@ -0,0 +1,64 @@
+package sous
+
+import "testing"
+
+func TestPlacement_Validate(t *testing.T) {
+	valid := Placement{RequiredAttributes: map[string]string{"zone": "a"}, MaxInstancesPerHost: 1}
+	if flaws := valid.Validate(); len(flaws) != 0 {
+		t.Errorf("%+v: got flaws %v", valid, flaws)
+	}
+	for _, invalid := range []Placement{
+		{MaxInstancesPerHost: -1},
+		{PreferredAttributes: map[string]string{"": "a"}},
+	} {
+		if flaws := invalid.Validate(); len(flaws) != 1 {
+			t.Errorf("%+v: got flaws %v; want one", invalid, flaws)
+		}
+	}
+}
+
+func TestPlacement_ValidateFor(t *testing.T) {
+	singularity := &Cluster{Name: "left", Kind: ClusterKindSingularity}
+	kubernetes := &Cluster{Name: "k8s", Kind: ClusterKindKubernetes}
+	p := Placement{RequiredAttributes: map[string]string{"zone": "a"}, RackSensitive: true, MaxInstancesPerHost: 1}
+	if flaws := p.ValidateFor(singularity); len(flaws) != 0 {
+		t.Errorf("singularity: got flaws %v", flaws)
+	}
+	if flaws := p.ValidateFor(kubernetes); len(flaws) != 2 {
+		t.Errorf("kubernetes: got flaws %v; want two", flaws)
+	}
+	if flaws := (Placement{MaxInstancesPerHost: 2}).ValidateFor(singularity); len(flaws) != 1 {
+		t.Errorf("singularity, 2 per host: got flaws %v; want one", flaws)
+	}
+	attrsOnly := Placement{RequiredAttributes: map[string]string{"zone": "a"}}
+	if flaws := attrsOnly.ValidateFor(kubernetes); len(flaws) != 0 {
+		t.Errorf("kubernetes attributes: got flaws %v", flaws)
+	}
+}
+
+func TestPlacement_diff(t *testing.T) {
+	a := Placement{RequiredAttributes: map[string]string{"zone": "a"}}
+	if !a.Equal(a.Clone()) {
+		t.Errorf("%+v not equal to its clone", a)
+	}
+	if !(Placement{}).Equal(Placement{RequiredAttributes: map[string]string{}}) {
+		t.Errorf("nil and empty attributes not equal")
+	}
+	b := a.Clone()
+	b.RequiredAttributes["zone"] = "b"
+	b.MaxInstancesPerHost = 2
+	if diffs := a.diff(b); len(diffs) != 2 {
+		t.Errorf("got diffs %v; want two", diffs)
+	}
+	if a.RequiredAttributes["zone"] != "a" {
+		t.Errorf("Clone shares RequiredAttributes")
+	}
+}
+
+func TestFlattenDeployConfigs_placement(t *testing.T) {
+	p := Placement{RackSensitive: true}
+	dc := flattenDeployConfigs([]DeployConfig{{}, {Placement: p}, {Placement: Placement{MaxInstancesPerHost: 1}}})
+	if !dc.Placement.Equal(p) {
+		t.Errorf("got placement %+v; want %+v", dc.Placement, p)
+	}
+}
@@ -4,21 +4,32 @@ import (
 	"container/ring"
 	"sort"
 	"sync"
+	"time"
 
+	"github.com/opentable/sous/util/logging"
 	"github.com/pborman/uuid"
 )
 
 // MaxRefsPerR11nQueue is the maximum number of rectifications to cache in memory.
 const MaxRefsPerR11nQueue = 100
 
-// R11nQueue is a queue of rectifications.
+// R11nQueue is a queue of rectifications. Rectifications with a higher
+// Priority are handled first; those with equal priority in the order they were
+// pushed.
 type R11nQueue struct {
-	cap           int
-	queue         chan *QueuedR11n
+	cap int
+	// pending holds the rectifications waiting to be handled, in the order
+	// they will be handled.
+	pending []*QueuedR11n
+	// ready is signalled whenever a rectification is added to pending.
+	ready         chan struct{}
 	refs, allRefs map[R11nID]*QueuedR11n
 	fifoRefs      *ring.Ring
 	handler       func(*QueuedR11n) DiffResolution
 	start         bool
+	events        *ResolveEvents
+	store         R11nStore
+	ls            logging.LogSink
 	sync.Mutex
 }
 
@@ -50,6 +61,23 @@ func R11nQueueCap(cap int) R11nQueueOpt {
 	}
 }
 
+// R11nQueueEvents publishes the progress of each rectification to events.
+func R11nQueueEvents(events *ResolveEvents) R11nQueueOpt {
+	return func(rq *R11nQueue) {
+		rq.events = events
+	}
+}
+
+// R11nQueueStore records each rectification in store while it is queued, so
+// that it can be restored after a restart. Failures to write to store are
+// reported to ls, and do not stop the rectification.
+func R11nQueueStore(store R11nStore, ls logging.LogSink) R11nQueueOpt {
+	return func(rq *R11nQueue) {
+		rq.store = store
+		rq.ls = ls
+	}
+}
+
 // R11nQueueStartWithHandler starts processing the queue using the supplied
 // handler.
 func R11nQueueStartWithHandler(handler func(*QueuedR11n) DiffResolution) R11nQueueOpt {
@@ -97,7 +125,7 @@ func (rq *R11nQueue) ByID(id R11nID) (*QueuedR11n, bool) {
 func (rq *R11nQueue) init() *R11nQueue {
 	rq.Lock()
 	defer rq.Unlock()
-	rq.queue = make(chan *QueuedR11n, rq.cap)
+	rq.ready = make(chan struct{}, 1)
 	rq.refs = map[R11nID]*QueuedR11n{}
 	rq.allRefs = map[R11nID]*QueuedR11n{}
 	rq.fifoRefs = ring.New(MaxRefsPerR11nQueue)
@@ -109,7 +137,9 @@ type QueuedR11n struct {
 	ID            R11nID
 	Pos           int
 	Rectification *Rectification
-	done          chan struct{}
+	// QueuedAt is when the rectification was first queued.
+	QueuedAt time.Time
+	done     chan struct{}
 }
 
 // R11nID is a QueuedR11n identifier.
@@ -128,11 +158,15 @@ func (rq *R11nQueue) Start(handler func(*QueuedR11n) DiffResolution) <-chan Diff
 	go func() {
 		for {
 			qr := rq.next()
-			results <- handler(qr)
+			rq.events.publishR11n(R11nStartedEvent, qr, nil)
+			dr := handler(qr)
+			rq.events.publishR11n(R11nResolvedEvent, qr, &dr)
+			results <- dr
 			rq.Lock()
 			close(qr.done)
 			delete(rq.refs, qr.ID)
 			rq.Unlock()
+			rq.unstore(qr)
 		}
 	}()
 	return results
@@ -158,7 +192,7 @@ func (rq *R11nQueue) Wait(id R11nID) (DiffResolution, bool) {
 func (rq *R11nQueue) Push(r *Rectification) (*QueuedR11n, bool) {
 	rq.Lock()
 	defer rq.Unlock()
-	if len(rq.queue) == rq.cap {
+	if len(rq.pending) == rq.cap {
 		return nil, false
 	}
 	return rq.internalPush(r), true
@@ -166,13 +200,65 @@ func (rq *R11nQueue) Push(r *Rectification) (*QueuedR11n, bool) {
 
 // internalPush assumes rq is already locked.
 func (rq *R11nQueue) internalPush(r *Rectification) *QueuedR11n {
-	id := NewR11nID()
+	qr := rq.insert(NewR11nID(), r, time.Now())
+	if rq.store != nil {
+		rq.storeR11n(qr)
+		rq.storeStages(qr)
+	}
+	rq.events.publishR11n(R11nQueuedEvent, qr, nil)
+	return qr
+}
+
+// restore adds a rectification read from the store back to the queue under
+// its original ID. It ignores the queue's capacity, since the rectification
+// was accepted before the restart.
+func (rq *R11nQueue) restore(id R11nID, r *Rectification, queuedAt time.Time) *QueuedR11n {
+	rq.Lock()
+	defer rq.Unlock()
+	qr := rq.insert(id, r, queuedAt)
+	if rq.store != nil {
+		rq.storeStages(qr)
+	}
+	rq.events.publishR11n(R11nQueuedEvent, qr, nil)
+	return qr
+}
+
+// storeR11n records qr in the store.
+func (rq *R11nQueue) storeR11n(qr *QueuedR11n) {
+	if err := rq.store.StoreR11n(NewStoredR11n(qr)); err != nil {
+		reportR11nStoreError(rq.ls, qr, err)
+	}
+}
+
+// storeStages records qr in the store again each time its rollout moves to
+// a new stage, so that a restored rollout resumes where it left off. It
+// assumes rq is already locked, so qr cannot have started.
+func (rq *R11nQueue) storeStages(qr *QueuedR11n) {
+	qr.Rectification.Lock()
+	defer qr.Rectification.Unlock()
+	qr.Rectification.onStage = func() { rq.storeR11n(qr) }
+}
+
+// insert adds r to pending behind any rectification of the same or a higher
+// priority, and signals next. It assumes rq is already locked.
+func (rq *R11nQueue) insert(id R11nID, r *Rectification, queuedAt time.Time) *QueuedR11n {
+	pos := sort.Search(len(rq.pending), func(i int) bool {
+		return rq.pending[i].Rectification.Priority < r.Priority
+	})
 	qr := &QueuedR11n{
 		ID:            id,
-		Pos:           len(rq.queue),
+		Pos:           pos,
 		Rectification: r,
+		QueuedAt:      queuedAt,
 		done:          make(chan struct{}),
 	}
+	rq.pending = append(rq.pending, nil)
+	copy(rq.pending[pos+1:], rq.pending[pos:])
+	rq.pending[pos] = qr
+	for _, later := range rq.pending[pos+1:] {
+		later.Pos++
+	}
+
 	rq.refs[id] = qr
 	rq.allRefs[id] = qr
 	rq.fifoRefs = rq.fifoRefs.Next()
@@ -181,10 +267,72 @@ func (rq *R11nQueue) internalPush(r *Rectification) *QueuedR11n {
 		delete(rq.allRefs, idToDelete)
 	}
 	rq.fifoRefs.Value = id
-	rq.queue <- qr
+
+	select {
+	case rq.ready <- struct{}{}:
+	default:
+	}
 	return qr
 }
 
+// Cancel removes the rectification with the given ID from the queue, if it
+// has not yet started, and returns it and true. Anyone waiting for it receives
+// a resolution with Desc CancelledDiff. A staged rollout which has started is
+// cancelled too, unless it has reached its final stage: Cancel waits for it
+// to stop (see Rectification.Cancel). If no such rectification can be
+// cancelled, Cancel returns nil and false.
+func (rq *R11nQueue) Cancel(id R11nID) (*QueuedR11n, bool) {
+	rq.Lock()
+	qr := rq.cancel(id)
+	if qr == nil {
+		running, ok := rq.refs[id]
+		rq.Unlock()
+		if !ok || !running.Rectification.Cancel() {
+			return nil, false
+		}
+		// The worker publishes the resolution and unstores it.
+		<-running.done
+		return running, running.Rectification.Resolution.Desc == CancelledDiff
+	}
+	rq.Unlock()
+	rq.unstore(qr)
+	return qr, true
+}
+
+// cancel assumes rq is already locked.
+func (rq *R11nQueue) cancel(id R11nID) *QueuedR11n {
+	for i, qr := range rq.pending {
+		if qr.ID != id {
+			continue
+		}
+		rq.pending = append(rq.pending[:i], rq.pending[i+1:]...)
+		for _, later := range rq.pending[i:] {
+			later.Pos--
+		}
+		delete(rq.refs, id)
+		qr.Pos = -1
+		dr := DiffResolution{
+			DeploymentID: qr.Rectification.Pair.ID(),
+			Desc:         CancelledDiff,
+		}
+		qr.Rectification.Resolution = dr
+		close(qr.done)
+		rq.events.publishR11n(R11nCancelledEvent, qr, &dr)
+		return qr
+	}
+	return nil
+}
+
+// unstore removes qr from the store, once it has been handled or cancelled.
+func (rq *R11nQueue) unstore(qr *QueuedR11n) {
+	if rq.store == nil {
+		return
+	}
+	if err := rq.store.RemoveR11n(qr.ID); err != nil {
+		reportR11nStoreError(rq.ls, qr, err)
+	}
+}
+
 // PushIfEmpty adds an item to the queue if it is empty, and returns the wrapper
 // added and true if successful. If the queue is not empty, or is full, it
 // returns nil, false.
@@ -201,17 +349,26 @@ func (rq *R11nQueue) PushIfEmpty(r *Rectification) (*QueuedR11n, bool) {
 
 // Len returns the current number of items in the queue.
 func (rq *R11nQueue) Len() int {
-	return len(rq.queue)
+	rq.Lock()
+	defer rq.Unlock()
+	return len(rq.pending)
 }
 
 // next waits until there is something on the queue to
 // return and then returns it.
 func (rq *R11nQueue) next() *QueuedR11n {
-	qr := <-rq.queue
-	rq.Lock()
-	defer rq.Unlock()
-	rq.handlePopped(qr.ID)
-	return qr
+	for {
+		rq.Lock()
+		if len(rq.pending) > 0 {
+			qr := rq.pending[0]
+			rq.pending = rq.pending[1:]
+			rq.handlePopped(qr.ID)
+			rq.Unlock()
+			return qr
+		}
+		rq.Unlock()
+		<-rq.ready
+	}
 }
 
 // handlePopped assumes rq is locked.
@@ -12,6 +12,7 @@ type (
 		PushIfEmpty(r *Rectification) (*QueuedR11n, bool)
 		Push(r *Rectification) (*QueuedR11n, bool)
 		Wait(did DeploymentID, id R11nID) (DiffResolution, bool)
+		Cancel(did DeploymentID, id R11nID) (*QueuedR11n, bool)
 		Queues() map[DeploymentID]*R11nQueue
 	}
 
@@ -77,6 +78,19 @@ func (rqs *R11nQueueSet) Wait(did DeploymentID, id R11nID) (DiffResolution, bool
 	return rq.Wait(id)
 }
 
+// Cancel cancels the r11n with id id, if it is waiting in the queue for did.
+// If there is no queue for did, or id is not waiting in it, it returns nil,
+// false.
+func (rqs *R11nQueueSet) Cancel(did DeploymentID, id R11nID) (*QueuedR11n, bool) {
+	rqs.Lock()
+	rq, ok := rqs.set[did]
+	rqs.Unlock()
+	if !ok {
+		return nil, false
+	}
+	return rq.Cancel(id)
+}
+
 // Queues returns a snapshot of queues in this set.
 func (rqs *R11nQueueSet) Queues() map[DeploymentID]*R11nQueue {
 	rqs.Lock()
@@ -115,6 +129,15 @@ func (s QueueSetSpy) Wait(did DeploymentID, id R11nID) (DiffResolution, bool) {
 	return res.Get(0).(DiffResolution), res.Bool(1)
 }
 
+// Cancel is a spy implementation of QueueSet
+func (s QueueSetSpy) Cancel(did DeploymentID, id R11nID) (*QueuedR11n, bool) {
+	res := s.Called(did, id)
+	if res.Get(0) == nil {
+		return nil, false
+	}
+	return res.Get(0).(*QueuedR11n), res.Bool(1)
+}
+
 // Queues is a spy implementation of QueueSet
 func (s QueueSetSpy) Queues() map[DeploymentID]*R11nQueue {
 	res := s.Called()
@@ -435,3 +435,65 @@ func checkR11nHasRepo(repo string) func(*QueuedR11n) error {
 		return nil
 	}
 }
+
+func TestR11nQueue_Push_priority(t *testing.T) {
+	rq := NewR11nQueue()
+	auto1 := makeTestR11nWithRepo("auto1")
+	auto2 := makeTestR11nWithRepo("auto2")
+	manual1 := makeTestR11nWithRepo("manual1")
+	manual1.Priority = R11nPriorityManual
+	manual2 := makeTestR11nWithRepo("manual2")
+	manual2.Priority = R11nPriorityManual
+
+	for _, r := range []*Rectification{auto1, auto2, manual1, manual2} {
+		if _, ok := rq.Push(r); !ok {
+			t.Fatal("setup failed to push r11n")
+		}
+	}
+
+	var gotPos []int
+	for _, qr := range rq.Snapshot() {
+		gotPos = append(gotPos, qr.Pos)
+	}
+	if fmt.Sprint(gotPos) != "[0 1 2 3]" {
+		t.Errorf("got positions %v; want [0 1 2 3]", gotPos)
+	}
+
+	for _, repo := range []string{"manual1", "manual2", "auto1", "auto2"} {
+		if err := checkR11nHasRepo(repo)(rq.next()); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestR11nQueue_Cancel(t *testing.T) {
+	rq := NewR11nQueue()
+	first, _ := rq.Push(makeTestR11nWithRepo("one"))
+	second, _ := rq.Push(makeTestR11nWithRepo("two"))
+	third, _ := rq.Push(makeTestR11nWithRepo("three"))
+
+	cancelled, ok := rq.Cancel(second.ID)
+	if !ok || cancelled != second {
+		t.Fatalf("got %v, %t; want the second rectification, true", cancelled, ok)
+	}
+	if _, ok := rq.Cancel(second.ID); ok {
+		t.Errorf("cancelled the same rectification twice")
+	}
+	if got := rq.Len(); got != 2 {
+		t.Errorf("got length %d; want 2", got)
+	}
+	if third.Pos != 1 {
+		t.Errorf("got position %d for the third rectification; want 1", third.Pos)
+	}
+	rez, ok := rq.Wait(second.ID)
+	if !ok || rez.Desc != CancelledDiff {
+		t.Errorf("got %v, %t waiting for cancelled rectification; want %q, true", rez, ok, CancelledDiff)
+	}
+
+	if got := rq.next(); got != first {
+		t.Errorf("got %v; want the first rectification", got)
+	}
+	if _, ok := rq.Cancel(first.ID); ok {
+		t.Errorf("cancelled a rectification which has started")
+	}
+}
@@ -0,0 +1,194 @@
+package sous
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+)
+
+type (
+	// An R11nStore persists queued rectifications, so that they survive a
+	// restart of the server.
+	R11nStore interface {
+		// StoreR11n records a rectification which has been queued.
+		StoreR11n(StoredR11n) error
+		// RemoveR11n forgets a rectification which has been handled or
+		// cancelled.
+		RemoveR11n(R11nID) error
+		// LoadR11ns returns every stored rectification, in the order they
+		// were queued.
+		LoadR11ns() ([]StoredR11n, error)
+	}
+
+	// A StoredR11n is the persistent form of a QueuedR11n.
+	StoredR11n struct {
+		ID           R11nID
+		DeploymentID DeploymentID
+		Priority     R11nPriority
+		QueuedAt     time.Time
+		Prior, Post  *Deployable
+		ExecutorData interface{}
+		// Stage is the stage a staged rollout had reached, or nil if it had
+		// not begun.
+		Stage *RolloutStage
+	}
+
+	// R11nRestoreBasis is what stored rectifications are re-resolved against
+	// when they are restored, so that none outlives the intent it was queued
+	// for.
+	R11nRestoreBasis struct {
+		// Intended is the intended deployments, from the current GDM.
+		Intended Deployments
+		// Actual is the state of the deployments currently running.
+		Actual DeployStates
+		// Leads returns true if this server rectifies cluster. If it is nil,
+		// this server rectifies every cluster.
+		Leads func(cluster string) bool
+	}
+)
+
+// NewStoredR11n returns the persistent form of qr.
+func NewStoredR11n(qr *QueuedR11n) StoredR11n {
+	pair := qr.Rectification.Pair
+	sr := StoredR11n{
+		ID:           qr.ID,
+		DeploymentID: pair.ID(),
+		Priority:     qr.Rectification.Priority,
+		QueuedAt:     qr.QueuedAt,
+		Prior:        pair.Prior,
+		Post:         pair.Post,
+		ExecutorData: pair.ExecutorData,
+	}
+	if stage, ok := qr.Rectification.Stage(); ok {
+		sr.Stage = &stage
+	}
+	return sr
+}
+
+// Rectification returns a new Rectification of the stored pair. If a staged
+// rollout had begun, it resumes from the stored stage.
+func (sr StoredR11n) Rectification() *Rectification {
+	r := NewRectification(DeployablePair{Prior: sr.Prior, Post: sr.Post, ExecutorData: sr.ExecutorData})
+	r.Pair.SetID(sr.DeploymentID)
+	r.Priority = sr.Priority
+	if sr.Stage != nil {
+		stage := *sr.Stage
+		r.stage = &stage
+	}
+	return r
+}
+
+// Persist makes the queues of rqs durable. It restores the rectifications in
+// store under their original IDs, e.g. after the server restarts, and from
+// then on records in store each rectification queued, until it has been
+// handled or cancelled. Failures to write to store are reported to ls.
+//
+// Only rectifications of deployments matching rf, in clusters this server
+// leads, are restored, so that servers sharing a store each restore their own.
+// Each is re-resolved against basis: its Prior is replaced by the deployment
+// running now, and it is dropped from store if the GDM no longer intends its
+// Post, or if nothing remains to be done. Each is given gate as its Gate, as
+// the Resolver does for the rectifications it queues. Persist returns the
+// rectifications restored. If store cannot be read, it returns the error and
+// rqs is left as it was.
+func (rqs *R11nQueueSet) Persist(store R11nStore, rf *ResolveFilter, basis R11nRestoreBasis, gate RolloutGate, ls logging.LogSink) ([]*QueuedR11n, error) {
+	stored, err := store.LoadR11ns()
+	if err != nil {
+		return nil, err
+	}
+	rqs.Lock()
+	defer rqs.Unlock()
+	opt := R11nQueueStore(store, ls)
+	rqs.opts = append(rqs.opts, opt)
+	for _, queue := range rqs.set {
+		queue.Lock()
+		opt(queue)
+		queue.Unlock()
+	}
+
+	var restored []*QueuedR11n
+	for _, sr := range stored {
+		did := sr.DeploymentID
+		if !rf.FilterClusterName(did.Cluster) || !rf.FilterManifestID(did.ManifestID) {
+			continue
+		}
+		if basis.Leads != nil && !basis.Leads(did.Cluster) {
+			// Left in store for the leader of the cluster.
+			continue
+		}
+		current, ok := basis.reresolve(sr)
+		if !ok {
+			reportR11nDropped(ls, sr, store.RemoveR11n(sr.ID))
+			continue
+		}
+		r := current.Rectification()
+		r.Gate = gate
+		queue, ok := rqs.set[did]
+		if !ok {
+			queue = NewR11nQueue(rqs.opts...)
+			rqs.set[did] = queue
+		}
+		restored = append(restored, queue.restore(sr.ID, r, sr.QueuedAt))
+	}
+	return restored, nil
+}
+
+// reresolve returns sr with its Prior replaced by the deployment running now.
+// It returns false if sr has been superseded, because the GDM no longer
+// intends its Post, or if its Post is already running.
+func (basis R11nRestoreBasis) reresolve(sr StoredR11n) (StoredR11n, bool) {
+	intended, intends := basis.Intended.Get(sr.DeploymentID)
+	if (sr.Post != nil) != intends {
+		return sr, false
+	}
+	if intends {
+		different, _ := sr.Post.Deployment.Diff(intended)
+		if different || len(sr.Post.Deployment.PolicyDiff(intended)) > 0 {
+			return sr, false
+		}
+	}
+
+	actual, running := basis.Actual.Get(sr.DeploymentID)
+	if !running {
+		if sr.Post == nil {
+			return sr, false
+		}
+		sr.Prior = nil
+		return sr, true
+	}
+	if sr.Post != nil && actual.Status == DeployStatusActive {
+		if different, _ := actual.Deployment.Diff(sr.Post.Deployment); !different {
+			return sr, false
+		}
+	}
+	sr.Prior = &Deployable{Deployment: actual.Deployment.Clone(), Status: actual.Status}
+	sr.ExecutorData = actual.ExecutorData
+	return sr, true
+}
+
+func reportR11nDropped(ls logging.LogSink, sr StoredR11n, err error) {
+	if ls == nil {
+		return
+	}
+	if err != nil {
+		messages.ReportLogFieldsMessage(
+			fmt.Sprintf("Failed to drop superseded rectification %s of %s: %v", sr.ID, sr.DeploymentID, err),
+			logging.WarningLevel, ls, sr.DeploymentID, err)
+		return
+	}
+	messages.ReportLogFieldsMessage(
+		fmt.Sprintf("Dropped stored rectification %s of %s: it is superseded or complete", sr.ID, sr.DeploymentID),
+		logging.InformationLevel, ls, sr.DeploymentID)
+}
+
+func reportR11nStoreError(ls logging.LogSink, qr *QueuedR11n, err error) {
+	if ls == nil {
+		return
+	}
+	did := qr.Rectification.Pair.ID()
+	messages.ReportLogFieldsMessage(
+		fmt.Sprintf("Failed to store queued rectification %s of %s: %v", qr.ID, did, err),
+		logging.WarningLevel, ls, did, err)
+}
@@ -0,0 +1,213 @@
+package sous
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testR11nStore struct {
+	stored []StoredR11n
+	sync.Mutex
+}
+
+func (s *testR11nStore) StoreR11n(sr StoredR11n) error {
+	s.Lock()
+	defer s.Unlock()
+	for i, old := range s.stored {
+		if old.ID == sr.ID {
+			s.stored[i] = sr
+			return nil
+		}
+	}
+	s.stored = append(s.stored, sr)
+	return nil
+}
+
+func (s *testR11nStore) RemoveR11n(id R11nID) error {
+	s.Lock()
+	defer s.Unlock()
+	for i, sr := range s.stored {
+		if sr.ID == id {
+			s.stored = append(s.stored[:i], s.stored[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *testR11nStore) LoadR11ns() ([]StoredR11n, error) {
+	s.Lock()
+	defer s.Unlock()
+	return append([]StoredR11n{}, s.stored...), nil
+}
+
+func (s *testR11nStore) ids() []R11nID {
+	s.Lock()
+	defer s.Unlock()
+	var ids []R11nID
+	for _, sr := range s.stored {
+		ids = append(ids, sr.ID)
+	}
+	return ids
+}
+
+func TestR11nQueueSet_Persist(t *testing.T) {
+	store := &testR11nStore{}
+	ls := logging.SilentLogSet()
+
+	before := NewR11nQueueSet()
+	restored, err := before.Persist(store, &ResolveFilter{}, R11nRestoreBasis{}, nil, ls)
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+
+	one := makeTestR11nWithRepo("one")
+	two := makeTestR11nWithRepo("two")
+	two.Priority = R11nPriorityManual
+	other := makeTestR11nWithRepo("three")
+	other.Pair.name.Cluster = "other"
+	var queued []*QueuedR11n
+	for _, r := range []*Rectification{one, two, other} {
+		qr, ok := before.Push(r)
+		require.True(t, ok)
+		queued = append(queued, qr)
+	}
+	cancelled, ok := before.Push(makeTestR11nWithRepo("one"))
+	require.True(t, ok)
+	_, ok = before.Cancel(cancelled.Rectification.Pair.ID(), cancelled.ID)
+	require.True(t, ok)
+	assert.Equal(t, []R11nID{queued[0].ID, queued[1].ID, queued[2].ID}, store.ids())
+
+	// A restarted server, resolving only the cluster of one and two.
+	handled := make(chan R11nID, 3)
+	after := NewR11nQueueSet(R11nQueueStartWithHandler(func(qr *QueuedR11n) DiffResolution {
+		handled <- qr.ID
+		return DiffResolution{DeploymentID: qr.Rectification.Pair.ID(), Desc: ModifyDiff}
+	}))
+	gate := &DeployerRolloutGate{}
+	rf := &ResolveFilter{Cluster: NewResolveFieldMatcher(one.Pair.ID().Cluster)}
+	basis := R11nRestoreBasis{Intended: NewDeployments(), Actual: NewDeployStates()}
+	for _, r := range []*Rectification{one, two} {
+		basis.Intended.Add(r.Pair.Post.Deployment.Clone())
+	}
+	restored, err = after.Persist(store, rf, basis, gate, ls)
+	require.NoError(t, err)
+	require.Len(t, restored, 2)
+	assert.Equal(t, queued[0].ID, restored[0].ID)
+	assert.Equal(t, R11nPriorityManual, restored[1].Rectification.Priority)
+	assert.Equal(t, RolloutGate(gate), restored[1].Rectification.Gate)
+	assert.Equal(t, one.Pair.ID(), restored[0].Rectification.Pair.ID())
+
+	for _, qr := range restored {
+		rez, ok := after.Wait(qr.Rectification.Pair.ID(), qr.ID)
+		require.True(t, ok)
+		assert.Equal(t, ModifyDiff, rez.Desc)
+	}
+	// Handled rectifications are removed from the store, once their handler
+	// returns; the one for the other cluster is left for its own server.
+	deadline := time.Now().Add(time.Second)
+	for len(store.ids()) > 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, []R11nID{queued[2].ID}, store.ids())
+}
+
+func TestR11nQueueSet_Persist_reresolve(t *testing.T) {
+	store := &testR11nStore{}
+	ls := logging.SilentLogSet()
+	before := NewR11nQueueSet()
+	_, err := before.Persist(store, &ResolveFilter{}, R11nRestoreBasis{}, nil, ls)
+	require.NoError(t, err)
+
+	pairs := map[string]DeployablePair{}
+	for _, name := range []string{"current", "superseded", "complete", "follower"} {
+		pair := stagedPair(Rollout{})
+		pair.Post.ClusterName = name
+		pair.Prior.ClusterName = name
+		pair.SetID(pair.Post.ID())
+		pairs[name] = pair
+		_, ok := before.Push(NewRectification(pair))
+		require.True(t, ok)
+	}
+
+	basis := R11nRestoreBasis{
+		Intended: NewDeployments(),
+		Actual:   NewDeployStates(),
+		Leads:    func(cluster string) bool { return cluster != "follower" },
+	}
+	for name, pair := range pairs {
+		intended := pair.Post.Deployment.Clone()
+		if name == "superseded" {
+			intended.NumInstances = 3
+		}
+		basis.Intended.Add(intended)
+		// The deployment running has changed since the rectification was
+		// stored, and is complete for one.
+		running := pair.Prior.Deployment.Clone()
+		running.NumInstances = 8
+		if name == "complete" {
+			running = pair.Post.Deployment.Clone()
+		}
+		basis.Actual.Add(&DeployState{Deployment: *running, Status: DeployStatusActive})
+	}
+
+	after := NewR11nQueueSet()
+	restored, err := after.Persist(store, &ResolveFilter{}, basis, nil, ls)
+	require.NoError(t, err)
+	require.Len(t, restored, 1)
+	pair := restored[0].Rectification.Pair
+	assert.Equal(t, "current", pair.ID().Cluster)
+	assert.Equal(t, 8, pair.Prior.NumInstances, "the Prior should be what is running now")
+	assert.Equal(t, 10, pair.Post.NumInstances)
+
+	// Superseded and complete rectifications are dropped from the store;
+	// the one for a cluster led by another server is left for it.
+	store.Lock()
+	var left []string
+	for _, sr := range store.stored {
+		left = append(left, sr.DeploymentID.Cluster)
+	}
+	store.Unlock()
+	assert.Equal(t, []string{"current", "follower"}, left)
+}
+
+func TestR11nQueueSet_Persist_stagedRollout(t *testing.T) {
+	store := &testR11nStore{}
+	rqs := NewR11nQueueSet(R11nQueueStartWithHandler(func(qr *QueuedR11n) DiffResolution {
+		qr.Rectification.Begin(&stageRecordingDeployer{})
+		return qr.Rectification.Wait()
+	}))
+	_, err := rqs.Persist(store, &ResolveFilter{}, R11nRestoreBasis{}, nil, logging.SilentLogSet())
+	require.NoError(t, err)
+
+	r := NewRectification(stagedPair(Rollout{
+		Strategy:        RolloutCanary,
+		CanaryInstances: 2,
+		HealthGate:      true,
+	}))
+	gate := &rolloutGateSpy{block: true, waiting: make(chan struct{})}
+	r.Gate = gate
+	qr, ok := rqs.Push(r)
+	require.True(t, ok)
+	<-gate.waiting
+
+	// The stage reached is stored, so that it can be resumed.
+	store.Lock()
+	require.Len(t, store.stored, 1)
+	assert.Equal(t, &RolloutStage{Index: 0, Count: 2, Instances: 2}, store.stored[0].Stage)
+	store.Unlock()
+
+	// The rollout is cancelled although it has started.
+	cancelled, ok := rqs.Cancel(r.Pair.ID(), qr.ID)
+	require.True(t, ok)
+	assert.Equal(t, CancelledDiff, cancelled.Rectification.Resolution.Desc)
+	deadline := time.Now().Add(time.Second)
+	for len(store.ids()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Empty(t, store.ids())
+}
@@ -1,17 +1,49 @@
 package sous
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
 
 // Rectification represents the rectification of a single DeployablePair.
 type Rectification struct {
 	// Pair is not a pointer as it's considered an immutable instruction.
 	Pair DeployablePair
+	// Priority orders this rectification in its R11nQueue: it is handled
+	// before any waiting rectification of lower priority.
+	Priority R11nPriority
 	// Resolution is the final resolution of this single rectification.
 	Resolution DiffResolution
-	once       sync.Once
-	done       chan struct{}
+	// Gate, if not nil, is consulted between the stages of a staged rollout
+	// whose Rollout has HealthGate set.
+	Gate  RolloutGate
+	once  sync.Once
+	done  chan struct{}
+	stage *RolloutStage
+	// cancel is closed to stop a staged rollout; see Cancel.
+	cancel    chan struct{}
+	cancelled bool
+	// onStage, if not nil, is called each time a staged rollout moves to a
+	// new stage.
+	onStage func()
+	// pause waits between stages; sleepUnlessCancelled if nil.
+	pause func(time.Duration, <-chan struct{}) bool
+	sync.RWMutex
 }
 
+// R11nPriority is the priority of a Rectification.
+type R11nPriority int
+
+const (
+	// R11nPriorityAuto is the priority of rectifications queued by the
+	// AutoResolver.
+	R11nPriorityAuto R11nPriority = 0
+	// R11nPriorityManual is the priority of rectifications requested by a
+	// user, e.g. by sous deploy, so that an urgent rollback does not wait
+	// behind routine auto-resolve work.
+	R11nPriorityManual R11nPriority = 10
+)
+
 // NewRectification is used to rectify differences on a single Deployment.
 // After this its useful life is over.
 func NewRectification(dp DeployablePair) *Rectification {
@@ -26,7 +58,7 @@ func NewRectification(dp DeployablePair) *Rectification {
 // once.
 func (r *Rectification) Begin(d Deployer) {
 	r.once.Do(func() {
-		r.Resolution = d.Rectify(&r.Pair)
+		r.Resolution = r.rectify(d)
 		// TODO SS: This select statement is a bandage around the problem
 		// that somehow this channel is being closed before reaching the line
 		// below. I doubt it's a bug in sync.Once (though that should be
@@ -48,3 +80,155 @@ func (r *Rectification) Wait() DiffResolution {
 	<-r.done
 	return r.Resolution
 }
+
+// Stage returns the rollout stage this rectification is currently in, and
+// true, if it is a staged rollout which has begun. Otherwise it returns the
+// zero RolloutStage and false.
+func (r *Rectification) Stage() (RolloutStage, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	if r.stage == nil {
+		return RolloutStage{}, false
+	}
+	return *r.stage, true
+}
+
+// Cancel stops a staged rollout which has begun, before its final stage. The
+// rollout stops as soon as it is waiting for a stage to become healthy or
+// pausing between stages, and its canary is removed, leaving the prior
+// version as it was. Its resolution has Desc CancelledDiff. Cancel returns
+// false, and does nothing, if r is not a staged rollout in progress, or has
+// reached its final stage.
+func (r *Rectification) Cancel() bool {
+	r.Lock()
+	defer r.Unlock()
+	if r.stage == nil || r.stage.Final() || r.cancelled {
+		return false
+	}
+	select {
+	case <-r.done:
+		return false
+	default:
+	}
+	close(r.cancelChan())
+	r.cancelled = true
+	return true
+}
+
+// cancelChan assumes r is already locked.
+func (r *Rectification) cancelChan() chan struct{} {
+	if r.cancel == nil {
+		r.cancel = make(chan struct{})
+	}
+	return r.cancel
+}
+
+func (r *Rectification) setStage(s RolloutStage) {
+	r.Lock()
+	r.stage = &s
+	onStage := r.onStage
+	r.Unlock()
+	if onStage != nil {
+		onStage()
+	}
+}
+
+// stages returns the rollout stages for r.Pair. Only changes of version to
+// existing deployments are staged.
+func (r *Rectification) stages() []RolloutStage {
+	p := r.Pair
+	if p.Prior == nil || p.Post == nil || p.Prior.Deployment == nil || p.Post.Deployment == nil {
+		return nil
+	}
+	if p.Prior.Deployment.SourceID.Equal(p.Post.Deployment.SourceID) {
+		return nil
+	}
+	rollout := p.Post.Deployment.Rollout
+	if !rollout.Staged() {
+		return nil
+	}
+	return rollout.Stages(p.Post.Deployment.NumInstances)
+}
+
+// rectify applies r.Pair. A staged rollout runs the new version in a canary
+// deployment, scaled up at each stage but the last, alongside the prior
+// version, which is left as it was. The final stage applies r.Pair itself,
+// and removes the canary. If r already has a stage, e.g. because it was
+// restored after a restart, the rollout resumes from that stage.
+func (r *Rectification) rectify(d Deployer) DiffResolution {
+	stages := r.stages()
+	if len(stages) < 2 {
+		return d.Rectify(&r.Pair)
+	}
+
+	r.Lock()
+	cancel := r.cancelChan()
+	start := 0
+	if r.stage != nil && r.stage.Count == len(stages) {
+		start = r.stage.Index
+	}
+	r.Unlock()
+	pause := r.pause
+	if pause == nil {
+		pause = sleepUnlessCancelled
+	}
+
+	rollout := r.Pair.Post.Deployment.Rollout
+	var canary *Deployable
+	if start > 0 {
+		canary = r.Pair.canaryAt(stages[start-1])
+	}
+	halt := func(stage RolloutStage, err error) DiffResolution {
+		rez := DiffResolution{DeploymentID: r.Pair.ID()}
+		if err == ErrRolloutCancelled {
+			rez.Desc = CancelledDiff
+		} else {
+			rez.Desc = "rollout halted"
+			rez.Error = WrapResolveError(&RolloutHaltedError{Stage: stage, Err: err})
+		}
+		r.removeCanary(d, canary, &rez)
+		return rez
+	}
+
+	for _, stage := range stages[start:] {
+		r.setStage(stage)
+		if stage.Final() {
+			rez := d.Rectify(&r.Pair)
+			r.removeCanary(d, canary, &rez)
+			return rez
+		}
+		pair := &DeployablePair{
+			Prior: canary,
+			Post:  r.Pair.canaryAt(stage),
+			name:  CanaryID(r.Pair.ID()),
+		}
+		// If this stage failed, the canary is as it was before it, if it
+		// exists at all.
+		if rez := d.Rectify(pair); rez.Error != nil {
+			return halt(stage, rez.Error)
+		}
+		canary = pair.Post
+		if rollout.HealthGate && r.Gate != nil {
+			if err := r.Gate.AwaitStage(pair, stage, cancel); err != nil {
+				return halt(stage, err)
+			}
+		}
+		if !pause(rollout.Pause(), cancel) {
+			return halt(stage, ErrRolloutCancelled)
+		}
+	}
+	// Not reached: the last stage is always final.
+	return DiffResolution{DeploymentID: r.Pair.ID()}
+}
+
+// removeCanary removes the canary deployment, if there is one. If that
+// fails, and rez has no error, the failure is recorded in rez.
+func (r *Rectification) removeCanary(d Deployer, canary *Deployable, rez *DiffResolution) {
+	if canary == nil {
+		return
+	}
+	removed := d.Rectify(&DeployablePair{Prior: canary, name: CanaryID(r.Pair.ID())})
+	if removed.Error != nil && rez.Error == nil {
+		rez.Error = removed.Error
+	}
+}
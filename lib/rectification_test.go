@@ -1,8 +1,13 @@
 package sous
 
 import (
+	"fmt"
 	"testing"
 	"time"
+
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSingleRectification_Resolve_completes(t *testing.T) {
@@ -27,3 +32,244 @@ func TestSingleRectification_Resolve_completes(t *testing.T) {
 		t.Errorf("resolution took more than a second")
 	}
 }
+
+type rolloutGateSpy struct {
+	stages []RolloutStage
+	fail   bool
+	// block, if true, waits for the rollout to be cancelled.
+	block   bool
+	waiting chan struct{}
+}
+
+func (g *rolloutGateSpy) AwaitStage(pair *DeployablePair, stage RolloutStage, cancel <-chan struct{}) error {
+	g.stages = append(g.stages, stage)
+	if g.block {
+		close(g.waiting)
+		<-cancel
+		return ErrRolloutCancelled
+	}
+	if g.fail {
+		return fmt.Errorf("unhealthy")
+	}
+	return nil
+}
+
+type stageRecordingDeployer struct {
+	DummyDeployer
+	pairs []*DeployablePair
+	// fail, if set, reports which pairs fail to be rectified.
+	fail func(*DeployablePair) bool
+}
+
+func (d *stageRecordingDeployer) Rectify(p *DeployablePair) DiffResolution {
+	d.pairs = append(d.pairs, p)
+	rez := DiffResolution{DeploymentID: p.ID(), Desc: ModifyDiff}
+	if d.fail != nil && d.fail(p) {
+		rez.Error = WrapResolveError(fmt.Errorf("failed"))
+	}
+	return rez
+}
+
+// instances returns the number of instances running each version after each
+// pair rectified by d, starting from prior.
+func (d *stageRecordingDeployer) instances(prior *Deployment) []map[string]int {
+	running := map[DeploymentID]*Deployment{prior.ID(): prior}
+	var counts []map[string]int
+	for _, p := range d.pairs {
+		if p.Post == nil {
+			delete(running, p.ID())
+		} else {
+			running[p.ID()] = p.Post.Deployment
+		}
+		count := map[string]int{}
+		for _, dep := range running {
+			count[dep.SourceID.Version.String()] += dep.NumInstances
+		}
+		counts = append(counts, count)
+	}
+	return counts
+}
+
+func stagedPair(rollout Rollout) DeployablePair {
+	prior := DeploymentFixture("")
+	prior.NumInstances = 7
+	post := prior.Clone()
+	post.SourceID.Version = semv.MustParse("9.9.9")
+	post.NumInstances = 10
+	post.Rollout = rollout
+	pair := DeployablePair{
+		Prior: &Deployable{Deployment: prior},
+		Post:  &Deployable{Deployment: post},
+	}
+	pair.SetID(post.ID())
+	return pair
+}
+
+func noPause(time.Duration, <-chan struct{}) bool { return true }
+
+func TestRectification_StagedRollout(t *testing.T) {
+	pair := stagedPair(Rollout{
+		Strategy:        RolloutCanary,
+		CanaryInstances: 1,
+		Steps:           []int{50},
+		PauseSeconds:    30,
+		HealthGate:      true,
+	})
+	sr := NewRectification(pair)
+	var paused []time.Duration
+	sr.pause = func(d time.Duration, _ <-chan struct{}) bool {
+		paused = append(paused, d)
+		return true
+	}
+	gate := &rolloutGateSpy{}
+	sr.Gate = gate
+	d := &stageRecordingDeployer{}
+
+	sr.Begin(d)
+	rez := sr.Wait()
+
+	require.Nil(t, rez.Error)
+	require.Len(t, d.pairs, 4)
+	canaryID := CanaryID(pair.ID())
+	// The canary is scaled up alongside the prior version, which is left
+	// alone until the final stage.
+	assert.Equal(t, AddedKind, d.pairs[0].Kind())
+	assert.Equal(t, ModifiedKind, d.pairs[1].Kind())
+	for i, want := range []int{1, 5} {
+		assert.Equal(t, canaryID, d.pairs[i].ID())
+		assert.Equal(t, canaryID, d.pairs[i].Post.Deployment.ID())
+		assert.Equal(t, want, d.pairs[i].Post.Deployment.NumInstances)
+	}
+	assert.Equal(t, pair.ID(), d.pairs[2].ID())
+	assert.Equal(t, 7, d.pairs[2].Prior.Deployment.NumInstances)
+	assert.Equal(t, 10, d.pairs[2].Post.Deployment.NumInstances)
+	// Then the canary is removed.
+	assert.Equal(t, canaryID, d.pairs[3].ID())
+	assert.Equal(t, RemovedKind, d.pairs[3].Kind())
+
+	// Nothing ever reduces the total number of instances below the prior's.
+	old := pair.Prior.Deployment.SourceID.Version.String()
+	assert.Equal(t, []map[string]int{
+		{old: 7, "9.9.9": 1},
+		{old: 7, "9.9.9": 5},
+		{"9.9.9": 15},
+		{"9.9.9": 10},
+	}, d.instances(pair.Prior.Deployment))
+
+	assert.Len(t, gate.stages, 2)
+	assert.Equal(t, []time.Duration{30 * time.Second, 30 * time.Second}, paused)
+
+	stage, ok := sr.Stage()
+	assert.True(t, ok)
+	assert.True(t, stage.Final())
+}
+
+func TestRectification_StagedRolloutHalts(t *testing.T) {
+	pair := stagedPair(Rollout{
+		Strategy:        RolloutCanary,
+		CanaryInstances: 2,
+		HealthGate:      true,
+	})
+	sr := NewRectification(pair)
+	sr.Gate = &rolloutGateSpy{fail: true}
+	sr.pause = noPause
+	d := &stageRecordingDeployer{}
+
+	sr.Begin(d)
+	rez := sr.Wait()
+
+	require.NotNil(t, rez.Error)
+	assert.Equal(t, pair.ID(), rez.DeploymentID)
+	// The canary is created, then removed; the prior version is untouched.
+	require.Len(t, d.pairs, 2)
+	assert.Equal(t, AddedKind, d.pairs[0].Kind())
+	assert.Equal(t, RemovedKind, d.pairs[1].Kind())
+	assert.Equal(t, CanaryID(pair.ID()), d.pairs[1].ID())
+	stage, _ := sr.Stage()
+	assert.Equal(t, RolloutStage{Index: 0, Count: 2, Instances: 2}, stage)
+}
+
+func TestRectification_StagedRolloutCanaryNotCreated(t *testing.T) {
+	pair := stagedPair(Rollout{
+		Strategy:        RolloutCanary,
+		CanaryInstances: 2,
+	})
+	sr := NewRectification(pair)
+	sr.pause = noPause
+	d := &stageRecordingDeployer{fail: func(p *DeployablePair) bool {
+		return p.Kind() == AddedKind
+	}}
+
+	sr.Begin(d)
+	rez := sr.Wait()
+
+	require.NotNil(t, rez.Error)
+	// The canary was never created, so there is none to remove.
+	require.Len(t, d.pairs, 1)
+	assert.Equal(t, AddedKind, d.pairs[0].Kind())
+}
+
+func TestRectification_StagedRolloutCancelled(t *testing.T) {
+	pair := stagedPair(Rollout{
+		Strategy:        RolloutCanary,
+		CanaryInstances: 2,
+		HealthGate:      true,
+	})
+	sr := NewRectification(pair)
+	gate := &rolloutGateSpy{block: true, waiting: make(chan struct{})}
+	sr.Gate = gate
+	d := &stageRecordingDeployer{}
+
+	assert.False(t, sr.Cancel(), "cancelled before it began")
+	go sr.Begin(d)
+	<-gate.waiting
+	assert.True(t, sr.Cancel())
+	rez := sr.Wait()
+
+	assert.Nil(t, rez.Error)
+	assert.Equal(t, CancelledDiff, rez.Desc)
+	require.Len(t, d.pairs, 2)
+	assert.Equal(t, RemovedKind, d.pairs[1].Kind())
+	assert.False(t, sr.Cancel(), "cancelled after it finished")
+}
+
+func TestRectification_StagedRolloutResumes(t *testing.T) {
+	pair := stagedPair(Rollout{
+		Strategy:        RolloutCanary,
+		CanaryInstances: 1,
+		Steps:           []int{50},
+	})
+	stored := StoredR11n{
+		DeploymentID: pair.ID(),
+		Prior:        pair.Prior,
+		Post:         pair.Post,
+		Stage:        &RolloutStage{Index: 1, Count: 3, Instances: 5},
+	}
+	sr := stored.Rectification()
+	sr.pause = noPause
+	d := &stageRecordingDeployer{}
+
+	sr.Begin(d)
+	sr.Wait()
+
+	require.Len(t, d.pairs, 3)
+	// The canary created before the restart is scaled on from stage 1.
+	assert.Equal(t, 1, d.pairs[0].Prior.Deployment.NumInstances)
+	assert.Equal(t, 5, d.pairs[0].Post.Deployment.NumInstances)
+	assert.Equal(t, 10, d.pairs[1].Post.Deployment.NumInstances)
+	assert.Equal(t, RemovedKind, d.pairs[2].Kind())
+}
+
+func TestRectification_UnstagedWhenVersionUnchanged(t *testing.T) {
+	pair := stagedPair(Rollout{Strategy: RolloutCanary, CanaryInstances: 1})
+	pair.Post.Deployment.SourceID = pair.Prior.Deployment.SourceID
+	sr := NewRectification(pair)
+	d := &stageRecordingDeployer{}
+
+	sr.Begin(d)
+	sr.Wait()
+
+	assert.Len(t, d.pairs, 1)
+	_, ok := sr.Stage()
+	assert.False(t, ok)
+}
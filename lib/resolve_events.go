@@ -0,0 +1,134 @@
+package sous
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// ResolveEventKind is the kind of a ResolveEvent.
+	ResolveEventKind string
+
+	// A ResolveEvent reports progress of resolution on a server.
+	ResolveEvent struct {
+		Kind ResolveEventKind
+		At   time.Time
+		// DeploymentID and R11nID identify the rectification, for
+		// rectification events.
+		DeploymentID *DeploymentID `json:",omitempty"`
+		R11nID       R11nID        `json:",omitempty"`
+		// Expected is the resolution expected of the rectification, given the
+		// kind of change it makes, for rectification events.
+		Expected ResolutionType `json:",omitempty"`
+		// Resolution is the result of a rectification, or of a single diff in
+		// a resolve cycle.
+		Resolution *DiffResolution `json:",omitempty"`
+		// Status is the status of the resolve cycle, for cycle events.
+		Status *ResolveStatus `json:",omitempty"`
+		// Completed and InProgress are the last complete and the current
+		// resolve cycles, and Deployments the intended deployments, as
+		// reported by /status, for StatusEvents.
+		Completed   *ResolveStatus `json:",omitempty"`
+		InProgress  *ResolveStatus `json:",omitempty"`
+		Deployments []*Deployment  `json:",omitempty"`
+	}
+
+	// ResolveEvents distributes ResolveEvents to subscribers. The zero value
+	// is ready to use, and a nil *ResolveEvents discards every event.
+	ResolveEvents struct {
+		sync.Mutex
+		subs map[chan ResolveEvent]struct{}
+	}
+)
+
+const (
+	// StatusEvent reports the status of resolution when a subscription
+	// starts.
+	StatusEvent = ResolveEventKind("status")
+	// ResolveStartedEvent reports the start of a resolve cycle.
+	ResolveStartedEvent = ResolveEventKind("resolve-started")
+	// DiffResolvedEvent reports the resolution of a single diff in the
+	// current resolve cycle.
+	DiffResolvedEvent = ResolveEventKind("diff-resolved")
+	// ResolveCompletedEvent reports the end of a resolve cycle.
+	ResolveCompletedEvent = ResolveEventKind("resolve-completed")
+	// R11nQueuedEvent reports that a rectification was queued.
+	R11nQueuedEvent = ResolveEventKind("r11n-queued")
+	// R11nStartedEvent reports that a rectification was started.
+	R11nStartedEvent = ResolveEventKind("r11n-started")
+	// R11nResolvedEvent reports that a rectification was finished.
+	R11nResolvedEvent = ResolveEventKind("r11n-resolved")
+	// R11nCancelledEvent reports that a queued rectification was cancelled.
+	R11nCancelledEvent = ResolveEventKind("r11n-cancelled")
+)
+
+// resolveEventBuffer is the number of events buffered for each subscriber.
+const resolveEventBuffer = 100
+
+// NewResolveEvents returns a new ResolveEvents with no subscribers.
+func NewResolveEvents() *ResolveEvents {
+	return &ResolveEvents{}
+}
+
+// Subscribe returns a channel of the events published from now on, and a
+// function to end the subscription. The channel is closed when the
+// subscription ends. A subscriber which falls too far behind is unsubscribed,
+// rather than holding up resolution.
+func (re *ResolveEvents) Subscribe() (<-chan ResolveEvent, func()) {
+	ch := make(chan ResolveEvent, resolveEventBuffer)
+	re.Lock()
+	defer re.Unlock()
+	if re.subs == nil {
+		re.subs = map[chan ResolveEvent]struct{}{}
+	}
+	re.subs[ch] = struct{}{}
+	return ch, func() {
+		re.Lock()
+		defer re.Unlock()
+		re.unsubscribe(ch)
+	}
+}
+
+// unsubscribe assumes re is locked.
+func (re *ResolveEvents) unsubscribe(ch chan ResolveEvent) {
+	if _, ok := re.subs[ch]; ok {
+		delete(re.subs, ch)
+		close(ch)
+	}
+}
+
+// Publish sends ev to every subscriber. If ev.At is zero, it is set to the
+// current time. Publish never blocks.
+func (re *ResolveEvents) Publish(ev ResolveEvent) {
+	if re == nil {
+		return
+	}
+	if ev.At.IsZero() {
+		ev.At = time.Now()
+	}
+	re.Lock()
+	defer re.Unlock()
+	for ch := range re.subs {
+		select {
+		case ch <- ev:
+		default:
+			re.unsubscribe(ch)
+		}
+	}
+}
+
+// publishR11n publishes an event about the rectification qr.
+func (re *ResolveEvents) publishR11n(kind ResolveEventKind, qr *QueuedR11n, rez *DiffResolution) {
+	if re == nil {
+		return
+	}
+	pair := qr.Rectification.Pair
+	did := pair.ID()
+	re.Publish(ResolveEvent{
+		Kind:         kind,
+		DeploymentID: &did,
+		R11nID:       qr.ID,
+		Expected:     pair.Kind().ExpectedResolutionType(),
+		Resolution:   rez,
+	})
+}
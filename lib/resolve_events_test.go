@@ -0,0 +1,155 @@
+package sous
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveEvents_Publish(t *testing.T) {
+	re := NewResolveEvents()
+	events, unsubscribe := re.Subscribe()
+
+	re.Publish(ResolveEvent{Kind: ResolveStartedEvent})
+	ev := <-events
+	if ev.Kind != ResolveStartedEvent {
+		t.Errorf("got %q; want %q", ev.Kind, ResolveStartedEvent)
+	}
+	if ev.At.IsZero() {
+		t.Errorf("published event has no time")
+	}
+
+	unsubscribe()
+	if _, open := <-events; open {
+		t.Errorf("channel open after unsubscribe")
+	}
+	// Unsubscribing twice, and publishing with no subscribers, are harmless.
+	unsubscribe()
+	re.Publish(ResolveEvent{Kind: ResolveCompletedEvent})
+
+	var nilEvents *ResolveEvents
+	nilEvents.Publish(ResolveEvent{Kind: ResolveCompletedEvent})
+}
+
+func TestResolveEvents_slowSubscriber(t *testing.T) {
+	re := NewResolveEvents()
+	slow, _ := re.Subscribe()
+	fast, unsubscribe := re.Subscribe()
+	defer unsubscribe()
+
+	for i := 0; i <= resolveEventBuffer; i++ {
+		re.Publish(ResolveEvent{Kind: DiffResolvedEvent})
+		<-fast
+	}
+
+	count := 0
+	for range slow {
+		count++
+	}
+	if count != resolveEventBuffer {
+		t.Errorf("slow subscriber got %d events; want %d", count, resolveEventBuffer)
+	}
+
+	re.Publish(ResolveEvent{Kind: ResolveCompletedEvent})
+	if ev := <-fast; ev.Kind != ResolveCompletedEvent {
+		t.Errorf("fast subscriber got %q; want %q", ev.Kind, ResolveCompletedEvent)
+	}
+}
+
+func TestR11nQueue_events(t *testing.T) {
+	re := NewResolveEvents()
+	events, unsubscribe := re.Subscribe()
+	defer unsubscribe()
+
+	rq := NewR11nQueue(R11nQueueEvents(re), R11nQueueStartWithHandler(func(*QueuedR11n) DiffResolution {
+		return DiffResolution{Desc: ModifyDiff}
+	}))
+	r := &Rectification{Pair: DeployablePair{name: DeploymentID{Cluster: "c1"}}}
+	qr, ok := rq.Push(r)
+	if !ok {
+		t.Fatalf("push failed")
+	}
+
+	for _, kind := range []ResolveEventKind{R11nQueuedEvent, R11nStartedEvent, R11nResolvedEvent} {
+		select {
+		case ev := <-events:
+			if ev.Kind != kind {
+				t.Fatalf("got %q event; want %q", ev.Kind, kind)
+			}
+			if ev.R11nID != qr.ID {
+				t.Errorf("%s: got r11n %q; want %q", kind, ev.R11nID, qr.ID)
+			}
+			if ev.DeploymentID == nil || ev.DeploymentID.Cluster != "c1" {
+				t.Errorf("%s: got deployment %v; want cluster c1", kind, ev.DeploymentID)
+			}
+			if kind == R11nResolvedEvent && (ev.Resolution == nil || ev.Resolution.Desc != ModifyDiff) {
+				t.Errorf("%s: got resolution %v; want %q", kind, ev.Resolution, ModifyDiff)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %q event", kind)
+		}
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	stream := strings.Join([]string{
+		`event: resolve-started`,
+		`data: {"Kind":"resolve-started","Status":{"Phase":"x"}}`,
+		``,
+		`: keepalive`,
+		``,
+		`event: diff-resolved`,
+		`data: {"Kind":"diff-resolved","Resolution":{"Desc":"unchanged"}}`,
+		``,
+		`event: resolve-completed`,
+		`data: {"Kind":"resolve-completed","Status":{"Phase":"y"}}`,
+		``,
+		``,
+	}, "\n")
+
+	data := &statusData{}
+	var kinds []ResolveEventKind
+	err := readEvents(strings.NewReader(stream), func(ev ResolveEvent) bool {
+		kinds = append(kinds, ev.Kind)
+		data.apply(ev)
+		return ev.Kind != DiffResolvedEvent
+	})
+	if err != nil {
+		t.Errorf("readEvents returned %v after f returned false", err)
+	}
+	if len(kinds) != 2 {
+		t.Fatalf("got events %v; want 2 events", kinds)
+	}
+	if data.InProgress == nil || len(data.InProgress.Log) != 1 || data.InProgress.Log[0].Desc != StableDiff {
+		t.Errorf("in-progress status %+v should have one unchanged resolution", data.InProgress)
+	}
+
+	data = &statusData{}
+	err = readEvents(strings.NewReader(stream), func(ev ResolveEvent) bool {
+		data.apply(ev)
+		return true
+	})
+	if err == nil {
+		t.Errorf("readEvents returned nil at end of stream")
+	}
+	if data.Completed == nil || data.Completed.Phase != "y" || data.InProgress != data.Completed {
+		t.Errorf("got completed %+v, in progress %+v; want both from resolve-completed", data.Completed, data.InProgress)
+	}
+}
+
+func TestStatusData_apply(t *testing.T) {
+	data := &statusData{}
+	if data.apply(ResolveEvent{Kind: R11nQueuedEvent}) {
+		t.Errorf("r11n events should not change status")
+	}
+	if data.apply(ResolveEvent{Kind: DiffResolvedEvent, Resolution: &DiffResolution{}}) {
+		t.Errorf("diff resolved outside a cycle should not change status")
+	}
+	completed, inProgress := &ResolveStatus{Phase: "done"}, &ResolveStatus{Phase: "going"}
+	if !data.apply(ResolveEvent{Kind: StatusEvent, Completed: completed, InProgress: inProgress}) {
+		t.Errorf("status event should change status")
+	}
+	if data.Completed != completed || data.InProgress != inProgress {
+		t.Errorf("status event not applied: %+v", data)
+	}
+}
@@ -3,6 +3,7 @@ package sous
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/logging/messages"
@@ -17,6 +18,11 @@ type (
 		*ResolveFilter
 		ls       logging.LogSink
 		QueueSet *R11nQueueSet
+		// Events, if not nil, receives the progress of each resolution.
+		Events *ResolveEvents
+		// preFreeze remembers intent from before each freeze, to tell drift
+		// repairs from frozen changes.
+		preFreeze preFreezeIntents
 	}
 
 	// DeploymentPredicate takes a *Deployment and returns true if the
@@ -43,6 +49,8 @@ func (r *Resolver) queueDiffs(dcs *DeployableChans, results chan DiffResolution)
 	var wg sync.WaitGroup
 	for p := range dcs.Pairs {
 		sr := NewRectification(*p)
+		sr.Priority = R11nPriorityAuto
+		sr.Gate = &DeployerRolloutGate{Deployer: r.Deployer, Registry: r.Registry}
 		messages.ReportLogFieldsMessageWithIDs("Adding to queset", logging.ExtraDebug1Level, r.ls, p, sr)
 		queued, ok := r.QueueSet.PushIfEmpty(sr)
 		if !ok {
@@ -75,10 +83,16 @@ func (r *Resolver) queueDiffs(dcs *DeployableChans, results chan DiffResolution)
 // the appropriate components to compute the intended deployment set, collect
 // the actual set, compute the diffs and then issue the commands to rectify
 // those differences.
-func (r *Resolver) Begin(intended Deployments, clusters Clusters) *ResolveRecorder {
+//
+// Changes to deployments frozen by freezes are not rectified unless their
+// freeze was overridden, but drift from what was intended before the freeze
+// is still repaired.
+func (r *Resolver) Begin(intended Deployments, clusters Clusters, freezes FreezeWindows) *ResolveRecorder {
 	intended = intended.Filter(r.FilterDeployment)
+	now := time.Now()
+	baseline := r.preFreeze.update(freezes, intended, now)
 
-	return NewResolveRecorder(intended, r.ls, func(recorder *ResolveRecorder) {
+	return newResolveRecorder(intended, r.ls, r.Events, func(recorder *ResolveRecorder) {
 		var actual DeployStates
 		var diffs *DeployableChans
 		var logger *DeployableChans
@@ -96,17 +110,22 @@ func (r *Resolver) Begin(intended Deployments, clusters Clusters) *ResolveRecord
 		})
 
 		recorder.performPhase("filtering running deployments", func() error {
-			actual = actual.Filter(r.FilterDeployStates)
+			// Canaries belong to the rollouts running them, not the GDM.
+			actual = actual.Filter(func(ds *DeployState) bool {
+				return r.FilterDeployStates(ds) && !IsCanary(ds.ID())
+			})
 			return nil
 		})
 
 		recorder.performPhase("generating diff", func() error {
+			// Drift is reported before rectifying, to track it over time.
+			reportDrift(r.ls, newDriftReport(actual, intended, clusters))
 			diffs = actual.Diff(intended)
 			return nil
 		})
 
 		recorder.performPhase("resolving deployment artifacts", func() error {
-			namer := diffs.ResolveNames(ctx, r.Registry)
+			namer := diffs.SkipPaused(ctx, now).EnforceFreezes(ctx, freezes, baseline, now, r.ls).ResolveNames(ctx, r.Registry)
 			logger = namer.Log(ctx, r.ls)
 			logger.Add(1)
 			go func() {
@@ -25,6 +25,8 @@ type (
 		Log []DiffResolution
 		// Errs collects errors during resolution
 		Errs ResolveErrors
+		// Rollbacks lists the automatic rollbacks made after resolution.
+		Rollbacks []Rollback `json:",omitempty"`
 	}
 
 	// ResolveRecorder represents the status of a resolve run.
@@ -67,6 +69,13 @@ const (
 	ModifyDiff = ResolutionType("updated")
 	// DeleteDiff - a deployment was active that wasn't intended at all, and was deleted.
 	DeleteDiff = ResolutionType("deleted")
+	// FrozenDiff - the deployment differs from the intended, but was not changed
+	// because of a freeze.
+	FrozenDiff = ResolutionType("frozen")
+	// PausedDiff - the deployment was not rectified because it is paused.
+	PausedDiff = ResolutionType("paused")
+	// CancelledDiff - the rectification was cancelled while it was queued.
+	CancelledDiff = ResolutionType("cancelled")
 )
 
 func (rez DiffResolution) String() string {
@@ -76,6 +85,12 @@ func (rez DiffResolution) String() string {
 // NewResolveRecorder creates a new ResolveRecorder and calls f with it as its
 // argument. It then returns that ResolveRecorder immediately.
 func NewResolveRecorder(intended Deployments, ls logging.LogSink, f func(*ResolveRecorder)) *ResolveRecorder {
+	return newResolveRecorder(intended, ls, nil, f)
+}
+
+// newResolveRecorder is like NewResolveRecorder, but also publishes the start
+// of the resolution, and each diff resolution, to events.
+func newResolveRecorder(intended Deployments, ls logging.LogSink, events *ResolveEvents, f func(*ResolveRecorder)) *ResolveRecorder {
 	rr := &ResolveRecorder{
 		status: &ResolveStatus{
 			Started:  time.Now(),
@@ -91,6 +106,10 @@ func NewResolveRecorder(intended Deployments, ls logging.LogSink, f func(*Resolv
 	for _, d := range intended.Snapshot() {
 		rr.status.Intended = append(rr.status.Intended, d)
 	}
+	if events != nil {
+		started := rr.CurrentStatus()
+		events.Publish(ResolveEvent{Kind: ResolveStartedEvent, Status: &started})
+	}
 
 	// Update status incrementally.
 	go func() {
@@ -102,6 +121,8 @@ func NewResolveRecorder(intended Deployments, ls logging.LogSink, f func(*Resolv
 					logging.Log.Debug.Printf("resolve error = %+v\n", rez.Error)
 				}
 			})
+			rez := rez
+			events.Publish(ResolveEvent{Kind: DiffResolvedEvent, Resolution: &rez})
 		}
 	}()
 
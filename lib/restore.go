@@ -0,0 +1,76 @@
+package sous
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// A PointInTime identifies an earlier version of the GDM, either by a
+	// storage specific Revision (e.g. a git commit) or by a time. Exactly one
+	// should be set.
+	PointInTime struct {
+		Revision string
+		At       time.Time
+	}
+
+	// A StateHistorian can read the GDM as it was at an earlier point in time.
+	StateHistorian interface {
+		// ReadStateAsOf returns the state as of the point in time. The etag of
+		// the returned state identifies the revision that was read.
+		ReadStateAsOf(PointInTime) (*State, error)
+	}
+)
+
+// Validate returns an error unless exactly one of Revision and At is set.
+func (pit PointInTime) Validate() error {
+	if pit.Revision == "" && pit.At.IsZero() {
+		return errors.New("a revision or a time is required")
+	}
+	if pit.Revision != "" && !pit.At.IsZero() {
+		return errors.New("only one of a revision or a time may be given")
+	}
+	return nil
+}
+
+func (pit PointInTime) String() string {
+	if pit.Revision != "" {
+		return pit.Revision
+	}
+	return pit.At.Format(time.RFC3339)
+}
+
+// Restore returns a copy of s whose manifests are as they were in past. If
+// mid is not nil, only that manifest is restored, and it is removed if it did
+// not exist in past.
+//
+// Defs are never restored: they describe the clusters and their settings as
+// they are now, rather than the history of what was deployed to them.
+func (s *State) Restore(past *State, mid *ManifestID) *State {
+	restored := s.Clone()
+	if mid == nil {
+		restored.Manifests = past.Manifests.Clone()
+		return restored
+	}
+	if m, has := past.Manifests.Get(*mid); has {
+		restored.Manifests.Set(*mid, m.Clone())
+	} else {
+		restored.Manifests.Remove(*mid)
+	}
+	return restored
+}
+
+// RestoreChanges lists the changes to deployments that writing restored in
+// place of s would make.
+func (s *State) RestoreChanges(restored *State) ([]DeploymentChange, error) {
+	current, err := s.Deployments()
+	if err != nil {
+		return nil, err
+	}
+	intended, err := restored.Deployments()
+	if err != nil {
+		return nil, err
+	}
+	return GDMChanges(current.Diff(intended).Collect()), nil
+}
@@ -0,0 +1,76 @@
+package sous
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPointInTime_Validate(t *testing.T) {
+	assert.Error(t, PointInTime{}.Validate())
+	assert.Error(t, PointInTime{Revision: "abc", At: time.Now()}.Validate())
+	assert.NoError(t, PointInTime{Revision: "abc"}.Validate())
+	assert.NoError(t, PointInTime{At: time.Now()}.Validate())
+}
+
+func TestState_Restore(t *testing.T) {
+	past := DefaultStateFixture()
+	current := DefaultStateFixture()
+	current.SetEtag("current")
+
+	mids := current.Manifests.Keys()
+	changed, removed := mids[0], mids[1]
+	pm, _ := past.Manifests.Get(changed)
+	m, _ := current.Manifests.Get(changed)
+	ds := m.Deployments["cluster0"]
+	ds.NumInstances = 17
+	m.Deployments["cluster0"] = ds
+	current.Manifests.Remove(removed)
+	added := ManifestID{Source: SourceLocation{Repo: "github.com/new/repo"}}
+	current.Manifests.Add(&Manifest{Source: added.Source, Kind: ManifestKindService})
+
+	t.Run("whole state", func(t *testing.T) {
+		restored := current.Restore(past, nil)
+
+		assert.Equal(t, past.Manifests.Len(), restored.Manifests.Len())
+		rm, has := restored.Manifests.Get(changed)
+		require.True(t, has)
+		assert.Equal(t, pm.Deployments["cluster0"].NumInstances, rm.Deployments["cluster0"].NumInstances)
+		_, has = restored.Manifests.Get(added)
+		assert.False(t, has)
+
+		etag, err := restored.GetEtag()
+		require.NoError(t, err)
+		assert.Equal(t, "current", etag, "restored state is written over the current state")
+
+		cm, _ := current.Manifests.Get(changed)
+		assert.Equal(t, 17, cm.Deployments["cluster0"].NumInstances, "current state is not modified")
+	})
+
+	t.Run("single manifest", func(t *testing.T) {
+		restored := current.Restore(past, &changed)
+		rm, _ := restored.Manifests.Get(changed)
+		assert.Equal(t, pm.Deployments["cluster0"].NumInstances, rm.Deployments["cluster0"].NumInstances)
+		_, has := restored.Manifests.Get(removed)
+		assert.False(t, has, "other manifests are left alone")
+
+		restored = current.Restore(past, &removed)
+		_, has = restored.Manifests.Get(removed)
+		assert.True(t, has)
+
+		restored = current.Restore(past, &added)
+		_, has = restored.Manifests.Get(added)
+		assert.False(t, has, "manifests that did not exist are removed")
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		restored := current.Restore(past, &changed)
+		changes, err := current.RestoreChanges(restored)
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, "modified", changes[0].Kind)
+		assert.Equal(t, changed, changes[0].DeploymentID.ManifestID)
+	})
+}
@@ -0,0 +1,198 @@
+package sous
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+)
+
+type (
+	// A Rollback records Sous automatically reverting a failed deployment to
+	// its last known good version.
+	Rollback struct {
+		DeploymentID
+		// From is the version which failed.
+		From SourceID
+		// To is the last version known to have deployed successfully.
+		To SourceID
+		// Reason explains why the rollback happened.
+		Reason string
+		// User is the user the change was written to the GDM as.
+		User User
+		// At is when the rollback was written.
+		At time.Time
+		// Error is set if writing the rollback to the GDM failed.
+		Error string `json:",omitempty"`
+	}
+
+	// A LastGoodStore records the last version of each deployment seen to be
+	// stable, so that automatic rollbacks survive a restart of the server, and
+	// a change of the server leading a cluster.
+	LastGoodStore interface {
+		// RecordLastGood records that version is the last one seen stable.
+		RecordLastGood(did DeploymentID, version SourceID) error
+		// LastGood returns the last version recorded for did, and true, or
+		// false if there is none.
+		LastGood(did DeploymentID) (SourceID, bool, error)
+	}
+
+	// memoryLastGood is a LastGoodStore which holds versions in memory only.
+	memoryLastGood struct {
+		versions map[DeploymentID]SourceID
+		sync.Mutex
+	}
+
+	rollbackMessage struct {
+		logging.CallerInfo
+		rollback Rollback
+	}
+)
+
+// RollbackUser is the user recorded against GDM changes made by automatic
+// rollbacks.
+var RollbackUser = User{Name: "Sous Auto-Rollback", Email: "sous-rollback@localhost"}
+
+func (rb Rollback) String() string {
+	return fmt.Sprintf("%s rolled back from %s to %s: %s", rb.DeploymentID, rb.From.Version, rb.To.Version, rb.Reason)
+}
+
+// isFailedStatus returns true if rez reports that the intended deployment is
+// in place but has failed.
+func isFailedStatus(rez DiffResolution) bool {
+	if rez.Desc != StableDiff || rez.Error == nil {
+		return false
+	}
+	if _, is := rez.Error.error.(*FailedStatusError); is {
+		return true
+	}
+	return rez.Error.Type == fmt.Sprintf("%T", &FailedStatusError{})
+}
+
+// rollbackFailures rolls back any deployments in gdm which opted in to
+// automatic rollback and which failed in the resolution recorded by rs. It
+// also records the versions of deployments which are stable, as candidates for
+// future rollbacks. Rollbacks performed are appended to rs.Rollbacks. A
+// rollback during a freeze overrides it, lest the resolver refuse to apply
+// it.
+func (ar *AutoResolver) rollbackFailures(gdm Deployments, freezes FreezeWindows, rs *ResolveStatus) {
+	ar.lastGoodLock.Lock()
+	defer ar.lastGoodLock.Unlock()
+	if ar.lastGood == nil {
+		ar.lastGood = map[DeploymentID]SourceID{}
+	}
+	if ar.LastGood == nil {
+		ar.LastGood = &memoryLastGood{}
+	}
+
+	for _, rez := range rs.Log {
+		dep, ok := gdm.Get(rez.DeploymentID)
+		if !ok {
+			continue
+		}
+		if rez.Desc == StableDiff && rez.Error == nil {
+			ar.recordLastGood(rez.DeploymentID, dep.SourceID)
+			continue
+		}
+		if !dep.AutoRollback || ar.DeploymentManager == nil || !isFailedStatus(rez) {
+			continue
+		}
+		good, known, err := ar.LastGood.LastGood(rez.DeploymentID)
+		if err != nil {
+			logging.ReportError(ar.LogSink, err)
+			continue
+		}
+		if !known || good.Equal(dep.SourceID) {
+			logging.ReportMsg(ar.LogSink, logging.WarningLevel,
+				fmt.Sprintf("Not rolling back %s: no earlier version known to be good", rez.DeploymentID))
+			continue
+		}
+
+		now := time.Now()
+		rb := Rollback{
+			DeploymentID: rez.DeploymentID,
+			From:         dep.SourceID,
+			To:           good,
+			Reason:       fmt.Sprintf("deploy of %s failed; reverting to last good version %s", dep.SourceID.Version, good.Version),
+			User:         RollbackUser,
+			At:           now,
+		}
+		rolled := dep.Clone()
+		rolled.SourceID = good
+		if _, frozen := freezes.FrozenAt(rez.DeploymentID, now); frozen {
+			rolled.FreezeOverride = &FreezeOverride{User: RollbackUser, Reason: rb.Reason, At: now}
+		}
+		if err := ar.DeploymentManager.WriteDeployment(rolled, RollbackUser); err != nil {
+			rb.Error = err.Error()
+		}
+		reportRollback(ar.LogSink, rb)
+		rs.Rollbacks = append(rs.Rollbacks, rb)
+	}
+}
+
+// recordLastGood records version in ar.LastGood, unless it was the last
+// version recorded for did. It assumes ar.lastGoodLock is held.
+func (ar *AutoResolver) recordLastGood(did DeploymentID, version SourceID) {
+	if recorded, ok := ar.lastGood[did]; ok && recorded.Equal(version) {
+		return
+	}
+	if err := ar.LastGood.RecordLastGood(did, version); err != nil {
+		logging.ReportError(ar.LogSink, err)
+		return
+	}
+	ar.lastGood[did] = version
+}
+
+// RecordLastGood implements LastGoodStore.
+func (m *memoryLastGood) RecordLastGood(did DeploymentID, version SourceID) error {
+	m.Lock()
+	defer m.Unlock()
+	if m.versions == nil {
+		m.versions = map[DeploymentID]SourceID{}
+	}
+	m.versions[did] = version
+	return nil
+}
+
+// LastGood implements LastGoodStore.
+func (m *memoryLastGood) LastGood(did DeploymentID) (SourceID, bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	version, ok := m.versions[did]
+	return version, ok, nil
+}
+
+func reportRollback(ls logging.LogSink, rb Rollback) {
+	msg := rollbackMessage{
+		CallerInfo: logging.GetCallerInfo(logging.NotHere()),
+		rollback:   rb,
+	}
+	logging.Deliver(msg, ls)
+}
+
+func (msg rollbackMessage) DefaultLevel() logging.Level {
+	if msg.rollback.Error != "" {
+		return logging.WarningLevel
+	}
+	return logging.InformationLevel
+}
+
+func (msg rollbackMessage) Message() string {
+	if msg.rollback.Error != "" {
+		return fmt.Sprintf("Automatic rollback failed: %s", msg.rollback.Error)
+	}
+	return "Automatic rollback"
+}
+
+func (msg rollbackMessage) EachField(f logging.FieldReportFn) {
+	f("@loglov3-otl", "sous-generic-v1")
+	msg.CallerInfo.EachField(f)
+	f("sous-deployment-id", msg.rollback.DeploymentID.String())
+	f("sous-manifest-id", msg.rollback.ManifestID.String())
+	f("sous-cluster-name", msg.rollback.Cluster)
+	f("sous-rollback-from", msg.rollback.From.String())
+	f("sous-rollback-to", msg.rollback.To.String())
+	f("sous-rollback-reason", msg.rollback.Reason)
+	f("sous-rollback-user", msg.rollback.User.String())
+}
@@ -0,0 +1,145 @@
+package sous
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nyarly/spies"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoResolver_rollbackFailures(t *testing.T) {
+	ar := setupAR()
+	dm, spy := NewDeploymentManagerSpy()
+	spy.Any("WriteDeployment", nil)
+	ar.DeploymentManager = dm
+
+	dep := DeploymentFixture("")
+	dep.AutoRollback = true
+	good := dep.SourceID
+	did := dep.ID()
+
+	gdm := NewDeployments(dep)
+	stable := &ResolveStatus{Log: []DiffResolution{{DeploymentID: did, Desc: StableDiff}}}
+	ar.rollbackFailures(gdm, nil, stable)
+	assert.Empty(t, stable.Rollbacks)
+
+	bad := dep.Clone()
+	bad.SourceID.Version = semv.MustParse("0.0.2")
+	gdm = NewDeployments(bad)
+	failed := &ResolveStatus{Log: []DiffResolution{{
+		DeploymentID: did,
+		Desc:         StableDiff,
+		Error:        WrapResolveError(&FailedStatusError{}),
+	}}}
+	ar.rollbackFailures(gdm, nil, failed)
+
+	require.Len(t, failed.Rollbacks, 1)
+	rb := failed.Rollbacks[0]
+	assert.Equal(t, did, rb.DeploymentID)
+	assert.Equal(t, good, rb.To)
+	assert.Equal(t, bad.SourceID, rb.From)
+	assert.Equal(t, RollbackUser, rb.User)
+	assert.NotEmpty(t, rb.Reason)
+	assert.Empty(t, rb.Error)
+
+	calls := spy.CallsTo("WriteDeployment")
+	require.Len(t, calls, 1)
+	written := calls[0].PassedArgs().Get(0).(*Deployment)
+	assert.Equal(t, good, written.SourceID)
+	assert.Nil(t, written.FreezeOverride)
+	assert.Equal(t, RollbackUser, calls[0].PassedArgs().Get(1))
+}
+
+func TestAutoResolver_rollbackFailures_AfterRestart(t *testing.T) {
+	store := &memoryLastGood{}
+	dep := DeploymentFixture("")
+	dep.AutoRollback = true
+	good := dep.SourceID
+	did := dep.ID()
+
+	before := setupAR()
+	before.LastGood = store
+	stable := &ResolveStatus{Log: []DiffResolution{{DeploymentID: did, Desc: StableDiff}}}
+	before.rollbackFailures(NewDeployments(dep), nil, stable)
+
+	// A new server, during a freeze, finds the last good version in the
+	// store, and overrides the freeze to roll back to it.
+	after := setupAR()
+	after.LastGood = store
+	dm, spy := NewDeploymentManagerSpy()
+	spy.Any("WriteDeployment", nil)
+	after.DeploymentManager = dm
+	bad := dep.Clone()
+	bad.SourceID.Version = semv.MustParse("0.0.2")
+	freezes := FreezeWindows{{
+		Start:  time.Now().Add(-time.Hour),
+		End:    time.Now().Add(time.Hour),
+		Reason: "holidays",
+	}}
+	failed := &ResolveStatus{Log: []DiffResolution{{
+		DeploymentID: did,
+		Desc:         StableDiff,
+		Error:        WrapResolveError(&FailedStatusError{}),
+	}}}
+	after.rollbackFailures(NewDeployments(bad), freezes, failed)
+
+	require.Len(t, failed.Rollbacks, 1)
+	assert.Equal(t, good, failed.Rollbacks[0].To)
+	calls := spy.CallsTo("WriteDeployment")
+	require.Len(t, calls, 1)
+	written := calls[0].PassedArgs().Get(0).(*Deployment)
+	assert.Equal(t, good, written.SourceID)
+	require.NotNil(t, written.FreezeOverride)
+	assert.Equal(t, RollbackUser, written.FreezeOverride.User)
+	assert.True(t, freezes.Permits(written, time.Now()))
+}
+
+func TestAutoResolver_rollbackFailures_NotOptedIn(t *testing.T) {
+	ar := setupAR()
+	dm, spy := NewDeploymentManagerSpy()
+	ar.DeploymentManager = dm
+
+	dep := DeploymentFixture("")
+	did := dep.ID()
+	ar.LastGood = &memoryLastGood{versions: map[DeploymentID]SourceID{
+		did: MustNewSourceID("github.com/opentable/example", "", "0.0.0"),
+	}}
+
+	failed := &ResolveStatus{Log: []DiffResolution{{
+		DeploymentID: did,
+		Desc:         StableDiff,
+		Error:        WrapResolveError(&FailedStatusError{}),
+	}}}
+	ar.rollbackFailures(NewDeployments(dep), nil, failed)
+
+	assert.Empty(t, failed.Rollbacks)
+	assert.Empty(t, spy.CallsTo("WriteDeployment"))
+}
+
+func TestAutoResolver_rollbackFailures_WriteError(t *testing.T) {
+	ar := setupAR()
+	dm, spy := NewDeploymentManagerSpy()
+	spy.MatchMethod("WriteDeployment", spies.AnyArgs, fmt.Errorf("no write for you"))
+	ar.DeploymentManager = dm
+
+	dep := DeploymentFixture("")
+	dep.AutoRollback = true
+	did := dep.ID()
+	ar.LastGood = &memoryLastGood{versions: map[DeploymentID]SourceID{
+		did: MustNewSourceID("github.com/opentable/example", "", "0.0.0"),
+	}}
+
+	failed := &ResolveStatus{Log: []DiffResolution{{
+		DeploymentID: did,
+		Desc:         StableDiff,
+		Error:        WrapResolveError(&FailedStatusError{}),
+	}}}
+	ar.rollbackFailures(NewDeployments(dep), nil, failed)
+
+	require.Len(t, failed.Rollbacks, 1)
+	assert.Equal(t, "no write for you", failed.Rollbacks[0].Error)
+}
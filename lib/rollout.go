@@ -0,0 +1,294 @@
+package sous
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Rollout describes how a change to a deployment is applied. The zero value
+	// applies every change in a single step.
+	Rollout struct {
+		// Strategy is the rollout strategy. Legal values are "" (all at once) and
+		// "canary".
+		Strategy RolloutStrategy `yaml:",omitempty"`
+		// CanaryInstances is the number of instances running the new version in
+		// the first stage of a canary rollout.
+		CanaryInstances int `yaml:",omitempty"`
+		// Steps are percentages of NumInstances to run the new version on in
+		// each stage after the canary. They must be increasing and no greater
+		// than 100. A final stage at 100% is always added.
+		Steps []int `yaml:",omitempty"`
+		// PauseSeconds is the time to wait between stages.
+		PauseSeconds int `yaml:",omitempty"`
+		// HealthGate, if true, waits for each stage to report healthy before
+		// moving on to the next.
+		HealthGate bool `yaml:",omitempty"`
+		// GateTimeoutSeconds is the maximum time to wait for a stage to become
+		// healthy. If zero, DefaultRolloutGateTimeout is used.
+		GateTimeoutSeconds int `yaml:",omitempty"`
+	}
+
+	// RolloutStrategy names a strategy for rolling out changes.
+	RolloutStrategy string
+
+	// A RolloutStage is one step of a staged rollout.
+	RolloutStage struct {
+		// Index is the zero based index of this stage.
+		Index int
+		// Count is the total number of stages in the rollout.
+		Count int
+		// Instances is the number of instances running the new version at this
+		// stage. Until the final stage they run in a canary deployment,
+		// alongside the unchanged instances of the prior version.
+		Instances int
+	}
+
+	// A RolloutGate decides whether a staged rollout may proceed past a stage.
+	RolloutGate interface {
+		// AwaitStage returns nil once the deployment in pair.Post is healthy, or
+		// an error if it fails or the rollout's gate timeout expires. If cancel
+		// is closed first, it returns ErrRolloutCancelled.
+		AwaitStage(pair *DeployablePair, stage RolloutStage, cancel <-chan struct{}) error
+	}
+
+	// DeployerRolloutGate is a RolloutGate which polls a Deployer until the
+	// staged deployment is active.
+	DeployerRolloutGate struct {
+		Deployer Deployer
+		Registry Registry
+		// PollInterval is the time between polls; DefaultRolloutGatePoll if zero.
+		PollInterval time.Duration
+	}
+
+	// RolloutHaltedError is returned when a staged rollout stops before its
+	// final stage.
+	RolloutHaltedError struct {
+		Stage RolloutStage
+		Err   error
+	}
+
+	// RolloutFlaw describes an invalid Rollout.
+	RolloutFlaw struct {
+		Rollout *Rollout
+		Problem string
+		context []interface{}
+	}
+)
+
+const (
+	// RolloutAllAtOnce applies changes in a single step.
+	RolloutAllAtOnce RolloutStrategy = ""
+	// RolloutCanary applies changes first to CanaryInstances instances, then to
+	// increasing percentages of instances.
+	RolloutCanary RolloutStrategy = "canary"
+
+	// DefaultRolloutGateTimeout is how long to wait for a stage to become
+	// healthy if the rollout doesn't say.
+	DefaultRolloutGateTimeout = 10 * time.Minute
+	// DefaultRolloutGatePoll is how often DeployerRolloutGate polls.
+	DefaultRolloutGatePoll = 5 * time.Second
+
+	// CanaryFlavor is added to the flavor of a deployment to name its canary:
+	// the deployment which runs the new version alongside the prior version
+	// until the final stage of a staged rollout.
+	CanaryFlavor = "sous-canary"
+)
+
+// ErrRolloutCancelled is returned by a RolloutGate when the rollout it is
+// waiting on is cancelled.
+var ErrRolloutCancelled = errors.New("rollout cancelled")
+
+// CanaryID returns the ID of the canary deployment of did.
+func CanaryID(did DeploymentID) DeploymentID {
+	if did.ManifestID.Flavor == "" {
+		did.ManifestID.Flavor = CanaryFlavor
+	} else {
+		did.ManifestID.Flavor += "-" + CanaryFlavor
+	}
+	return did
+}
+
+// IsCanary returns true if did is the ID of a canary deployment. Canaries are
+// created and removed by the rollouts which need them, and are never part of
+// the intended state.
+func IsCanary(did DeploymentID) bool {
+	f := did.ManifestID.Flavor
+	return f == CanaryFlavor || strings.HasSuffix(f, "-"+CanaryFlavor)
+}
+
+func (s RolloutStage) String() string {
+	return fmt.Sprintf("stage %d/%d (%d instances)", s.Index+1, s.Count, s.Instances)
+}
+
+// Final returns true if this is the last stage of its rollout.
+func (s RolloutStage) Final() bool {
+	return s.Index == s.Count-1
+}
+
+// Clone returns a deep copy of this Rollout.
+func (r Rollout) Clone() Rollout {
+	c := r
+	if r.Steps != nil {
+		c.Steps = append([]int{}, r.Steps...)
+	}
+	return c
+}
+
+// Equal returns true if r and o are the same rollout.
+func (r Rollout) Equal(o Rollout) bool {
+	if len(r.Steps) != len(o.Steps) {
+		return false
+	}
+	for i := range r.Steps {
+		if r.Steps[i] != o.Steps[i] {
+			return false
+		}
+	}
+	return r.Strategy == o.Strategy &&
+		r.CanaryInstances == o.CanaryInstances &&
+		r.PauseSeconds == o.PauseSeconds &&
+		r.HealthGate == o.HealthGate &&
+		r.GateTimeoutSeconds == o.GateTimeoutSeconds
+}
+
+// Staged returns true if this rollout applies changes in more than one step.
+func (r Rollout) Staged() bool {
+	return r.Strategy != RolloutAllAtOnce
+}
+
+// Pause returns the time to wait between stages.
+func (r Rollout) Pause() time.Duration {
+	return time.Duration(r.PauseSeconds) * time.Second
+}
+
+// GateTimeout returns the maximum time to wait for a stage to become healthy.
+func (r Rollout) GateTimeout() time.Duration {
+	if r.GateTimeoutSeconds <= 0 {
+		return DefaultRolloutGateTimeout
+	}
+	return time.Duration(r.GateTimeoutSeconds) * time.Second
+}
+
+// Stages returns the stages of rolling out to numInstances instances. There
+// is always at least one stage, and the last stage is always numInstances.
+func (r Rollout) Stages(numInstances int) []RolloutStage {
+	counts := []int{}
+	add := func(n int) {
+		if n <= 0 || n >= numInstances {
+			return
+		}
+		if len(counts) > 0 && n <= counts[len(counts)-1] {
+			return
+		}
+		counts = append(counts, n)
+	}
+	if r.Strategy == RolloutCanary {
+		add(r.CanaryInstances)
+		for _, pct := range r.Steps {
+			add((numInstances*pct + 99) / 100)
+		}
+	}
+	counts = append(counts, numInstances)
+
+	stages := make([]RolloutStage, len(counts))
+	for i, n := range counts {
+		stages[i] = RolloutStage{Index: i, Count: len(counts), Instances: n}
+	}
+	return stages
+}
+
+// Validate returns a slice of Flaws.
+func (r *Rollout) Validate() []Flaw {
+	var flaws []Flaw
+	flaw := func(f string, a ...interface{}) {
+		flaws = append(flaws, &RolloutFlaw{Rollout: r, Problem: fmt.Sprintf(f, a...)})
+	}
+	switch r.Strategy {
+	default:
+		flaw("unknown rollout strategy %q", r.Strategy)
+	case RolloutAllAtOnce, RolloutCanary:
+	}
+	if r.CanaryInstances < 0 {
+		flaw("negative canary instances: %d", r.CanaryInstances)
+	}
+	prev := 0
+	for _, pct := range r.Steps {
+		if pct <= prev || pct > 100 {
+			flaw("rollout steps must be increasing percentages no greater than 100, got %v", r.Steps)
+			break
+		}
+		prev = pct
+	}
+	if r.PauseSeconds < 0 {
+		flaw("negative rollout pause: %d", r.PauseSeconds)
+	}
+	if r.GateTimeoutSeconds < 0 {
+		flaw("negative rollout gate timeout: %d", r.GateTimeoutSeconds)
+	}
+	return flaws
+}
+
+func (f *RolloutFlaw) String() string {
+	return fmt.Sprintf("Invalid rollout: %s", f.Problem)
+}
+
+// AddContext implements Flaw.
+func (f *RolloutFlaw) AddContext(name string, thing interface{}) {
+	f.context = append(f.context, name, thing)
+}
+
+// Repair implements Flaw. Invalid rollouts can't be repaired automatically.
+func (f *RolloutFlaw) Repair() error {
+	return errors.Errorf("Can't repair %s", f)
+}
+
+func (e *RolloutHaltedError) Error() string {
+	return fmt.Sprintf("rollout halted after %s: %v", e.Stage, e.Err)
+}
+
+// AwaitStage implements RolloutGate.
+func (g *DeployerRolloutGate) AwaitStage(pair *DeployablePair, stage RolloutStage, cancel <-chan struct{}) error {
+	post := pair.Post.Deployment
+	poll := g.PollInterval
+	if poll == 0 {
+		poll = DefaultRolloutGatePoll
+	}
+	deadline := time.Now().Add(post.Rollout.GateTimeout())
+	clusters := Clusters{post.ClusterName: post.Cluster}
+	for {
+		states, err := g.Deployer.RunningDeployments(g.Registry, clusters)
+		if err == nil {
+			if ds, ok := states.Get(pair.ID()); ok && ds.SourceID.Equal(post.SourceID) {
+				switch ds.Status {
+				case DeployStatusFailed:
+					return errors.Errorf("%s failed: %s", stage, ds.ExecutorMessage)
+				case DeployStatusActive:
+					return nil
+				}
+			}
+		}
+		if time.Now().After(deadline) {
+			return errors.Errorf("%s not healthy after %s", stage, post.Rollout.GateTimeout())
+		}
+		if !sleepUnlessCancelled(poll, cancel) {
+			return ErrRolloutCancelled
+		}
+	}
+}
+
+// sleepUnlessCancelled waits for d, and returns true, unless cancel is closed
+// first, when it returns false.
+func sleepUnlessCancelled(d time.Duration, cancel <-chan struct{}) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-cancel:
+		return false
+	}
+}
@@ -0,0 +1,58 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stageInstances(stages []RolloutStage) []int {
+	counts := []int{}
+	for _, s := range stages {
+		counts = append(counts, s.Instances)
+	}
+	return counts
+}
+
+func TestRollout_Stages(t *testing.T) {
+	tests := []struct {
+		rollout Rollout
+		n       int
+		want    []int
+	}{
+		{Rollout{}, 10, []int{10}},
+		{Rollout{Strategy: RolloutCanary}, 10, []int{10}},
+		{Rollout{Strategy: RolloutCanary, CanaryInstances: 1}, 10, []int{1, 10}},
+		{Rollout{Strategy: RolloutCanary, CanaryInstances: 1, Steps: []int{25, 50}}, 10, []int{1, 3, 5, 10}},
+		// Steps which don't increase the instance count are dropped.
+		{Rollout{Strategy: RolloutCanary, CanaryInstances: 2, Steps: []int{10, 100}}, 10, []int{2, 10}},
+		{Rollout{Strategy: RolloutCanary, CanaryInstances: 5}, 3, []int{3}},
+	}
+	for _, test := range tests {
+		stages := test.rollout.Stages(test.n)
+		assert.Equal(t, test.want, stageInstances(stages), "%+v of %d", test.rollout, test.n)
+		last := stages[len(stages)-1]
+		assert.True(t, last.Final())
+		assert.Equal(t, len(stages), last.Count)
+	}
+}
+
+func TestRollout_Validate(t *testing.T) {
+	assert.Empty(t, (&Rollout{}).Validate())
+	assert.Empty(t, (&Rollout{Strategy: RolloutCanary, CanaryInstances: 1, Steps: []int{10, 50}}).Validate())
+
+	assert.Len(t, (&Rollout{Strategy: "blue-green"}).Validate(), 1)
+	assert.Len(t, (&Rollout{Strategy: RolloutCanary, CanaryInstances: -1}).Validate(), 1)
+	assert.Len(t, (&Rollout{Strategy: RolloutCanary, Steps: []int{50, 20}}).Validate(), 1)
+	assert.Len(t, (&Rollout{Strategy: RolloutCanary, Steps: []int{150}}).Validate(), 1)
+	assert.Len(t, (&Rollout{Strategy: RolloutCanary, PauseSeconds: -5}).Validate(), 1)
+}
+
+func TestDeployConfig_RolloutIsNotADiff(t *testing.T) {
+	dc := DeployConfigFixture("")
+	other := dc.Clone()
+	other.Rollout = Rollout{Strategy: RolloutCanary, CanaryInstances: 1}
+	same, diffs := dc.Diff(other)
+	assert.True(t, same, "%v", diffs)
+	assert.Equal(t, other.Rollout, other.Clone().Rollout)
+}
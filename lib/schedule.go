@@ -0,0 +1,110 @@
+package sous
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cronFields describes the fields of a cron expression: minute, hour, day of
+// month, month and day of week, with their ranges and any names their values
+// may be given by.
+var cronFields = []struct {
+	name     string
+	min, max int
+	names    []string
+}{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+}
+
+var cronMacros = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+
+// ValidateCron returns an error if expr is not a valid cron expression: five
+// fields, each a comma separated list of *, a value or a range, optionally
+// with a /step, or one of the macros like @daily.
+func ValidateCron(expr string) error {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@") {
+		if !containsString(cronMacros, expr) {
+			return errors.Errorf("unknown cron macro %q", expr)
+		}
+		return nil
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return errors.Errorf("cron expression %q has %d fields, not %d", expr, len(fields), len(cronFields))
+	}
+	for i, field := range fields {
+		cf := cronFields[i]
+		for _, item := range strings.Split(field, ",") {
+			if err := validateCronItem(item, cf.min, cf.max, cf.names); err != nil {
+				return errors.Wrapf(err, "cron expression %q: %s", expr, cf.name)
+			}
+		}
+	}
+	return nil
+}
+
+func validateCronItem(item string, min, max int, names []string) error {
+	parts := strings.SplitN(item, "/", 2)
+	if len(parts) == 2 {
+		if step, err := strconv.Atoi(parts[1]); err != nil || step < 1 {
+			return errors.Errorf("%q has an invalid step", item)
+		}
+	}
+	span := parts[0]
+	if span == "*" {
+		return nil
+	}
+	bounds := strings.SplitN(span, "-", 2)
+	values := make([]int, len(bounds))
+	for i, b := range bounds {
+		v, err := cronValue(b, min, names)
+		if err != nil || v < min || v > max {
+			return errors.Errorf("%q is not between %d and %d", b, min, max)
+		}
+		values[i] = v
+	}
+	if len(values) == 2 && values[0] > values[1] {
+		return errors.Errorf("range %q is backwards", span)
+	}
+	return nil
+}
+
+func cronValue(s string, min int, names []string) (int, error) {
+	for i, n := range names {
+		if strings.EqualFold(s, n) {
+			return min + i, nil
+		}
+	}
+	return strconv.Atoi(s)
+}
+
+// validateSchedule returns Flaws with the scheduling of dc, which is only
+// significant for deployments whose Kind IsScheduled.
+func (dc *DeployConfig) validateSchedule() []Flaw {
+	var flaws []Flaw
+	if dc.Schedule == "" {
+		flaws = append(flaws, FatalFlaw("scheduled deployments require a Schedule"))
+	} else if err := ValidateCron(dc.Schedule); err != nil {
+		flaws = append(flaws, FatalFlaw("invalid Schedule: %s", err))
+	}
+	if dc.ScheduleTimeZone != "" {
+		if _, err := time.LoadLocation(dc.ScheduleTimeZone); err != nil {
+			flaws = append(flaws, FatalFlaw("invalid ScheduleTimeZone %q: %s", dc.ScheduleTimeZone, err))
+		}
+	}
+	if dc.MaxExecutionSeconds < 0 {
+		flaws = append(flaws, FatalFlaw("MaxExecutionSeconds must not be negative, not %d", dc.MaxExecutionSeconds))
+	}
+	if dc.RetriesOnFailure < 0 {
+		flaws = append(flaws, FatalFlaw("RetriesOnFailure must not be negative, not %d", dc.RetriesOnFailure))
+	}
+	return flaws
+}
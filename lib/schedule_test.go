@@ -0,0 +1,62 @@
+package sous
+
+import "testing"
+
+func TestValidateCron(t *testing.T) {
+	for _, valid := range []string{
+		"* * * * *",
+		"*/15 0-6 1,15 * *",
+		"30 3 * JAN-MAR mon-fri",
+		"0 0 * * 7",
+		"0 12 1-31/2 * SUN",
+		"@daily",
+	} {
+		if err := ValidateCron(valid); err != nil {
+			t.Errorf("ValidateCron(%q): %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * FUN",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"@fortnightly",
+	} {
+		if err := ValidateCron(invalid); err == nil {
+			t.Errorf("ValidateCron(%q): no error", invalid)
+		}
+	}
+}
+
+func TestDeployment_Validate_schedule(t *testing.T) {
+	d := &Deployment{
+		Kind: ScheduledJob,
+		DeployConfig: DeployConfig{
+			Resources: Resources{"cpus": "1", "memory": "100", "ports": "1"},
+			Startup:   Startup{SkipCheck: true},
+			Schedule:  "0 2 * * *",
+		},
+	}
+	if flaws := d.Validate(); len(flaws) != 0 {
+		t.Errorf("got flaws %v", flaws)
+	}
+
+	d.Schedule = "0 2 * *"
+	d.ScheduleTimeZone = "Mars/Olympus_Mons"
+	d.MaxExecutionSeconds = -1
+	d.RetriesOnFailure = -1
+	if flaws := d.Validate(); len(flaws) != 4 {
+		t.Errorf("got flaws %v; want 4", flaws)
+	}
+
+	d.Kind = ManifestKindService
+	if flaws := d.Validate(); len(flaws) != 0 {
+		t.Errorf("scheduling checked for a service: got flaws %v", flaws)
+	}
+}
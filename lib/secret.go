@@ -0,0 +1,270 @@
+package sous
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// A SecretRef refers to a secret, to be resolved when a deployment is
+	// rectified rather than stored in the GDM. In an Env, it is written
+	// secret://Path#Key.
+	SecretRef struct {
+		// Path identifies the secret in the SecretProvider.
+		Path string
+		// Key selects one value of a secret with several, e.g. the password
+		// in a secret holding a username and password.
+		Key string
+	}
+
+	// A SecretProvider resolves SecretRefs to their values.
+	SecretProvider interface {
+		Secret(SecretRef) (string, error)
+	}
+
+	// SecretDefs is a collection of SecretDef.
+	SecretDefs []SecretDef
+
+	// A SecretDef declares a secret that deployments may refer to.
+	SecretDef struct {
+		// Path is the Path of the SecretRefs this definition allows.
+		Path string
+		// Desc describes the secret.
+		Desc string `yaml:",omitempty"`
+		// Keys lists the keys which may be referred to. If it is empty, any
+		// key may be.
+		Keys []string `yaml:",omitempty"`
+		// Clusters lists the clusters whose deployments may refer to this
+		// secret. If it is empty, deployments to any cluster may.
+		Clusters []string `yaml:",omitempty"`
+	}
+)
+
+// SecretScheme begins every env value which is a SecretRef.
+const SecretScheme = "secret://"
+
+// SecretRefsLabel is the metadata fieldname that records, as JSON, which env
+// variables of a deploy were resolved from which SecretRefs.
+const SecretRefsLabel = "com.opentable.sous.secret_refs"
+
+// RedactedValue replaces the value of a secret wherever it would be logged.
+const RedactedValue = "<redacted>"
+
+// IsSecretRef returns true if value is written as a SecretRef, whether or
+// not it is valid.
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, SecretScheme)
+}
+
+// ParseSecretRef parses a SecretRef from value.
+func ParseSecretRef(value string) (SecretRef, error) {
+	if !IsSecretRef(value) {
+		return SecretRef{}, errors.Errorf("%q does not begin with %s", value, SecretScheme)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(value, SecretScheme), "#", 2)
+	ref := SecretRef{Path: parts[0]}
+	if len(parts) == 2 {
+		ref.Key = parts[1]
+		if ref.Key == "" {
+			return SecretRef{}, errors.Errorf("%q has an empty key", value)
+		}
+	}
+	if ref.Path == "" {
+		return SecretRef{}, errors.Errorf("%q has no path", value)
+	}
+	if ref.Path != path.Clean(ref.Path) || strings.HasPrefix(ref.Path, "/") || strings.HasPrefix(ref.Path, "../") {
+		return SecretRef{}, errors.Errorf("%q has a path which is not clean and relative", value)
+	}
+	return ref, nil
+}
+
+func (ref SecretRef) String() string {
+	if ref.Key == "" {
+		return SecretScheme + ref.Path
+	}
+	return SecretScheme + ref.Path + "#" + ref.Key
+}
+
+// SecretRefs returns the SecretRefs in e, by variable name. Values written as
+// SecretRefs which are not valid are returned as errors.
+func (e Env) SecretRefs() (map[string]SecretRef, error) {
+	refs := map[string]SecretRef{}
+	for _, name := range e.names() {
+		value := e[name]
+		if !IsSecretRef(value) {
+			continue
+		}
+		ref, err := ParseSecretRef(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "env %s", name)
+		}
+		refs[name] = ref
+	}
+	return refs, nil
+}
+
+func (e Env) names() []string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveSecrets returns a copy of e with every SecretRef replaced by its
+// value from sp, and the SecretRefs which were replaced.
+func (e Env) ResolveSecrets(sp SecretProvider) (Env, map[string]SecretRef, error) {
+	refs, err := e.SecretRefs()
+	if err != nil {
+		return nil, nil, err
+	}
+	resolved := make(Env, len(e))
+	for name, value := range e {
+		resolved[name] = value
+	}
+	if len(refs) == 0 {
+		return resolved, refs, nil
+	}
+	if sp == nil {
+		return nil, nil, errors.Errorf("env refers to secrets, but no secret provider is configured")
+	}
+	for name, ref := range refs {
+		value, err := sp.Secret(ref)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "resolving %s for env %s", ref, name)
+		}
+		resolved[name] = value
+	}
+	return resolved, refs, nil
+}
+
+// RestoreSecretRefs replaces the resolved value of each of refs in e with the
+// SecretRef it was resolved from, so that it can be compared with the GDM.
+func (e Env) RestoreSecretRefs(refs map[string]SecretRef) {
+	for name, ref := range refs {
+		if _, ok := e[name]; ok {
+			e[name] = ref.String()
+		}
+	}
+}
+
+// ValidateFor returns a Flaw for each variable of e which is a SecretRef, if
+// deployments to cluster cannot resolve them. Only Singularity clusters
+// resolve SecretRefs; Kubernetes would show the value in the pod spec.
+func (e Env) ValidateFor(cluster *Cluster) []Flaw {
+	if cluster == nil || cluster.NormalizedKind() == ClusterKindSingularity {
+		return nil
+	}
+	var flaws []Flaw
+	for _, name := range e.names() {
+		if IsSecretRef(e[name]) {
+			flaws = append(flaws, FatalFlaw("env %s: secret references are not supported by %s cluster %s.", name, cluster.NormalizedKind(), cluster.Name))
+		}
+	}
+	return flaws
+}
+
+// Redacted returns a copy of e with the values of the variables named in
+// refs replaced by RedactedValue.
+func (e Env) Redacted(refs map[string]SecretRef) Env {
+	r := make(Env, len(e))
+	for name, value := range e {
+		if _, secret := refs[name]; secret {
+			value = RedactedValue
+		}
+		r[name] = value
+	}
+	return r
+}
+
+// EncodeSecretRefs encodes refs for the SecretRefsLabel.
+func EncodeSecretRefs(refs map[string]SecretRef) string {
+	strs := make(map[string]string, len(refs))
+	for name, ref := range refs {
+		strs[name] = ref.String()
+	}
+	b, _ := json.Marshal(strs)
+	return string(b)
+}
+
+// DecodeSecretRefs decodes refs encoded by EncodeSecretRefs.
+func DecodeSecretRefs(label string) (map[string]SecretRef, error) {
+	strs := map[string]string{}
+	if err := json.Unmarshal([]byte(label), &strs); err != nil {
+		return nil, errors.Wrapf(err, "decoding %s", SecretRefsLabel)
+	}
+	refs := make(map[string]SecretRef, len(strs))
+	for name, str := range strs {
+		ref, err := ParseSecretRef(str)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding %s", SecretRefsLabel)
+		}
+		refs[name] = ref
+	}
+	return refs, nil
+}
+
+// Clone returns a deep copy of this SecretDefs.
+func (sds SecretDefs) Clone() SecretDefs {
+	if sds == nil {
+		return nil
+	}
+	c := make(SecretDefs, len(sds))
+	for i, sd := range sds {
+		sd.Keys = append([]string(nil), sd.Keys...)
+		sd.Clusters = append([]string(nil), sd.Clusters...)
+		c[i] = sd
+	}
+	return c
+}
+
+// Check returns a Flaw for each SecretRef in d's Env which sds does not allow.
+func (sds SecretDefs) Check(d *Deployment) []Flaw {
+	var flaws []Flaw
+	for _, name := range d.Env.names() {
+		value := d.Env[name]
+		if !IsSecretRef(value) {
+			continue
+		}
+		ref, err := ParseSecretRef(value)
+		if err != nil {
+			// Deployment.Validate reports malformed refs.
+			continue
+		}
+		if err := sds.allows(ref, d.ClusterName); err != nil {
+			flaws = append(flaws, FatalFlaw("%s env %s: %s", d.ID(), name, err))
+		}
+	}
+	return flaws
+}
+
+func (sds SecretDefs) allows(ref SecretRef, cluster string) error {
+	for _, sd := range sds {
+		if sd.Path != ref.Path {
+			continue
+		}
+		if len(sd.Keys) > 0 && !containsString(sd.Keys, ref.Key) {
+			return fmt.Errorf("%s: key %q is not one of %s", ref, ref.Key, strings.Join(sd.Keys, ", "))
+		}
+		if len(sd.Clusters) > 0 && !containsString(sd.Clusters, cluster) {
+			return fmt.Errorf("%s may not be used in cluster %s", ref, cluster)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s is not declared in Defs.Secrets", ref)
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type mapSecrets map[string]string
+
+func (ms mapSecrets) Secret(ref SecretRef) (string, error) {
+	v, ok := ms[ref.String()]
+	if !ok {
+		return "", errors.Errorf("no secret %s", ref)
+	}
+	return v, nil
+}
+
+func TestParseSecretRef(t *testing.T) {
+	valid := map[string]SecretRef{
+		"secret://db/prod#password": {Path: "db/prod", Key: "password"},
+		"secret://api-key":          {Path: "api-key"},
+	}
+	for in, want := range valid {
+		got, err := ParseSecretRef(in)
+		if err != nil {
+			t.Errorf("ParseSecretRef(%q): %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseSecretRef(%q) = %+v; want %+v", in, got, want)
+		}
+		if got.String() != in {
+			t.Errorf("%+v.String() = %q; want %q", got, got.String(), in)
+		}
+	}
+	for _, in := range []string{
+		"db/prod#password",
+		"secret://",
+		"secret://#password",
+		"secret://db/prod#",
+		"secret:///etc/passwd",
+		"secret://../outside",
+		"secret://db/../../outside",
+	} {
+		if ref, err := ParseSecretRef(in); err == nil {
+			t.Errorf("ParseSecretRef(%q) = %+v; want error", in, ref)
+		}
+	}
+}
+
+func TestEnv_ResolveSecrets(t *testing.T) {
+	env := Env{
+		"PLAIN":    "value",
+		"PASSWORD": "secret://db/prod#password",
+	}
+	resolved, refs, err := env.ResolveSecrets(mapSecrets{"secret://db/prod#password": "hunter2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved["PASSWORD"] != "hunter2" || resolved["PLAIN"] != "value" {
+		t.Errorf("got resolved env %v", resolved)
+	}
+	if env["PASSWORD"] != "secret://db/prod#password" {
+		t.Errorf("ResolveSecrets modified its receiver")
+	}
+	if len(refs) != 1 {
+		t.Errorf("got refs %v; want PASSWORD only", refs)
+	}
+
+	redacted := resolved.Redacted(refs)
+	if redacted["PASSWORD"] != RedactedValue || redacted["PLAIN"] != "value" {
+		t.Errorf("got redacted env %v", redacted)
+	}
+
+	decoded, err := DecodeSecretRefs(EncodeSecretRefs(refs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved.RestoreSecretRefs(decoded)
+	if !resolved.Equal(env) {
+		t.Errorf("restored env %v; want %v", resolved, env)
+	}
+
+	if _, _, err := env.ResolveSecrets(nil); err == nil {
+		t.Errorf("no error resolving secrets without a provider")
+	}
+	if _, _, err := env.ResolveSecrets(mapSecrets{}); err == nil {
+		t.Errorf("no error resolving a missing secret")
+	}
+	if _, _, err := (Env{"PLAIN": "value"}).ResolveSecrets(nil); err != nil {
+		t.Errorf("error resolving env without secrets: %v", err)
+	}
+}
+
+func TestSecretDefs_Check(t *testing.T) {
+	defs := SecretDefs{
+		{Path: "db/prod", Keys: []string{"user", "password"}, Clusters: []string{"prod"}},
+		{Path: "api-key"},
+	}
+	check := func(cluster, value string, wantFlaws int) {
+		t.Helper()
+		d := &Deployment{ClusterName: cluster, DeployConfig: DeployConfig{Env: Env{"X": value}}}
+		if flaws := defs.Check(d); len(flaws) != wantFlaws {
+			t.Errorf("%s in %s: got flaws %v; want %d", value, cluster, flaws, wantFlaws)
+		}
+	}
+	check("prod", "secret://db/prod#password", 0)
+	check("prod", "secret://api-key", 0)
+	check("prod", "not a secret", 0)
+	check("prod", "secret://db/prod#host", 1)
+	check("dev", "secret://db/prod#password", 1)
+	check("prod", "secret://undeclared#key", 1)
+}
+
+func TestDeployConfig_Validate_secretRef(t *testing.T) {
+	dc := DeployConfig{Resources: Resources{"cpus": "1", "memory": "100", "ports": "1"}, Env: Env{"X": "secret://"}}
+	if len(dc.Validate()) == 0 {
+		t.Errorf("no flaws for malformed secret ref")
+	}
+}
+
+func TestEnv_ValidateFor(t *testing.T) {
+	singularity := &Cluster{Name: "left", Kind: ClusterKindSingularity}
+	kubernetes := &Cluster{Name: "k8s", Kind: ClusterKindKubernetes}
+	env := Env{"DB_HOST": "db.example.com", "DB_PASSWORD": "secret://db/prod#password"}
+	if flaws := env.ValidateFor(singularity); len(flaws) != 0 {
+		t.Errorf("secret ref on singularity: got flaws %v", flaws)
+	}
+	if flaws := env.ValidateFor(kubernetes); len(flaws) != 1 {
+		t.Errorf("secret ref on kubernetes: got flaws %v; want one", flaws)
+	}
+	if flaws := (Env{"DB_HOST": "db.example.com"}).ValidateFor(kubernetes); len(flaws) != 0 {
+		t.Errorf("plain env on kubernetes: got flaws %v", flaws)
+	}
+}
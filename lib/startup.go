@@ -15,6 +15,8 @@ type Startup struct { //                             Singularity fields
 	Timeout         int `yaml:",omitempty"` // Healthcheck.StartupTimeoutSeconds
 	ConnectInterval int `yaml:",omitempty"` // Healthcheck.StartupIntervalSeconds
 
+	// CheckReadyProtocol is one of CheckReadyHTTP, CheckReadyHTTPS,
+	// CheckReadyTCP or CheckReadyExec. Singularity only supports HTTP(S).
 	CheckReadyProtocol        string `yaml:",omitempty"` // Healthcheck.Protocol
 	CheckReadyURIPath         string `yaml:",omitempty"` // Healthcheck.URI
 	CheckReadyPortIndex       int    `yaml:",omitempty"` // Healthcheck.PortIndex
@@ -23,12 +25,29 @@ type Startup struct { //                             Singularity fields
 	CheckReadyInterval        int    `yaml:",omitempty"` // Healthcheck.IntervalSeconds
 	CheckReadyRetries         int    `yaml:",omitempty"` // Healthcheck.MaxRetries
 
+	// CheckReadyCommand is the command run in the container by an EXEC
+	// check, which passes if it exits 0.
+	CheckReadyCommand []string `yaml:",omitempty"`
+
 	// ??? We don't deploy fixed port services...
 	// ??? CheckReadyPortNumber int    `yaml:",omitempty"` // Healthcheck.PortNumber
 
 	// XXX it would be possible to do a CheckReadyTimeout instead of MaxRetries...
 }
 
+// The values of Startup.CheckReadyProtocol.
+const (
+	// CheckReadyHTTP checks readiness with an HTTP GET of CheckReadyURIPath.
+	CheckReadyHTTP = "HTTP"
+	// CheckReadyHTTPS checks readiness with an HTTPS GET of CheckReadyURIPath.
+	CheckReadyHTTPS = "HTTPS"
+	// CheckReadyTCP checks readiness by connecting to the port at
+	// CheckReadyPortIndex.
+	CheckReadyTCP = "TCP"
+	// CheckReadyExec checks readiness by running CheckReadyCommand.
+	CheckReadyExec = "EXEC"
+)
+
 var zeroStartup = Startup{}
 
 // Validate implements Flawed on Startup.
@@ -61,14 +80,18 @@ func (s *Startup) Validate() []Flaw {
 
 		switch s.CheckReadyProtocol {
 		default:
-			flaws = append(flaws, FatalFlaw("CheckReadyProtocol must be HTTP or HTTPS, was %q.", s.CheckReadyProtocol))
-		case "https", "http":
-			flaws = append(flaws, NewFlaw(fmt.Sprintf("CheckReadyProtocol must be HTTP or HTTPS, was %q (lowercase).", s.CheckReadyProtocol),
+			flaws = append(flaws, FatalFlaw("CheckReadyProtocol must be HTTP, HTTPS, TCP or EXEC, was %q.", s.CheckReadyProtocol))
+		case "https", "http", "tcp", "exec":
+			flaws = append(flaws, NewFlaw(fmt.Sprintf("CheckReadyProtocol must be HTTP, HTTPS, TCP or EXEC, was %q (lowercase).", s.CheckReadyProtocol),
 				func() error {
 					s.CheckReadyProtocol = strings.ToUpper(s.CheckReadyProtocol)
 					return nil
 				}))
-		case "HTTPS", "HTTP":
+		case CheckReadyHTTPS, CheckReadyHTTP, CheckReadyTCP, CheckReadyExec:
+		}
+
+		if strings.ToUpper(s.CheckReadyProtocol) == CheckReadyExec && len(s.CheckReadyCommand) == 0 {
+			flaws = append(flaws, FatalFlaw("CheckReadyProtocol EXEC requires a CheckReadyCommand."))
 		}
 
 		for _, status := range s.CheckReadyFailureStatuses {
@@ -81,6 +104,22 @@ func (s *Startup) Validate() []Flaw {
 	return flaws
 }
 
+// ValidateFor returns a Flaw if s requires a check which deployments to
+// cluster cannot enforce. Only Kubernetes clusters support TCP and EXEC
+// checks.
+func (s Startup) ValidateFor(cluster *Cluster) []Flaw {
+	if s.SkipCheck || cluster == nil {
+		return nil
+	}
+	switch p := strings.ToUpper(s.CheckReadyProtocol); p {
+	case CheckReadyTCP, CheckReadyExec:
+		if kind := cluster.NormalizedKind(); kind != ClusterKindKubernetes {
+			return []Flaw{FatalFlaw("CheckReadyProtocol %s is not supported by %s cluster %s; use HTTP, HTTPS or SkipCheck there.", p, kind, cluster.Name)}
+		}
+	}
+	return nil
+}
+
 // MergeDefaults merges default values with a Startup and returns the result
 func (s Startup) MergeDefaults(base Startup) Startup {
 	n := base
@@ -130,6 +169,10 @@ func (s Startup) MergeDefaults(base Startup) Startup {
 		n.CheckReadyRetries = s.CheckReadyRetries
 	}
 
+	if len(n.CheckReadyCommand) == len(zeroStartup.CheckReadyCommand) {
+		n.CheckReadyCommand = s.CheckReadyCommand
+	}
+
 	return n
 }
 
@@ -203,6 +246,11 @@ func (s Startup) UnmergeDefaults(base, old Startup) Startup {
 		n.CheckReadyRetries = zeroStartup.CheckReadyRetries
 	}
 
+	if equalStrings(base.CheckReadyCommand, s.CheckReadyCommand) &&
+		len(old.CheckReadyCommand) == len(zeroStartup.CheckReadyCommand) {
+		n.CheckReadyCommand = zeroStartup.CheckReadyCommand
+	}
+
 	return n
 }
 
@@ -279,5 +327,21 @@ func (s Startup) diff(o Startup) []string {
 		diff("CheckReadyURITimeout; this %d, other %d", l.CheckReadyURITimeout, r.CheckReadyURITimeout)
 	}
 
+	if !equalStrings(l.CheckReadyCommand, r.CheckReadyCommand) {
+		diff("CheckReadyCommand; this %q, other %q", l.CheckReadyCommand, r.CheckReadyCommand)
+	}
+
 	return diffs
 }
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -154,3 +154,61 @@ func TestStartup_diff(t *testing.T) {
 		t.Fatalf("got diff %q; want %q", actual, expected)
 	}
 }
+
+func (s *StartupTest) TestMergeCommand() {
+	defaults := Startup{CheckReadyProtocol: CheckReadyExec, CheckReadyCommand: []string{"true"}}
+	s.Equal([]string{"true"}, defaults.MergeDefaults(Startup{}).CheckReadyCommand)
+	s.Equal([]string{"ready", "-q"}, defaults.MergeDefaults(Startup{CheckReadyCommand: []string{"ready", "-q"}}).CheckReadyCommand)
+	s.GetPut(defaults, Startup{CheckReadyCommand: []string{"ready", "-q"}})
+}
+
+func TestStartup_Validate_protocols(t *testing.T) {
+	for _, valid := range []Startup{
+		{CheckReadyProtocol: CheckReadyHTTP},
+		{CheckReadyProtocol: CheckReadyTCP},
+		{CheckReadyProtocol: CheckReadyExec, CheckReadyCommand: []string{"/bin/ready"}},
+		{SkipCheck: true},
+	} {
+		if flaws := valid.Validate(); len(flaws) != 0 {
+			t.Errorf("%+v: got flaws %v", valid, flaws)
+		}
+	}
+	for _, invalid := range []Startup{
+		{CheckReadyProtocol: "UDP"},
+		{CheckReadyProtocol: CheckReadyExec},
+	} {
+		if flaws := invalid.Validate(); len(flaws) == 0 {
+			t.Errorf("%+v: no flaws", invalid)
+		}
+	}
+
+	lower := Startup{CheckReadyProtocol: "tcp"}
+	flaws := lower.Validate()
+	if len(flaws) != 1 {
+		t.Fatalf("got flaws %v; want one", flaws)
+	}
+	if err := flaws[0].Repair(); err != nil {
+		t.Fatal(err)
+	}
+	if lower.CheckReadyProtocol != CheckReadyTCP {
+		t.Errorf("repaired protocol is %q; want %q", lower.CheckReadyProtocol, CheckReadyTCP)
+	}
+}
+
+func TestStartup_ValidateFor(t *testing.T) {
+	singularity := &Cluster{Name: "left", Kind: ClusterKindSingularity}
+	kubernetes := &Cluster{Name: "k8s", Kind: ClusterKindKubernetes}
+	tcp := Startup{CheckReadyProtocol: CheckReadyTCP}
+	if flaws := tcp.ValidateFor(kubernetes); len(flaws) != 0 {
+		t.Errorf("TCP on kubernetes: got flaws %v", flaws)
+	}
+	if flaws := tcp.ValidateFor(singularity); len(flaws) != 1 {
+		t.Errorf("TCP on singularity: got flaws %v; want one", flaws)
+	}
+	if flaws := (Startup{CheckReadyProtocol: CheckReadyHTTP}).ValidateFor(singularity); len(flaws) != 0 {
+		t.Errorf("HTTP on singularity: got flaws %v", flaws)
+	}
+	if flaws := (Startup{CheckReadyProtocol: CheckReadyExec, SkipCheck: true}).ValidateFor(singularity); len(flaws) != 0 {
+		t.Errorf("skipped EXEC on singularity: got flaws %v", flaws)
+	}
+}
@@ -33,14 +33,32 @@ type (
 		Resources FieldDefinitions
 		// Metadata contains the definitions for metadata fields
 		Metadata FieldDefinitions
+		// FreezeWindows lists periods during which deployments to some
+		// clusters must not change.
+		FreezeWindows FreezeWindows `yaml:",omitempty"`
+		// Secrets declares the secrets deployments may refer to in their Env.
+		Secrets SecretDefs `yaml:",omitempty"`
+		// Lint lists the organisation's rules about deployments. Changes which
+		// break rules of severity LintError are rejected.
+		Lint LintRules `yaml:",omitempty"`
 	}
 
 	// EnvDefs is a collection of EnvDef
 	EnvDefs []EnvDef
-	// EnvDef is an environment variable definition.
+	// EnvDef is an environment variable definition. Deployments which set
+	// the variable are checked against it; see EnvDefs.Check.
 	EnvDef struct {
-		Name, Desc, Scope string
-		Type              VarType
+		Name, Desc string
+		// Scope says where the variable may be set: one of EnvScopeAny,
+		// EnvScopeCluster or EnvScopeManifest.
+		Scope string
+		// Type is the type of the variable's values.
+		Type VarType
+		// Values lists the allowed values of a variable of type VarTypeEnum.
+		Values []string `yaml:",omitempty"`
+		// Pattern is the regular expression which the whole of every value of
+		// a variable of type VarTypeRegex must match.
+		Pattern string `yaml:",omitempty"`
 	}
 
 	// FieldDefinitions is just a type alias for a slice of FieldDefinition-s
@@ -69,8 +87,8 @@ type (
 	Cluster struct {
 		// Name is the unique name of this cluster.
 		Name string
-		// Kind is the kind of cluster. Currently the only legal value is
-		// "singularity"
+		// Kind is the kind of cluster. Legal values are "singularity" and
+		// "kubernetes"; empty means "singularity".
 		Kind string
 		// BaseURL is the main entrypoint URL for interacting with this cluster.
 		BaseURL string
@@ -81,15 +99,24 @@ type (
 		// AllowedAdvisories lists the artifact advisories which are permissible in
 		// this cluster
 		AllowedAdvisories []string
+		// RequireApproval means changes to deployments in this cluster must be
+		// approved by someone other than their author before they are made.
+		RequireApproval bool `yaml:",omitempty"`
+		// Approvers lists users (by email) who may approve changes to any
+		// deployment in this cluster, in addition to each manifest's owners.
+		Approvers []string `yaml:",omitempty"`
+		// Writers lists users (by email) who may change deployments in this
+		// cluster. If it is empty, anyone may, subject to the owners of each
+		// manifest. It is only enforced by servers which authorize writes.
+		Writers []string `yaml:",omitempty"`
 	}
 
 	// EnvDefaults is a list of named environment variables along with their values.
 	EnvDefaults map[string]Var
-	// Var is a strongly typed string for use in environment variables and YAML
-	// files. It will implement sane YAML marshalling and unmarshalling. (Not
-	// yet implemented.)
+	// Var is a string for use in environment variables and YAML files, whose
+	// type is given by the EnvDef of the same name, if there is one.
 	Var string
-	// VarType represents the type of a Var (not yet implemented).
+	// VarType represents the type of a Var. See EnvDef.Check.
 	VarType string
 )
 
@@ -136,6 +163,9 @@ func (d Defs) Clone() Defs {
 	d.EnvVars = d.EnvVars.Clone()
 	d.Resources = d.Resources.Clone()
 	d.Metadata = d.Metadata.Clone()
+	d.FreezeWindows = d.FreezeWindows.Clone()
+	d.Secrets = d.Secrets.Clone()
+	d.Lint = d.Lint.Clone()
 	return d
 }
 
@@ -164,13 +194,18 @@ func (c Cluster) Clone() *Cluster {
 	allowedAdvisories := make([]string, len(c.AllowedAdvisories))
 	copy(allowedAdvisories, c.AllowedAdvisories)
 	c.AllowedAdvisories = allowedAdvisories
+	c.Approvers = append([]string(nil), c.Approvers...)
+	c.Writers = append([]string(nil), c.Writers...)
 	return &c
 }
 
 // Clone returns a deep copy of this EnvDefs.
 func (evs EnvDefs) Clone() EnvDefs {
 	e := make(EnvDefs, len(evs))
-	copy(e, evs)
+	for i, ev := range evs {
+		ev.Values = append([]string(nil), ev.Values...)
+		e[i] = ev
+	}
 	return e
 }
 
@@ -215,6 +250,7 @@ func (s *State) Validate() []Flaw {
 	}
 	for _, depl := range ds.Snapshot() {
 		flaws = append(flaws, depl.Validate()...)
+		flaws = append(flaws, s.Defs.Secrets.Check(depl)...)
 	}
 
 	for _, f := range flaws {
@@ -0,0 +1,160 @@
+package sous
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/opentable/sous/util/yaml"
+)
+
+type (
+	// A StateComparison lists the ways one copy of the state differs from
+	// another, for instance the GDM as held by two StateManagers.
+	StateComparison struct {
+		// Defs lists the differences between the two copies' Defs.
+		Defs Differences
+		// Changes are the changes which would make the deployments of the
+		// first copy into those of the second, ordered by deployment: "added"
+		// deployments are only in the second, "removed" deployments are only
+		// in the first, and "modified" deployments differ between them.
+		Changes []DeploymentChange
+	}
+
+	// A StateReconciler keeps two copies of the state, and can compare them.
+	StateReconciler interface {
+		// ReconcileState reads both copies of the state, and reports how
+		// they differ.
+		ReconcileState() (*StateComparison, error)
+	}
+)
+
+// CompareStates reports how to differs from from.
+func CompareStates(from, to *State) (*StateComparison, error) {
+	fromDeps, err := from.Deployments()
+	if err != nil {
+		return nil, err
+	}
+	toDeps, err := to.Deployments()
+	if err != nil {
+		return nil, err
+	}
+	changes := GDMChanges(fromDeps.Diff(toDeps).Collect())
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].DeploymentID.String() < changes[j].DeploymentID.String()
+	})
+	defs, err := diffDefs(from.Defs, to.Defs)
+	if err != nil {
+		return nil, err
+	}
+	return &StateComparison{Defs: defs, Changes: changes}, nil
+}
+
+// Same returns true if the copies compared do not differ.
+func (sc *StateComparison) Same() bool {
+	return len(sc.Defs) == 0 && len(sc.Changes) == 0
+}
+
+// diffDefs describes the differences between this and other. Definitions
+// are compared by name, and as YAML, so that storage which does not preserve
+// their order, or the difference between empty and missing lists, does not
+// count as a difference.
+func diffDefs(this, other Defs) (Differences, error) {
+	var diffs Differences
+	diff := func(format string, a ...interface{}) { diffs = append(diffs, fmt.Sprintf(format, a...)) }
+
+	byName := func(kind string, these, others map[string]interface{}) error {
+		names := []string{}
+		for name := range these {
+			names = append(names, name)
+		}
+		for name := range others {
+			if _, has := these[name]; !has {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			t, inThis := these[name]
+			o, inOther := others[name]
+			switch {
+			case !inOther:
+				diff("%s %q; only in this", kind, name)
+			case !inThis:
+				diff("%s %q; only in other", kind, name)
+			default:
+				different, err := differentYAML(t, o)
+				if err != nil {
+					return err
+				}
+				if different {
+					diff("%s %q; this and other differ", kind, name)
+				}
+			}
+		}
+		return nil
+	}
+
+	if this.DockerRepo != other.DockerRepo {
+		diff("docker repo; this: %q; other: %q", this.DockerRepo, other.DockerRepo)
+	}
+	if err := byName("cluster", clustersByName(this.Clusters), clustersByName(other.Clusters)); err != nil {
+		return nil, err
+	}
+	if err := byName("env var def", envDefsByName(this.EnvVars), envDefsByName(other.EnvVars)); err != nil {
+		return nil, err
+	}
+	if err := byName("resource def", fieldDefsByName(this.Resources), fieldDefsByName(other.Resources)); err != nil {
+		return nil, err
+	}
+	if err := byName("metadata def", fieldDefsByName(this.Metadata), fieldDefsByName(other.Metadata)); err != nil {
+		return nil, err
+	}
+	if err := byName("defs", map[string]interface{}{
+		"FreezeWindows": this.FreezeWindows,
+		"Secrets":       this.Secrets,
+		"Lint":          this.Lint,
+	}, map[string]interface{}{
+		"FreezeWindows": other.FreezeWindows,
+		"Secrets":       other.Secrets,
+		"Lint":          other.Lint,
+	}); err != nil {
+		return nil, err
+	}
+	return diffs, nil
+}
+
+func differentYAML(this, other interface{}) (bool, error) {
+	t, err := yaml.Marshal(this)
+	if err != nil {
+		return false, err
+	}
+	o, err := yaml.Marshal(other)
+	if err != nil {
+		return false, err
+	}
+	return string(t) != string(o), nil
+}
+
+func clustersByName(cs Clusters) map[string]interface{} {
+	named := map[string]interface{}{}
+	for name, c := range cs {
+		named[name] = c
+	}
+	return named
+}
+
+func envDefsByName(defs EnvDefs) map[string]interface{} {
+	named := map[string]interface{}{}
+	for _, d := range defs {
+		named[d.Name] = d
+	}
+	return named
+}
+
+func fieldDefsByName(defs FieldDefinitions) map[string]interface{} {
+	named := map[string]interface{}{}
+	for _, d := range defs {
+		named[d.Name] = d
+	}
+	return named
+}
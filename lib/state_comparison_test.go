@@ -0,0 +1,72 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareStates(t *testing.T) {
+	from := DefaultStateFixture()
+	to := from.Clone()
+
+	cmp, err := CompareStates(from, to)
+	require.NoError(t, err)
+	assert.True(t, cmp.Same(), "%#v", cmp)
+
+	to.Defs.DockerRepo = "registry.example.com"
+	delete(to.Defs.Clusters, "cluster2")
+	for _, m := range to.Manifests.Snapshot() {
+		delete(m.Deployments, "cluster2")
+	}
+	to.Defs.Clusters["cluster1"].BaseURL = "http://elsewhere.example.com"
+	to.Defs.EnvVars = append(to.Defs.EnvVars, EnvDef{Name: "NEW_VAR", Type: VarTypeString})
+
+	cmp, err = CompareStates(from, to)
+	require.NoError(t, err)
+	assert.False(t, cmp.Same())
+	assert.Contains(t, cmp.Defs, `cluster "cluster1"; this and other differ`)
+	assert.Contains(t, cmp.Defs, `cluster "cluster2"; only in this`)
+	assert.Contains(t, cmp.Defs, `env var def "NEW_VAR"; only in other`)
+	assert.Len(t, cmp.Defs, 4)
+
+	for _, dc := range cmp.Changes {
+		assert.Equal(t, "removed", dc.Kind, "%s", dc.DeploymentID)
+		assert.Equal(t, "cluster2", dc.DeploymentID.Cluster)
+	}
+	assert.NotEmpty(t, cmp.Changes)
+}
+
+func TestCompareStates_emptyLists(t *testing.T) {
+	from := NewState()
+	from.Defs.Clusters = Clusters{"east": &Cluster{Name: "east"}}
+	to := NewState()
+	to.Defs.Clusters = Clusters{"east": &Cluster{Name: "east", AllowedAdvisories: []string{}}}
+
+	cmp, err := CompareStates(from, to)
+	require.NoError(t, err)
+	assert.True(t, cmp.Same(), "an empty list should match a missing one: %#v", cmp)
+}
+
+func TestCompareStates_policy(t *testing.T) {
+	from := DefaultStateFixture()
+	to := from.Clone()
+	for _, m := range to.Manifests.Snapshot() {
+		m = m.Clone()
+		m.AutoRollback = true
+		for cluster, spec := range m.Deployments {
+			spec.Pause = &Pause{Reason: "incident"}
+			m.Deployments[cluster] = spec
+		}
+		to.Manifests.Set(m.ID(), m)
+	}
+
+	cmp, err := CompareStates(from, to)
+	require.NoError(t, err)
+	require.NotEmpty(t, cmp.Changes, "differences only in how Sous manages deployments should be reported")
+	for _, dc := range cmp.Changes {
+		assert.Equal(t, "modified", dc.Kind, "%s", dc.DeploymentID)
+		assert.Len(t, dc.Diffs, 2, "%s", dc.DeploymentID)
+	}
+}
@@ -16,6 +16,17 @@ type (
 		StateWriter
 	}
 
+	// A StateWatcher notices when the state is written, so that its readers
+	// need not poll it.
+	StateWatcher interface {
+		// WatchState returns a channel which receives a value after the state
+		// is written, until done is closed. Writes in quick succession may be
+		// received as one. If the state is not watched, the channel never
+		// receives. The channel is never closed, since a closed channel would
+		// always be ready in its receivers' selects.
+		WatchState(done <-chan struct{}) <-chan struct{}
+	}
+
 	// DummyStateManager is used for testing
 	DummyStateManager struct {
 		*State
@@ -3,10 +3,12 @@ package sous
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"regexp"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -14,6 +16,7 @@ import (
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/restful"
 	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -397,6 +400,8 @@ func TestStatusPoller(t *testing.T) {
 			}
 		} else if gdmRE.MatchString(url) {
 			rw.Write(gdmJSON)
+		} else if r.URL.Path == "/events" {
+			rw.WriteHeader(404)
 		} else {
 			t.Errorf("Bad request: %#v", r)
 			rw.WriteHeader(500)
@@ -519,6 +524,162 @@ func TestStatusPoller(t *testing.T) {
 	}
 }
 
+func TestStatusPoller_EventStream(t *testing.T) {
+	repoName := "github.com/opentable/example"
+	deployment := `{
+		"clustername": "main",
+		"sourceid": {
+			"location": "` + repoName + `",
+			"version": "1.0.1+1234"
+		},
+		"flavor": "canhaz"
+	}`
+	var serversJSON []byte
+	gdmJSON := []byte(`{"deployments": [` + deployment + `]}`)
+
+	event := func(rw http.ResponseWriter, kind, data string) {
+		fmt.Fprintf(rw, "event: %s\n", kind)
+		for _, line := range strings.Split(data, "\n") {
+			fmt.Fprintf(rw, "data: %s\n", line)
+		}
+		fmt.Fprint(rw, "\n")
+	}
+
+	h := func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		default:
+			t.Errorf("Bad request: %#v", r)
+			rw.WriteHeader(500)
+		case "/servers":
+			rw.Write(serversJSON)
+		case "/gdm":
+			rw.Write(gdmJSON)
+		case "/events":
+			rw.Header().Set("Content-Type", "text/event-stream")
+			event(rw, "status", `{
+				"kind": "status",
+				"inprogress": {"intended": [`+deployment+`], "log": [], "started": "2017-10-11T14:26:05.975369893Z"}
+			}`)
+			event(rw, "diff-resolved", `{
+				"kind": "diff-resolved",
+				"resolution": {"manifestid": "`+repoName+`~canhaz", "desc": "unchanged"}
+			}`)
+			rw.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(h))
+	defer srv.Close()
+	serversJSON = []byte(`{"servers": [{"clustername": "main", "url":"` + srv.URL + `"}]}`)
+
+	rf := &ResolveFilter{
+		Repo: NewResolveFieldMatcher(repoName),
+	}
+	rf.SetTag("")
+
+	cl, err := restful.NewClient(srv.URL, logging.SilentLogSet())
+	if err != nil {
+		t.Fatalf("Error building HTTP client: %#v", err)
+	}
+	poller := NewStatusPoller(cl, rf, User{Name: "Test User"}, logging.SilentLogSet())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*PollTimeout)
+	defer cancel()
+	rState, err := poller.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Error waiting for poller: %v", err)
+	}
+	if rState != ResolveComplete {
+		t.Errorf("Resolve state was %s not %s", rState, ResolveComplete)
+	}
+}
+
+// TestSubPoller_followMatchesPollOnce checks that following /events reports
+// the same state as polling /status, when both report the same statuses.
+func TestSubPoller_followMatchesPollOnce(t *testing.T) {
+	repoName := "github.com/opentable/example"
+	deployment := func(version string) *Deployment {
+		return &Deployment{
+			ClusterName: "main",
+			SourceID:    MustNewSourceID(repoName, "", version),
+			Flavor:      "canhaz",
+		}
+	}
+	started := time.Date(2017, 10, 11, 14, 26, 5, 0, time.UTC)
+	resolved := func(desc ResolutionType) []DiffResolution {
+		return []DiffResolution{{ManifestID: MustParseManifestID(repoName + "~canhaz"), Desc: desc}}
+	}
+
+	cases := map[string]statusData{
+		"intended": {
+			InProgress: &ResolveStatus{Started: started, Intended: []*Deployment{deployment("1.0.1")}, Log: resolved(StableDiff)},
+		},
+		"intended by an old server": {
+			Deployments: []*Deployment{deployment("1.0.1")},
+			InProgress:  &ResolveStatus{Started: started, Log: resolved(StableDiff)},
+		},
+		"another version": {
+			Deployments: []*Deployment{deployment("1.0.0")},
+			InProgress:  &ResolveStatus{Started: started, Log: resolved(StableDiff)},
+		},
+		"completed": {
+			Deployments: []*Deployment{deployment("1.0.1")},
+			Completed:   &ResolveStatus{Started: started, Log: resolved(ComingDiff)},
+		},
+		"not started": {},
+	}
+
+	for name, status := range cases {
+		t.Run(name, func(t *testing.T) {
+			statusJSON, err := json.Marshal(status)
+			require.NoError(t, err)
+			eventJSON, err := json.Marshal(ResolveEvent{
+				Kind:        StatusEvent,
+				Completed:   status.Completed,
+				InProgress:  status.InProgress,
+				Deployments: status.Deployments,
+			})
+			require.NoError(t, err)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				default:
+					rw.WriteHeader(404)
+				case "/status":
+					rw.Write(statusJSON)
+				case "/events":
+					rw.Header().Set("Content-Type", "text/event-stream")
+					fmt.Fprintf(rw, "event: status\ndata: %s\n\n", eventJSON)
+					rw.(http.Flusher).Flush()
+					<-r.Context().Done()
+				}
+			}))
+			defer srv.Close()
+
+			rf := &ResolveFilter{Repo: NewResolveFieldMatcher(repoName)}
+			require.NoError(t, rf.SetTag("1.0.1"))
+			sub, err := newSubPoller("main", srv.URL, rf, User{Name: "Test User"}, logging.SilentLogSet())
+			require.NoError(t, err)
+
+			polled := sub.pollOnce()
+
+			rs := make(chan pollResult)
+			done := make(chan struct{})
+			followed := make(chan bool)
+			go func() { followed <- sub.follow(rs, done) }()
+			select {
+			case followedResult := <-rs:
+				assert.Equal(t, polled, followedResult)
+			case <-time.After(time.Second):
+				t.Error("no result from /events")
+			}
+			close(done)
+			assert.True(t, <-followed, "follow should end when done is closed")
+		})
+	}
+}
+
 func TestStatusPoller_OldServer2(t *testing.T) {
 	serversRE := regexp.MustCompile(`/servers$`)
 	statusRE := regexp.MustCompile(`/status$`)
@@ -533,6 +694,8 @@ func TestStatusPoller_OldServer2(t *testing.T) {
 			rw.Write(statusJSON)
 		} else if gdmRE.MatchString(url) {
 			rw.Write(gdmJSON)
+		} else if r.URL.Path == "/events" {
+			rw.WriteHeader(404)
 		} else {
 			t.Errorf("Bad request: %#v", r)
 			rw.WriteHeader(500)
@@ -642,6 +805,8 @@ func TestStatusPoller_MesosFailed(t *testing.T) {
 			}
 		} else if gdmRE.MatchString(url) {
 			rw.Write(gdmJSON)
+		} else if r.URL.Path == "/events" {
+			rw.WriteHeader(404)
 		} else {
 			t.Errorf("Bad request: %#v", r)
 			rw.WriteHeader(500)
@@ -780,6 +945,8 @@ func TestStatusPoller_NotIntended(t *testing.T) {
 			rw.Write(statusJSON)
 		} else if gdmRE.MatchString(url) {
 			rw.Write(gdmJSON)
+		} else if r.URL.Path == "/events" {
+			rw.WriteHeader(404)
 		} else {
 			t.Errorf("Bad request: %#v", r)
 			rw.WriteHeader(500)
@@ -861,6 +1028,8 @@ func TestStatusPoller_OldServer(t *testing.T) {
 		} else if statusRE.MatchString(url) {
 			rw.WriteHeader(404)
 			rw.Write([]byte{})
+		} else if r.URL.Path == "/events" {
+			rw.WriteHeader(404)
 		} else {
 			t.Errorf("Bad request: %#v", r)
 			rw.WriteHeader(500)
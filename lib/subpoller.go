@@ -1,8 +1,13 @@
 package sous
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/opentable/sous/util/logging"
@@ -13,6 +18,8 @@ import (
 type (
 	subPoller struct {
 		restful.HTTPClient
+		// http makes the requests HTTPClient does not, i.e. for /events.
+		http                     *http.Client
 		ClusterName, URL         string
 		locationFilter, idFilter *ResolveFilter
 		User                     User
@@ -39,6 +46,7 @@ func newSubPoller(clusterName, serverURL string, baseFilter *ResolveFilter, user
 		ClusterName:    clusterName,
 		URL:            serverURL,
 		HTTPClient:     cl,
+		http:           &cl.Client,
 		locationFilter: &loc,
 		idFilter:       &id,
 		User:           user,
@@ -46,10 +54,14 @@ func newSubPoller(clusterName, serverURL string, baseFilter *ResolveFilter, user
 	}, nil
 }
 
-// start issues a new /status request every half second, reporting the state as computed.
-// c.f. pollOnce.
+// start reports the state as computed, following the server's /events stream
+// if it has one, and otherwise issuing a new /status request every half
+// second. c.f. pollOnce.
 func (sub *subPoller) start(rs chan pollResult, done chan struct{}) {
 	rs <- pollResult{url: sub.URL, stat: ResolveNotPolled}
+	if sub.follow(rs, done) {
+		return
+	}
 	pollResult := sub.pollOnce()
 	rs <- pollResult
 	ticker := time.NewTicker(PollTimeout)
@@ -65,6 +77,119 @@ func (sub *subPoller) start(rs chan pollResult, done chan struct{}) {
 	}
 }
 
+// follow reports the state as computed from each event on the server's
+// /events stream. It returns true once done is closed, or false if the stream
+// is not available or breaks, in which case the caller should poll instead.
+func (sub *subPoller) follow(rs chan pollResult, done chan struct{}) bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	req, err := http.NewRequest("GET", strings.TrimSuffix(sub.URL, "/")+"/events", nil)
+	if err != nil {
+		return false
+	}
+	req = req.WithContext(ctx)
+	for k, v := range sub.User.HTTPHeaders() {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	res, err := sub.http.Do(req)
+	if err != nil {
+		reportDebugSubPollerMessage(fmt.Sprintf("%s: no event stream, polling: %s", sub.ClusterName, err), sub.logs)
+		return false
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/event-stream") {
+		reportDebugSubPollerMessage(fmt.Sprintf("%s: no event stream (%s), polling", sub.ClusterName, res.Status), sub.logs)
+		return false
+	}
+
+	data := &statusData{}
+	err = readEvents(res.Body, func(ev ResolveEvent) bool {
+		if !data.apply(ev) {
+			return true
+		}
+		select {
+		case rs <- sub.stateOf(data):
+			return true
+		case <-done:
+			return false
+		}
+	})
+	select {
+	case <-done:
+		return true
+	default:
+	}
+	reportDebugSubPollerMessage(fmt.Sprintf("%s: event stream ended, polling: %v", sub.ClusterName, err), sub.logs)
+	return false
+}
+
+// readEvents calls f with each event read from an /events stream, until the
+// stream ends or f returns false.
+func readEvents(r io.Reader, f func(ResolveEvent) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var data []byte
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) == 0 {
+				continue
+			}
+			ev := ResolveEvent{}
+			if err := json.Unmarshal(data, &ev); err != nil {
+				return errors.Wrapf(err, "decoding event")
+			}
+			data = nil
+			if !f(ev) {
+				return nil
+			}
+		case strings.HasPrefix(line, "data:"):
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+// apply updates data with ev, as though /status had been requested again. It
+// returns false if ev does not change the status.
+func (data *statusData) apply(ev ResolveEvent) bool {
+	switch ev.Kind {
+	default:
+		return false
+	case StatusEvent:
+		data.Completed, data.InProgress = ev.Completed, ev.InProgress
+		data.Deployments = ev.Deployments
+	case ResolveStartedEvent:
+		data.InProgress = ev.Status
+	case DiffResolvedEvent:
+		if data.InProgress == nil || ev.Resolution == nil {
+			return false
+		}
+		data.InProgress.Log = append(data.InProgress.Log, *ev.Resolution)
+	case ResolveCompletedEvent:
+		// The server goes on reporting a finished cycle as in progress until
+		// the next one starts.
+		data.Completed, data.InProgress = ev.Status, ev.Status
+	}
+	return true
+}
+
 func (sub *subPoller) result(rs ResolveState, data *statusData, err error) pollResult {
 	resolveID := "<none in progress>"
 	if data.InProgress != nil {
@@ -90,6 +215,12 @@ func (sub *subPoller) pollOnce() pollResult {
 	}
 	sub.httpErrorCount = 0
 
+	return sub.stateOf(data)
+}
+
+// stateOf computes the state of resolution reported by data, whether it was
+// read from /status or built from /events.
+func (sub *subPoller) stateOf(data *statusData) pollResult {
 	// This serves to maintain backwards compatibility.
 	// XXX One day, remove it.
 	if data.Completed != nil && len(data.Completed.Intended) == 0 {
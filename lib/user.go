@@ -8,6 +8,10 @@ type User struct {
 	Name string `env:"SOUS_USER_NAME"`
 	// Email is the email address of this user.
 	Email string `env:"SOUS_USER_EMAIL"`
+	// Token is a signed token identifying this user to servers which do not
+	// trust the name and email headers. It is never sent to servers other
+	// than as a header.
+	Token string `env:"SOUS_USER_TOKEN" json:"-" yaml:",omitempty"`
 }
 
 // String returns the name and email in standard email address format, i.e.:
@@ -32,10 +36,28 @@ func (u User) Complete() bool {
 	return u.Name != "" && u.Email != ""
 }
 
+// ListedIn returns true if any of names is this user's email (ignoring case)
+// or name.
+func (u User) ListedIn(names []string) bool {
+	for _, n := range names {
+		if (u.Email != "" && strings.EqualFold(n, u.Email)) || (u.Name != "" && n == u.Name) {
+			return true
+		}
+	}
+	return false
+}
+
 // HTTPHeaders returns a map suitable to use as HTTP headers to be consumed by the server.
 func (u User) HTTPHeaders() map[string]string {
-	return map[string]string{
+	headers := map[string]string{
 		"Sous-User-Name":  u.Name,
 		"Sous-User-Email": u.Email,
 	}
+	if u.Token != "" {
+		headers["Authorization"] = UserTokenScheme + " " + u.Token
+	}
+	return headers
 }
+
+// UserTokenScheme is the HTTP authorization scheme used to send User.Token.
+const UserTokenScheme = "Sous-Token"
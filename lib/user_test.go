@@ -22,3 +22,18 @@ func TestUser_Complete(t *testing.T) {
 	assert.False(t, User{Email: "y"}.Complete())
 	assert.True(t, User{Name: "x", Email: "y"}.Complete())
 }
+
+func TestUser_ListedIn(t *testing.T) {
+	u := User{Name: "Judson", Email: "jlester@opentable.com"}
+	assert.True(t, u.ListedIn([]string{"someone", "JLester@OpenTable.com"}))
+	assert.True(t, u.ListedIn([]string{"Judson"}))
+	assert.False(t, u.ListedIn([]string{"judson", "other@opentable.com"}))
+	assert.False(t, User{}.ListedIn([]string{""}))
+}
+
+func TestUser_HTTPHeaders(t *testing.T) {
+	u := User{Name: "x", Email: "y"}
+	assert.NotContains(t, u.HTTPHeaders(), "Authorization")
+	u.Token = "abc"
+	assert.Equal(t, "Sous-Token abc", u.HTTPHeaders()["Authorization"])
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+// holdForApproval divides the changes from current to intended deployments
+// into those which may be made now, and those to clusters which require
+// approval. The latter are stored as a pending change. It returns the
+// deployments to write now, and a description of the pending change, if any.
+func holdForApproval(ls logging.LogSink, store sous.PendingChangeStore, rm *restful.RouteMap, user ClientUser, clusters sous.Clusters, current, intended sous.Deployments) (sous.Deployments, *PendingChangeBody, error) {
+	allowed, proposed := sous.SplitProtected(current, intended, clusters)
+	if len(proposed) == 0 {
+		return intended, nil, nil
+	}
+	if store == nil {
+		return sous.NewDeployments(), nil, errors.New("changes to these clusters require approval, but this server cannot store pending changes")
+	}
+
+	pc := sous.NewPendingChange(sous.User(user), proposed, time.Now())
+	if err := store.ProposeChange(pc); err != nil {
+		return sous.NewDeployments(), nil, errors.Wrapf(err, "storing pending change")
+	}
+	messages.ReportLogFieldsMessage(
+		fmt.Sprintf("Change %s by %s is awaiting approval", pc.ID, pc.Author),
+		logging.InformationLevel, ls)
+
+	body := &PendingChangeBody{PendingChange: pc, Changes: pc.Changes(current)}
+	loc, err := rm.URIFor("pending-change", nil, restful.KV{"id", string(pc.ID)})
+	if err != nil {
+		return sous.NewDeployments(), nil, err
+	}
+	body.location = loc
+	return allowed, body, nil
+}
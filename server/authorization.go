@@ -0,0 +1,139 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opentable/sous/config"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+)
+
+type (
+	// An Authorizer decides whether users may make writes. Authorize returns
+	// an *AuthorizationError if user may not make w.
+	Authorizer interface {
+		Authorize(user ClientUser, w Write) error
+	}
+
+	// A Write describes a PUT or DELETE exchange to be authorized.
+	Write struct {
+		// Method and Resource are the HTTP method and the name of the
+		// resource in the route map, e.g. "PUT" and "gdm".
+		Method, Resource string
+		// State is the current state, before the write.
+		State *sous.State
+		// Deployments lists the deployments the write changes.
+		Deployments []sous.DeploymentID
+		// Manifests lists manifests the write changes other than through
+		// their deployments.
+		Manifests []sous.ManifestID
+		// Global is true if the write changes something which belongs to no
+		// manifest, e.g. the list of servers.
+		Global bool
+		// Sibling is true if the write is signed by a sibling server, with
+		// the key the servers share.
+		Sibling bool
+	}
+
+	// An AuthorizationError explains why a write was denied.
+	AuthorizationError struct {
+		User   ClientUser
+		Write  Write
+		Reason string
+	}
+
+	// ACLAuthorizer authorizes writes by the owners of the manifests changed,
+	// and the Writers of the clusters changed. Admins may make any write, and
+	// only admins may make global writes, except that sibling servers may
+	// gossip.
+	ACLAuthorizer struct {
+		Admins []string
+	}
+)
+
+// NewAuthorizer returns the Authorizer described by cfg, or nil if writes are
+// not authorized.
+func NewAuthorizer(cfg config.AuthorizationConfig) Authorizer {
+	if !cfg.Enforce {
+		return nil
+	}
+	return ACLAuthorizer{Admins: cfg.Admins}
+}
+
+func (err *AuthorizationError) Error() string {
+	user := sous.User(err.User).String()
+	if user == "" {
+		user = "anonymous user"
+	}
+	return fmt.Sprintf("%s may not %s %s: %s", user, err.Write.Method, err.Write.Resource, err.Reason)
+}
+
+// Authorize implements Authorizer on ACLAuthorizer.
+func (a ACLAuthorizer) Authorize(user ClientUser, w Write) error {
+	deny := func(format string, args ...interface{}) error {
+		return &AuthorizationError{User: user, Write: w, Reason: fmt.Sprintf(format, args...)}
+	}
+	if w.Sibling && w.Method == "PUT" && w.Resource == "gossip" {
+		return nil
+	}
+	u := sous.User(user)
+	if u.Name == "" && u.Email == "" {
+		return deny("the request does not identify a user")
+	}
+	if u.ListedIn(a.Admins) {
+		return nil
+	}
+	if w.Global {
+		return deny("only admins may")
+	}
+
+	state := w.State
+	if state == nil {
+		state = sous.NewState()
+	}
+	mids := append([]sous.ManifestID(nil), w.Manifests...)
+	for _, did := range w.Deployments {
+		c, ok := state.Defs.Clusters[did.Cluster]
+		if ok && len(c.Writers) > 0 && !u.ListedIn(c.Writers) {
+			return deny("not a writer for cluster %s (writers: %s)", did.Cluster, strings.Join(c.Writers, ", "))
+		}
+		mids = append(mids, did.ManifestID)
+	}
+	for _, mid := range mids {
+		m, ok := state.Manifests.Get(mid)
+		if !ok || len(m.Owners) == 0 {
+			continue
+		}
+		if !u.ListedIn(m.Owners) {
+			return deny("not an owner of %s (owners: %s)", mid, strings.Join(m.Owners, ", "))
+		}
+	}
+	return nil
+}
+
+// authorize returns an error if a is not nil and denies user the write. Denials
+// are logged.
+func authorize(ls logging.LogSink, a Authorizer, user ClientUser, w Write) error {
+	if a == nil {
+		return nil
+	}
+	err := a.Authorize(user, w)
+	if err != nil {
+		messages.ReportLogFieldsMessage(fmt.Sprintf("Denied: %s", err), logging.WarningLevel, ls)
+	}
+	return err
+}
+
+// changedDeployments returns the IDs of the deployments which differ between
+// current and intended.
+func changedDeployments(current, intended sous.Deployments) []sous.DeploymentID {
+	var dids []sous.DeploymentID
+	for _, pair := range current.Diff(intended).Collect() {
+		if pair.Kind() != sous.SameKind {
+			dids = append(dids, pair.ID())
+		}
+	}
+	return dids
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func authorizationFixture(t *testing.T) (*sous.State, *sous.Deployment) {
+	dep := sous.DeploymentFixture("")
+	dep.Owners = sous.NewOwnerSet("owner@example.com")
+	state := sous.NewState()
+	state.Defs.Clusters = sous.Clusters{
+		dep.ClusterName: {Name: dep.ClusterName},
+		"locked":        {Name: "locked", Writers: []string{"writer@example.com", "owner@example.com"}},
+	}
+	ms, err := sous.NewDeployments(dep).RawManifests(state.Defs)
+	require.NoError(t, err)
+	state.Manifests = ms
+	return state, dep
+}
+
+func TestNewAuthorizer(t *testing.T) {
+	assert.Nil(t, NewAuthorizer(config.AuthorizationConfig{}))
+	a := NewAuthorizer(config.AuthorizationConfig{Enforce: true, Admins: []string{"admin@example.com"}})
+	assert.Equal(t, ACLAuthorizer{Admins: []string{"admin@example.com"}}, a)
+}
+
+func TestACLAuthorizer(t *testing.T) {
+	state, dep := authorizationFixture(t)
+	locked := dep.Clone()
+	locked.ClusterName = "locked"
+	newManifest := dep.Clone()
+	newManifest.Flavor = "new"
+
+	a := ACLAuthorizer{Admins: []string{"admin@example.com"}}
+	write := func(dids ...sous.DeploymentID) Write {
+		return Write{Method: "PUT", Resource: "gdm", State: state, Deployments: dids}
+	}
+
+	testCases := []struct {
+		desc    string
+		user    ClientUser
+		write   Write
+		allowed bool
+		reason  string
+	}{
+		{"anonymous", ClientUser{}, write(), false, "does not identify a user"},
+		{"owner", ClientUser{Email: "owner@example.com"}, write(dep.ID()), true, ""},
+		{"not owner", ClientUser{Email: "writer@example.com"}, write(dep.ID()), false, "not an owner of"},
+		{"admin", ClientUser{Email: "ADMIN@example.com"}, write(dep.ID(), locked.ID()), true, ""},
+		{"cluster writer", ClientUser{Email: "owner@example.com"}, write(locked.ID()), true, ""},
+		{"new manifest", ClientUser{Email: "writer@example.com"}, write(newManifest.ID()), true, ""},
+		{"not cluster writer", ClientUser{Email: "someone@example.com"}, write(newManifest.ID(), locked.ID()), false, "not a writer for cluster locked"},
+		{"manifest", ClientUser{Email: "writer@example.com"}, Write{State: state, Manifests: []sous.ManifestID{dep.ManifestID()}}, false, "not an owner of"},
+		{"global", ClientUser{Email: "owner@example.com"}, Write{Global: true}, false, "only admins"},
+		{"global admin", ClientUser{Name: "Admin", Email: "admin@example.com"}, Write{Global: true}, true, ""},
+		{"sibling gossip", ClientUser{}, Write{Method: "PUT", Resource: "gossip", Global: true, Sibling: true}, true, ""},
+		{"unsigned gossip", ClientUser{}, Write{Method: "PUT", Resource: "gossip", Global: true}, false, "does not identify a user"},
+		{"sibling other write", ClientUser{}, Write{Method: "PUT", Resource: "servers", Global: true, Sibling: true}, false, "does not identify a user"},
+		{"untargeted", ClientUser{Email: "someone@example.com"}, Write{}, true, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := a.Authorize(tc.user, tc.write)
+			if tc.allowed {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.IsType(t, &AuthorizationError{}, err)
+			assert.Contains(t, err.Error(), tc.reason)
+		})
+	}
+}
+
+func TestHandlesGDMPutUnauthorized(t *testing.T) {
+	put := func(t *testing.T, user ClientUser) (*sous.DummyStateManager, interface{}, int) {
+		t.Helper()
+		state, dep := authorizationFixture(t)
+		sm := &sous.DummyStateManager{State: state}
+		changed := dep.Clone()
+		changed.NumInstances++
+		bs, err := json.Marshal(GDMWrapper{Deployments: []*sous.Deployment{changed}})
+		require.NoError(t, err)
+
+		ls, _ := logging.NewLogSinkSpy()
+		h := &PUTGDMHandler{
+			Request:      httptest.NewRequest("PUT", "http://sous.example.com/gdm", bytes.NewBuffer(bs)),
+			LogSink:      ls,
+			StateManager: sm,
+			Authorizer:   ACLAuthorizer{},
+			User:         user,
+		}
+		body, status := h.Exchange()
+		return sm, body, status
+	}
+
+	t.Run("denied", func(t *testing.T) {
+		sm, body, status := put(t, ClientUser{Name: "Someone", Email: "someone@example.com"})
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Contains(t, body, "Someone <someone@example.com> may not PUT gdm: not an owner of")
+		assert.Equal(t, 0, sm.WriteCount)
+	})
+
+	t.Run("owner", func(t *testing.T) {
+		sm, _, status := put(t, ClientUser{Name: "Owner", Email: "owner@example.com"})
+		assert.Equal(t, http.StatusNoContent, status)
+		assert.Equal(t, 1, sm.WriteCount)
+	})
+}
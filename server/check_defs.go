@@ -0,0 +1,18 @@
+package server
+
+import (
+	sous "github.com/opentable/sous/lib"
+)
+
+// checkDefs returns a Flaw for each way intended breaks defs: by referring to
+// secrets defs does not declare for it, by setting env vars contrary to their
+// definitions, or by breaking a lint rule of severity LintError.
+func checkDefs(defs sous.Defs, intended sous.Deployments) []sous.Flaw {
+	var flaws []sous.Flaw
+	for _, d := range intended.Snapshot() {
+		flaws = append(flaws, defs.Secrets.Check(d)...)
+		flaws = append(flaws, defs.EnvVars.Check(d)...)
+		flaws = append(flaws, defs.Lint.Errors(d)...)
+	}
+	return flaws
+}
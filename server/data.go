@@ -4,8 +4,10 @@ import (
 	"crypto/sha512"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"sort"
+	"time"
 
 	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/restful"
@@ -27,6 +29,14 @@ type (
 	// ServerListData is the DTO for lists of servers
 	ServerListData struct { // not actually a stutter - "server" means two different things.
 		Servers []NameData
+		// Leaders maps the clusters with an elected leader to the URL of
+		// the server leading each.
+		Leaders map[string]string `json:",omitempty"`
+	}
+
+	// GossipData lists the live Sous servers known to a server.
+	GossipData struct {
+		Members []sous.Member
 	}
 
 	// GDMWrapper is the DTO wrapper for sous.Deployments
@@ -51,6 +61,21 @@ type (
 	QueueDesc struct {
 		sous.DeploymentID
 		Length int
+		// Items describes each rectification in the queue, in the order they
+		// will be handled, starting with any being worked on.
+		Items []QueueItemDesc
+	}
+
+	// QueueItemDesc describes a single rectification in a deployment's queue.
+	QueueItemDesc struct {
+		ID sous.R11nID
+		// Pos is the position of the rectification in the queue; it is -1
+		// once the rectification has started.
+		Pos      int
+		Priority sous.R11nPriority
+		QueuedAt time.Time
+		// RolloutStage is the current stage of a staged rollout, or nil.
+		RolloutStage *sous.RolloutStage `json:",omitempty"`
 	}
 
 	// SingleDeploymentBody is the response struct returned from handlers
@@ -59,6 +84,68 @@ type (
 		Meta       ResponseMeta
 		Deployment sous.Deployment
 	}
+
+	// RestoreBody describes a restoration of the GDM to an earlier point in
+	// time.
+	RestoreBody struct {
+		// Revision identifies the earlier state being restored.
+		Revision string
+		// Base is the etag of the current state the restoration applies to.
+		Base string
+		// Changes lists the changes the restoration makes to deployments.
+		Changes []sous.DeploymentChange
+	}
+
+	// DriftBody reports how the deployments running on the clusters differ
+	// from the GDM.
+	DriftBody struct {
+		// Clusters are the names of the clusters compared with the GDM.
+		Clusters []string
+		// Changes lists the changes rectifying would make, ordered by
+		// deployment.
+		Changes []sous.DeploymentChange
+	}
+
+	// StateReconciliationBody reports how the copies of the GDM kept by a
+	// server's StateManager differ.
+	StateReconciliationBody struct {
+		// Defs lists the differences between the copies' Defs.
+		Defs sous.Differences
+		// Changes lists how the deployments of the second copy differ from
+		// the first, ordered by deployment.
+		Changes []sous.DeploymentChange
+	}
+
+	// PauseBody describes the pause of a single deployment.
+	PauseBody struct {
+		// Pause is the deployment's pause, or nil if it is not paused. When
+		// pausing a deployment, only Reason and Until are read: the server
+		// records who paused it, and when.
+		Pause *sous.Pause
+	}
+
+	// LintBody reports the breaches of the GDM's lint rules.
+	LintBody struct {
+		// Rules lists the problems with the rules themselves.
+		Rules []string
+		// Flaws lists every breach of the rules, ordered by deployment.
+		Flaws []sous.LintFlaw
+	}
+
+	// PendingChangeBody describes a change awaiting approval.
+	PendingChangeBody struct {
+		sous.PendingChange
+		// Changes describes the proposed changes relative to the current GDM.
+		Changes []sous.DeploymentChange
+		// location is the URI of the pending change, sent as the Location
+		// header when the change is first proposed.
+		location string
+	}
+
+	// PendingChangesBody lists changes awaiting approval.
+	PendingChangesBody struct {
+		Changes []sous.PendingChange
+	}
 )
 
 // EmptyReceiver implements Comparable on ServerListData
@@ -171,3 +258,85 @@ func (b *SingleDeploymentBody) etag() string {
 	hash.Write(ds)
 	return "w/" + base64.URLEncoding.EncodeToString(hash.Sum(nil))
 }
+
+// EmptyReceiver implements Comparable on RestoreBody
+func (b *RestoreBody) EmptyReceiver() restful.Comparable {
+	return &RestoreBody{}
+}
+
+// VariancesFrom implements Comparable on RestoreBody
+func (b *RestoreBody) VariancesFrom(other restful.Comparable) restful.Variances {
+	switch ob := other.(type) {
+	default:
+		return restful.Variances{"Not a RestoreBody"}
+	case *RestoreBody:
+		vs := restful.Variances{}
+		if b.Revision != ob.Revision {
+			vs = append(vs, fmt.Sprintf("Revision: %q != %q", b.Revision, ob.Revision))
+		}
+		if b.Base != ob.Base {
+			vs = append(vs, fmt.Sprintf("Base: %q != %q", b.Base, ob.Base))
+		}
+		if len(b.Changes) != len(ob.Changes) {
+			vs = append(vs, fmt.Sprintf("Changes: %d != %d", len(b.Changes), len(ob.Changes)))
+		}
+		return vs
+	}
+}
+
+// EmptyReceiver implements Comparable on PauseBody
+func (b *PauseBody) EmptyReceiver() restful.Comparable {
+	return &PauseBody{}
+}
+
+// VariancesFrom implements Comparable on PauseBody
+func (b *PauseBody) VariancesFrom(other restful.Comparable) restful.Variances {
+	switch ob := other.(type) {
+	default:
+		return restful.Variances{"Not a PauseBody"}
+	case *PauseBody:
+		vs := restful.Variances{}
+		if (b.Pause == nil) != (ob.Pause == nil) {
+			return append(vs, "Pause: only one is nil")
+		}
+		if b.Pause == nil {
+			return vs
+		}
+		if b.Pause.Reason != ob.Pause.Reason {
+			vs = append(vs, fmt.Sprintf("Reason: %q != %q", b.Pause.Reason, ob.Pause.Reason))
+		}
+		if !b.Pause.Until.Equal(ob.Pause.Until) {
+			vs = append(vs, fmt.Sprintf("Until: %s != %s", b.Pause.Until, ob.Pause.Until))
+		}
+		return vs
+	}
+}
+
+// EmptyReceiver implements Comparable on PendingChangeBody
+func (b *PendingChangeBody) EmptyReceiver() restful.Comparable {
+	return &PendingChangeBody{}
+}
+
+// VariancesFrom implements Comparable on PendingChangeBody
+func (b *PendingChangeBody) VariancesFrom(other restful.Comparable) restful.Variances {
+	switch ob := other.(type) {
+	default:
+		return restful.Variances{"Not a PendingChangeBody"}
+	case *PendingChangeBody:
+		vs := restful.Variances{}
+		if b.ID != ob.ID {
+			vs = append(vs, fmt.Sprintf("ID: %q != %q", b.ID, ob.ID))
+		}
+		if b.Status != ob.Status {
+			vs = append(vs, fmt.Sprintf("Status: %q != %q", b.Status, ob.Status))
+		}
+		return vs
+	}
+}
+
+// AddHeaders implements restful.HeaderAdder on PendingChangeBody.
+func (b *PendingChangeBody) AddHeaders(h http.Header) {
+	if b.location != "" {
+		h.Set("Location", b.location)
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+// eventStream serves /events: a stream of sous.ResolveEvents, as Server-Sent
+// Events. Each event's type is its Kind, and its data is the event as JSON.
+// The first event is a sous.StatusEvent with the same statuses and deployments
+// as /status.
+type eventStream struct {
+	events       *sous.ResolveEvents
+	autoResolver *sous.AutoResolver
+	filter       *sous.ResolveFilter
+	log          logging.LogSink
+}
+
+// EventStreamKeepalive is how often a comment is sent on an idle event
+// stream, so that proxies do not close it.
+const EventStreamKeepalive = 15 * time.Second
+
+func newEventStream(ctx ComponentLocator) *eventStream {
+	return &eventStream{
+		events:       ctx.Events,
+		autoResolver: ctx.AutoResolver,
+		filter:       ctx.ResolveFilter,
+		log:          ctx.LogSink,
+	}
+}
+
+func (es *eventStream) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.Error(w, "only GET is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if es.events == nil {
+		http.Error(w, "events are not published by this server", http.StatusNotFound)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before taking the snapshot, so no event is missed.
+	events, unsubscribe := es.events.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	snapshot := sous.ResolveEvent{Kind: sous.StatusEvent, At: time.Now()}
+	if es.autoResolver != nil {
+		snapshot.Completed, snapshot.InProgress = es.autoResolver.Statuses()
+		for _, d := range es.autoResolver.GDM.Filter(es.filter.FilterDeployment).Snapshot() {
+			snapshot.Deployments = append(snapshot.Deployments, d)
+		}
+	}
+	if err := writeEvent(w, snapshot); err != nil {
+		logging.ReportError(es.log, err)
+		return
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(EventStreamKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case ev, open := <-events:
+			if !open {
+				// We fell behind: the client must reconnect.
+				return
+			}
+			if err := writeEvent(w, ev); err != nil {
+				logging.ReportError(es.log, err)
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, ev sous.ResolveEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return errors.Wrapf(err, "encoding %s event", ev.Kind)
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Kind, data)
+	return err
+}
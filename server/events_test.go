@@ -0,0 +1,83 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+)
+
+func TestEventStream(t *testing.T) {
+	events := sous.NewResolveEvents()
+	srv := httptest.NewServer(newEventStream(ComponentLocator{
+		LogSink: logging.SilentLogSet(),
+		Events:  events,
+	}))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("got Content-Type %q; want text/event-stream", ct)
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	next := func(kind sous.ResolveEventKind) sous.ResolveEvent {
+		t.Helper()
+		var ev sous.ResolveEvent
+		for _, prefix := range []string{"event: ", "data: ", ""} {
+			select {
+			case line := <-lines:
+				if !strings.HasPrefix(line, prefix) || (prefix == "" && line != "") {
+					t.Fatalf("got line %q; want prefix %q", line, prefix)
+				}
+				if prefix == "event: " && line != "event: "+string(kind) {
+					t.Fatalf("got %q; want %s event", line, kind)
+				}
+				if prefix == "data: " {
+					if err := json.Unmarshal([]byte(strings.TrimPrefix(line, prefix)), &ev); err != nil {
+						t.Fatal(err)
+					}
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("timed out waiting for %s event", kind)
+			}
+		}
+		return ev
+	}
+
+	next(sous.StatusEvent)
+
+	events.Publish(sous.ResolveEvent{
+		Kind:   sous.ResolveStartedEvent,
+		Status: &sous.ResolveStatus{Phase: "starting"},
+	})
+	ev := next(sous.ResolveStartedEvent)
+	if ev.Status == nil || ev.Status.Phase != "starting" {
+		t.Errorf("got status %+v; want phase starting", ev.Status)
+	}
+}
+
+func TestEventStreamDisabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newEventStream(ComponentLocator{}).ServeHTTP(rec, httptest.NewRequest("GET", "/events", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d; want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -34,4 +34,13 @@ func TestResourcesFulfillInterfaces(t *testing.T) {
 	assert.Implements(t, (*restful.Getable)(nil), newDeployQueueResource(ComponentLocator{}))
 
 	assert.Implements(t, (*restful.Getable)(nil), newR11nResource(ComponentLocator{}))
+
+	assert.Implements(t, (*restful.Getable)(nil), newHistoryResource(ComponentLocator{}))
+
+	assert.Implements(t, (*restful.Getable)(nil), newRestoreResource(ComponentLocator{}))
+	assert.Implements(t, (*restful.Putable)(nil), newRestoreResource(ComponentLocator{}))
+
+	assert.Implements(t, (*restful.Getable)(nil), newPendingChangesResource(ComponentLocator{}))
+	assert.Implements(t, (*restful.Getable)(nil), newPendingChangeResource(ComponentLocator{}))
+	assert.Implements(t, (*restful.Putable)(nil), newPendingChangeResource(ComponentLocator{}))
 }
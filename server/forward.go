@@ -0,0 +1,150 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+)
+
+const (
+	// forwardedByHeader names the server which forwarded a request to its
+	// leader, so that the leader does not forward it again while leases
+	// change hands.
+	forwardedByHeader = "Sous-Forwarded-By"
+	// forwardedSignatureHeader authenticates forwardedByHeader. It holds the
+	// time the request was forwarded, in seconds since the epoch, and the
+	// signature of the forwarding with the key the servers share, separated
+	// by a dot.
+	forwardedSignatureHeader = "Sous-Forwarded-Signature"
+)
+
+// forwardClient forwards requests to leaders. It does not wait on a leader
+// for longer than a sibling server should take.
+var forwardClient = &http.Client{Timeout: sous.SiblingTimeout}
+
+// leaderFor returns the URL of the server leading cluster, if it is not this
+// one and req may be forwarded to it. Requests forwarded already by a sibling
+// server are not forwarded again; the forwarding is only believed if it is
+// signed with l.Key, so servers which share no key may forward a request more
+// than once while leases change hands.
+func leaderFor(l *sous.Leadership, req *http.Request, cluster string) (string, bool) {
+	if l == nil || forwardedBySibling(l.Key, req, time.Now()) {
+		return "", false
+	}
+	leader, ok := l.Leader(cluster)
+	if !ok || leader == l.Holder {
+		return "", false
+	}
+	return leader, true
+}
+
+// forwardedBySibling returns true if req was forwarded by a sibling server
+// which signed the forwarding with key, no more than sous.SiblingTimeout from
+// now.
+func forwardedBySibling(key []byte, req *http.Request, now time.Time) bool {
+	by := req.Header.Get(forwardedByHeader)
+	parts := strings.SplitN(req.Header.Get(forwardedSignatureHeader), ".", 2)
+	if by == "" || len(parts) != 2 {
+		return false
+	}
+	at, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(at, 0)); age < -sous.SiblingTimeout || age > sous.SiblingTimeout {
+		return false
+	}
+	return sous.VerifySiblingSignature(key, parts[1], by, parts[0], req.Method, req.URL.RequestURI())
+}
+
+// forwardToLeader passes req on to the server at leader, and relays its
+// response. The links in a successful response are made absolute, so that
+// the client follows them to the leader. The client's headers are passed on,
+// so the leader identifies the client by them; identities established by
+// client certificates do not survive forwarding. The forwarding is signed
+// with l.Key, if it is set, so that the leader does not forward it again.
+func forwardToLeader(ls logging.LogSink, rw http.ResponseWriter, req *http.Request, l *sous.Leadership, leader string) (interface{}, int) {
+	base, err := url.Parse(leader)
+	if err != nil {
+		return fmt.Sprintf("Invalid leader URL %q: %s.", leader, err), http.StatusBadGateway
+	}
+	target := base.ResolveReference(&url.URL{Path: req.URL.Path, RawQuery: req.URL.RawQuery})
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return fmt.Sprintf("Error reading body: %s.", err), http.StatusBadRequest
+	}
+	fwd, err := http.NewRequest(req.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Sprintf("Error forwarding to leader %s: %s.", leader, err), http.StatusBadGateway
+	}
+	for name, values := range req.Header {
+		fwd.Header[name] = values
+	}
+	fwd.Header.Set(forwardedByHeader, l.Holder)
+	fwd.Header.Del(forwardedSignatureHeader)
+	if len(l.Key) > 0 {
+		at := strconv.FormatInt(time.Now().Unix(), 10)
+		sig := sous.SiblingSignature(l.Key, l.Holder, at, req.Method, target.RequestURI())
+		fwd.Header.Set(forwardedSignatureHeader, at+"."+sig)
+	}
+
+	messages.ReportLogFieldsMessage(fmt.Sprintf("Forwarding %s %s to leader %s", req.Method, req.URL, leader),
+		logging.DebugLevel, ls, leader)
+
+	rz, err := forwardClient.Do(fwd)
+	if err != nil {
+		return fmt.Sprintf("Error forwarding to leader %s: %s.", leader, err), http.StatusBadGateway
+	}
+	defer rz.Body.Close()
+	rzBody, err := ioutil.ReadAll(rz.Body)
+	if err != nil {
+		return fmt.Sprintf("Error reading response from leader %s: %s.", leader, err), http.StatusBadGateway
+	}
+
+	if loc := rz.Header.Get("Location"); loc != "" {
+		rw.Header().Set("Location", absoluteLink(base, loc))
+	}
+	if rz.StatusCode >= 300 {
+		return string(bytes.TrimSpace(rzBody)), rz.StatusCode
+	}
+	if len(rzBody) == 0 {
+		return nil, rz.StatusCode
+	}
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(rzBody, &data); err != nil {
+		return fmt.Sprintf("Error parsing response from leader %s: %s.", leader, err), http.StatusBadGateway
+	}
+	// The leader's etag canary is replaced when the response is relayed.
+	delete(data, rz.Header.Get("Etag"))
+	if meta, ok := data["Meta"].(map[string]interface{}); ok {
+		if links, ok := meta["Links"].(map[string]interface{}); ok {
+			for name, link := range links {
+				if l, ok := link.(string); ok {
+					links[name] = absoluteLink(base, l)
+				}
+			}
+		}
+	}
+	return data, rz.StatusCode
+}
+
+// absoluteLink resolves link, as returned by the server at base, to an
+// absolute URL.
+func absoluteLink(base *url.URL, link string) string {
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return base.ResolveReference(ref).String()
+}
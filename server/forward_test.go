@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedLeaseStore grants every lease to leaders[cluster], or to whoever asks
+// if the cluster has no leader.
+type fixedLeaseStore map[string]string
+
+func (s fixedLeaseStore) AcquireLease(cluster, holder string, ttl time.Duration) (sous.Lease, error) {
+	if leader, ok := s[cluster]; ok {
+		holder = leader
+	}
+	return sous.Lease{Cluster: cluster, Holder: holder, Expires: time.Now().Add(ttl)}, nil
+}
+
+func (s fixedLeaseStore) ReleaseLease(cluster, holder string) error {
+	return nil
+}
+
+func testLeadership(holder string, leaders map[string]string, clusters ...string) *sous.Leadership {
+	l := sous.NewLeadership(holder, fixedLeaseStore(leaders), logging.SilentLogSet())
+	l.Contend(clusters...)
+	return l
+}
+
+func TestPUTSingleDeploymentHandler_forwardsToLeader(t *testing.T) {
+	var forwarded *http.Request
+	var forwardedBody string
+	leader := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		forwarded = req
+		body, _ := ioutil.ReadAll(req.Body)
+		forwardedBody = string(body)
+		rw.Header().Set("Etag", "leader-etag")
+		rw.Header().Set("Location", "/deploy-queue-item?action=some-id")
+		rw.WriteHeader(201)
+		rw.Write([]byte(`{"leader-etag": "canary", "Meta": {"Links": {"queuedDeployAction": "/deploy-queue-item?action=some-id"}}}`))
+	}))
+	defer leader.Close()
+
+	qs, qsSpy := sous.NewQueueSetSpy()
+	cl := ComponentLocator{
+		LogSink:    logging.SilentLogSet(),
+		QueueSet:   qs,
+		Leadership: testLeadership("http://follower", map[string]string{"cluster1": leader.URL}, "cluster1"),
+	}
+	cl.Leadership.Key = testGossipKey
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/single-deployment?repo=github.com%2Fuser1%2Frepo1&cluster=cluster1", bytes.NewBufferString(`{"Deployment": {}}`))
+	req.Header.Set("Sous-User-Email", "dev@example.com")
+	psd := newSingleDeploymentResource(cl).Put(routemap(cl), rw, req, nil)
+
+	data, status := psd.Exchange()
+	require.Equal(t, 201, status, "%v", data)
+	require.NotNil(t, forwarded)
+	assert.Equal(t, "PUT", forwarded.Method)
+	assert.Equal(t, "/single-deployment", forwarded.URL.Path)
+	assert.Equal(t, "cluster1", forwarded.URL.Query().Get("cluster"))
+	assert.Equal(t, `{"Deployment": {}}`, forwardedBody)
+	assert.Equal(t, "dev@example.com", forwarded.Header.Get("Sous-User-Email"))
+	assert.Equal(t, "http://follower", forwarded.Header.Get(forwardedByHeader))
+	assert.True(t, forwardedBySibling(testGossipKey, forwarded, time.Now()), "the forwarding should be signed")
+
+	assert.Equal(t, leader.URL+"/deploy-queue-item?action=some-id", rw.Header().Get("Location"))
+	body := data.(map[string]interface{})
+	assert.NotContains(t, body, "leader-etag")
+	links := body["Meta"].(map[string]interface{})["Links"].(map[string]interface{})
+	assert.Equal(t, leader.URL+"/deploy-queue-item?action=some-id", links["queuedDeployAction"])
+	assert.Empty(t, qsSpy.CallsTo("Push"), "the follower should not queue the deployment itself")
+}
+
+func TestLeaderFor(t *testing.T) {
+	l := testLeadership("http://one", map[string]string{"cluster2": "http://two"}, "cluster1", "cluster2")
+	req := httptest.NewRequest("PUT", "/single-deployment", nil)
+
+	_, ok := leaderFor(l, req, "cluster1")
+	assert.False(t, ok, "this server leads cluster1")
+	leader, ok := leaderFor(l, req, "cluster2")
+	assert.True(t, ok)
+	assert.Equal(t, "http://two", leader)
+	_, ok = leaderFor(l, req, "cluster3")
+	assert.False(t, ok, "no leader is known for cluster3")
+	_, ok = leaderFor(nil, req, "cluster2")
+	assert.False(t, ok)
+
+	l.Key = testGossipKey
+	sign := func(key []byte, at time.Time) {
+		unix := strconv.FormatInt(at.Unix(), 10)
+		req.Header.Set(forwardedByHeader, "http://three")
+		req.Header.Set(forwardedSignatureHeader, unix+"."+sous.SiblingSignature(key, "http://three", unix, "PUT", "/single-deployment"))
+	}
+	req.Header.Set(forwardedByHeader, "http://three")
+	_, ok = leaderFor(l, req, "cluster2")
+	assert.True(t, ok, "an unsigned forwarding should not be believed")
+	sign([]byte("wrong key"), time.Now())
+	_, ok = leaderFor(l, req, "cluster2")
+	assert.True(t, ok, "a forwarding signed with another key should not be believed")
+	sign(testGossipKey, time.Now().Add(-time.Hour))
+	_, ok = leaderFor(l, req, "cluster2")
+	assert.True(t, ok, "a stale forwarding should not be believed")
+	sign(testGossipKey, time.Now())
+	_, ok = leaderFor(l, req, "cluster2")
+	assert.False(t, ok, "a request forwarded by a sibling should not be forwarded again")
+}
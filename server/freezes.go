@@ -0,0 +1,28 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+)
+
+// guardFreezes rejects changes from current to intended deployments which are
+// frozen, unless req overrides the freeze with the FreezeOverrideHeader. Any
+// override is recorded on the intended deployments and audited.
+func guardFreezes(ls logging.LogSink, req *http.Request, user ClientUser, windows sous.FreezeWindows, current, intended sous.Deployments) error {
+	now := time.Now()
+	var override *sous.FreezeOverride
+	if reason := req.Header.Get(sous.FreezeOverrideHeader); reason != "" {
+		override = &sous.FreezeOverride{User: sous.User(user), Reason: reason, At: now}
+	}
+	overridden, err := windows.Guard(current, intended, override, now)
+	if err != nil {
+		return err
+	}
+	if override != nil {
+		sous.ReportFreezeOverrides(ls, *override, overridden)
+	}
+	return nil
+}
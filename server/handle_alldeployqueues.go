@@ -38,10 +38,24 @@ func (h *GETAllDeployQueuesHandler) Exchange() (interface{}, int) {
 
 	queues := h.QueueSet.Queues()
 	for did, q := range queues {
-		data.Queues[did.String()] = QueueDesc{
+		desc := QueueDesc{
 			DeploymentID: did,
 			Length:       q.Len(),
+			Items:        []QueueItemDesc{},
 		}
+		for _, qr := range q.Snapshot() {
+			item := QueueItemDesc{
+				ID:       qr.ID,
+				Pos:      qr.Pos,
+				Priority: qr.Rectification.Priority,
+				QueuedAt: qr.QueuedAt,
+			}
+			if stage, ok := qr.Rectification.Stage(); ok {
+				item.RolloutStage = &stage
+			}
+			desc.Items = append(desc.Items, item)
+		}
+		data.Queues[did.String()] = desc
 	}
 	return data, 200
 }
@@ -112,6 +112,27 @@ func assertNumQueues(t *testing.T, dr DeploymentQueuesResponse, wantLen int) {
 	}
 }
 
+func TestGETAllDeployQueuesHandler_Exchange_items(t *testing.T) {
+	qs := sous.NewR11nQueueSet()
+	auto, _ := qs.Push(newR11n("one"))
+	manual := newR11n("one")
+	manual.Priority = sous.R11nPriorityManual
+	urgent, _ := qs.Push(manual)
+
+	data, status := (&GETAllDeployQueuesHandler{QueueSet: qs}).Exchange()
+	assertStatusCode200(t, status)
+	items := assertIsDeploymentQueuesResponse(t, data).Queues[newDid("one").String()].Items
+	if len(items) != 2 {
+		t.Fatalf("got %d items; want 2", len(items))
+	}
+	if items[0].ID != urgent.ID || items[0].Pos != 0 || items[0].Priority != sous.R11nPriorityManual {
+		t.Errorf("got first item %+v; want the manual r11n at position 0", items[0])
+	}
+	if items[1].ID != auto.ID || items[1].Pos != 1 || items[1].QueuedAt.IsZero() {
+		t.Errorf("got second item %+v; want the auto r11n at position 1", items[1])
+	}
+}
+
 func assertQueueLength(t *testing.T, dr DeploymentQueuesResponse, did sous.DeploymentID, wantCount int) {
 	t.Helper()
 	gotCount := dr.Queues[did.String()].Length
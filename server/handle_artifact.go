@@ -7,6 +7,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/firsterr"
+	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/restful"
 	"github.com/samsalisbury/semv"
 )
@@ -14,6 +15,7 @@ import (
 type (
 	// ArtifactResource provides the /artifact endpoint
 	ArtifactResource struct {
+		userExtractor
 		restful.QueryParser
 		context ComponentLocator
 	}
@@ -23,11 +25,15 @@ type (
 		*http.Request
 		restful.QueryValues
 		sous.Inserter
+		logging.LogSink
+		State      *sous.State
+		Authorizer Authorizer
+		User       ClientUser
 	}
 )
 
 func newArtifactResource(ctx ComponentLocator) *ArtifactResource {
-	return &ArtifactResource{context: ctx}
+	return &ArtifactResource{userExtractor: newUserExtractor(ctx), context: ctx}
 }
 
 // Put implements Putable on ArtifactResource, which marks it as accepting PUT requests
@@ -36,6 +42,10 @@ func (ar *ArtifactResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req
 		Request:     req,
 		QueryValues: ar.ParseQuery(req),
 		Inserter:    ar.context.Inserter,
+		LogSink:     ar.context.LogSink,
+		State:       ar.context.liveState(),
+		Authorizer:  ar.context.Authorizer,
+		User:        ar.GetUser(req),
 	}
 }
 
@@ -53,6 +63,19 @@ func (pah *PUTArtifactHandler) Exchange() (interface{}, int) {
 		return err, http.StatusNotAcceptable
 	}
 
+	// Artifacts belong to every manifest built from their source.
+	w := Write{Method: "PUT", Resource: "artifact", State: pah.State}
+	if pah.State != nil {
+		for mid := range pah.State.Manifests.Snapshot() {
+			if mid.Source == sid.Location {
+				w.Manifests = append(w.Manifests, mid)
+			}
+		}
+	}
+	if err := authorize(pah.LogSink, pah.Authorizer, pah.User, w); err != nil {
+		return err.Error(), http.StatusForbidden
+	}
+
 	err = pah.Inserter.Insert(sid, ba.Name, "", ba.Qualities)
 	if err != nil {
 		return err, http.StatusNotAcceptable
@@ -52,6 +52,9 @@ func (h *GETDeployQueueHandler) Exchange() (interface{}, int) {
 		queued[i] = queuedDeployment{
 			ID: qr.ID,
 		}
+		if stage, ok := qr.Rectification.Stage(); ok {
+			queued[i].RolloutStage = &stage
+		}
 	}
 	return deployQueueResponse{Queue: queued}, 200
 }
@@ -62,4 +65,6 @@ type deployQueueResponse struct {
 
 type queuedDeployment struct {
 	ID sous.R11nID
+	// RolloutStage is the current stage of a staged rollout, or nil.
+	RolloutStage *sous.RolloutStage `json:",omitempty"`
 }
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+type (
+	// DriftResource defines the /drift endpoint, which reports how the
+	// deployments running on the clusters differ from the GDM.
+	DriftResource struct {
+		context ComponentLocator
+	}
+
+	// GETDriftHandler handles GET requests to /drift.
+	GETDriftHandler struct {
+		Resolver *sous.Resolver
+		State    *sous.State
+		// Cluster, if not empty, limits the report to a single cluster.
+		Cluster string
+		LogSink logging.LogSink
+	}
+)
+
+func newDriftResource(ctx ComponentLocator) *DriftResource {
+	return &DriftResource{context: ctx}
+}
+
+// Get implements restful.Getter on DriftResource.
+func (dr *DriftResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	h := &GETDriftHandler{
+		State:   dr.context.liveState(),
+		Cluster: req.URL.Query().Get("cluster"),
+		LogSink: dr.context.LogSink,
+	}
+	if dr.context.AutoResolver != nil {
+		h.Resolver = dr.context.AutoResolver.Resolver
+	}
+	return h
+}
+
+// Exchange implements restful.Exchanger on GETDriftHandler.
+func (h *GETDriftHandler) Exchange() (interface{}, int) {
+	if h.Resolver == nil {
+		return "Drift is not reported by this server", http.StatusServiceUnavailable
+	}
+	if h.State == nil {
+		return "Error reading state", http.StatusInternalServerError
+	}
+	intended, err := h.State.Deployments()
+	if err != nil {
+		return err.Error(), http.StatusInternalServerError
+	}
+	clusters := h.State.Defs.Clusters
+	if h.Cluster != "" {
+		cluster, ok := clusters[h.Cluster]
+		if !ok {
+			return "No cluster named " + h.Cluster, http.StatusNotFound
+		}
+		clusters = sous.Clusters{h.Cluster: cluster}
+		intended = intended.Filter(func(d *sous.Deployment) bool {
+			return d.ClusterName == h.Cluster
+		})
+	}
+	report, err := h.Resolver.Drift(intended, clusters)
+	if err != nil {
+		logging.ReportError(h.LogSink, errors.Wrapf(err, "reporting drift"))
+		return err.Error(), http.StatusInternalServerError
+	}
+	return DriftBody{Clusters: report.Clusters, Changes: report.Changes}, http.StatusOK
+}
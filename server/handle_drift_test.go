@@ -0,0 +1,62 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGETDriftHandler(t *testing.T) {
+	state := sous.NewState()
+	state.Defs.Clusters = sous.Clusters{
+		"east": &sous.Cluster{Name: "east"},
+		"west": &sous.Cluster{Name: "west"},
+	}
+	state.Manifests.Add(&sous.Manifest{
+		Source: sous.SourceLocation{Repo: "github.com/opentable/one"},
+		Kind:   sous.ManifestKindService,
+		Deployments: sous.DeploySpecs{
+			"east": {DeployConfig: sous.DeployConfig{NumInstances: 1}},
+			"west": {DeployConfig: sous.DeployConfig{NumInstances: 1}},
+		},
+	})
+	ls := logging.SilentLogSet()
+	rez := sous.NewResolver(sous.NewDummyDeployer(), sous.NewDummyRegistry(), &sous.ResolveFilter{}, ls, sous.NewR11nQueueSet())
+
+	t.Run("no resolver", func(t *testing.T) {
+		th := &GETDriftHandler{State: state, LogSink: ls}
+		_, status := th.Exchange()
+		assert.Equal(t, 503, status)
+	})
+
+	t.Run("all clusters", func(t *testing.T) {
+		th := &GETDriftHandler{Resolver: rez, State: state, LogSink: ls}
+		data, status := th.Exchange()
+		require.Equal(t, 200, status)
+		body, ok := data.(DriftBody)
+		require.True(t, ok, "got %T", data)
+		assert.Equal(t, []string{"east", "west"}, body.Clusters)
+		require.Len(t, body.Changes, 2)
+		assert.Equal(t, "added", body.Changes[0].Kind)
+		assert.Equal(t, "east", body.Changes[0].DeploymentID.Cluster)
+	})
+
+	t.Run("one cluster", func(t *testing.T) {
+		th := &GETDriftHandler{Resolver: rez, State: state, Cluster: "west", LogSink: ls}
+		data, status := th.Exchange()
+		require.Equal(t, 200, status)
+		body := data.(DriftBody)
+		assert.Equal(t, []string{"west"}, body.Clusters)
+		require.Len(t, body.Changes, 1)
+		assert.Equal(t, "west", body.Changes[0].DeploymentID.Cluster)
+	})
+
+	t.Run("unknown cluster", func(t *testing.T) {
+		th := &GETDriftHandler{Resolver: rez, State: state, Cluster: "north", LogSink: ls}
+		_, status := th.Exchange()
+		assert.Equal(t, 404, status)
+	})
+}
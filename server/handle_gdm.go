@@ -29,14 +29,17 @@ type (
 	PUTGDMHandler struct {
 		*http.Request
 		logging.LogSink
-		GDM          *sous.State
-		StateManager sous.StateManager
-		User         ClientUser
+		GDM            *sous.State
+		StateManager   sous.StateManager
+		PendingChanges sous.PendingChangeStore
+		Authorizer     Authorizer
+		User           ClientUser
+		routeMap       *restful.RouteMap
 	}
 )
 
 func newGDMResource(ctx ComponentLocator) *GDMResource {
-	return &GDMResource{context: ctx}
+	return &GDMResource{userExtractor: newUserExtractor(ctx), context: ctx}
 }
 
 // Get implements Getable on GDMResource
@@ -72,13 +75,16 @@ func (h *GETGDMHandler) Exchange() (interface{}, int) {
 }
 
 // Put implements Putable on GDMResource
-func (gr *GDMResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+func (gr *GDMResource) Put(rm *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
 	return &PUTGDMHandler{
-		Request:      req,
-		LogSink:      gr.context.LogSink,
-		GDM:          gr.context.liveState(),
-		StateManager: gr.context.StateManager,
-		User:         gr.GetUser(req),
+		Request:        req,
+		LogSink:        gr.context.LogSink,
+		GDM:            gr.context.liveState(),
+		StateManager:   gr.context.StateManager,
+		PendingChanges: gr.context.PendingChanges,
+		Authorizer:     gr.context.Authorizer,
+		User:           gr.GetUser(req),
+		routeMap:       rm,
 	}
 }
 
@@ -98,6 +104,27 @@ func (h *PUTGDMHandler) Exchange() (interface{}, int) {
 		return msg, http.StatusInternalServerError
 	}
 
+	current, err := state.Deployments()
+	if err != nil {
+		msg := "Error reading current deployments"
+		reportHandleGDMMessage(msg, nil, err, h.LogSink)
+		return msg, http.StatusInternalServerError
+	}
+
+	gate := writeGate{
+		LogSink:        h.LogSink,
+		Request:        h.Request,
+		User:           h.User,
+		Authorizer:     h.Authorizer,
+		PendingChanges: h.PendingChanges,
+		RouteMap:       h.routeMap,
+	}
+	deps, pending, rejected := gate.check(Write{Method: "PUT", Resource: "gdm", State: state}, current, deps)
+	if rejected != nil {
+		reportHandleGDMMessage("Rejected change to GDM", nil, rejected, h.LogSink)
+		return rejected.Error(), rejected.Status
+	}
+
 	state.Manifests, err = deps.PutbackManifests(state.Defs, state.Manifests)
 	if err != nil {
 		msg := "Error getting state"
@@ -122,6 +149,9 @@ func (h *PUTGDMHandler) Exchange() (interface{}, int) {
 		return msg, http.StatusInternalServerError
 	}
 
+	if pending != nil {
+		return pending, http.StatusAccepted
+	}
 	return "", http.StatusNoContent
 }
 
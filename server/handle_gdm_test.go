@@ -1,7 +1,12 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
@@ -61,3 +66,86 @@ func TestReturnFlawMsg(t *testing.T) {
 	assert.Contains(t, flawsMsg, "Missing resource")
 
 }
+
+func TestHandlesGDMPutFrozen(t *testing.T) {
+	put := func(t *testing.T, headers map[string]string) (*sous.DummyStateManager, int) {
+		t.Helper()
+		frozen := sous.DeploymentFixture("")
+		thawed := frozen.Clone()
+		thawed.ClusterName = "cluster-2"
+		state := sous.NewState()
+		state.Defs.Clusters = sous.Clusters{
+			"cluster-1": {Name: "cluster-1"},
+			"cluster-2": {Name: "cluster-2"},
+		}
+		state.Defs.FreezeWindows = sous.FreezeWindows{{
+			Start:    time.Now().Add(-time.Hour),
+			End:      time.Now().Add(time.Hour),
+			Clusters: []string{"cluster-1"},
+			Reason:   "holiday",
+		}}
+		ms, err := sous.NewDeployments(frozen, thawed).RawManifests(state.Defs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state.Manifests = ms
+		sm := &sous.DummyStateManager{State: state}
+
+		deps, err := state.Clone().Deployments()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range deps.Snapshot() {
+			d.NumInstances++
+		}
+		data := GDMWrapper{}
+		for _, d := range deps.Snapshot() {
+			data.Deployments = append(data.Deployments, d)
+		}
+		bs, err := json.Marshal(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest("PUT", "http://sous.example.com/gdm", bytes.NewBuffer(bs))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		ls, _ := logging.NewLogSinkSpy()
+		h := &PUTGDMHandler{
+			Request:      req,
+			LogSink:      ls,
+			StateManager: sm,
+			User:         ClientUser{Name: "Test User", Email: "testuser@example.com"},
+		}
+		_, status := h.Exchange()
+		return sm, status
+	}
+
+	t.Run("rejected", func(t *testing.T) {
+		sm, status := put(t, nil)
+		assert.Equal(t, http.StatusLocked, status)
+		assert.Equal(t, 0, sm.WriteCount)
+	})
+
+	t.Run("overridden", func(t *testing.T) {
+		sm, status := put(t, map[string]string{sous.FreezeOverrideHeader: "urgent fix"})
+		assert.Equal(t, http.StatusNoContent, status)
+		assert.Equal(t, 1, sm.WriteCount)
+
+		deps, err := sm.State.Deployments()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range deps.Snapshot() {
+			if d.ClusterName != "cluster-1" {
+				assert.Nil(t, d.FreezeOverride, d.ID().String())
+				continue
+			}
+			if assert.NotNil(t, d.FreezeOverride, d.ID().String()) {
+				assert.Equal(t, "urgent fix", d.FreezeOverride.Reason)
+				assert.Equal(t, "testuser@example.com", d.FreezeOverride.User.Email)
+			}
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+)
+
+type (
+	// GossipResource defines the /gossip endpoint, through which Sous servers
+	// tell each other which servers are live.
+	GossipResource struct {
+		userExtractor
+		context ComponentLocator
+	}
+
+	// PUTGossipHandler handles PUT requests to /gossip. The body announces
+	// the calling server, and the response lists the live servers known to
+	// this one.
+	PUTGossipHandler struct {
+		Membership *sous.Membership
+		Log        logging.LogSink
+		Authorizer Authorizer
+		User       ClientUser
+		req        *http.Request
+	}
+)
+
+func newGossipResource(ctx ComponentLocator) *GossipResource {
+	return &GossipResource{userExtractor: newUserExtractor(ctx), context: ctx}
+}
+
+// Put implements restful.Putable on GossipResource.
+func (gr *GossipResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &PUTGossipHandler{
+		Membership: gr.context.Membership,
+		Log:        gr.context.LogSink,
+		Authorizer: gr.context.Authorizer,
+		User:       gr.GetUser(req),
+		req:        req,
+	}
+}
+
+// Exchange implements restful.Exchanger on PUTGossipHandler. Gossip is a
+// global write: it is authorized for admins, and for sibling servers which
+// sign their announcements with the membership key.
+func (h *PUTGossipHandler) Exchange() (interface{}, int) {
+	if h.Membership == nil {
+		return "This server does not take part in gossip.", http.StatusNotFound
+	}
+	a := sous.Announcement{}
+	if err := json.NewDecoder(h.req.Body).Decode(&a); err != nil {
+		return fmt.Sprintf("Invalid announcement: %s.", err), http.StatusBadRequest
+	}
+	if err := authorize(h.Log, h.Authorizer, h.User, Write{
+		Method:   "PUT",
+		Resource: "gossip",
+		Global:   true,
+		Sibling:  a.Verify(h.Membership.Key),
+	}); err != nil {
+		return err.Error(), http.StatusForbidden
+	}
+	if err := h.Membership.Announce(a); err != nil {
+		return fmt.Sprintf("Announcement refused: %s.", err), http.StatusForbidden
+	}
+	return GossipData{Members: h.Membership.Live()}, http.StatusOK
+}
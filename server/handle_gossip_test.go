@@ -0,0 +1,134 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/config"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testGossipKey = []byte("shared secret")
+
+// startGossipServer starts a Sous server for cluster on loopback, which
+// gossips with seeds.
+func startGossipServer(t *testing.T, cluster string, seeds ...string) (*httptest.Server, *sous.Membership, chan struct{}) {
+	srv := httptest.NewUnstartedServer(nil)
+	url := "http://" + srv.Listener.Addr().String()
+	ls := logging.SilentLogSet()
+	m := sous.NewMembership(sous.Member{ClusterName: cluster, URL: url}, seeds, sous.NewHTTPMembershipTransport(ls), ls)
+	m.Interval = 10 * time.Millisecond
+	m.FailAfter = 200 * time.Millisecond
+	m.Key = testGossipKey
+	srv.Config.Handler = Handler(ComponentLocator{
+		LogSink:    ls,
+		Config:     &config.Config{},
+		Membership: m,
+	}, http.NotFoundHandler(), ls)
+	srv.Start()
+	done := make(chan struct{})
+	m.Start(done)
+	return srv, m, done
+}
+
+// serverList retrieves /servers from the server at url.
+func serverList(t *testing.T, url string) map[string]string {
+	cl, err := restful.NewClient(url, logging.SilentLogSet())
+	require.NoError(t, err)
+	data := ServerListData{}
+	_, err = cl.Retrieve("./servers", nil, &data, nil)
+	require.NoError(t, err)
+	list := map[string]string{}
+	for _, s := range data.Servers {
+		list[s.ClusterName] = s.URL
+	}
+	return list
+}
+
+func eventually(t *testing.T, f func() bool) bool {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if f() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return f()
+}
+
+func TestGossip_loopback(t *testing.T) {
+	left, _, leftDone := startGossipServer(t, "left")
+	defer left.Close()
+	defer close(leftDone)
+	right, _, rightDone := startGossipServer(t, "right", left.URL)
+	defer right.Close()
+	defer close(rightDone)
+	center, _, centerDone := startGossipServer(t, "center", left.URL)
+
+	all := map[string]string{"left": left.URL, "right": right.URL, "center": center.URL}
+	for _, srv := range []*httptest.Server{left, right, center} {
+		url := srv.URL
+		assert.True(t, eventually(t, func() bool { return assert.ObjectsAreEqual(all, serverList(t, url)) }),
+			"%s did not find all its siblings: %v", url, serverList(t, url))
+	}
+
+	close(centerDone)
+	center.Close()
+	remaining := map[string]string{"left": left.URL, "right": right.URL}
+	for _, srv := range []*httptest.Server{left, right} {
+		url := srv.URL
+		assert.True(t, eventually(t, func() bool { return assert.ObjectsAreEqual(remaining, serverList(t, url)) }),
+			"%s did not detect the failed sibling: %v", url, serverList(t, url))
+	}
+}
+
+// gossipRequest returns a PUT to /gossip announcing a.
+func gossipRequest(t *testing.T, a sous.Announcement) *http.Request {
+	body, err := json.Marshal(a)
+	require.NoError(t, err)
+	return httptest.NewRequest("PUT", "/gossip", bytes.NewReader(body))
+}
+
+func TestPUTGossipHandler_Exchange(t *testing.T) {
+	m := sous.NewMembership(sous.Member{ClusterName: "left", URL: "http://left"}, []string{"http://seed"}, nil, logging.SilentLogSet())
+	m.Key = testGossipKey
+	admins := ACLAuthorizer{Admins: []string{"admin@example.com"}}
+	started := time.Now().Add(-time.Minute).UnixNano()
+	right := sous.Member{ClusterName: "right", URL: "http://right", Incarnation: started, Heartbeat: 7}
+	handler := func(a sous.Announcement, user ClientUser) *PUTGossipHandler {
+		return &PUTGossipHandler{Membership: m, Log: logging.SilentLogSet(), Authorizer: admins, User: user, req: gossipRequest(t, a)}
+	}
+
+	// Unsigned gossip is a global write, which only admins may make.
+	_, status := handler(sous.Announcement{Member: right}, ClientUser{}).Exchange()
+	assert.Equal(t, 403, status)
+	// Even admins may only announce seeds and known members unsigned.
+	_, status = handler(sous.Announcement{Member: right}, ClientUser{Email: "admin@example.com"}).Exchange()
+	assert.Equal(t, 403, status)
+	assert.Len(t, m.Live(), 1)
+
+	data, status := handler(sous.SignAnnouncement(right, testGossipKey), ClientUser{}).Exchange()
+	require.Equal(t, 200, status, "%v", data)
+	members := data.(GossipData).Members
+	require.Len(t, members, 2)
+	assert.Equal(t, right, members[1])
+
+	seed := sous.Member{ClusterName: "center", URL: "http://seed", Incarnation: started, Heartbeat: 1}
+	_, status = handler(sous.Announcement{Member: seed}, ClientUser{Email: "admin@example.com"}).Exchange()
+	assert.Equal(t, 200, status)
+
+	bad := &PUTGossipHandler{Membership: m, Log: logging.SilentLogSet(), req: httptest.NewRequest("PUT", "/gossip", bytes.NewBufferString("{"))}
+	_, status = bad.Exchange()
+	assert.Equal(t, 400, status)
+
+	_, status = (&PUTGossipHandler{req: gossipRequest(t, sous.Announcement{Member: right})}).Exchange()
+	assert.Equal(t, 404, status)
+}
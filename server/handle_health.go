@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
+	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/restful"
 	"github.com/samsalisbury/semv"
 )
@@ -14,13 +15,17 @@ type (
 	}
 
 	getHealthHandler struct {
-		version semv.Version
+		version    semv.Version
+		leadership *sous.Leadership
 	}
 
 	// Health is the DTO for representing the health of the Sous server
 	Health struct {
 		Version  string
 		Revision string
+		// Leaders maps the clusters this server contends to lead to the
+		// URL of the server leading each.
+		Leaders map[string]string `json:",omitempty"`
 	}
 )
 
@@ -30,13 +35,18 @@ func newHealthResource(loc ComponentLocator) *healthResource {
 
 func (hr *healthResource) Get(*restful.RouteMap, http.ResponseWriter, *http.Request, httprouter.Params) restful.Exchanger {
 	return &getHealthHandler{
-		version: hr.locator.Version,
+		version:    hr.locator.Version,
+		leadership: hr.locator.Leadership,
 	}
 }
 
 func (ghh *getHealthHandler) Exchange() (interface{}, int) {
-	return Health{
+	health := Health{
 		Version:  ghh.version.Format("M.m.p"),
 		Revision: ghh.version.Format("?"),
-	}, 200
+	}
+	if ghh.leadership != nil {
+		health.Leaders = ghh.leadership.Leaders()
+	}
+	return health, 200
 }
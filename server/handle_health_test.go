@@ -28,3 +28,16 @@ func TestHandleHealth_Get(t *testing.T) {
 		t.Errorf("Expecting %q; got %q", version, rez.Version)
 	}
 }
+
+func TestHandleHealth_Get_leaders(t *testing.T) {
+	h := &getHealthHandler{
+		version:    semv.MustParse("3.4.5"),
+		leadership: testLeadership("http://one", map[string]string{"cluster2": "http://two"}, "cluster1", "cluster2"),
+	}
+	data, _ := h.Exchange()
+
+	leaders := data.(Health).Leaders
+	if len(leaders) != 2 || leaders["cluster1"] != "http://one" || leaders["cluster2"] != "http://two" {
+		t.Errorf("Expecting cluster1 led by http://one and cluster2 by http://two; got %v", leaders)
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+type (
+	// HistoryResource describes resources for the change history of the GDM.
+	HistoryResource struct {
+		context ComponentLocator
+	}
+
+	// GETHistoryHandler handles GET exchanges for the GDM change history.
+	GETHistoryHandler struct {
+		HistoryReader sous.HistoryReader
+		Filter        sous.HistoryFilter
+		FilterErr     error
+		LogSink       logging.LogSink
+	}
+
+	historyResponse struct {
+		Changes []sous.StateChange
+	}
+)
+
+func newHistoryResource(ctx ComponentLocator) *HistoryResource {
+	return &HistoryResource{context: ctx}
+}
+
+// Get returns a configured GETHistoryHandler.
+func (r *HistoryResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	qv := restful.QueryValues{Values: req.URL.Query()}
+	filter, err := historyFilterFromValues(qv)
+	return &GETHistoryHandler{
+		HistoryReader: r.context.HistoryReader,
+		Filter:        filter,
+		FilterErr:     err,
+		LogSink:       r.context.LogSink,
+	}
+}
+
+// Exchange returns a historyResponse listing recorded changes to the GDM.
+func (h *GETHistoryHandler) Exchange() (interface{}, int) {
+	if h.FilterErr != nil {
+		return h.FilterErr.Error(), http.StatusBadRequest
+	}
+	if h.HistoryReader == nil {
+		return "GDM history is not recorded by this server", http.StatusServiceUnavailable
+	}
+	changes, err := h.HistoryReader.ReadHistory(h.Filter)
+	if err != nil {
+		logging.ReportError(h.LogSink, errors.Wrapf(err, "reading GDM history"))
+		return err.Error(), http.StatusInternalServerError
+	}
+	return historyResponse{Changes: changes}, http.StatusOK
+}
+
+func historyFilterFromValues(qv restful.QueryValues) (sous.HistoryFilter, error) {
+	filter := sous.HistoryFilter{}
+
+	if repo, _ := qv.Single("repo", ""); repo != "" {
+		mid, err := manifestIDFromValues(qv)
+		if err != nil {
+			return filter, err
+		}
+		filter.Manifest = &mid
+	}
+
+	var err error
+	if filter.Cluster, err = qv.Single("cluster", ""); err != nil {
+		return filter, err
+	}
+	if filter.User, err = qv.Single("user", ""); err != nil {
+		return filter, err
+	}
+
+	since, err := qv.Single("since", "")
+	if err != nil {
+		return filter, err
+	}
+	if since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return filter, errors.Wrapf(err, "parsing since")
+		}
+	}
+
+	limit, err := qv.Single("limit", "")
+	if err != nil {
+		return filter, err
+	}
+	if limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			return filter, errors.Wrapf(err, "parsing limit")
+		}
+	}
+
+	return filter, nil
+}
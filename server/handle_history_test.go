@@ -0,0 +1,89 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type historyReaderFunc func(sous.HistoryFilter) ([]sous.StateChange, error)
+
+func (f historyReaderFunc) ReadHistory(filter sous.HistoryFilter) ([]sous.StateChange, error) {
+	return f(filter)
+}
+
+func TestHistoryResource_Get(t *testing.T) {
+	c := ComponentLocator{}
+	rm := routemap(c)
+	hr := newHistoryResource(c)
+
+	req := makeRequestWithQuery(t, "repo=github.com%2Fexample%2Fone&flavor=sweet&cluster=east&user=ann&limit=5&since=2018-01-02T15%3A04%3A05Z")
+	got := hr.Get(rm, nil, req, nil).(*GETHistoryHandler)
+
+	require.NoError(t, got.FilterErr)
+	require.NotNil(t, got.Filter.Manifest)
+	assert.Equal(t, "github.com/example/one", got.Filter.Manifest.Source.Repo)
+	assert.Equal(t, "sweet", got.Filter.Manifest.Flavor)
+	assert.Equal(t, "east", got.Filter.Cluster)
+	assert.Equal(t, "ann", got.Filter.User)
+	assert.Equal(t, 5, got.Filter.Limit)
+	assert.Equal(t, 2018, got.Filter.Since.Year())
+
+	req = makeRequestWithQuery(t, "")
+	got = hr.Get(rm, nil, req, nil).(*GETHistoryHandler)
+	require.NoError(t, got.FilterErr)
+	assert.Nil(t, got.Filter.Manifest)
+
+	req = makeRequestWithQuery(t, "limit=lots")
+	got = hr.Get(rm, nil, req, nil).(*GETHistoryHandler)
+	assert.Error(t, got.FilterErr)
+}
+
+func TestGETHistoryHandler_Exchange(t *testing.T) {
+	ls, _ := logging.NewLogSinkSpy()
+
+	t.Run("bad filter", func(t *testing.T) {
+		h := &GETHistoryHandler{FilterErr: fmt.Errorf("bad"), LogSink: ls}
+		_, status := h.Exchange()
+		assert.Equal(t, 400, status)
+	})
+
+	t.Run("no history", func(t *testing.T) {
+		h := &GETHistoryHandler{LogSink: ls}
+		_, status := h.Exchange()
+		assert.Equal(t, 503, status)
+	})
+
+	t.Run("reader error", func(t *testing.T) {
+		h := &GETHistoryHandler{
+			HistoryReader: historyReaderFunc(func(sous.HistoryFilter) ([]sous.StateChange, error) {
+				return nil, fmt.Errorf("database down")
+			}),
+			LogSink: ls,
+		}
+		_, status := h.Exchange()
+		assert.Equal(t, 500, status)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		var gotFilter sous.HistoryFilter
+		h := &GETHistoryHandler{
+			HistoryReader: historyReaderFunc(func(f sous.HistoryFilter) ([]sous.StateChange, error) {
+				gotFilter = f
+				return []sous.StateChange{{ID: 7}}, nil
+			}),
+			Filter:  sous.HistoryFilter{Cluster: "east"},
+			LogSink: ls,
+		}
+		body, status := h.Exchange()
+		assert.Equal(t, 200, status)
+		assert.Equal(t, "east", gotFilter.Cluster)
+		if assert.IsType(t, historyResponse{}, body) {
+			assert.Len(t, body.(historyResponse).Changes, 1)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/restful"
+)
+
+type (
+	// LintResource defines the /lint endpoint, which checks the GDM against
+	// its lint rules.
+	LintResource struct {
+		context ComponentLocator
+	}
+
+	// GETLintHandler handles GET requests to /lint.
+	GETLintHandler struct {
+		State *sous.State
+	}
+)
+
+func newLintResource(ctx ComponentLocator) *LintResource {
+	return &LintResource{context: ctx}
+}
+
+// Get implements restful.Getter on LintResource.
+func (lr *LintResource) Get(*restful.RouteMap, http.ResponseWriter, *http.Request, httprouter.Params) restful.Exchanger {
+	return &GETLintHandler{
+		State: lr.context.liveState(),
+	}
+}
+
+// Exchange implements restful.Exchanger on GETLintHandler.
+func (h *GETLintHandler) Exchange() (interface{}, int) {
+	if h.State == nil {
+		return "Error reading state", http.StatusInternalServerError
+	}
+	body := LintBody{Rules: []string{}, Flaws: []sous.LintFlaw{}}
+	for _, f := range h.State.Defs.Lint.Validate() {
+		body.Rules = append(body.Rules, fmt.Sprint(f))
+	}
+	flaws, err := h.State.Lint()
+	if err != nil {
+		return err.Error(), http.StatusInternalServerError
+	}
+	body.Flaws = append(body.Flaws, flaws...)
+	return body, http.StatusOK
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/opentable/sous/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lintState() *sous.State {
+	state := sous.NewState()
+	state.Defs.Clusters = sous.Clusters{"prod": &sous.Cluster{Name: "prod"}}
+	state.Defs.Lint = sous.LintRules{
+		{Name: "ha", Field: "NumInstances", Op: ">=", Value: "2"},
+		{Name: "small", Severity: sous.LintWarning, Field: "Resources.memory", Op: "<", Value: "1024"},
+	}
+	state.Manifests.Add(&sous.Manifest{
+		Source: sous.SourceLocation{Repo: "github.com/opentable/one"},
+		Kind:   sous.ManifestKindService,
+		Deployments: sous.DeploySpecs{
+			"prod": {DeployConfig: sous.DeployConfig{NumInstances: 1, Resources: sous.Resources{"memory": "2048"}}},
+		},
+	})
+	state.Manifests.Add(&sous.Manifest{
+		Source: sous.SourceLocation{Repo: "github.com/opentable/two"},
+		Kind:   sous.ManifestKindService,
+		Deployments: sous.DeploySpecs{
+			"prod": {DeployConfig: sous.DeployConfig{NumInstances: 2, Resources: sous.Resources{"memory": "512"}}},
+		},
+	})
+	return state
+}
+
+func TestGETLintHandler(t *testing.T) {
+	th := &GETLintHandler{State: lintState()}
+
+	data, status := th.Exchange()
+	require.Equal(t, 200, status)
+	body, ok := data.(LintBody)
+	require.True(t, ok, "got %T", data)
+	assert.Empty(t, body.Rules)
+	require.Len(t, body.Flaws, 2)
+	assert.Equal(t, "ha", body.Flaws[0].Rule)
+	assert.Equal(t, sous.LintError, body.Flaws[0].Severity)
+	assert.Equal(t, "github.com/opentable/one", body.Flaws[0].DeploymentID.ManifestID.Source.Repo)
+	assert.Equal(t, "small", body.Flaws[1].Rule)
+	assert.Equal(t, sous.LintWarning, body.Flaws[1].Severity)
+}
+
+func TestGETLintHandler_invalidRule(t *testing.T) {
+	state := lintState()
+	state.Defs.Lint = append(state.Defs.Lint, sous.LintRule{Name: "broken", Field: "Nonsense", Op: "==", Value: "1"})
+	th := &GETLintHandler{State: state}
+
+	data, status := th.Exchange()
+	require.Equal(t, 200, status)
+	assert.Len(t, data.(LintBody).Rules, 1)
+}
+
+func TestCheckDefs_lint(t *testing.T) {
+	state := lintState()
+	ds, err := state.Deployments()
+	require.NoError(t, err)
+
+	flaws := checkDefs(state.Defs, ds)
+	require.Len(t, flaws, 1, "warnings should not be rejected")
+	assert.Contains(t, flaws[0].(sous.LintFlaw).Desc, "NumInstances")
+}
@@ -32,20 +32,26 @@ type (
 		logging.LogSink
 		*http.Request
 		restful.QueryValues
-		User        ClientUser
-		StateWriter sous.StateWriter
+		User           ClientUser
+		StateWriter    sous.StateWriter
+		PendingChanges sous.PendingChangeStore
+		Authorizer     Authorizer
+		routeMap       *restful.RouteMap
 	}
 
 	// DELETEManifestHandler handles DELETE exchanges for manifests
 	DELETEManifestHandler struct {
 		*sous.State
+		logging.LogSink
 		restful.QueryValues
+		User        ClientUser
 		StateWriter sous.StateWriter
+		Authorizer  Authorizer
 	}
 )
 
 func newManifestResource(ctx ComponentLocator) *ManifestResource {
-	return &ManifestResource{context: ctx}
+	return &ManifestResource{userExtractor: newUserExtractor(ctx), context: ctx}
 }
 
 // Get implements Getable for ManifestResource
@@ -57,14 +63,17 @@ func (mr *ManifestResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req
 }
 
 // Put implements Putable for ManifestResource
-func (mr *ManifestResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+func (mr *ManifestResource) Put(rm *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
 	return &PUTManifestHandler{
-		State:       mr.context.liveState(),
-		LogSink:     mr.context.LogSink,
-		Request:     req,
-		QueryValues: mr.ParseQuery(req),
-		User:        mr.GetUser(req),
-		StateWriter: sous.StateWriter(mr.context.StateManager),
+		State:          mr.context.liveState(),
+		LogSink:        mr.context.LogSink,
+		Request:        req,
+		QueryValues:    mr.ParseQuery(req),
+		User:           mr.GetUser(req),
+		StateWriter:    sous.StateWriter(mr.context.StateManager),
+		PendingChanges: mr.context.PendingChanges,
+		Authorizer:     mr.context.Authorizer,
+		routeMap:       rm,
 	}
 }
 
@@ -72,8 +81,11 @@ func (mr *ManifestResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req
 func (mr *ManifestResource) Delete(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
 	return &DELETEManifestHandler{
 		State:       mr.context.liveState(),
+		LogSink:     mr.context.LogSink,
 		QueryValues: mr.ParseQuery(req),
+		User:        mr.GetUser(req),
 		StateWriter: sous.StateWriter(mr.context.StateManager),
+		Authorizer:  mr.context.Authorizer,
 	}
 }
 
@@ -96,10 +108,23 @@ func (dmh *DELETEManifestHandler) Exchange() (interface{}, int) {
 	if err != nil {
 		return err, http.StatusNotFound
 	}
-	_, there := dmh.State.Manifests.Get(mid)
+	old, there := dmh.State.Manifests.Get(mid)
 	if !there {
 		return nil, http.StatusNotFound
 	}
+	current, err := manifestDeployments(dmh.State.Defs, old)
+	if err != nil {
+		return err.Error(), http.StatusInternalServerError
+	}
+	if err := authorize(dmh.LogSink, dmh.Authorizer, dmh.User, Write{
+		Method:      "DELETE",
+		Resource:    "manifest",
+		State:       dmh.State,
+		Deployments: current.Keys(),
+		Manifests:   []sous.ManifestID{mid},
+	}); err != nil {
+		return err.Error(), http.StatusForbidden
+	}
 	dmh.State.Manifests.Remove(mid)
 
 	return nil, http.StatusNoContent
@@ -121,9 +146,76 @@ func (pmh *PUTManifestHandler) Exchange() (interface{}, int) {
 		messages.ReportLogFieldsMessageToConsole("Exchange contains flaws", logging.ExtraDebug1Level, pmh.LogSink, flaws)
 		return "Invalid manifest", http.StatusBadRequest
 	}
+	old, _ := pmh.State.Manifests.Get(mid)
+	current, err := manifestDeployments(pmh.State.Defs, old)
+	if err != nil {
+		return err.Error(), http.StatusInternalServerError
+	}
+	intended, err := manifestDeployments(pmh.State.Defs, m)
+	if err != nil {
+		return err.Error(), http.StatusBadRequest
+	}
+	gate := writeGate{
+		LogSink:        pmh.LogSink,
+		Request:        pmh.Request,
+		User:           pmh.User,
+		Authorizer:     pmh.Authorizer,
+		PendingChanges: pmh.PendingChanges,
+		RouteMap:       pmh.routeMap,
+	}
+	_, pending, rejected := gate.check(Write{
+		Method:    "PUT",
+		Resource:  "manifest",
+		State:     pmh.State,
+		Manifests: []sous.ManifestID{mid},
+	}, current, intended)
+	if rejected != nil {
+		return rejected.Error(), rejected.Status
+	}
+	// Record any freeze override on the manifest, so the resolver will
+	// rectify the change.
+	for _, d := range intended.Snapshot() {
+		if spec, has := m.Deployments[d.ClusterName]; has && d.FreezeOverride != nil {
+			spec.FreezeOverride = d.FreezeOverride
+			m.Deployments[d.ClusterName] = spec
+		}
+	}
+	if pending != nil {
+		// Write everything but the changes held for approval.
+		for _, pd := range pending.Deployments {
+			cluster := pd.DeploymentID.Cluster
+			if old != nil {
+				if spec, had := old.Deployments[cluster]; had {
+					m.Deployments[cluster] = spec
+					continue
+				}
+			}
+			delete(m.Deployments, cluster)
+		}
+	}
 	pmh.State.Manifests.Set(mid, m)
 	if err := pmh.StateWriter.WriteState(pmh.State, sous.User(pmh.User)); err != nil {
 		return errors.Wrapf(err, "state recording collision - retry"), http.StatusConflict
 	}
+	if pending != nil {
+		return pending, http.StatusAccepted
+	}
 	return m, http.StatusOK
 }
+
+// manifestDeployments returns the deployments of m to the clusters in defs.
+// Deployments to other clusters are ignored, since they cannot require
+// approval.
+func manifestDeployments(defs sous.Defs, m *sous.Manifest) (sous.Deployments, error) {
+	state := &sous.State{Defs: defs, Manifests: sous.NewManifests()}
+	if m != nil {
+		m = m.Clone()
+		for cluster := range m.Deployments {
+			if _, known := defs.Clusters[cluster]; !known {
+				delete(m.Deployments, cluster)
+			}
+		}
+		state.Manifests.Add(m)
+	}
+	return state.Deployments()
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
@@ -174,3 +175,116 @@ func TestHandlesManifestPut(t *testing.T) {
 	assert.Equal(changed.Owners[1], "judson")
 
 }
+
+func TestHandlesManifestPut_invalidEnv(t *testing.T) {
+	q, err := url.ParseQuery("repo=gh")
+	require.NoError(t, err)
+	state := sous.NewState()
+	state.Defs.Clusters = sous.Clusters{"ci": &sous.Cluster{Name: "ci"}}
+	state.Defs.EnvVars = sous.EnvDefs{{Name: "WORKERS", Type: sous.VarTypeInt}}
+	writer := &sous.DummyStateManager{State: state}
+
+	manifest := &sous.Manifest{
+		Source: sous.SourceLocation{Repo: "gh"},
+		Owners: []string{"sam"},
+		Kind:   sous.ManifestKindService,
+		Deployments: sous.DeploySpecs{
+			"ci": sous.DeploySpec{
+				DeployConfig: sous.DeployConfig{
+					Resources: sous.Resources{
+						"cpus":   "0.1",
+						"memory": "100",
+						"ports":  "1",
+					},
+					Env: sous.Env{"WORKERS": "lots"},
+				},
+			},
+		},
+	}
+	buf := &bytes.Buffer{}
+	json.NewEncoder(buf).Encode(manifest)
+	req, err := http.NewRequest("PUT", "", buf)
+	require.NoError(t, err)
+
+	th := &PUTManifestHandler{
+		Request:     req,
+		StateWriter: writer,
+		State:       state,
+		QueryValues: restful.QueryValues{Values: q},
+		LogSink:     logging.Log,
+	}
+
+	data, status := th.Exchange()
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, data, "WORKERS")
+	_, found := state.Manifests.Get(sous.ManifestID{Source: sous.SourceLocation{Repo: "gh"}})
+	assert.False(t, found)
+}
+
+func TestHandlesManifestPut_frozen(t *testing.T) {
+	put := func(t *testing.T, override string) (*sous.State, interface{}, int) {
+		q, err := url.ParseQuery("repo=gh")
+		require.NoError(t, err)
+		state := sous.NewState()
+		state.Defs.Clusters = sous.Clusters{"ci": &sous.Cluster{Name: "ci"}}
+		state.Defs.FreezeWindows = sous.FreezeWindows{{
+			Start:  time.Now().Add(-time.Hour),
+			End:    time.Now().Add(time.Hour),
+			Reason: "holiday",
+		}}
+		writer := &sous.DummyStateManager{State: state}
+
+		manifest := &sous.Manifest{
+			Source: sous.SourceLocation{Repo: "gh"},
+			Owners: []string{"sam"},
+			Kind:   sous.ManifestKindService,
+			Deployments: sous.DeploySpecs{
+				"ci": sous.DeploySpec{
+					DeployConfig: sous.DeployConfig{
+						Resources: sous.Resources{
+							"cpus":   "0.1",
+							"memory": "100",
+							"ports":  "1",
+						},
+					},
+				},
+			},
+		}
+		buf := &bytes.Buffer{}
+		json.NewEncoder(buf).Encode(manifest)
+		req, err := http.NewRequest("PUT", "", buf)
+		require.NoError(t, err)
+		if override != "" {
+			req.Header.Set(sous.FreezeOverrideHeader, override)
+		}
+
+		th := &PUTManifestHandler{
+			Request:     req,
+			StateWriter: writer,
+			State:       state,
+			QueryValues: restful.QueryValues{Values: q},
+			LogSink:     logging.Log,
+			User:        ClientUser{Name: "Sam", Email: "sam@example.com"},
+		}
+		data, status := th.Exchange()
+		return state, data, status
+	}
+
+	t.Run("rejected", func(t *testing.T) {
+		state, _, status := put(t, "")
+		assert.Equal(t, http.StatusLocked, status)
+		_, found := state.Manifests.Get(sous.ManifestID{Source: sous.SourceLocation{Repo: "gh"}})
+		assert.False(t, found)
+	})
+
+	t.Run("overridden", func(t *testing.T) {
+		state, data, status := put(t, "urgent fix")
+		require.Equal(t, http.StatusOK, status, "%v", data)
+		m, found := state.Manifests.Get(sous.ManifestID{Source: sous.SourceLocation{Repo: "gh"}})
+		require.True(t, found)
+		fo := m.Deployments["ci"].FreezeOverride
+		require.NotNil(t, fo)
+		assert.Equal(t, "urgent fix", fo.Reason)
+		assert.Equal(t, "sam@example.com", fo.User.Email)
+	})
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/sous/util/restful"
+)
+
+type (
+	// PauseResource defines the /pause endpoint, which pauses and resumes
+	// the rectification of single deployments.
+	PauseResource struct {
+		context ComponentLocator
+	}
+
+	// PauseHandler contains the data common to the /pause handlers.
+	PauseHandler struct {
+		userExtractor
+		DeploymentManager sous.DeploymentManager
+		StateReader       sous.StateReader
+		Authorizer        Authorizer
+		LogSink           logging.LogSink
+		req               *http.Request
+	}
+
+	// GETPauseHandler handles GET requests to /pause.
+	GETPauseHandler struct {
+		PauseHandler
+	}
+
+	// PUTPauseHandler handles PUT requests to /pause, which pause a
+	// deployment.
+	PUTPauseHandler struct {
+		PauseHandler
+	}
+
+	// DELETEPauseHandler handles DELETE requests to /pause, which resume a
+	// paused deployment.
+	DELETEPauseHandler struct {
+		PauseHandler
+	}
+)
+
+func newPauseResource(ctx ComponentLocator) *PauseResource {
+	return &PauseResource{context: ctx}
+}
+
+func (pr *PauseResource) newPauseHandler(req *http.Request) PauseHandler {
+	return PauseHandler{
+		userExtractor:     newUserExtractor(pr.context),
+		DeploymentManager: pr.context.DeploymentManager,
+		StateReader:       pr.context.StateManager,
+		Authorizer:        pr.context.Authorizer,
+		LogSink:           pr.context.LogSink,
+		req:               req,
+	}
+}
+
+// Get implements restful.Getter on PauseResource.
+func (pr *PauseResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETPauseHandler{PauseHandler: pr.newPauseHandler(req)}
+}
+
+// Put implements restful.Putter on PauseResource.
+func (pr *PauseResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &PUTPauseHandler{PauseHandler: pr.newPauseHandler(req)}
+}
+
+// Delete implements restful.Deleter on PauseResource.
+func (pr *PauseResource) Delete(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &DELETEPauseHandler{PauseHandler: pr.newPauseHandler(req)}
+}
+
+// readDeployment returns the deployment named in the request, or a response
+// explaining why it could not.
+func (h *PauseHandler) readDeployment() (*sous.Deployment, string, int) {
+	did, err := deploymentIDFromValues(restful.QueryValues{Values: h.req.URL.Query()})
+	if err != nil {
+		return nil, fmt.Sprintf("Cannot decode Deployment ID: %s.", err), http.StatusBadRequest
+	}
+	dep, err := h.DeploymentManager.ReadDeployment(did)
+	if err != nil {
+		return nil, fmt.Sprintf("No deployment with ID %q: %v", did, err), http.StatusNotFound
+	}
+	return dep, "", http.StatusOK
+}
+
+// write authorizes the client to change the pause of dep, and writes dep.
+func (h *PauseHandler) write(method string, dep *sous.Deployment) (string, int) {
+	state, err := h.StateReader.ReadState()
+	if err != nil {
+		return fmt.Sprintf("Failed to read state: %s.", err), http.StatusInternalServerError
+	}
+	clientUser := h.GetUser(h.req)
+	if err := authorize(h.LogSink, h.Authorizer, clientUser, Write{
+		Method:      method,
+		Resource:    "pause",
+		State:       state,
+		Deployments: []sous.DeploymentID{dep.ID()},
+	}); err != nil {
+		return fmt.Sprintf("%s.", err), http.StatusForbidden
+	}
+	if err := h.DeploymentManager.WriteDeployment(dep, sous.User(clientUser)); err != nil {
+		return fmt.Sprintf("Failed to write deployment: %s.", err), http.StatusInternalServerError
+	}
+	return "", http.StatusOK
+}
+
+// Exchange implements restful.Exchanger on GETPauseHandler. It returns the
+// deployment's pause, even if it has lapsed.
+func (h *GETPauseHandler) Exchange() (interface{}, int) {
+	dep, msg, status := h.readDeployment()
+	if dep == nil {
+		return msg, status
+	}
+	return &PauseBody{Pause: dep.Pause}, http.StatusOK
+}
+
+// Exchange implements restful.Exchanger on PUTPauseHandler. It pauses the
+// deployment, replacing any earlier pause.
+func (h *PUTPauseHandler) Exchange() (interface{}, int) {
+	body := PauseBody{}
+	if err := json.NewDecoder(h.req.Body).Decode(&body); err != nil {
+		return fmt.Sprintf("Error parsing body: %s.", err), http.StatusBadRequest
+	}
+	if body.Pause == nil {
+		return "No pause given.", http.StatusBadRequest
+	}
+	now := time.Now()
+	pause := *body.Pause
+	if flaws := pause.Validate(now); len(flaws) > 0 {
+		return fmt.Sprintf("Invalid pause: %q", flaws), http.StatusBadRequest
+	}
+
+	dep, msg, status := h.readDeployment()
+	if dep == nil {
+		return msg, status
+	}
+	pause.User = sous.User(h.GetUser(h.req))
+	pause.At = now
+	dep.Pause = &pause
+	if msg, status := h.write("PUT", dep); status != http.StatusOK {
+		return msg, status
+	}
+	messages.ReportLogFieldsMessage(
+		fmt.Sprintf("Deployment %s paused by %s until %s: %s", dep.ID(), pause.User, pause.Until.Format(time.RFC3339), pause.Reason),
+		logging.WarningLevel, h.LogSink, dep.ID(), pause)
+	return &PauseBody{Pause: &pause}, http.StatusOK
+}
+
+// Exchange implements restful.Exchanger on DELETEPauseHandler. It resumes
+// the deployment, so that it is rectified on the next resolution.
+func (h *DELETEPauseHandler) Exchange() (interface{}, int) {
+	dep, msg, status := h.readDeployment()
+	if dep == nil {
+		return msg, status
+	}
+	if dep.Pause == nil {
+		return &PauseBody{}, http.StatusOK
+	}
+	dep.Pause = nil
+	if msg, status := h.write("DELETE", dep); status != http.StatusOK {
+		return msg, status
+	}
+	messages.ReportLogFieldsMessage(
+		fmt.Sprintf("Deployment %s resumed by %s", dep.ID(), sous.User(h.GetUser(h.req))),
+		logging.WarningLevel, h.LogSink, dep.ID())
+	return &PauseBody{}, http.StatusOK
+}
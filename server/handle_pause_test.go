@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pauseTestQuery = "?repo=github.com%2Fopentable%2Fone&cluster=prod"
+
+func pauseTestLocator() ComponentLocator {
+	sm := sous.NewDummyStateManager()
+	sm.State.Defs.Clusters = sous.Clusters{"prod": &sous.Cluster{Name: "prod"}}
+	sm.State.Manifests.Add(&sous.Manifest{
+		Source: sous.SourceLocation{Repo: "github.com/opentable/one"},
+		Kind:   sous.ManifestKindService,
+		Deployments: sous.DeploySpecs{
+			"prod": {DeployConfig: sous.DeployConfig{NumInstances: 1}},
+		},
+	})
+	return ComponentLocator{
+		LogSink:           logging.SilentLogSet(),
+		StateManager:      sm,
+		DeploymentManager: sous.MakeDeploymentManager(sm),
+	}
+}
+
+func TestPauseResource(t *testing.T) {
+	cl := pauseTestLocator()
+	pr := newPauseResource(cl)
+	rm := routemap(cl)
+	did := sous.DeploymentID{ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/one"}}, Cluster: "prod"}
+
+	get := func() *PauseBody {
+		req := httptest.NewRequest("GET", "/pause"+pauseTestQuery, nil)
+		data, status := pr.Get(rm, nil, req, nil).Exchange()
+		require.Equal(t, 200, status, "%v", data)
+		return data.(*PauseBody)
+	}
+	put := func(body string) (interface{}, int) {
+		req := httptest.NewRequest("PUT", "/pause"+pauseTestQuery, bytes.NewBufferString(body))
+		req.Header.Set("Sous-User-Email", "ops@example.com")
+		return pr.Put(rm, nil, req, nil).Exchange()
+	}
+
+	assert.Nil(t, get().Pause)
+
+	_, status := put(`{"Pause": {"Until": "2099-01-01T00:00:00Z"}}`)
+	assert.Equal(t, 400, status, "a pause without a reason")
+	_, status = put(`{"Pause": {"Reason": "incident", "Until": "2001-01-01T00:00:00Z"}}`)
+	assert.Equal(t, 400, status, "a pause which has lapsed")
+
+	data, status := put(`{"Pause": {"Reason": "incident", "Until": "2099-01-01T00:00:00Z"}}`)
+	require.Equal(t, 200, status, "%v", data)
+	pause := get().Pause
+	require.NotNil(t, pause)
+	assert.Equal(t, "incident", pause.Reason)
+	assert.Equal(t, "ops@example.com", pause.User.Email)
+	assert.False(t, pause.At.IsZero())
+
+	dep, err := cl.DeploymentManager.ReadDeployment(did)
+	require.NoError(t, err)
+	assert.True(t, dep.Pause.ActiveAt(time.Now()))
+
+	req := httptest.NewRequest("DELETE", "/pause"+pauseTestQuery, nil)
+	_, status = pr.Delete(rm, nil, req, nil).Exchange()
+	require.Equal(t, 200, status)
+	assert.Nil(t, get().Pause)
+
+	req = httptest.NewRequest("GET", "/pause?repo=github.com%2Fopentable%2Fother&cluster=prod", nil)
+	_, status = pr.Get(rm, nil, req, nil).Exchange()
+	assert.Equal(t, 404, status)
+}
+
+func TestPauseResource_unauthorized(t *testing.T) {
+	cl := pauseTestLocator()
+	cl.Authorizer = ACLAuthorizer{}
+	pr := newPauseResource(cl)
+
+	req := httptest.NewRequest("PUT", "/pause"+pauseTestQuery, bytes.NewBufferString(`{"Pause": {"Reason": "incident", "Until": "2099-01-01T00:00:00Z"}}`))
+	_, status := pr.Put(routemap(cl), nil, req, nil).Exchange()
+	assert.Equal(t, 403, status)
+}
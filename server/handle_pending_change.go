@@ -0,0 +1,225 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+type (
+	// PendingChangesResource describes the list of changes awaiting approval.
+	PendingChangesResource struct {
+		context ComponentLocator
+	}
+
+	// GETPendingChangesHandler handles GET exchanges for the list of changes
+	// awaiting approval.
+	GETPendingChangesHandler struct {
+		logging.LogSink
+		Store sous.PendingChangeStore
+	}
+
+	// PendingChangeResource describes a single change awaiting approval.
+	//
+	// GET describes the change; a PUT whose body has the Status "approved" or
+	// "rejected" reviews it. Approved changes are written to the GDM.
+	PendingChangeResource struct {
+		userExtractor
+		context ComponentLocator
+	}
+
+	// GETPendingChangeHandler handles GET exchanges for a pending change.
+	GETPendingChangeHandler struct {
+		pendingChange
+	}
+
+	// PUTPendingChangeHandler handles PUT exchanges for a pending change.
+	PUTPendingChangeHandler struct {
+		pendingChange
+		*http.Request
+		Authorizer Authorizer
+		User       ClientUser
+	}
+
+	pendingChange struct {
+		logging.LogSink
+		Store        sous.PendingChangeStore
+		StateManager sous.StateManager
+		ID           sous.PendingChangeID
+		QueryErr     error
+	}
+)
+
+func newPendingChangesResource(ctx ComponentLocator) *PendingChangesResource {
+	return &PendingChangesResource{context: ctx}
+}
+
+func newPendingChangeResource(ctx ComponentLocator) *PendingChangeResource {
+	return &PendingChangeResource{userExtractor: newUserExtractor(ctx), context: ctx}
+}
+
+// Get returns a configured GETPendingChangesHandler.
+func (r *PendingChangesResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, _ *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETPendingChangesHandler{
+		LogSink: r.context.LogSink,
+		Store:   r.context.PendingChanges,
+	}
+}
+
+// Exchange returns a PendingChangesBody listing the changes awaiting approval.
+func (h *GETPendingChangesHandler) Exchange() (interface{}, int) {
+	if h.Store == nil {
+		return "pending changes are not stored by this server", http.StatusServiceUnavailable
+	}
+	changes, err := h.Store.ListPendingChanges()
+	if err != nil {
+		logging.ReportError(h.LogSink, errors.Wrapf(err, "listing pending changes"))
+		return err.Error(), http.StatusInternalServerError
+	}
+	return PendingChangesBody{Changes: changes}, http.StatusOK
+}
+
+func (r *PendingChangeResource) pendingChange(req *http.Request) pendingChange {
+	qv := restful.QueryValues{Values: req.URL.Query()}
+	id, err := qv.Single("id")
+	return pendingChange{
+		LogSink:      r.context.LogSink,
+		Store:        r.context.PendingChanges,
+		StateManager: r.context.StateManager,
+		ID:           sous.PendingChangeID(id),
+		QueryErr:     err,
+	}
+}
+
+// Get returns a configured GETPendingChangeHandler.
+func (r *PendingChangeResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETPendingChangeHandler{pendingChange: r.pendingChange(req)}
+}
+
+// Put returns a configured PUTPendingChangeHandler.
+func (r *PendingChangeResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &PUTPendingChangeHandler{
+		pendingChange: r.pendingChange(req),
+		Request:       req,
+		Authorizer:    r.context.Authorizer,
+		User:          r.GetUser(req),
+	}
+}
+
+// Exchange returns a PendingChangeBody describing the change.
+func (h *GETPendingChangeHandler) Exchange() (interface{}, int) {
+	pc, state, body, status := h.read()
+	if pc == nil {
+		return body, status
+	}
+	current, err := state.Deployments()
+	if err != nil {
+		return err.Error(), http.StatusInternalServerError
+	}
+	return &PendingChangeBody{PendingChange: *pc, Changes: pc.Changes(current)}, http.StatusOK
+}
+
+// Exchange approves or rejects the change. An approved change is written to
+// the GDM on behalf of its author, as long as the deployments it changes are
+// still as they were when it was proposed.
+func (h *PUTPendingChangeHandler) Exchange() (interface{}, int) {
+	pc, state, body, status := h.read()
+	if pc == nil {
+		return body, status
+	}
+
+	review := PendingChangeBody{}
+	if err := json.NewDecoder(h.Request.Body).Decode(&review); err != nil {
+		return fmt.Sprintf("Error parsing body: %s.", err), http.StatusBadRequest
+	}
+	if pc.Status != sous.PendingStatusPending {
+		return fmt.Sprintf("pending change %s is already %s", pc.ID, pc.Status), http.StatusConflict
+	}
+
+	// Which users may review the change is decided by CheckReview, so the
+	// write has no targets of its own.
+	if err := authorize(h.LogSink, h.Authorizer, h.User, Write{
+		Method:   "PUT",
+		Resource: "pending-change",
+		State:    state,
+	}); err != nil {
+		return err.Error(), http.StatusForbidden
+	}
+	reviewer := sous.User(h.User)
+	if err := pc.CheckReview(reviewer, review.Status, state); err != nil {
+		return err.Error(), http.StatusForbidden
+	}
+
+	current, err := state.Deployments()
+	if err != nil {
+		return err.Error(), http.StatusInternalServerError
+	}
+	if review.Status == sous.PendingStatusApproved {
+		if err := pc.CheckCurrent(current); err != nil {
+			return err.Error(), http.StatusConflict
+		}
+		intended := pc.Intended(current)
+		proposed := sous.NewDeployments()
+		for _, pd := range pc.Deployments {
+			if pd.Deployment != nil {
+				proposed.Add(pd.Deployment)
+			}
+		}
+		if flaws := checkDefs(state.Defs, proposed); len(flaws) > 0 {
+			return fmt.Sprintf("Invalid change: %v", flaws), http.StatusBadRequest
+		}
+		if err := guardFreezes(h.LogSink, h.Request, h.User, state.Defs.FreezeWindows, current, intended); err != nil {
+			return err.Error(), http.StatusLocked
+		}
+		if state.Manifests, err = intended.PutbackManifests(state.Defs, state.Manifests); err != nil {
+			return err.Error(), http.StatusInternalServerError
+		}
+		if err := h.StateManager.WriteState(state, pc.Author); err != nil {
+			logging.ReportError(h.LogSink, errors.Wrapf(err, "writing approved change %s", pc.ID))
+			return errors.Wrapf(err, "state recording collision - retry").Error(), http.StatusConflict
+		}
+	}
+
+	now := time.Now()
+	if err := h.Store.ReviewPendingChange(pc.ID, review.Status, reviewer, now); err != nil {
+		return err.Error(), http.StatusConflict
+	}
+	messages.ReportLogFieldsMessage(
+		fmt.Sprintf("Change %s by %s was %s by %s", pc.ID, pc.Author, review.Status, reviewer),
+		logging.WarningLevel, h.LogSink)
+
+	pc.Status, pc.Reviewer, pc.Reviewed = review.Status, reviewer, now
+	return &PendingChangeBody{PendingChange: *pc, Changes: pc.Changes(current)}, http.StatusOK
+}
+
+// read returns the pending change and the current state. If the change cannot
+// be read, it is nil, and the returned body and status describe why.
+func (h pendingChange) read() (*sous.PendingChange, *sous.State, interface{}, int) {
+	if h.QueryErr != nil {
+		return nil, nil, h.QueryErr.Error(), http.StatusBadRequest
+	}
+	if h.Store == nil {
+		return nil, nil, "pending changes are not stored by this server", http.StatusServiceUnavailable
+	}
+	pc, err := h.Store.ReadPendingChange(h.ID)
+	if err != nil {
+		logging.ReportError(h.LogSink, errors.Wrapf(err, "reading pending change %s", h.ID))
+		return nil, nil, err.Error(), http.StatusInternalServerError
+	}
+	if pc == nil {
+		return nil, nil, fmt.Sprintf("no pending change %s", h.ID), http.StatusNotFound
+	}
+	state, err := h.StateManager.ReadState()
+	if err != nil {
+		return nil, nil, "error reading current state", http.StatusInternalServerError
+	}
+	return pc, state, nil, http.StatusOK
+}
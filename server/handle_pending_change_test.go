@@ -0,0 +1,171 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	pendingAuthor = ClientUser{Name: "Author", Email: "author@example.com"}
+	pendingOwner  = ClientUser{Name: "Owner", Email: "owner@example.com"}
+)
+
+// proposePendingChange PUTs a change to the GDM for a cluster which requires
+// approval, and returns the resulting state manager, store and pending change.
+func proposePendingChange(t *testing.T) (*sous.DummyStateManager, *sous.MemoryPendingChangeStore, *PendingChangeBody) {
+	t.Helper()
+	dep := sous.DeploymentFixture("")
+	dep.Owners = sous.NewOwnerSet(pendingOwner.Email)
+	state := sous.NewState()
+	state.Defs.Clusters = sous.Clusters{
+		dep.ClusterName: {Name: dep.ClusterName, RequireApproval: true},
+	}
+	ms, err := sous.NewDeployments(dep).RawManifests(state.Defs)
+	require.NoError(t, err)
+	state.Manifests = ms
+	sm := &sous.DummyStateManager{State: state}
+	store := sous.NewMemoryPendingChangeStore()
+
+	changed := dep.Clone()
+	changed.NumInstances++
+	bs, err := json.Marshal(GDMWrapper{Deployments: []*sous.Deployment{changed}})
+	require.NoError(t, err)
+
+	ls, _ := logging.NewLogSinkSpy()
+	h := &PUTGDMHandler{
+		Request:        httptest.NewRequest("PUT", "http://sous.example.com/gdm", bytes.NewBuffer(bs)),
+		LogSink:        ls,
+		StateManager:   sm,
+		PendingChanges: store,
+		User:           pendingAuthor,
+		routeMap:       routemap(ComponentLocator{}),
+	}
+	body, status := h.Exchange()
+	require.Equal(t, http.StatusAccepted, status, "%v", body)
+	pending, is := body.(*PendingChangeBody)
+	require.True(t, is, "%T is not a *PendingChangeBody", body)
+
+	deps, err := sm.State.Deployments()
+	require.NoError(t, err)
+	written, has := deps.Get(dep.ID())
+	require.True(t, has)
+	assert.Equal(t, dep.NumInstances, written.NumInstances, "change was written before approval")
+
+	return sm, store, pending
+}
+
+func reviewPendingChange(t *testing.T, sm sous.StateManager, store sous.PendingChangeStore, id sous.PendingChangeID, reviewer ClientUser, status sous.PendingStatus) (interface{}, int) {
+	t.Helper()
+	bs, err := json.Marshal(PendingChangeBody{PendingChange: sous.PendingChange{Status: status}})
+	require.NoError(t, err)
+	ls, _ := logging.NewLogSinkSpy()
+	h := &PUTPendingChangeHandler{
+		pendingChange: pendingChange{
+			LogSink:      ls,
+			Store:        store,
+			StateManager: sm,
+			ID:           id,
+		},
+		Request: httptest.NewRequest("PUT", "http://sous.example.com/pending-change?id="+string(id), bytes.NewBuffer(bs)),
+		User:    reviewer,
+	}
+	return h.Exchange()
+}
+
+func TestHandlesGDMPutPendingApproval(t *testing.T) {
+	_, store, pending := proposePendingChange(t)
+
+	assert.Equal(t, pendingAuthor.Email, pending.Author.Email)
+	assert.Equal(t, sous.PendingStatusPending, pending.Status)
+	assert.Len(t, pending.Changes, 1)
+
+	header := http.Header{}
+	pending.AddHeaders(header)
+	perr := sous.PendingApprovalFromLocation(header.Get("Location"))
+	require.NotNil(t, perr, "no pending change in location %q", header.Get("Location"))
+	assert.Equal(t, pending.ID, perr.ID)
+
+	listed, err := store.ListPendingChanges()
+	require.NoError(t, err)
+	assert.Len(t, listed, 1)
+}
+
+func TestHandlesPendingChangePut(t *testing.T) {
+	t.Run("author cannot approve", func(t *testing.T) {
+		sm, store, pending := proposePendingChange(t)
+		_, status := reviewPendingChange(t, sm, store, pending.ID, pendingAuthor, sous.PendingStatusApproved)
+		assert.Equal(t, http.StatusForbidden, status)
+	})
+
+	t.Run("owner approves", func(t *testing.T) {
+		sm, store, pending := proposePendingChange(t)
+		writes := sm.WriteCount
+		body, status := reviewPendingChange(t, sm, store, pending.ID, pendingOwner, sous.PendingStatusApproved)
+		require.Equal(t, http.StatusOK, status, "%v", body)
+		assert.Equal(t, writes+1, sm.WriteCount)
+
+		deps, err := sm.State.Deployments()
+		require.NoError(t, err)
+		for _, pd := range pending.Deployments {
+			written, has := deps.Get(pd.DeploymentID)
+			require.True(t, has)
+			assert.Equal(t, pd.Deployment.NumInstances, written.NumInstances)
+		}
+
+		read, err := store.ReadPendingChange(pending.ID)
+		require.NoError(t, err)
+		assert.Equal(t, sous.PendingStatusApproved, read.Status)
+		assert.Equal(t, pendingOwner.Email, read.Reviewer.Email)
+
+		_, status = reviewPendingChange(t, sm, store, pending.ID, pendingOwner, sous.PendingStatusRejected)
+		assert.Equal(t, http.StatusConflict, status)
+	})
+
+	t.Run("author rejects", func(t *testing.T) {
+		sm, store, pending := proposePendingChange(t)
+		writes := sm.WriteCount
+		_, status := reviewPendingChange(t, sm, store, pending.ID, pendingAuthor, sous.PendingStatusRejected)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, writes, sm.WriteCount)
+	})
+
+	t.Run("stale change", func(t *testing.T) {
+		sm, store, pending := proposePendingChange(t)
+		for _, m := range sm.State.Manifests.Snapshot() {
+			for cluster, spec := range m.Deployments {
+				spec.NumInstances += 10
+				m.Deployments[cluster] = spec
+			}
+		}
+		writes := sm.WriteCount
+		body, status := reviewPendingChange(t, sm, store, pending.ID, pendingOwner, sous.PendingStatusApproved)
+		assert.Equal(t, http.StatusConflict, status, "%v", body)
+		assert.Contains(t, body, "stale")
+		assert.Equal(t, writes, sm.WriteCount)
+
+		read, err := store.ReadPendingChange(pending.ID)
+		require.NoError(t, err)
+		assert.Equal(t, sous.PendingStatusPending, read.Status)
+	})
+
+	t.Run("unknown change", func(t *testing.T) {
+		sm, store, _ := proposePendingChange(t)
+		_, status := reviewPendingChange(t, sm, store, "missing", pendingOwner, sous.PendingStatusApproved)
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
+
+func TestHandlesPendingChangeGetWithoutID(t *testing.T) {
+	r := newPendingChangeResource(ComponentLocator{PendingChanges: sous.NewMemoryPendingChangeStore()})
+	req := httptest.NewRequest("GET", "http://sous.example.com/pending-change", nil)
+	_, status := r.Get(nil, nil, req, nil).Exchange()
+	assert.Equal(t, http.StatusBadRequest, status)
+}
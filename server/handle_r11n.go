@@ -7,6 +7,8 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
 	"github.com/opentable/sous/util/restful"
 )
 
@@ -25,6 +27,20 @@ type (
 		R11nID            sous.R11nID
 		R11nIDErr         error
 	}
+
+	// DELETER11nHandler handles cancelling r11ns which are still queued, and
+	// staged rollouts in progress.
+	DELETER11nHandler struct {
+		userExtractor
+		QueueSet        sous.QueueSet
+		StateReader     sous.StateReader
+		Authorizer      Authorizer
+		LogSink         logging.LogSink
+		DeploymentID    sous.DeploymentID
+		DeploymentIDErr error
+		R11nID          sous.R11nID
+		req             *http.Request
+	}
 )
 
 func newR11nResource(ctx ComponentLocator) *R11nResource {
@@ -54,6 +70,66 @@ func (r *R11nResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req *http
 	}
 }
 
+// Delete returns a configured DELETER11nHandler.
+func (r *R11nResource) Delete(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	did, didErr := deploymentIDFromValues(restful.QueryValues{Values: req.URL.Query()})
+	rid, _ := r11nIDFromRoute(req)
+	return &DELETER11nHandler{
+		userExtractor:   newUserExtractor(r.context),
+		QueueSet:        r.context.QueueSet,
+		StateReader:     r.context.StateManager,
+		Authorizer:      r.context.Authorizer,
+		LogSink:         r.context.LogSink,
+		DeploymentID:    did,
+		DeploymentIDErr: didErr,
+		R11nID:          rid,
+		req:             req,
+	}
+}
+
+// Exchange cancels the targeted r11n, returning its cancelled resolution and
+// 200. A staged rollout which has started is stopped, unless it has reached
+// its final stage. It returns 409 if the r11n has already started and cannot
+// be stopped, and 404 if it is not queued at all.
+func (h *DELETER11nHandler) Exchange() (interface{}, int) {
+	if h.DeploymentIDErr != nil {
+		return nil, http.StatusNotFound
+	}
+	state, err := h.StateReader.ReadState()
+	if err != nil {
+		return fmt.Sprintf("Failed to read state: %s.", err), http.StatusInternalServerError
+	}
+	clientUser := h.GetUser(h.req)
+	if err := authorize(h.LogSink, h.Authorizer, clientUser, Write{
+		Method:      "DELETE",
+		Resource:    "deploy-queue-item",
+		State:       state,
+		Deployments: []sous.DeploymentID{h.DeploymentID},
+	}); err != nil {
+		return fmt.Sprintf("%s.", err), http.StatusForbidden
+	}
+
+	qr, ok := h.QueueSet.Cancel(h.DeploymentID, h.R11nID)
+	if !ok {
+		queue, ok := h.QueueSet.Queues()[h.DeploymentID]
+		if !ok {
+			return r11nResponse{}, http.StatusNotFound
+		}
+		if _, ok := queue.ByID(h.R11nID); ok {
+			return fmt.Sprintf("Rectification %s has already started, and cannot be stopped.", h.R11nID), http.StatusConflict
+		}
+		return r11nResponse{}, http.StatusNotFound
+	}
+	messages.ReportLogFieldsMessage(
+		fmt.Sprintf("Queued rectification %s of %s cancelled by %s", qr.ID, h.DeploymentID, sous.User(clientUser)),
+		logging.WarningLevel, h.LogSink, h.DeploymentID, qr.ID)
+	rez := qr.Rectification.Resolution
+	return r11nResponse{
+		QueuePosition: qr.Pos,
+		Resolution:    &rez,
+	}, http.StatusOK
+}
+
 // Exchange returns the targeted r11nResponse and 200 if it exists, other
 // non-200 responses otherwise.
 func (h *GETR11nHandler) Exchange() (interface{}, int) {
@@ -80,9 +156,13 @@ func (h *GETR11nHandler) Exchange() (interface{}, int) {
 	if !ok {
 		return r11nResponse{}, http.StatusNotFound
 	}
-	return r11nResponse{
+	rz := r11nResponse{
 		QueuePosition: qr.Pos,
-	}, http.StatusOK
+	}
+	if stage, ok := qr.Rectification.Stage(); ok {
+		rz.RolloutStage = &stage
+	}
+	return rz, http.StatusOK
 }
 
 type r11nResponse struct {
@@ -90,4 +170,6 @@ type r11nResponse struct {
 	// Pointer here is just to allow nil which is a clearer indication of
 	// "nothing to see here" than a JSON-marshalled zero value would be.
 	Resolution *sous.DiffResolution
+	// RolloutStage is the current stage of a staged rollout, or nil.
+	RolloutStage *sous.RolloutStage `json:",omitempty"`
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
 )
 
 // TestNewR11nResource checks that the same queue set passed to the
@@ -301,3 +302,58 @@ func TestGETR11nHandler_Exchange_wait_success(t *testing.T) {
 		}
 	}
 }
+
+func TestDELETER11nHandler_Exchange(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	qh := func(qr *sous.QueuedR11n) sous.DiffResolution {
+		<-block
+		return sous.DiffResolution{}
+	}
+	queues := sous.NewR11nQueueSet(sous.R11nQueueStartWithHandler(qh))
+	started, ok := queues.Push(newR11n("one"))
+	if !ok {
+		t.Fatal("setup failed to push r11n")
+	}
+	waiting, ok := queues.Push(newR11n("one"))
+	if !ok {
+		t.Fatal("setup failed to push r11n")
+	}
+	// Wait for the queue to start the first r11n.
+	queue := queues.Queues()[newDid("one")]
+	for i := 0; queue.Len() != 1 && i < 100; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel := func(did sous.DeploymentID, id sous.R11nID) (interface{}, int) {
+		h := &DELETER11nHandler{
+			QueueSet:     queues,
+			StateReader:  sous.NewDummyStateManager(),
+			LogSink:      logging.SilentLogSet(),
+			DeploymentID: did,
+			R11nID:       id,
+			req:          &http.Request{Header: http.Header{}},
+		}
+		return h.Exchange()
+	}
+
+	if _, status := cancel(newDid("one"), started.ID); status != http.StatusConflict {
+		t.Errorf("got status %d cancelling a started r11n; want %d", status, http.StatusConflict)
+	}
+	if _, status := cancel(newDid("two"), waiting.ID); status != http.StatusNotFound {
+		t.Errorf("got status %d cancelling in the wrong queue; want %d", status, http.StatusNotFound)
+	}
+
+	body, status := cancel(newDid("one"), waiting.ID)
+	if status != http.StatusOK {
+		t.Fatalf("got status %d; want %d", status, http.StatusOK)
+	}
+	rez := body.(r11nResponse).Resolution
+	if rez == nil || rez.Desc != sous.CancelledDiff {
+		t.Errorf("got resolution %v; want %q", rez, sous.CancelledDiff)
+	}
+
+	if _, status := cancel(newDid("one"), waiting.ID); status != http.StatusNotFound {
+		t.Errorf("got status %d cancelling twice; want %d", status, http.StatusNotFound)
+	}
+}
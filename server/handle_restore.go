@@ -0,0 +1,204 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+type (
+	// RestoreResource describes the restoration of the GDM, or a single
+	// manifest, to an earlier point in time.
+	//
+	// GET describes what a restoration would change; PUT performs it. Since
+	// the description includes the etag of the current GDM, a PUT is rejected
+	// if the GDM has changed since the GET.
+	RestoreResource struct {
+		userExtractor
+		context ComponentLocator
+	}
+
+	// GETRestoreHandler handles GET exchanges for restorations.
+	GETRestoreHandler struct {
+		restoration
+	}
+
+	// PUTRestoreHandler handles PUT exchanges for restorations.
+	PUTRestoreHandler struct {
+		restoration
+		*http.Request
+		StateWriter    sous.StateWriter
+		PendingChanges sous.PendingChangeStore
+		Authorizer     Authorizer
+		User           ClientUser
+		routeMap       *restful.RouteMap
+	}
+
+	restoration struct {
+		logging.LogSink
+		State          *sous.State
+		StateHistorian sous.StateHistorian
+		PointInTime    sous.PointInTime
+		ManifestID     *sous.ManifestID
+		QueryErr       error
+	}
+)
+
+func newRestoreResource(ctx ComponentLocator) *RestoreResource {
+	return &RestoreResource{userExtractor: newUserExtractor(ctx), context: ctx}
+}
+
+func (r *RestoreResource) restoration(req *http.Request) restoration {
+	qv := restful.QueryValues{Values: req.URL.Query()}
+	pit, mid, err := restoreParamsFromValues(qv)
+	return restoration{
+		LogSink:        r.context.LogSink,
+		State:          r.context.liveState(),
+		StateHistorian: r.context.StateHistorian,
+		PointInTime:    pit,
+		ManifestID:     mid,
+		QueryErr:       err,
+	}
+}
+
+// Get returns a configured GETRestoreHandler.
+func (r *RestoreResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETRestoreHandler{restoration: r.restoration(req)}
+}
+
+// Put returns a configured PUTRestoreHandler.
+func (r *RestoreResource) Put(rm *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &PUTRestoreHandler{
+		restoration:    r.restoration(req),
+		Request:        req,
+		StateWriter:    r.context.StateManager,
+		PendingChanges: r.context.PendingChanges,
+		Authorizer:     r.context.Authorizer,
+		User:           r.GetUser(req),
+		routeMap:       rm,
+	}
+}
+
+// Exchange returns a RestoreBody describing the restoration.
+func (h *GETRestoreHandler) Exchange() (interface{}, int) {
+	_, body, status := h.restore()
+	return body, status
+}
+
+// Exchange writes the restored state, and returns a RestoreBody describing
+// the restoration. The restored deployments are checked as any other write to
+// the GDM would be; those to clusters which require approval are held as a
+// pending change, which is described by the response instead.
+func (h *PUTRestoreHandler) Exchange() (interface{}, int) {
+	restored, body, status := h.restore()
+	if status != http.StatusOK {
+		return body, status
+	}
+	current, err := h.State.Deployments()
+	if err != nil {
+		return err.Error(), http.StatusInternalServerError
+	}
+	intended, err := restored.Deployments()
+	if err != nil {
+		return err.Error(), http.StatusInternalServerError
+	}
+
+	w := Write{Method: "PUT", Resource: "restore", State: h.State}
+	if h.ManifestID != nil {
+		w.Manifests = []sous.ManifestID{*h.ManifestID}
+	}
+	gate := writeGate{
+		LogSink:        h.LogSink,
+		Request:        h.Request,
+		User:           h.User,
+		Authorizer:     h.Authorizer,
+		PendingChanges: h.PendingChanges,
+		RouteMap:       h.routeMap,
+	}
+	allowed, pending, rejected := gate.check(w, current, intended)
+	if rejected != nil {
+		return rejected.Error(), rejected.Status
+	}
+	if restored.Manifests, err = allowed.PutbackManifests(restored.Defs, restored.Manifests); err != nil {
+		return err.Error(), http.StatusInternalServerError
+	}
+
+	if err := h.StateWriter.WriteState(restored, sous.User(h.User)); err != nil {
+		logging.ReportError(h.LogSink, errors.Wrapf(err, "writing restored state"))
+		return errors.Wrapf(err, "state recording collision - retry").Error(), http.StatusConflict
+	}
+	if pending != nil {
+		return pending, http.StatusAccepted
+	}
+	return body, http.StatusOK
+}
+
+func (h restoration) restore() (*sous.State, interface{}, int) {
+	if h.QueryErr != nil {
+		return nil, h.QueryErr.Error(), http.StatusBadRequest
+	}
+	if h.StateHistorian == nil {
+		return nil, "earlier states are not available from this server", http.StatusServiceUnavailable
+	}
+	if h.State == nil {
+		return nil, "error reading current state", http.StatusInternalServerError
+	}
+
+	past, err := h.StateHistorian.ReadStateAsOf(h.PointInTime)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading state as of %s", h.PointInTime).Error(), http.StatusNotFound
+	}
+	if h.ManifestID != nil {
+		if _, has := h.State.Manifests.Get(*h.ManifestID); !has {
+			if _, had := past.Manifests.Get(*h.ManifestID); !had {
+				return nil, "no such manifest now or then", http.StatusNotFound
+			}
+		}
+	}
+
+	restored := h.State.Restore(past, h.ManifestID)
+	changes, err := h.State.RestoreChanges(restored)
+	if err != nil {
+		return nil, err.Error(), http.StatusInternalServerError
+	}
+
+	body := &RestoreBody{Changes: changes}
+	body.Revision, _ = past.GetEtag()
+	body.Base, _ = h.State.GetEtag()
+	return restored, body, http.StatusOK
+}
+
+func restoreParamsFromValues(qv restful.QueryValues) (sous.PointInTime, *sous.ManifestID, error) {
+	pit := sous.PointInTime{}
+
+	var err error
+	if pit.Revision, err = qv.Single("revision", ""); err != nil {
+		return pit, nil, err
+	}
+	at, err := qv.Single("at", "")
+	if err != nil {
+		return pit, nil, err
+	}
+	if at != "" {
+		if pit.At, err = time.Parse(time.RFC3339, at); err != nil {
+			return pit, nil, errors.Wrapf(err, "parsing at")
+		}
+	}
+	if err := pit.Validate(); err != nil {
+		return pit, nil, err
+	}
+
+	if repo, _ := qv.Single("repo", ""); repo == "" {
+		return pit, nil, nil
+	}
+	mid, err := manifestIDFromValues(qv)
+	if err != nil {
+		return pit, nil, err
+	}
+	return pit, &mid, nil
+}
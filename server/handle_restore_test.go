@@ -0,0 +1,151 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stateHistorianFunc func(sous.PointInTime) (*sous.State, error)
+
+func (f stateHistorianFunc) ReadStateAsOf(pit sous.PointInTime) (*sous.State, error) {
+	return f(pit)
+}
+
+type recordingStateWriter struct {
+	written *sous.State
+	err     error
+}
+
+func (w *recordingStateWriter) WriteState(s *sous.State, u sous.User) error {
+	w.written = s
+	return w.err
+}
+
+func restoreRequest() *http.Request {
+	return httptest.NewRequest("PUT", "http://sous.example.com/restore", nil)
+}
+
+func TestRestoreParamsFromValues(t *testing.T) {
+	parse := func(query string) (sous.PointInTime, *sous.ManifestID, error) {
+		req := makeRequestWithQuery(t, query)
+		return restoreParamsFromValues(restful.QueryValues{Values: req.URL.Query()})
+	}
+
+	pit, mid, err := parse("revision=abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", pit.Revision)
+	assert.Nil(t, mid)
+
+	pit, mid, err = parse("at=2018-01-02T15%3A04%3A05Z&repo=github.com%2Fexample%2Fone&flavor=sweet")
+	require.NoError(t, err)
+	assert.Equal(t, 2018, pit.At.Year())
+	require.NotNil(t, mid)
+	assert.Equal(t, "github.com/example/one", mid.Source.Repo)
+	assert.Equal(t, "sweet", mid.Flavor)
+
+	_, _, err = parse("")
+	assert.Error(t, err)
+	_, _, err = parse("revision=abc&at=2018-01-02T15%3A04%3A05Z")
+	assert.Error(t, err)
+	_, _, err = parse("at=yesterday")
+	assert.Error(t, err)
+}
+
+func TestRestoreHandlers_Exchange(t *testing.T) {
+	ls, _ := logging.NewLogSinkSpy()
+
+	past := sous.DefaultStateFixture()
+	past.SetEtag("past-rev")
+	current := sous.DefaultStateFixture()
+	current.SetEtag("current-rev")
+	mid := current.Manifests.Keys()[0]
+	current.Manifests.Remove(mid)
+
+	historian := stateHistorianFunc(func(pit sous.PointInTime) (*sous.State, error) {
+		if pit.Revision != "past-rev" {
+			return nil, fmt.Errorf("no such revision %q", pit.Revision)
+		}
+		return past, nil
+	})
+
+	restoration := func(rev string) restoration {
+		return restoration{
+			LogSink:        ls,
+			State:          current,
+			StateHistorian: historian,
+			PointInTime:    sous.PointInTime{Revision: rev},
+		}
+	}
+
+	t.Run("GET", func(t *testing.T) {
+		h := &GETRestoreHandler{restoration: restoration("past-rev")}
+		data, status := h.Exchange()
+		require.Equal(t, 200, status, "%v", data)
+		body := data.(*RestoreBody)
+		assert.Equal(t, "past-rev", body.Revision)
+		assert.Equal(t, "current-rev", body.Base)
+		require.NotEmpty(t, body.Changes)
+		for _, dc := range body.Changes {
+			assert.Equal(t, "added", dc.Kind)
+			assert.Equal(t, mid, dc.DeploymentID.ManifestID)
+		}
+	})
+
+	t.Run("GET unknown revision", func(t *testing.T) {
+		h := &GETRestoreHandler{restoration: restoration("nope")}
+		_, status := h.Exchange()
+		assert.Equal(t, 404, status)
+	})
+
+	t.Run("GET without historian", func(t *testing.T) {
+		r := restoration("past-rev")
+		r.StateHistorian = nil
+		h := &GETRestoreHandler{restoration: r}
+		_, status := h.Exchange()
+		assert.Equal(t, 503, status)
+	})
+
+	t.Run("PUT", func(t *testing.T) {
+		sw := &recordingStateWriter{}
+		h := &PUTRestoreHandler{restoration: restoration("past-rev"), Request: restoreRequest(), StateWriter: sw}
+		data, status := h.Exchange()
+		require.Equal(t, 200, status, "%v", data)
+		require.NotNil(t, sw.written)
+		_, has := sw.written.Manifests.Get(mid)
+		assert.True(t, has)
+		etag, err := sw.written.GetEtag()
+		require.NoError(t, err)
+		assert.Equal(t, "current-rev", etag)
+	})
+
+	t.Run("PUT frozen", func(t *testing.T) {
+		sw := &recordingStateWriter{}
+		r := restoration("past-rev")
+		r.State = current.Clone()
+		r.State.Defs.FreezeWindows = sous.FreezeWindows{{
+			Start:  time.Now().Add(-time.Hour),
+			End:    time.Now().Add(time.Hour),
+			Reason: "holiday",
+		}}
+		h := &PUTRestoreHandler{restoration: r, Request: restoreRequest(), StateWriter: sw}
+		_, status := h.Exchange()
+		assert.Equal(t, http.StatusLocked, status)
+		assert.Nil(t, sw.written)
+	})
+
+	t.Run("PUT collision", func(t *testing.T) {
+		sw := &recordingStateWriter{err: fmt.Errorf("etag mismatch")}
+		h := &PUTRestoreHandler{restoration: restoration("past-rev"), Request: restoreRequest(), StateWriter: sw}
+		_, status := h.Exchange()
+		assert.Equal(t, 409, status)
+	})
+}
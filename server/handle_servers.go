@@ -7,6 +7,7 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/opentable/sous/config"
+	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/restful"
 )
@@ -14,53 +15,92 @@ import (
 type (
 	// ServerListResource dispatches /servers
 	ServerListResource struct {
+		userExtractor
 		context ComponentLocator
 	}
 
 	// ServerListHandler handles GET for /servers
 	ServerListHandler struct {
-		Config *config.Config
+		Config     *config.Config
+		Membership *sous.Membership
+		Leadership *sous.Leadership
 	}
 
 	// ServerListUpdater handles PUT for /servers
 	ServerListUpdater struct {
 		*http.Request
-		Config *config.Config
-		Log    logging.LogSink
+		Config     *config.Config
+		Membership *sous.Membership
+		Log        logging.LogSink
+		Authorizer Authorizer
+		User       ClientUser
 	}
 )
 
 func newServerListResource(context ComponentLocator) *ServerListResource {
-	return &ServerListResource{context: context}
+	return &ServerListResource{userExtractor: newUserExtractor(context), context: context}
 }
 
 // Get implements Getable on ServerListResource, which marks it as accepting GET requests
 func (slr *ServerListResource) Get(*restful.RouteMap, http.ResponseWriter, *http.Request, httprouter.Params) restful.Exchanger {
 	return &ServerListHandler{
-		Config: slr.context.Config,
+		Config:     slr.context.Config,
+		Membership: slr.context.Membership,
+		Leadership: slr.context.Leadership,
 	}
 }
 
 // Put implements Putable on ServerListResource, which marks is as accepting PUT requests
 func (slr *ServerListResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
 	return &ServerListUpdater{
-		Config:  slr.context.Config,
-		Log:     slr.context.LogSink,
-		Request: req,
+		Config:     slr.context.Config,
+		Membership: slr.context.Membership,
+		Log:        slr.context.LogSink,
+		Authorizer: slr.context.Authorizer,
+		User:       slr.GetUser(req),
+		Request:    req,
 	}
 }
 
-// Exchange implements restful.Exchanger on ServerListHandler
+// Exchange implements restful.Exchanger on ServerListHandler. If the server
+// takes part in gossip, it lists the live servers; otherwise, the configured
+// SiblingURLs. Where a cluster has an elected leader, the leader is listed
+// for it, so that clients deploy through the server which rectifies it.
 func (slh *ServerListHandler) Exchange() (interface{}, int) {
 	data := ServerListData{Servers: []NameData{}}
-	for name, url := range slh.Config.SiblingURLs {
+	siblings := slh.Config.SiblingURLs
+	if slh.Membership != nil {
+		siblings = slh.Membership.Siblings()
+	}
+	if slh.Leadership != nil {
+		data.Leaders = slh.Leadership.Leaders()
+		led := map[string]string{}
+		for name, url := range siblings {
+			led[name] = url
+		}
+		for name, leader := range data.Leaders {
+			led[name] = leader
+		}
+		siblings = led
+	}
+	for name, url := range siblings {
 		data.Servers = append(data.Servers, NameData{ClusterName: name, URL: url})
 	}
 	return data, 200
 }
 
-// Exchange implements restful.Exchanger on ServerListUpdater
+// Exchange implements restful.Exchanger on ServerListUpdater. If the server
+// takes part in gossip, the servers are also added as seeds, so that they are
+// listed once they are found to be live.
 func (slh *ServerListUpdater) Exchange() (interface{}, int) {
+	if err := authorize(slh.Log, slh.Authorizer, slh.User, Write{
+		Method:   "PUT",
+		Resource: "servers",
+		Global:   true,
+	}); err != nil {
+		return err.Error(), http.StatusForbidden
+	}
+
 	dec := json.NewDecoder(slh.Request.Body)
 	data := ServerListData{Servers: []NameData{}}
 	dec.Decode(&data)
@@ -73,6 +113,9 @@ func (slh *ServerListUpdater) Exchange() (interface{}, int) {
 
 	for _, server := range data.Servers {
 		slh.Config.SiblingURLs[server.ClusterName] = server.URL
+		if slh.Membership != nil {
+			slh.Membership.AddSeeds(server.URL)
+		}
 	}
 
 	return data, 200
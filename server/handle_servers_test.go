@@ -34,3 +34,23 @@ func TestHandleServerList_Get(t *testing.T) {
 	assert.Equal(list.Servers[0].ClusterName, "left")
 	assert.Equal(list.Servers[1].ClusterName, "right")
 }
+
+func TestHandleServerList_Get_leaders(t *testing.T) {
+	h := &ServerListHandler{
+		Config: &config.Config{
+			SiblingURLs: map[string]string{"left": "https://left-1.sous.com", "right": "https://right.sous.com"},
+		},
+		Leadership: testLeadership("https://left-1.sous.com", map[string]string{"left": "https://left-2.sous.com"}, "left"),
+	}
+
+	rez, stat := h.Exchange()
+	assert.Equal(t, 200, stat)
+	list := rez.(ServerListData)
+
+	assert.Equal(t, map[string]string{"left": "https://left-2.sous.com"}, list.Leaders)
+	urls := map[string]string{}
+	for _, s := range list.Servers {
+		urls[s.ClusterName] = s.URL
+	}
+	assert.Equal(t, map[string]string{"left": "https://left-2.sous.com", "right": "https://right.sous.com"}, urls)
+}
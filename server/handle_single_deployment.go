@@ -7,6 +7,7 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/restful"
 )
 
@@ -20,8 +21,15 @@ type (
 	// specs. See Exchange method for more details.
 	PUTSingleDeploymentHandler struct {
 		SingleDeploymentHandler
-		QueueSet sous.QueueSet
-		routeMap *restful.RouteMap
+		QueueSet       sous.QueueSet
+		StateReader    sous.StateReader
+		PendingChanges sous.PendingChangeStore
+		Authorizer     Authorizer
+		LogSink        logging.LogSink
+		// Leadership, if not nil, says which server rectifies each cluster;
+		// deployments to clusters led by other servers are forwarded to them.
+		Leadership *sous.Leadership
+		routeMap   *restful.RouteMap
 	}
 
 	// GETSingleDeploymentHandler retrieves manifests containing single deployment
@@ -50,6 +58,7 @@ func newSingleDeploymentResource(cl ComponentLocator) *SingleDeploymentResource
 func (sdr *SingleDeploymentResource) newSingleDeploymentHandler(req *http.Request, rw http.ResponseWriter) SingleDeploymentHandler {
 	dm := sdr.context.DeploymentManager
 	return SingleDeploymentHandler{
+		userExtractor:     newUserExtractor(sdr.context),
 		DeploymentManager: dm,
 		responseWriter:    rw,
 		req:               req,
@@ -67,6 +76,11 @@ func (sdr *SingleDeploymentResource) Put(rm *restful.RouteMap, rw http.ResponseW
 	return &PUTSingleDeploymentHandler{
 		SingleDeploymentHandler: sdh,
 		QueueSet:                sdr.context.QueueSet,
+		StateReader:             sdr.context.StateManager,
+		PendingChanges:          sdr.context.PendingChanges,
+		Authorizer:              sdr.context.Authorizer,
+		LogSink:                 sdr.context.LogSink,
+		Leadership:              sdr.context.Leadership,
 		routeMap:                rm,
 	}
 }
@@ -112,13 +126,18 @@ func (sdh *SingleDeploymentHandler) ok(code int, links map[string]string) (Singl
 // Exchange triggers a deployment action when receiving
 // a Manifest containing a deployment matching DeploymentID that differs
 // from the current actual deployment set. It first writes the new
-// deployment spec to the GDM.
+// deployment spec to the GDM. If another server leads the deployment's
+// cluster, the request is forwarded to it instead.
 func (psd *PUTSingleDeploymentHandler) Exchange() (interface{}, int) {
 	did, err := psd.depID()
 	if err != nil {
 		return psd.err(400, "Cannot decode Deployment ID: %s.", err)
 	}
 
+	if leader, ok := leaderFor(psd.Leadership, psd.req, did.Cluster); ok {
+		return forwardToLeader(psd.LogSink, psd.responseWriter, psd.req, psd.Leadership, leader)
+	}
+
 	if err := json.NewDecoder(psd.req.Body).Decode(&psd.Body); err != nil {
 		return psd.err(400, "Error parsing body: %s.", err)
 	}
@@ -138,7 +157,34 @@ func (psd *PUTSingleDeploymentHandler) Exchange() (interface{}, int) {
 		return psd.ok(200, nil)
 	}
 
-	user := sous.User(psd.GetUser(psd.req))
+	state, err := psd.StateReader.ReadState()
+	if err != nil {
+		return psd.err(500, "Failed to read state: %s.", err)
+	}
+	current := sous.NewDeployments(dep)
+	intended := sous.NewDeployments(&psd.Body.Deployment)
+	clientUser := psd.GetUser(psd.req)
+	gate := writeGate{
+		LogSink:        psd.LogSink,
+		Request:        psd.req,
+		User:           clientUser,
+		Authorizer:     psd.Authorizer,
+		PendingChanges: psd.PendingChanges,
+		RouteMap:       psd.routeMap,
+	}
+	_, pending, rejected := gate.check(Write{
+		Method:   "PUT",
+		Resource: "single-deployment",
+		State:    state,
+	}, current, intended)
+	if rejected != nil {
+		return rejected.Error(), rejected.Status
+	}
+	if pending != nil {
+		return pending, http.StatusAccepted
+	}
+
+	user := sous.User(clientUser)
 	if err := psd.DeploymentManager.WriteDeployment(&psd.Body.Deployment, user); err != nil {
 		return psd.err(500, "Failed to write deployment: %s.", err)
 	}
@@ -147,6 +193,7 @@ func (psd *PUTSingleDeploymentHandler) Exchange() (interface{}, int) {
 		Deployment: dep,
 	}})
 	r.Pair.SetID(did)
+	r.Priority = sous.R11nPriorityManual
 
 	qr, ok := psd.QueueSet.Push(r)
 	if !ok {
@@ -9,9 +9,11 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/nyarly/spies"
 	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/restful"
 	"github.com/samsalisbury/semv"
 )
@@ -22,6 +24,7 @@ func TestSingleDeploymentResource(t *testing.T) {
 	cl := ComponentLocator{
 		DeploymentManager: dm,
 		QueueSet:          qs,
+		StateManager:      sous.NewDummyStateManager(),
 	}
 	r := newSingleDeploymentResource(cl)
 
@@ -76,6 +79,9 @@ func TestSingleDeploymentResource(t *testing.T) {
 		if psdh.QueueSet != cl.QueueSet {
 			t.Errorf("PUT handler didn't get the QueueSet")
 		}
+		if psdh.StateReader != cl.StateManager {
+			t.Errorf("PUT handler didn't get the StateReader")
+		}
 		if psdh.routeMap != rm {
 			t.Errorf("PUT handler didn't get the route map")
 		}
@@ -89,6 +95,7 @@ type psdhExScenario struct {
 	status            int
 	deploymentManager *spies.Spy
 	queueSet          *spies.Spy
+	state             *sous.State
 }
 
 func (scn *psdhExScenario) hasDeployment(dep *sous.Deployment) {
@@ -161,14 +168,18 @@ func (scn psdhExScenario) assertNoR11nQueued(t *testing.T) {
 }
 
 func TestPUTSingleDeploymentHandler_Exchange(t *testing.T) {
-	setup := func(sent *SingleDeploymentBody, did map[string]string) *psdhExScenario {
+	setup := func(sent *SingleDeploymentBody, did map[string]string, headers ...string) *psdhExScenario {
 		// Setup
 
 		dmSpy, dmCtrl := sous.NewDeploymentManagerSpy()
 		qs, qsCtrl := sous.NewQueueSetSpy()
+		sm := sous.NewDummyStateManager()
+		ls, _ := logging.NewLogSinkSpy()
 		cl := ComponentLocator{
+			LogSink:           ls,
 			DeploymentManager: dmSpy,
 			QueueSet:          qs,
+			StateManager:      sm,
 		}
 		r := newSingleDeploymentResource(cl)
 
@@ -192,6 +203,9 @@ func TestPUTSingleDeploymentHandler_Exchange(t *testing.T) {
 		req := httptest.NewRequest("PUT", url.String(), body)
 		req.Header.Set("Sous-User-Name", "Test User")
 		req.Header.Set("Sous-User-Email", "testuser@example")
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
 
 		rw := httptest.NewRecorder()
 
@@ -201,6 +215,7 @@ func TestPUTSingleDeploymentHandler_Exchange(t *testing.T) {
 			handler:           psd,
 			deploymentManager: dmCtrl,
 			queueSet:          qsCtrl,
+			state:             sm.State,
 		}
 	}
 
@@ -284,6 +299,50 @@ func TestPUTSingleDeploymentHandler_Exchange(t *testing.T) {
 		scenario.assertStringBody(t, "Failed to write deployment")
 	})
 
+	freeze := sous.FreezeWindow{
+		Start:  time.Now().Add(-time.Hour),
+		End:    time.Now().Add(time.Hour),
+		Reason: "holiday",
+	}
+
+	t.Run("frozen", func(t *testing.T) {
+		dep := sous.DeploymentFixture("")
+		dep.NumInstances = 7
+		scenario := setup(&SingleDeploymentBody{Deployment: *dep}, didQuery("github.com/user1/repo1", "", "cluster1", ""))
+		scenario.state.Defs.FreezeWindows = sous.FreezeWindows{freeze}
+		scenario.hasDeployment(sous.DeploymentFixture(""))
+		scenario.exercise()
+
+		scenario.assertNoR11nQueued(t)
+		scenario.assertStatus(t, 423)
+		scenario.assertStringBody(t, "is frozen until")
+		if calls := scenario.deploymentManager.CallsTo("WriteDeployment"); len(calls) != 0 {
+			t.Errorf("Expected no deployment to be written, but one was.")
+		}
+	})
+
+	t.Run("frozen with override", func(t *testing.T) {
+		dep := sous.DeploymentFixture("")
+		dep.NumInstances = 7
+		scenario := setup(&SingleDeploymentBody{Deployment: *dep}, didQuery("github.com/user1/repo1", "", "cluster1", ""),
+			sous.FreezeOverrideHeader, "urgent fix")
+		scenario.state.Defs.FreezeWindows = sous.FreezeWindows{freeze}
+		scenario.hasDeployment(sous.DeploymentFixture(""))
+		scenario.queueSet.MatchMethod("Push", spies.AnyArgs, &sous.QueuedR11n{ID: "actionid1"}, true)
+		scenario.exercise()
+
+		scenario.assertStatus(t, 201)
+		scenario.assertDeploymentWritten(t)
+		calls := scenario.deploymentManager.CallsTo("WriteDeployment")
+		if len(calls) == 0 {
+			return
+		}
+		written := calls[0].PassedArgs().Get(0).(*sous.Deployment)
+		if written.FreezeOverride == nil || written.FreezeOverride.Reason != "urgent fix" {
+			t.Errorf("Expected the freeze override to be recorded, got %#v", written.FreezeOverride)
+		}
+	})
+
 	t.Run("PushToQueueSet error", func(t *testing.T) {
 		dep := sous.DeploymentFixture("")
 		dep.NumInstances = 7
@@ -6,6 +6,7 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/restful"
 )
 
@@ -28,11 +29,16 @@ type (
 		clusterName string
 		req         *http.Request
 		User        ClientUser
+		state       *sous.State
+		authorizer  Authorizer
+		pending     sous.PendingChangeStore
+		routeMap    *restful.RouteMap
+		log         logging.LogSink
 	}
 )
 
 func newStateDeploymentResource(loc ComponentLocator) *StateDeploymentResource {
-	return &StateDeploymentResource{loc: loc}
+	return &StateDeploymentResource{userExtractor: newUserExtractor(loc), loc: loc}
 }
 
 // Get implements restful.Getable on StateDeployments
@@ -44,12 +50,17 @@ func (res *StateDeploymentResource) Get(*restful.RouteMap, http.ResponseWriter,
 }
 
 // Put implements restful.Putable on StateDeployments
-func (res *StateDeploymentResource) Put(_ *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+func (res *StateDeploymentResource) Put(rm *restful.RouteMap, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
 	return &PUTStateDeployments{
 		cluster:     res.loc.ClusterManager,
 		clusterName: res.loc.ResolveFilter.Cluster.ValueOr("no-cluster"),
 		req:         req,
 		User:        res.GetUser(req),
+		state:       res.loc.liveState(),
+		authorizer:  res.loc.Authorizer,
+		pending:     res.loc.PendingChanges,
+		routeMap:    rm,
+		log:         res.loc.LogSink,
 	}
 }
 
@@ -79,10 +90,31 @@ func (psd *PUTStateDeployments) Exchange() (interface{}, int) {
 
 	deps := sous.NewDeployments(data.Deployments...)
 
-	err = psd.cluster.WriteCluster(psd.clusterName, deps, sous.User(psd.User))
+	current, err := psd.cluster.ReadCluster(psd.clusterName)
 	if err != nil {
 		return err, http.StatusInternalServerError
 	}
 
+	gate := writeGate{
+		LogSink:        psd.log,
+		Request:        psd.req,
+		User:           psd.User,
+		Authorizer:     psd.authorizer,
+		PendingChanges: psd.pending,
+		RouteMap:       psd.routeMap,
+	}
+	allowed, pending, rejected := gate.check(Write{Method: "PUT", Resource: "state-deployments", State: psd.state}, current, deps)
+	if rejected != nil {
+		return rejected.Error(), rejected.Status
+	}
+
+	err = psd.cluster.WriteCluster(psd.clusterName, allowed, sous.User(psd.User))
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+
+	if pending != nil {
+		return pending, http.StatusAccepted
+	}
 	return nil, http.StatusAccepted
 }
@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/nyarly/spies"
 	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
 )
 
 func TestGetStateDeployments(t *testing.T) {
@@ -62,10 +64,13 @@ func TestPutStateDeployments(t *testing.T) {
 		t.Fatal("error building request", err)
 	}
 
+	ctrl.MatchMethod("ReadCluster", spies.AnyArgs, sous.NewDeployments(), nil)
+
 	ex := &PUTStateDeployments{
 		cluster:     cm,
 		clusterName: "test-cluster",
 		req:         req,
+		state:       sous.NewState(),
 	}
 
 	data, status := ex.Exchange()
@@ -83,3 +88,98 @@ func TestPutStateDeployments(t *testing.T) {
 		t.Errorf("No calls to WriteCluster")
 	}
 }
+
+func TestPutStateDeployments_frozen(t *testing.T) {
+	cm, ctrl := sous.NewClusterManagerSpy()
+	ctrl.MatchMethod("ReadCluster", spies.AnyArgs, sous.NewDeployments(), nil)
+	dep := sous.DeploymentFixture("sequenced-repo")
+
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(GDMWrapper{Deployments: []*sous.Deployment{dep}}); err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("PUT", "", buf)
+	if err != nil {
+		t.Fatal("error building request", err)
+	}
+
+	state := sous.NewState()
+	state.Defs.FreezeWindows = sous.FreezeWindows{{
+		Start:  time.Now().Add(-time.Hour),
+		End:    time.Now().Add(time.Hour),
+		Reason: "holiday",
+	}}
+	ex := &PUTStateDeployments{
+		cluster:     cm,
+		clusterName: dep.ClusterName,
+		req:         req,
+		state:       state,
+		log:         logging.SilentLogSet(),
+	}
+
+	if _, status := ex.Exchange(); status != http.StatusLocked {
+		t.Errorf("Expected %d status, got %d", http.StatusLocked, status)
+	}
+	if len(ctrl.CallsTo("WriteCluster")) != 0 {
+		t.Errorf("Frozen deployments were written")
+	}
+}
+
+func TestPutStateDeployments_pendingApproval(t *testing.T) {
+	cm, ctrl := sous.NewClusterManagerSpy()
+	dep := sous.DeploymentFixture("sequenced-repo")
+	ctrl.MatchMethod("ReadCluster", spies.AnyArgs, sous.NewDeployments(dep), nil)
+	ctrl.MatchMethod("WriteCluster", spies.AnyArgs, nil)
+
+	changed := dep.Clone()
+	changed.NumInstances++
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(GDMWrapper{Deployments: []*sous.Deployment{changed}}); err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("PUT", "", buf)
+	if err != nil {
+		t.Fatal("error building request", err)
+	}
+
+	state := sous.NewState()
+	state.Defs.Clusters = sous.Clusters{
+		dep.ClusterName: {Name: dep.ClusterName, RequireApproval: true},
+	}
+	store := sous.NewMemoryPendingChangeStore()
+	ex := &PUTStateDeployments{
+		cluster:     cm,
+		clusterName: dep.ClusterName,
+		req:         req,
+		User:        ClientUser{Name: "Author", Email: "author@example.com"},
+		state:       state,
+		pending:     store,
+		routeMap:    routemap(ComponentLocator{}),
+		log:         logging.SilentLogSet(),
+	}
+
+	data, status := ex.Exchange()
+	if status != http.StatusAccepted {
+		t.Fatalf("Expected %d status, got %d", http.StatusAccepted, status)
+	}
+	if _, is := data.(*PendingChangeBody); !is {
+		t.Fatalf("Expected a *PendingChangeBody, got %T", data)
+	}
+
+	calls := ctrl.CallsTo("WriteCluster")
+	if len(calls) != 1 {
+		t.Fatalf("Expected 1 call to WriteCluster, got %d", len(calls))
+	}
+	written := calls[0].PassedArgs().Get(1).(sous.Deployments)
+	if w, has := written.Get(dep.ID()); !has || w.NumInstances != dep.NumInstances {
+		t.Errorf("Change requiring approval was written")
+	}
+
+	listed, err := store.ListPendingChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 {
+		t.Errorf("Expected 1 pending change, got %d", len(listed))
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+type (
+	// StateReconciliationResource defines the /state-reconciliation
+	// endpoint, which reports how the copies of the GDM kept by the server's
+	// StateManager differ, e.g. while it is being moved to a new storage
+	// backend.
+	StateReconciliationResource struct {
+		context ComponentLocator
+	}
+
+	// GETStateReconciliationHandler handles GET requests to
+	// /state-reconciliation.
+	GETStateReconciliationHandler struct {
+		Reconciler sous.StateReconciler
+		LogSink    logging.LogSink
+	}
+)
+
+func newStateReconciliationResource(ctx ComponentLocator) *StateReconciliationResource {
+	return &StateReconciliationResource{context: ctx}
+}
+
+// Get implements restful.Getter on StateReconciliationResource.
+func (sr *StateReconciliationResource) Get(_ *restful.RouteMap, _ http.ResponseWriter, _ *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETStateReconciliationHandler{
+		Reconciler: sr.context.StateReconciler,
+		LogSink:    sr.context.LogSink,
+	}
+}
+
+// Exchange implements restful.Exchanger on GETStateReconciliationHandler.
+func (h *GETStateReconciliationHandler) Exchange() (interface{}, int) {
+	if h.Reconciler == nil {
+		return "This server keeps only one copy of the GDM", http.StatusServiceUnavailable
+	}
+	cmp, err := h.Reconciler.ReconcileState()
+	if err != nil {
+		logging.ReportError(h.LogSink, errors.Wrapf(err, "reconciling state"))
+		return err.Error(), http.StatusInternalServerError
+	}
+	return StateReconciliationBody{Defs: cmp.Defs, Changes: cmp.Changes}, http.StatusOK
+}
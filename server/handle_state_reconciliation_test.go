@@ -0,0 +1,51 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedReconciler struct {
+	cmp *sous.StateComparison
+	err error
+}
+
+func (r fixedReconciler) ReconcileState() (*sous.StateComparison, error) {
+	return r.cmp, r.err
+}
+
+func TestGETStateReconciliationHandler(t *testing.T) {
+	ls := logging.SilentLogSet()
+
+	t.Run("no reconciler", func(t *testing.T) {
+		th := &GETStateReconciliationHandler{LogSink: ls}
+		_, status := th.Exchange()
+		assert.Equal(t, 503, status)
+	})
+
+	t.Run("differences", func(t *testing.T) {
+		did := sous.DeploymentID{ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/one"}}, Cluster: "east"}
+		cmp := &sous.StateComparison{
+			Defs:    sous.Differences{`cluster "west"; only in this`},
+			Changes: []sous.DeploymentChange{{DeploymentID: did, Kind: "removed"}},
+		}
+		th := &GETStateReconciliationHandler{Reconciler: fixedReconciler{cmp: cmp}, LogSink: ls}
+		data, status := th.Exchange()
+		require.Equal(t, 200, status)
+		body, ok := data.(StateReconciliationBody)
+		require.True(t, ok, "got %T", data)
+		assert.Equal(t, cmp.Defs, body.Defs)
+		assert.Equal(t, cmp.Changes, body.Changes)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		th := &GETStateReconciliationHandler{Reconciler: fixedReconciler{err: errors.New("secondary down")}, LogSink: ls}
+		_, status := th.Exchange()
+		assert.Equal(t, 500, status)
+	})
+}
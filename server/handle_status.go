@@ -2,6 +2,8 @@ package server
 
 import (
 	"net/http"
+	"sort"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/opentable/sous/lib"
@@ -21,7 +23,10 @@ type (
 	}
 
 	statusData struct {
-		Deployments           []*sous.Deployment
+		Deployments []*sous.Deployment
+		// Paused lists the deployments which are paused, and so are not
+		// being rectified.
+		Paused                []sous.DeploymentID `json:",omitempty"`
 		Completed, InProgress *sous.ResolveStatus
 	}
 )
@@ -41,9 +46,16 @@ func (sr *StatusResource) Get(*restful.RouteMap, http.ResponseWriter, *http.Requ
 // Exchange implements the Handler interface.
 func (h *StatusHandler) Exchange() (interface{}, int) {
 	status := statusData{}
+	now := time.Now()
 	for _, d := range h.AutoResolver.GDM.Filter(h.ResolveFilter.FilterDeployment).Snapshot() {
 		status.Deployments = append(status.Deployments, d)
+		if d.Pause.ActiveAt(now) {
+			status.Paused = append(status.Paused, d.ID())
+		}
 	}
+	sort.Slice(status.Paused, func(i, j int) bool {
+		return status.Paused[i].String() < status.Paused[j].String()
+	})
 	status.Completed, status.InProgress = h.AutoResolver.Statuses()
 	return status, http.StatusOK
 }
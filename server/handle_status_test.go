@@ -2,6 +2,7 @@ package server
 
 import (
 	"testing"
+	"time"
 
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
@@ -40,3 +41,22 @@ func TestHandlesStatusGet(t *testing.T) {
 	assert.Equal(status, 200)
 	assert.Len(data.(statusData).Deployments, 0)
 }
+
+func TestHandlesStatusGet_paused(t *testing.T) {
+	paused := sous.DeploymentFixture("")
+	paused.Pause = &sous.Pause{Reason: "incident", Until: time.Now().Add(time.Hour)}
+	lapsed := sous.DeploymentFixture("")
+	lapsed.ClusterName = "other"
+	lapsed.Pause = &sous.Pause{Reason: "incident", Until: time.Now().Add(-time.Hour)}
+
+	th := &StatusHandler{
+		AutoResolver: &sous.AutoResolver{
+			GDM:     sous.NewDeployments(paused, lapsed),
+			LogSink: logging.SilentLogSet(),
+		},
+		ResolveFilter: &sous.ResolveFilter{},
+	}
+	data, status := th.Exchange()
+	require.Equal(t, 200, status)
+	assert.Equal(t, []sous.DeploymentID{paused.ID()}, data.(statusData).Paused)
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/opentable/sous/config"
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+type (
+	// An Identifier identifies the user making a request.
+	Identifier interface {
+		Identify(*http.Request) (ClientUser, error)
+	}
+
+	// HeaderIdentifier identifies users by the Sous-User-Name and
+	// Sous-User-Email headers. Clients may claim to be anyone.
+	HeaderIdentifier struct{}
+
+	// TokenIdentifier identifies users by a token signed with Key, sent in the
+	// Authorization header. Tokens are issued with SignUserToken, and are
+	// refused once they expire.
+	TokenIdentifier struct {
+		Key []byte
+		now func() time.Time
+	}
+
+	// userTokenClaims is the signed payload of a user token. IssuedAt and
+	// Expires are Unix times.
+	userTokenClaims struct {
+		Name, Email string
+		IssuedAt    int64 `json:"iat"`
+		Expires     int64 `json:"exp"`
+	}
+
+	// TLSIdentifier identifies users by their verified TLS client
+	// certificate: its common name is the user's name, and its first email
+	// address is their email.
+	TLSIdentifier struct{}
+)
+
+// NewIdentifier returns the Identifier described by cfg.
+func NewIdentifier(cfg config.AuthorizationConfig) Identifier {
+	switch cfg.Identity {
+	default:
+		return HeaderIdentifier{}
+	case config.IdentityToken:
+		return TokenIdentifier{Key: []byte(cfg.TokenKey)}
+	case config.IdentityTLS:
+		return TLSIdentifier{}
+	}
+}
+
+// Identify implements Identifier on HeaderIdentifier.
+func (HeaderIdentifier) Identify(req *http.Request) (ClientUser, error) {
+	return ClientUser{
+		Name:  req.Header.Get("Sous-User-Name"),
+		Email: req.Header.Get("Sous-User-Email"),
+	}, nil
+}
+
+// DefaultUserTokenTTL is how long a user token lasts unless another TTL is
+// given when it is issued.
+const DefaultUserTokenTTL = 30 * 24 * time.Hour
+
+// SignUserToken returns a token identifying user, signed with key, which
+// expires after ttl.
+func SignUserToken(user sous.User, key []byte, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		return "", errors.Errorf("user token TTL must be positive, not %s", ttl)
+	}
+	now := time.Now()
+	return signUserToken(userTokenClaims{
+		Name:     user.Name,
+		Email:    user.Email,
+		IssuedAt: now.Unix(),
+		Expires:  now.Add(ttl).Unix(),
+	}, key)
+}
+
+func signUserToken(claims userTokenClaims, key []byte) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(tokenMAC(payload, key)), nil
+}
+
+func tokenMAC(payload, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// Identify implements Identifier on TokenIdentifier.
+func (ti TokenIdentifier) Identify(req *http.Request) (ClientUser, error) {
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, sous.UserTokenScheme+" ") {
+		return ClientUser{}, errors.Errorf("no %s in Authorization header", sous.UserTokenScheme)
+	}
+	parts := strings.Split(strings.TrimPrefix(auth, sous.UserTokenScheme+" "), ".")
+	if len(parts) != 2 {
+		return ClientUser{}, errors.New("malformed user token")
+	}
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return ClientUser{}, errors.Wrapf(err, "malformed user token")
+	}
+	sig, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return ClientUser{}, errors.Wrapf(err, "malformed user token")
+	}
+	if !hmac.Equal(sig, tokenMAC(payload, ti.Key)) {
+		return ClientUser{}, errors.New("user token has an invalid signature")
+	}
+	claims := userTokenClaims{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ClientUser{}, errors.Wrapf(err, "malformed user token")
+	}
+	now := time.Now
+	if ti.now != nil {
+		now = ti.now
+	}
+	if claims.Expires == 0 {
+		return ClientUser{}, errors.New("user token has no expiry; issue a new one")
+	}
+	if expires := time.Unix(claims.Expires, 0); !now().Before(expires) {
+		return ClientUser{}, errors.Errorf("user token expired at %s", expires.UTC().Format(time.RFC3339))
+	}
+	return ClientUser{Name: claims.Name, Email: claims.Email}, nil
+}
+
+// Identify implements Identifier on TLSIdentifier.
+func (TLSIdentifier) Identify(req *http.Request) (ClientUser, error) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return ClientUser{}, errors.New("no verified client certificate")
+	}
+	cert := req.TLS.VerifiedChains[0][0]
+	user := ClientUser{Name: cert.Subject.CommonName}
+	if len(cert.EmailAddresses) > 0 {
+		user.Email = cert.EmailAddresses[0]
+	}
+	return user, nil
+}
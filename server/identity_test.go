@@ -0,0 +1,101 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewIdentifier(t *testing.T) {
+	assert.IsType(t, HeaderIdentifier{}, NewIdentifier(config.AuthorizationConfig{}))
+	assert.IsType(t, TokenIdentifier{}, NewIdentifier(config.AuthorizationConfig{Identity: config.IdentityToken}))
+	assert.IsType(t, TLSIdentifier{}, NewIdentifier(config.AuthorizationConfig{Identity: config.IdentityTLS}))
+}
+
+func TestTokenIdentifier(t *testing.T) {
+	user := sous.User{Name: "Test User", Email: "testuser@example.com"}
+	key := []byte("secret")
+	token, err := SignUserToken(user, key, time.Hour)
+	require.NoError(t, err)
+
+	identify := func(key []byte, headers map[string]string) (ClientUser, error) {
+		req := httptest.NewRequest("PUT", "http://sous.example.com/gdm", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return TokenIdentifier{Key: key}.Identify(req)
+	}
+
+	user.Token = token
+	got, err := identify(key, user.HTTPHeaders())
+	require.NoError(t, err)
+	assert.Equal(t, ClientUser{Name: user.Name, Email: user.Email}, got)
+
+	_, err = identify([]byte("other"), user.HTTPHeaders())
+	assert.Error(t, err, "token signed with another key was accepted")
+
+	forged := sous.User{Name: "Test User", Email: "admin@example.com"}
+	forgedToken, err := SignUserToken(forged, []byte("other"), time.Hour)
+	require.NoError(t, err)
+	// The forged user, with the signature of the real one.
+	forgedToken = strings.Split(forgedToken, ".")[0] + "." + strings.Split(token, ".")[1]
+	_, err = identify(key, map[string]string{"Authorization": sous.UserTokenScheme + " " + forgedToken})
+	assert.Error(t, err, "forged token was accepted")
+
+	_, err = identify(key, map[string]string{"Sous-User-Name": "Test User", "Sous-User-Email": "testuser@example.com"})
+	assert.Error(t, err, "plain headers were accepted")
+
+	_, err = SignUserToken(user, key, 0)
+	assert.Error(t, err, "a token was issued which never lasts")
+}
+
+func TestTokenIdentifier_expiry(t *testing.T) {
+	key := []byte("secret")
+	issued := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	token, err := signUserToken(userTokenClaims{
+		Name:     "Test User",
+		Email:    "testuser@example.com",
+		IssuedAt: issued.Unix(),
+		Expires:  issued.Add(time.Hour).Unix(),
+	}, key)
+	require.NoError(t, err)
+	unexpiring, err := signUserToken(userTokenClaims{Name: "Test User", Email: "testuser@example.com"}, key)
+	require.NoError(t, err)
+
+	identify := func(token string, at time.Time) (ClientUser, error) {
+		req := httptest.NewRequest("PUT", "http://sous.example.com/gdm", nil)
+		req.Header.Set("Authorization", sous.UserTokenScheme+" "+token)
+		return TokenIdentifier{Key: key, now: func() time.Time { return at }}.Identify(req)
+	}
+
+	got, err := identify(token, issued.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "testuser@example.com", got.Email)
+	_, err = identify(token, issued.Add(time.Hour))
+	assert.Error(t, err, "an expired token was accepted")
+	_, err = identify(unexpiring, issued)
+	assert.Error(t, err, "a token without an expiry was accepted")
+}
+
+func TestTLSIdentifier(t *testing.T) {
+	req := httptest.NewRequest("PUT", "http://sous.example.com/gdm", nil)
+	_, err := TLSIdentifier{}.Identify(req)
+	assert.Error(t, err)
+
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{
+		Subject:        pkix.Name{CommonName: "Test User"},
+		EmailAddresses: []string{"testuser@example.com"},
+	}}}}
+	user, err := TLSIdentifier{}.Identify(req)
+	require.NoError(t, err)
+	assert.Equal(t, ClientUser{Name: "Test User", Email: "testuser@example.com"}, user)
+}
@@ -1,6 +1,9 @@
 package server
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
 	"net/http"
 	"net/http/pprof"
 	"os"
@@ -15,7 +18,9 @@ import (
 )
 
 type (
-	userExtractor struct{}
+	userExtractor struct {
+		identifier Identifier
+	}
 )
 
 type (
@@ -32,6 +37,33 @@ type (
 		*sous.AutoResolver
 		Version  semv.Version
 		QueueSet sous.QueueSet
+		// HistoryReader reports changes to the GDM; it is nil if the
+		// StateManager does not record history.
+		HistoryReader sous.HistoryReader
+		// StateHistorian reads earlier versions of the GDM; it is nil if the
+		// StateManager cannot.
+		StateHistorian sous.StateHistorian
+		// PendingChanges stores changes awaiting approval; it is nil if the
+		// StateManager cannot.
+		PendingChanges sous.PendingChangeStore
+		// StateReconciler compares the copies of the GDM kept by the
+		// StateManager; it is nil if the StateManager keeps only one.
+		StateReconciler sous.StateReconciler
+		// Identifier identifies the users making requests; if it is nil, users
+		// are identified by the Sous-User-Name and Sous-User-Email headers.
+		Identifier Identifier
+		// Authorizer authorizes writes; if it is nil, any user may make any
+		// write.
+		Authorizer Authorizer
+		// Events publishes the progress of resolution; if it is nil, /events
+		// is not available.
+		Events *sous.ResolveEvents
+		// Membership tracks the live sibling servers by gossip; if it is nil,
+		// the siblings are those in Config.SiblingURLs.
+		Membership *sous.Membership
+		// Leadership elects the server which rectifies each cluster; if it
+		// is nil, this server rectifies every cluster it resolves.
+		Leadership *sous.Leadership
 	}
 )
 
@@ -48,11 +80,21 @@ func (ctx ComponentLocator) liveState() *sous.State {
 	return state
 }
 
-func (userExtractor) GetUser(req *http.Request) ClientUser {
-	return ClientUser{
-		Name:  req.Header.Get("Sous-User-Name"),
-		Email: req.Header.Get("Sous-User-Email"),
+func newUserExtractor(ctx ComponentLocator) userExtractor {
+	return userExtractor{identifier: ctx.Identifier}
+}
+
+// GetUser returns the user making req. If they cannot be identified, it
+// returns the zero ClientUser.
+func (ue userExtractor) GetUser(req *http.Request) ClientUser {
+	if ue.identifier == nil {
+		ue.identifier = HeaderIdentifier{}
+	}
+	user, err := ue.identifier.Identify(req)
+	if err != nil {
+		return ClientUser{}
 	}
+	return user
 }
 
 // Run starts a server up.
@@ -61,6 +103,26 @@ func Run(laddr string, handler http.Handler) error {
 	return s.ListenAndServe()
 }
 
+// RunTLS starts a server up which only accepts HTTPS. If clientCAFile is not
+// empty, clients must present a certificate signed by one of the authorities
+// in it.
+func RunTLS(laddr string, handler http.Handler, certFile, keyFile, clientCAFile string) error {
+	s := &http.Server{Addr: laddr, Handler: handler, TLSConfig: &tls.Config{}}
+	if clientCAFile != "" {
+		pem, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return errors.Wrapf(err, "reading client CA file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.Errorf("no certificates found in %s", clientCAFile)
+		}
+		s.TLSConfig.ClientCAs = pool
+		s.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return s.ListenAndServeTLS(certFile, keyFile)
+}
+
 // Handler builds the http.Handler for the Sous server httprouter.
 func Handler(sc ComponentLocator, metrics http.Handler, ls logging.LogSink) http.Handler {
 	handler := mux(sc, ls)
@@ -81,6 +143,7 @@ func mux(sc ComponentLocator, ls logging.LogSink) *http.ServeMux {
 
 	handler := http.NewServeMux()
 	handler.Handle("/", router)
+	handler.Handle("/events", newEventStream(sc))
 	return handler
 }
 
@@ -92,12 +155,21 @@ func routemap(context ComponentLocator) *restful.RouteMap {
 		re("artifact", "/artifact", newArtifactResource(context))
 		re("status", "/status", newStatusResource(context))
 		re("servers", "/servers", newServerListResource(context))
+		re("gossip", "/gossip", newGossipResource(context))
 		re("health", "/health", newHealthResource(context))
 		re("state-deployments", "/state/deployments", newStateDeploymentResource(context))
 		re("all-deploy-queues", "/all-deploy-queues", newAllDeployQueuesResource(context))
 		re("deploy-queue", "/deploy-queue", newDeployQueueResource(context))
 		re("deploy-queue-item", "/deploy-queue-item", newR11nResource(context))
 		re("single-deployment", "/single-deployment", newSingleDeploymentResource(context))
+		re("history", "/history", newHistoryResource(context))
+		re("restore", "/restore", newRestoreResource(context))
+		re("pending-changes", "/pending-changes", newPendingChangesResource(context))
+		re("pending-change", "/pending-change", newPendingChangeResource(context))
+		re("lint", "/lint", newLintResource(context))
+		re("drift", "/drift", newDriftResource(context))
+		re("pause", "/pause", newPauseResource(context))
+		re("state-reconciliation", "/state-reconciliation", newStateReconciliationResource(context))
 	})
 }
 
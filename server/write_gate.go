@@ -0,0 +1,78 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/sous/util/restful"
+)
+
+type (
+	// A writeGate checks changes to deployments before they are written to the
+	// GDM. Every handler which writes deployments passes its changes through
+	// one, so that however a change arrives it is checked against the Defs,
+	// authorized, kept out of freeze windows, and held for approval where its
+	// cluster requires it.
+	writeGate struct {
+		logging.LogSink
+		Request        *http.Request
+		User           ClientUser
+		Authorizer     Authorizer
+		PendingChanges sous.PendingChangeStore
+		RouteMap       *restful.RouteMap
+	}
+
+	// A writeRejection explains why a writeGate refused a change, and carries
+	// the HTTP status to respond with.
+	writeRejection struct {
+		Status int
+		Reason string
+	}
+)
+
+func (r *writeRejection) Error() string {
+	return r.Reason
+}
+
+// check passes the change from current to intended deployments through the
+// gate. w describes the write for authorization; the deployments it changes
+// are filled in by check. Any freeze override is recorded on the intended
+// deployments.
+//
+// It returns the deployments which may be written now, and the pending change
+// holding the rest for approval, if any. If the change is refused, it returns
+// a writeRejection instead.
+func (g writeGate) check(w Write, current, intended sous.Deployments) (sous.Deployments, *PendingChangeBody, *writeRejection) {
+	if w.State == nil {
+		return sous.NewDeployments(), nil, &writeRejection{http.StatusInternalServerError, "error reading current state"}
+	}
+	defs := w.State.Defs
+
+	w.Deployments = changedDeployments(current, intended)
+	changed := sous.NewDeployments()
+	for _, did := range w.Deployments {
+		if d, has := intended.Get(did); has {
+			changed.Add(d)
+		}
+	}
+	if flaws := checkDefs(defs, changed); len(flaws) > 0 {
+		messages.ReportLogFieldsMessage("Rejected invalid change", logging.ExtraDebug1Level, g.LogSink, flaws)
+		return sous.NewDeployments(), nil, &writeRejection{http.StatusBadRequest, fmt.Sprintf("Invalid deployments: %v", flaws)}
+	}
+	if err := authorize(g.LogSink, g.Authorizer, g.User, w); err != nil {
+		return sous.NewDeployments(), nil, &writeRejection{http.StatusForbidden, err.Error()}
+	}
+	if err := guardFreezes(g.LogSink, g.Request, g.User, defs.FreezeWindows, current, intended); err != nil {
+		messages.ReportLogFieldsMessage(fmt.Sprintf("Rejected frozen change: %s", err), logging.WarningLevel, g.LogSink)
+		return sous.NewDeployments(), nil, &writeRejection{http.StatusLocked, err.Error()}
+	}
+
+	allowed, pending, err := holdForApproval(g.LogSink, g.PendingChanges, g.RouteMap, g.User, defs.Clusters, current, intended)
+	if err != nil {
+		return sous.NewDeployments(), nil, &writeRejection{http.StatusInternalServerError, fmt.Sprintf("Error holding changes for approval: %s", err)}
+	}
+	return allowed, pending, nil
+}